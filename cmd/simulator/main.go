@@ -1 +1,11 @@
-package simulator
+package main
+
+import (
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/simulator"
+)
+
+func main() {
+	conf := config.NewSimulatorConfig()
+	simulator.Main(conf)
+}
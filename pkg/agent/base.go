@@ -2,36 +2,75 @@ package agent
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/ryo-arima/circulator/pkg/agent/repository/api"
 	"github.com/ryo-arima/circulator/pkg/agent/repository/local"
+	agentpulsar "github.com/ryo-arima/circulator/pkg/agent/repository/pulsar"
+	"github.com/ryo-arima/circulator/pkg/agent/usecase"
 	"github.com/ryo-arima/circulator/pkg/config"
 	"github.com/ryo-arima/circulator/pkg/entity/model"
 	"github.com/ryo-arima/circulator/pkg/entity/request"
 )
 
-// Main handles agent operations - registers with server and starts gRPC server
+// Main handles agent operations - registers with server, starts the Pulsar pipeline and
+// serves gRPC until SIGINT or SIGTERM
 func Main(conf config.BaseConfig) {
 	conf.Logger.INFO(config.ABM, "Starting Agent")
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Register agent with server
-	if err := registerAgent(conf); err != nil {
+	agentUUID, err := registerAgent(conf)
+	if err != nil {
 		conf.Logger.FATAL(config.ABME2, "Failed to register agent", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	// Agent output goes through one producer, spooled to disk while Pulsar is unavailable
+	producer, err := agentpulsar.NewProducerRepository(&conf)
+	if err != nil {
+		conf.Logger.FATAL(config.ABME4, "Failed to create Pulsar producer", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Readings from Pulsar and from the gRPC stream endpoint are processed and published
+	// the same way
+	agentUsecase := usecase.NewAgentUsecase(conf, agentUUID, api.NewAPIAgentRepository(conf))
+	streamUsecase := usecase.NewStreamUsecase(conf, agentUUID, agentUsecase, producer)
+	commandUsecase := usecase.NewCommandUsecase(conf, agentUUID, agentUsecase, producer)
+
+	// Process sensor data from Pulsar alongside the gRPC stream endpoint. The producer is
+	// closed only after the pipeline and the gRPC server have stopped publishing.
+	pipelineDone := make(chan struct{})
+	go func() {
+		defer close(pipelineDone)
+		runPipeline(ctx, conf, agentUUID, producer, streamUsecase, commandUsecase)
+	}()
+	defer func() {
+		stop()
+		<-pipelineDone
+		producer.Close()
+	}()
+
 	// Start gRPC server with all registered services
-	if err := StartGRPCServer(conf, "50051"); err != nil {
+	if err := StartGRPCServer(ctx, conf, "50051", streamUsecase); err != nil {
 		conf.Logger.FATAL(config.ABME3, "Failed to start gRPC server", map[string]interface{}{
 			"error": err.Error(),
 		})
 	}
 }
 
-// registerAgent registers this agent with the server on startup
-func registerAgent(conf config.BaseConfig) error {
+// registerAgent registers this agent with the server on startup and returns the agent ID
+// the server assigned
+func registerAgent(conf config.BaseConfig) (string, error) {
 	conf.Logger.INFO(config.ABRA, "Registering agent with server")
 
 	// Create API client for registration
@@ -46,7 +85,7 @@ func registerAgent(conf config.BaseConfig) error {
 		conf.Logger.ERROR(config.ABRAE3, "Failed to get system info", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return err
+		return "", err
 	}
 
 	// Create agent info from system and configuration
@@ -84,12 +123,12 @@ func registerAgent(conf config.BaseConfig) error {
 
 	// Register with server using API client
 	ctx := context.Background()
-	_, err = apiClient.RegisterAgent(ctx, registerReq)
+	registerResp, err := apiClient.RegisterAgent(ctx, registerReq)
 	if err != nil {
 		conf.Logger.ERROR(config.ABRAE4, "Failed to register agent", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return err
+		return "", err
 	}
 
 	conf.Logger.INFO(config.ABRAS, "Agent registration completed", map[string]interface{}{
@@ -97,7 +136,8 @@ func registerAgent(conf config.BaseConfig) error {
 		"ip_address": agentInfo.IPAddress,
 		"port":       agentInfo.Port,
 		"version":    agentInfo.Version,
+		"agent_id":   registerResp.AgentID,
 	})
 
-	return nil
+	return registerResp.AgentID, nil
 }
@@ -2,36 +2,82 @@ package controller
 
 import (
 	"context"
+	"io"
 
-	"github.com/ryo-arima/circulator/pkg/agent/repository/api"
+	pb "github.com/ryo-arima/circulator/pkg/agent/gengrpc"
 	"github.com/ryo-arima/circulator/pkg/agent/usecase"
 	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // StreamController handles gRPC requests for stream processing
 type StreamController struct {
-	config       config.BaseConfig
-	agentUsecase *usecase.AgentUsecase
+	pb.UnimplementedStreamServiceServer
+	config        config.BaseConfig
+	streamUsecase *usecase.StreamUsecase
 }
 
-// NewStreamController creates a new StreamController instance
-func NewStreamController(conf config.BaseConfig) (*StreamController, error) {
-	// Initialize API repository for usecase dependency
-	apiRepo := api.NewAPIAgentRepository(conf)
-
-	agentUsecase := usecase.NewAgentUsecase(conf, apiRepo)
+// NewStreamController creates a new StreamController instance. Results are published
+// through streamUsecase like those of readings consumed from Pulsar.
+func NewStreamController(conf config.BaseConfig, streamUsecase *usecase.StreamUsecase) (*StreamController, error) {
 	return &StreamController{
-		config:       conf,
-		agentUsecase: agentUsecase,
+		config:        conf,
+		streamUsecase: streamUsecase,
 	}, nil
 }
 
-// ProcessStreamData processes incoming stream data through the agent usecase
-func (c *StreamController) ProcessStreamData(ctx context.Context, data config.IncomingAgentData) (*config.ProcessedAgentData, error) {
+// ProcessStreamData processes incoming stream data through the stream usecase
+func (c *StreamController) ProcessStreamData(ctx context.Context, data config.IncomingAgentData) (*model.ProcessedStreamData, error) {
 	c.config.Logger.DEBUG(config.ACAPSD, "Processing stream data via controller", map[string]interface{}{
 		"source":      data.Source,
 		"sensor_type": data.SensorType,
 	})
 
-	return c.agentUsecase.ProcessAgentData(ctx, data)
+	return c.streamUsecase.Process(ctx, data)
+}
+
+// ProcessStream implements the StreamService gRPC endpoint, answering each record with its processing result
+func (c *StreamController) ProcessStream(stream pb.StreamService_ProcessStreamServer) error {
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := c.ProcessStreamData(stream.Context(), config.IncomingAgentData{
+			UUID:       in.GetUuid(),
+			Source:     in.GetSource(),
+			SensorType: in.GetSensorType(),
+			Value:      in.GetValue(),
+			RawPayload: in.GetRawPayload(),
+		})
+		if err != nil {
+			c.config.Logger.ERROR(config.ACAPSE, "Failed to process streamed data", map[string]interface{}{
+				"error": err.Error(),
+				"uuid":  in.GetUuid(),
+			})
+			return status.Errorf(codes.Internal, "failed to process stream data: %v", err)
+		}
+
+		err = stream.Send(&pb.ProcessedStreamData{
+			Uuid:           result.UUID,
+			AgentUuid:      result.AgentUUID,
+			OriginalValue:  result.OriginalValue,
+			ProcessedValue: result.ProcessedValue,
+			Anomaly:        result.Anomaly,
+			Confidence:     result.Confidence,
+			ProcessingTime: result.ProcessingTime,
+			Timestamp:      timestamppb.New(result.Timestamp),
+			SensorType:     result.SensorType,
+		})
+		if err != nil {
+			return err
+		}
+	}
 }
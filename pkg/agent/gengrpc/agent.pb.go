@@ -357,7 +357,7 @@ const file_agent_proto_rawDesc = "" +
 	"\vCreateAgent\x12\x1f.stream_manager.v1.AgentRequest\x1a .stream_manager.v1.AgentResponse\x12P\n" +
 	"\vUpdateAgent\x12\x1f.stream_manager.v1.AgentRequest\x1a .stream_manager.v1.AgentResponse\x12F\n" +
 	"\vDeleteAgent\x12\x1f.stream_manager.v1.AgentRequest\x1a\x16.google.protobuf.Empty\x12S\n" +
-	"\x0eBootstrapAgent\x12\x1f.stream_manager.v1.AgentRequest\x1a .stream_manager.v1.AgentResponseB5Z3github.com/ryo-arima/circulator/pkg/agent/protob\x06proto3"
+	"\x0eBootstrapAgent\x12\x1f.stream_manager.v1.AgentRequest\x1a .stream_manager.v1.AgentResponseB1Z/github.com/ryo-arima/circulator/pkg/agent/protob\x06proto3"
 
 var (
 	file_agent_proto_rawDescOnce sync.Once
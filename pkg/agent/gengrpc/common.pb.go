@@ -401,7 +401,7 @@ const file_common_proto_rawDesc = "" +
 	"token_pair\x18\x01 \x01(\v2\x1c.stream_manager.v1.TokenPairR\ttokenPair2\xbc\x01\n" +
 	"\rCommonService\x12J\n" +
 	"\x05Login\x12\x1f.stream_manager.v1.LoginRequest\x1a .stream_manager.v1.LoginResponse\x12_\n" +
-	"\fRefreshToken\x12&.stream_manager.v1.RefreshTokenRequest\x1a'.stream_manager.v1.RefreshTokenResponseB5Z3github.com/ryo-arima/circulator/pkg/agent/protob\x06proto3"
+	"\fRefreshToken\x12&.stream_manager.v1.RefreshTokenRequest\x1a'.stream_manager.v1.RefreshTokenResponseB1Z/github.com/ryo-arima/circulator/pkg/agent/protob\x06proto3"
 
 var (
 	file_common_proto_rawDescOnce sync.Once
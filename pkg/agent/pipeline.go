@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	agentpulsar "github.com/ryo-arima/circulator/pkg/agent/repository/pulsar"
+	"github.com/ryo-arima/circulator/pkg/agent/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// pipelineRetryInterval is the delay before the pipeline reconnects after a failure
+const pipelineRetryInterval = 5 * time.Second
+
+// runPipeline consumes sensor data and commands from Pulsar and hands them to streamUsecase
+// and commandUsecase until ctx is cancelled, reconnecting whenever the Pulsar connection
+// cannot be set up or is lost. Heartbeats carrying the producer's spool backlog are sent throughout.
+func runPipeline(ctx context.Context, conf config.BaseConfig, agentUUID string, producer agentpulsar.ProducerRepository, streamUsecase *usecase.StreamUsecase, commandUsecase *usecase.CommandUsecase) {
+	heartbeatsDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatsDone)
+		sendHeartbeats(ctx, conf, agentUUID, producer, commandUsecase)
+	}()
+	defer func() { <-heartbeatsDone }()
+
+	for {
+		err := runPipelineOnce(ctx, conf, agentUUID, streamUsecase, commandUsecase)
+		if ctx.Err() != nil {
+			conf.Logger.INFO(config.ABPSTOP, "Agent pipeline stopped", nil)
+			return
+		}
+		if err != nil {
+			conf.Logger.ERROR(config.ABPERR, "Agent pipeline failed", map[string]interface{}{
+				"error": err.Error(),
+				"retry": pipelineRetryInterval.String(),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pipelineRetryInterval):
+		}
+	}
+}
+
+// runPipelineOnce connects to Pulsar and processes sensor readings and commands on one
+// connection. Both go through the consumer's dedup store, so redeliveries are processed once.
+// Once the agent is drained it stops taking readings but keeps applying commands.
+func runPipelineOnce(ctx context.Context, conf config.BaseConfig, agentUUID string, streamUsecase *usecase.StreamUsecase, commandUsecase *usecase.CommandUsecase) error {
+	conf.Logger.INFO(config.ABP, "Agent pipeline starting", map[string]interface{}{
+		"pulsar_url": conf.YamlConfig.Pulsar.URL,
+		"topic":      conf.YamlConfig.Pulsar.Topics.ExternalSensorData,
+	})
+
+	consumer, err := agentpulsar.NewConsumerRepository(&conf, agentUUID)
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Returns once ctx is cancelled
+		consumer.ConsumeCommands(ctx, func(command *model.Command) error {
+			return commandUsecase.HandleCommand(ctx, command)
+		})
+	}()
+	defer wg.Wait()
+
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+	go func() {
+		select {
+		case <-commandUsecase.Drained():
+			cancelStream()
+		case <-streamCtx.Done():
+		}
+	}()
+
+	consumer.ConsumeStreamData(streamCtx, func(data *model.IncomingStreamData) error {
+		return streamUsecase.HandleStreamData(ctx, data)
+	})
+	consumer.CloseStreamData()
+	return nil
+}
+
+// sendHeartbeats publishes a heartbeat with the agent's status every HealthCheckInterval
+// until ctx is cancelled
+func sendHeartbeats(ctx context.Context, conf config.BaseConfig, agentUUID string, producer agentpulsar.ProducerRepository, commandUsecase *usecase.CommandUsecase) {
+	interval := time.Duration(conf.YamlConfig.Application.Agent.HealthCheckInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Failures are logged by the producer; the next tick tries again
+		producer.PublishHeartbeat(agentUUID, commandUsecase.Status())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,17 +1,20 @@
 package agent
 
 import (
+	"context"
 	"net"
 
 	"google.golang.org/grpc"
 
 	"github.com/ryo-arima/circulator/pkg/agent/controller"
+	pb "github.com/ryo-arima/circulator/pkg/agent/gengrpc"
+	"github.com/ryo-arima/circulator/pkg/agent/usecase"
 	"github.com/ryo-arima/circulator/pkg/config"
 )
 
 // RegisterGRPCServices registers all gRPC services with Clean Architecture dependencies
 // Architecture: Controller -> Usecase -> Repository (API/Local/Pulsar) -> Config
-func RegisterGRPCServices(conf config.BaseConfig) *grpc.Server {
+func RegisterGRPCServices(conf config.BaseConfig, streamUsecase *usecase.StreamUsecase) *grpc.Server {
 	conf.Logger.INFO(config.ARSGSR, "Starting gRPC service registration")
 
 	// Create gRPC server
@@ -19,7 +22,7 @@ func RegisterGRPCServices(conf config.BaseConfig) *grpc.Server {
 
 	// Initialize controllers (presentation layer)
 	conf.Logger.DEBUG(config.ARIC, "Initializing controllers")
-	streamController, err := controller.NewStreamController(conf)
+	streamController, err := controller.NewStreamController(conf, streamUsecase)
 	if err != nil {
 		conf.Logger.ERROR(config.ARFISC, "Failed to initialize stream controller", map[string]interface{}{
 			"error": err.Error(),
@@ -28,30 +31,29 @@ func RegisterGRPCServices(conf config.BaseConfig) *grpc.Server {
 	}
 	commonController := controller.NewCommonController(conf)
 
-	// TODO: Register gRPC services when proto files are available
-	// pb.RegisterAgentServiceServer(server, streamController)
-	// pb.RegisterCommonServiceServer(server, commonController)
+	pb.RegisterStreamServiceServer(server, streamController)
 
-	_ = streamController // TODO: Remove these lines when proto services are registered
+	// TODO: Register the common service when CommonController implements it
+	// pb.RegisterCommonServiceServer(server, commonController)
 	_ = commonController
 
 	conf.Logger.INFO(config.ARGRPC, "gRPC services registration setup completed", map[string]interface{}{
-		"stream_controller_type": "initialized",
+		"stream_controller_type": "registered",
 		"common_controller_type": "initialized",
-		"ready_for":              "proto service registration",
 	})
 
 	return server
 }
 
-// StartGRPCServer starts the gRPC server with all registered services
-func StartGRPCServer(conf config.BaseConfig, port string) error {
+// StartGRPCServer starts the gRPC server with all registered services and stops it
+// gracefully once ctx is cancelled
+func StartGRPCServer(ctx context.Context, conf config.BaseConfig, port string, streamUsecase *usecase.StreamUsecase) error {
 	conf.Logger.INFO(config.ARSGRPC, "Starting gRPC server", map[string]interface{}{
 		"port": port,
 	})
 
 	// Register all services
-	server := RegisterGRPCServices(conf)
+	server := RegisterGRPCServices(conf, streamUsecase)
 
 	// Create listener
 	lis, err := net.Listen("tcp", ":"+port)
@@ -67,6 +69,11 @@ func StartGRPCServer(conf config.BaseConfig, port string) error {
 		"port": port,
 	})
 
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
 	// Start serving
 	if err := server.Serve(lis); err != nil {
 		conf.Logger.ERROR(config.ARFTSGRPC, "Failed to serve gRPC server", map[string]interface{}{
@@ -0,0 +1,262 @@
+package local
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+)
+
+// DedupRepository defines the interface for the agent's local idempotency store
+type DedupRepository interface {
+	IsProcessed(key string) bool
+	MarkProcessed(key string) error
+	Close() error
+}
+
+// dedupEntry is a single idempotency key as written to the append-only log
+type dedupEntry struct {
+	Key       string    `json:"key"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// dedupRepository implements DedupRepository with a bounded in-memory index
+// backed by an append-only log, so keys survive an agent restart
+type dedupRepository struct {
+	config     *config.BaseConfig
+	mu         sync.Mutex
+	filePath   string
+	file       *os.File
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	logLines   int
+}
+
+// NewDedupRepository creates a new local dedup repository for agent
+func NewDedupRepository(c *config.BaseConfig, dataDir string) (DedupRepository, error) {
+	maxEntries := c.YamlConfig.Pulsar.Dedup.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+
+	repo := &dedupRepository{
+		config:     c,
+		filePath:   fmt.Sprintf("%s/processed_messages.log", dataDir),
+		ttl:        c.YamlConfig.Pulsar.GetDedupTTL(),
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		c.Logger.ERROR(config.ALDERR, "Failed to create data directory", map[string]interface{}{
+			"error":    err.Error(),
+			"data_dir": dataDir,
+		})
+		return nil, err
+	}
+
+	if err := repo.load(); err != nil {
+		return nil, err
+	}
+
+	// Rewrite the log with only the live keys before appending to it
+	if err := repo.compact(); err != nil {
+		return nil, err
+	}
+
+	c.Logger.DEBUG(config.ALDINIT, "Agent local dedup repository initialized", map[string]interface{}{
+		"data_file":   repo.filePath,
+		"entries":     repo.order.Len(),
+		"max_entries": repo.maxEntries,
+		"ttl":         repo.ttl.String(),
+	})
+
+	return repo, nil
+}
+
+// IsProcessed reports whether key was marked processed and has not expired
+func (r *dedupRepository) IsProcessed(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	element, ok := r.entries[key]
+	if !ok {
+		return false
+	}
+	if time.Now().After(element.Value.(*dedupEntry).ExpiresAt) {
+		r.order.Remove(element)
+		delete(r.entries, key)
+		return false
+	}
+	return true
+}
+
+// MarkProcessed records key as processed for the configured TTL
+func (r *dedupRepository) MarkProcessed(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := &dedupEntry{
+		Key:       key,
+		ExpiresAt: time.Now().Add(r.ttl),
+	}
+	r.add(entry)
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := r.file.Write(append(data, '\n')); err != nil {
+		r.config.Logger.ERROR(config.ALDERR, "Failed to append idempotency key", map[string]interface{}{
+			"error": err.Error(),
+			"key":   key,
+		})
+		return err
+	}
+	r.logLines++
+
+	r.config.Logger.DEBUG(config.ALDMARK, "Agent marked idempotency key processed", map[string]interface{}{
+		"key": key,
+	})
+
+	// Keep the log from growing without bound once evictions start
+	if r.logLines > 2*r.maxEntries {
+		return r.compact()
+	}
+	return nil
+}
+
+// Close closes the underlying log file
+func (r *dedupRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.config.Logger.DEBUG(config.ALDCLOSE, "Closing agent local dedup repository", nil)
+
+	if r.file != nil {
+		if err := r.file.Close(); err != nil {
+			return err
+		}
+		r.file = nil
+	}
+	return nil
+}
+
+// add inserts entry as the most recent key, evicting the oldest beyond maxEntries
+func (r *dedupRepository) add(entry *dedupEntry) {
+	if element, ok := r.entries[entry.Key]; ok {
+		r.order.Remove(element)
+	}
+	r.entries[entry.Key] = r.order.PushBack(entry)
+
+	for r.order.Len() > r.maxEntries {
+		oldest := r.order.Front()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*dedupEntry).Key)
+	}
+}
+
+// load replays the log into memory, skipping expired and unreadable lines
+func (r *dedupRepository) load() error {
+	r.config.Logger.DEBUG(config.ALDLOAD, "Loading idempotency keys", map[string]interface{}{
+		"data_file": r.filePath,
+	})
+
+	file, err := os.Open(r.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		r.config.Logger.ERROR(config.ALDERR, "Failed to open idempotency key log", map[string]interface{}{
+			"error":     err.Error(),
+			"data_file": r.filePath,
+		})
+		return err
+	}
+	defer file.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry dedupEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if now.After(entry.ExpiresAt) {
+			continue
+		}
+		r.add(&entry)
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the log with the live keys and reopens it for appending
+func (r *dedupRepository) compact() error {
+	r.config.Logger.DEBUG(config.ALDCOMP, "Compacting idempotency key log", map[string]interface{}{
+		"data_file": r.filePath,
+		"entries":   r.order.Len(),
+	})
+
+	tmpPath := r.filePath + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		r.config.Logger.ERROR(config.ALDERR, "Failed to create compacted log", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	writer := bufio.NewWriter(tmp)
+	now := time.Now()
+	lines := 0
+	for element := r.order.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*dedupEntry)
+		if now.After(entry.ExpiresAt) {
+			continue
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		writer.Write(append(data, '\n'))
+		lines++
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	if err := os.Rename(tmpPath, r.filePath); err != nil {
+		r.config.Logger.ERROR(config.ALDERR, "Failed to replace idempotency key log", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	file, err := os.OpenFile(r.filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	r.logLines = lines
+
+	r.config.Logger.DEBUG(config.ALDSUCC, "Idempotency key log compacted", map[string]interface{}{
+		"entries": lines,
+	})
+	return nil
+}
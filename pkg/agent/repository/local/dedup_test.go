@@ -0,0 +1,127 @@
+package local
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+)
+
+// newTestConfig returns a config whose logger only reports fatal errors
+func newTestConfig() *config.BaseConfig {
+	conf := &config.BaseConfig{}
+	conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, conf)
+	return conf
+}
+
+func TestDedupRepository(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxEntries    int
+		existing      []dedupEntry // written to the log before the store opens
+		mark          []string
+		reopen        bool
+		wantProcessed []string
+		wantMissing   []string
+	}{
+		{
+			name:          "marked keys are processed",
+			maxEntries:    10,
+			mark:          []string{"a", "b"},
+			wantProcessed: []string{"a", "b"},
+			wantMissing:   []string{"c"},
+		},
+		{
+			name:          "oldest key is evicted beyond max entries",
+			maxEntries:    2,
+			mark:          []string{"a", "b", "c"},
+			wantProcessed: []string{"b", "c"},
+			wantMissing:   []string{"a"},
+		},
+		{
+			name:          "marking again makes a key the newest",
+			maxEntries:    2,
+			mark:          []string{"a", "b", "a", "c"},
+			wantProcessed: []string{"a", "c"},
+			wantMissing:   []string{"b"},
+		},
+		{
+			name:          "keys survive a restart",
+			maxEntries:    10,
+			mark:          []string{"a", "b"},
+			reopen:        true,
+			wantProcessed: []string{"a", "b"},
+		},
+		{
+			name:          "eviction survives a restart",
+			maxEntries:    2,
+			mark:          []string{"a", "b", "c"},
+			reopen:        true,
+			wantProcessed: []string{"b", "c"},
+			wantMissing:   []string{"a"},
+		},
+		{
+			name:       "expired keys in the log are skipped",
+			maxEntries: 10,
+			existing: []dedupEntry{
+				{Key: "old", ExpiresAt: time.Now().Add(-time.Minute)},
+				{Key: "live", ExpiresAt: time.Now().Add(time.Hour)},
+			},
+			wantProcessed: []string{"live"},
+			wantMissing:   []string{"old"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			conf := newTestConfig()
+			conf.YamlConfig.Pulsar.Dedup.MaxEntries = tt.maxEntries
+
+			if len(tt.existing) > 0 {
+				var log []byte
+				for _, entry := range tt.existing {
+					line, err := json.Marshal(entry)
+					if err != nil {
+						t.Fatal(err)
+					}
+					log = append(append(log, line...), '\n')
+				}
+				if err := os.WriteFile(filepath.Join(dir, "processed_messages.log"), log, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			repo, err := NewDedupRepository(conf, dir)
+			if err != nil {
+				t.Fatalf("NewDedupRepository() error = %v", err)
+			}
+			for _, key := range tt.mark {
+				if err := repo.MarkProcessed(key); err != nil {
+					t.Fatalf("MarkProcessed(%q) error = %v", key, err)
+				}
+			}
+			if tt.reopen {
+				repo.Close()
+				if repo, err = NewDedupRepository(conf, dir); err != nil {
+					t.Fatalf("NewDedupRepository() on reopen error = %v", err)
+				}
+			}
+			defer repo.Close()
+
+			for _, key := range tt.wantProcessed {
+				if !repo.IsProcessed(key) {
+					t.Errorf("IsProcessed(%q) = false, want true", key)
+				}
+			}
+			for _, key := range tt.wantMissing {
+				if repo.IsProcessed(key) {
+					t.Errorf("IsProcessed(%q) = true, want false", key)
+				}
+			}
+		})
+	}
+}
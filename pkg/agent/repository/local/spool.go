@@ -0,0 +1,348 @@
+package local
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+)
+
+// Overflow policies applied when the spool reaches its size cap
+const (
+	SpoolDropOldest = "drop_oldest"
+	SpoolDropNewest = "drop_newest"
+	SpoolBlock      = "block"
+)
+
+var (
+	// ErrSpoolFull is returned when a message is dropped by the drop_newest policy
+	ErrSpoolFull = errors.New("spool is full")
+	// ErrSpoolClosed is returned for operations on a closed spool
+	ErrSpoolClosed = errors.New("spool is closed")
+)
+
+// SpoolRepository defines the interface for the agent's disk-backed outgoing message spool
+type SpoolRepository interface {
+	Append(record *SpoolRecord) error
+	Peek() (*SpoolRecord, error)
+	Update(record *SpoolRecord) error
+	Remove(record *SpoolRecord) error
+	Stats() SpoolStats
+	Close() error
+}
+
+// SpoolRecord is an encoded Pulsar message waiting to be sent. SequenceID is the Pulsar
+// sequence ID the message was first sent with, or 0 before any attempt.
+type SpoolRecord struct {
+	Sequence   uint64            `json:"-"`
+	Output     string            `json:"output,omitempty"`
+	SequenceID int64             `json:"sequence_id,omitempty"`
+	Key        string            `json:"key"`
+	Properties map[string]string `json:"properties"`
+	Payload    []byte            `json:"payload"`
+	SpooledAt  time.Time         `json:"spooled_at"`
+}
+
+// SpoolStats describes the current spool backlog
+type SpoolStats struct {
+	Messages int   `json:"spool_messages"`
+	Bytes    int64 `json:"spool_bytes"`
+	Dropped  int64 `json:"spool_dropped"`
+}
+
+// spoolEntry indexes one spooled file
+type spoolEntry struct {
+	sequence uint64
+	size     int64
+}
+
+// spoolRepository implements SpoolRepository with one file per message, named by
+// sequence number so directory order is replay order
+type spoolRepository struct {
+	config   *config.BaseConfig
+	mu       sync.Mutex
+	space    *sync.Cond
+	dir      string
+	maxBytes int64
+	policy   string
+	entries  []spoolEntry
+	bytes    int64
+	dropped  int64
+	nextSeq  uint64
+	closed   bool
+}
+
+// NewSpoolRepository creates a new spool in dataDir/spool, picking up messages left by a previous run
+func NewSpoolRepository(c *config.BaseConfig, dataDir string) (SpoolRepository, error) {
+	spoolConfig := c.YamlConfig.Application.Agent.Spool
+
+	maxBytes := spoolConfig.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 64 * 1024 * 1024
+	}
+	policy := spoolConfig.OverflowPolicy
+	switch policy {
+	case SpoolDropOldest, SpoolDropNewest, SpoolBlock:
+	case "":
+		policy = SpoolDropOldest
+	default:
+		return nil, fmt.Errorf("unknown spool overflow policy: %s", policy)
+	}
+
+	repo := &spoolRepository{
+		config:   c,
+		dir:      filepath.Join(dataDir, "spool"),
+		maxBytes: maxBytes,
+		policy:   policy,
+		nextSeq:  1,
+	}
+	repo.space = sync.NewCond(&repo.mu)
+
+	if err := os.MkdirAll(repo.dir, 0755); err != nil {
+		c.Logger.ERROR(config.ALSPERR, "Failed to create spool directory", map[string]interface{}{
+			"error": err.Error(),
+			"dir":   repo.dir,
+		})
+		return nil, err
+	}
+
+	if err := repo.load(); err != nil {
+		return nil, err
+	}
+
+	c.Logger.INFO(config.ALSPINIT, "Agent spool initialized", map[string]interface{}{
+		"dir":             repo.dir,
+		"max_bytes":       repo.maxBytes,
+		"overflow_policy": repo.policy,
+		"messages":        len(repo.entries),
+		"bytes":           repo.bytes,
+	})
+
+	return repo, nil
+}
+
+// Append writes a record to the end of the spool, applying the overflow policy when full
+func (r *spoolRepository) Append(record *SpoolRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spool record: %w", err)
+	}
+	size := int64(len(data))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrSpoolClosed
+	}
+	if size > r.maxBytes {
+		r.dropped++
+		return ErrSpoolFull
+	}
+
+	for r.bytes+size > r.maxBytes {
+		switch r.policy {
+		case SpoolDropNewest:
+			r.dropped++
+			r.config.Logger.WARN(config.ALSPDROP, "Spool full, dropping newest message", map[string]interface{}{
+				"dropped": r.dropped,
+			})
+			return ErrSpoolFull
+		case SpoolBlock:
+			r.space.Wait()
+			if r.closed {
+				return ErrSpoolClosed
+			}
+		default:
+			oldest := r.entries[0]
+			if err := os.Remove(r.path(oldest.sequence)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			r.entries = r.entries[1:]
+			r.bytes -= oldest.size
+			r.dropped++
+			r.config.Logger.WARN(config.ALSPDROP, "Spool full, dropping oldest message", map[string]interface{}{
+				"sequence": oldest.sequence,
+				"dropped":  r.dropped,
+			})
+		}
+	}
+
+	sequence := r.nextSeq
+	tmpPath := r.path(sequence) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		r.config.Logger.ERROR(config.ALSPERR, "Failed to write spool record", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+	if err := os.Rename(tmpPath, r.path(sequence)); err != nil {
+		return err
+	}
+
+	record.Sequence = sequence
+	r.nextSeq++
+	r.entries = append(r.entries, spoolEntry{sequence: sequence, size: size})
+	r.bytes += size
+
+	r.config.Logger.DEBUG(config.ALSPAPP, "Message spooled", map[string]interface{}{
+		"sequence": sequence,
+		"messages": len(r.entries),
+		"bytes":    r.bytes,
+	})
+	return nil
+}
+
+// Peek returns the oldest record without removing it, or nil when the spool is empty
+func (r *spoolRepository) Peek() (*SpoolRecord, error) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil, ErrSpoolClosed
+	}
+	if len(r.entries) == 0 {
+		r.mu.Unlock()
+		return nil, nil
+	}
+	head := r.entries[0]
+	r.mu.Unlock()
+
+	data, err := os.ReadFile(r.path(head.sequence))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool record %d: %w", head.sequence, err)
+	}
+	var record SpoolRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spool record %d: %w", head.sequence, err)
+	}
+	record.Sequence = head.sequence
+	return &record, nil
+}
+
+// Update rewrites a record that is still spooled, keeping its place in the replay order
+func (r *spoolRepository) Update(record *SpoolRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spool record: %w", err)
+	}
+	size := int64(len(data))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrSpoolClosed
+	}
+	for i, entry := range r.entries {
+		if entry.sequence != record.Sequence {
+			continue
+		}
+		tmpPath := r.path(entry.sequence) + ".tmp"
+		if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+			r.config.Logger.ERROR(config.ALSPERR, "Failed to rewrite spool record", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return err
+		}
+		if err := os.Rename(tmpPath, r.path(entry.sequence)); err != nil {
+			return err
+		}
+		r.bytes += size - entry.size
+		r.entries[i].size = size
+		return nil
+	}
+	return nil
+}
+
+// Remove deletes a record once it has been sent
+func (r *spoolRepository) Remove(record *SpoolRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, entry := range r.entries {
+		if entry.sequence != record.Sequence {
+			continue
+		}
+		if err := os.Remove(r.path(entry.sequence)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		r.entries = append(r.entries[:i], r.entries[i+1:]...)
+		r.bytes -= entry.size
+		r.space.Broadcast()
+		return nil
+	}
+	return nil
+}
+
+// Stats returns the current backlog
+func (r *spoolRepository) Stats() SpoolStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return SpoolStats{
+		Messages: len(r.entries),
+		Bytes:    r.bytes,
+		Dropped:  r.dropped,
+	}
+}
+
+// Close releases writers blocked on a full spool. Spooled files are kept for the next run.
+func (r *spoolRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	r.space.Broadcast()
+
+	r.config.Logger.DEBUG(config.ALSPCLOSE, "Agent spool closed", map[string]interface{}{
+		"messages": len(r.entries),
+	})
+	return nil
+}
+
+// load indexes spooled files in sequence order and removes partial writes
+func (r *spoolRepository) load() error {
+	files, err := os.ReadDir(r.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			os.Remove(filepath.Join(r.dir, name))
+			continue
+		}
+		sequence, err := strconv.ParseUint(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			return err
+		}
+		r.entries = append(r.entries, spoolEntry{sequence: sequence, size: info.Size()})
+		r.bytes += info.Size()
+	}
+
+	sort.Slice(r.entries, func(i, j int) bool {
+		return r.entries[i].sequence < r.entries[j].sequence
+	})
+	if len(r.entries) > 0 {
+		r.nextSeq = r.entries[len(r.entries)-1].sequence + 1
+	}
+	return nil
+}
+
+// path returns the file holding the record with the given sequence number
+func (r *spoolRepository) path(sequence uint64) string {
+	return filepath.Join(r.dir, fmt.Sprintf("%020d.json", sequence))
+}
@@ -0,0 +1,220 @@
+package local
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestRecord returns a record whose encoded size is the same for keys of equal length
+func newTestRecord(key string) *SpoolRecord {
+	return &SpoolRecord{
+		Output:     "reports",
+		Key:        key,
+		Properties: map[string]string{"message-type": "agent_report"},
+		Payload:    []byte("payload"),
+		SpooledAt:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// newTestSpool opens a spool in dir that holds exactly records messages of newTestRecord's size
+func newTestSpool(t *testing.T, dir, policy string, records int) SpoolRepository {
+	t.Helper()
+	data, err := json.Marshal(newTestRecord("k0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conf := newTestConfig()
+	conf.YamlConfig.Application.Agent.Spool.OverflowPolicy = policy
+	conf.YamlConfig.Application.Agent.Spool.MaxBytes = int64(len(data) * records)
+	spool, err := NewSpoolRepository(conf, dir)
+	if err != nil {
+		t.Fatalf("NewSpoolRepository() error = %v", err)
+	}
+	return spool
+}
+
+func TestSpoolOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		wantErrs     []error
+		wantHead     string
+		wantMessages int
+		wantDropped  int64
+	}{
+		{
+			name:         "drop oldest removes the head",
+			policy:       SpoolDropOldest,
+			wantErrs:     []error{nil, nil, nil},
+			wantHead:     "k2",
+			wantMessages: 2,
+			wantDropped:  1,
+		},
+		{
+			name:         "default policy is drop oldest",
+			policy:       "",
+			wantErrs:     []error{nil, nil, nil},
+			wantHead:     "k2",
+			wantMessages: 2,
+			wantDropped:  1,
+		},
+		{
+			name:         "drop newest rejects the new message",
+			policy:       SpoolDropNewest,
+			wantErrs:     []error{nil, nil, ErrSpoolFull},
+			wantHead:     "k1",
+			wantMessages: 2,
+			wantDropped:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spool := newTestSpool(t, t.TempDir(), tt.policy, 2)
+			defer spool.Close()
+
+			for i, wantErr := range tt.wantErrs {
+				key := "k" + string(rune('1'+i))
+				if err := spool.Append(newTestRecord(key)); !errors.Is(err, wantErr) {
+					t.Fatalf("Append(%s) error = %v, want %v", key, err, wantErr)
+				}
+			}
+
+			head, err := spool.Peek()
+			if err != nil {
+				t.Fatalf("Peek() error = %v", err)
+			}
+			if head == nil || head.Key != tt.wantHead {
+				t.Errorf("Peek() = %+v, want key %s", head, tt.wantHead)
+			}
+			stats := spool.Stats()
+			if stats.Messages != tt.wantMessages || stats.Dropped != tt.wantDropped {
+				t.Errorf("Stats() = %+v, want %d messages and %d dropped", stats, tt.wantMessages, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestSpoolBlockPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		release  func(spool SpoolRepository) error
+		wantErr  error
+		wantHead string
+	}{
+		{
+			name: "sending the head makes room",
+			release: func(spool SpoolRepository) error {
+				head, err := spool.Peek()
+				if err != nil {
+					return err
+				}
+				return spool.Remove(head)
+			},
+			wantErr:  nil,
+			wantHead: "k2",
+		},
+		{
+			name:    "closing releases the writer",
+			release: func(spool SpoolRepository) error { return spool.Close() },
+			wantErr: ErrSpoolClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spool := newTestSpool(t, t.TempDir(), SpoolBlock, 2)
+			defer spool.Close()
+
+			for _, key := range []string{"k1", "k2"} {
+				if err := spool.Append(newTestRecord(key)); err != nil {
+					t.Fatalf("Append(%s) error = %v", key, err)
+				}
+			}
+
+			appended := make(chan error, 1)
+			go func() { appended <- spool.Append(newTestRecord("k3")) }()
+			select {
+			case err := <-appended:
+				t.Fatalf("Append(k3) on a full spool returned %v, want it to block", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			if err := tt.release(spool); err != nil {
+				t.Fatalf("release error = %v", err)
+			}
+			select {
+			case err := <-appended:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Append(k3) error = %v, want %v", err, tt.wantErr)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Append(k3) still blocked after release")
+			}
+
+			if tt.wantHead == "" {
+				return
+			}
+			head, err := spool.Peek()
+			if err != nil {
+				t.Fatalf("Peek() error = %v", err)
+			}
+			if head == nil || head.Key != tt.wantHead {
+				t.Errorf("Peek() = %+v, want key %s", head, tt.wantHead)
+			}
+			if stats := spool.Stats(); stats.Messages != 2 || stats.Dropped != 0 {
+				t.Errorf("Stats() = %+v, want 2 messages and none dropped", stats)
+			}
+		})
+	}
+}
+
+func TestSpoolReplayAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	spool := newTestSpool(t, dir, SpoolDropOldest, 10)
+	for _, key := range []string{"k1", "k2", "k3"} {
+		if err := spool.Append(newTestRecord(key)); err != nil {
+			t.Fatalf("Append(%s) error = %v", key, err)
+		}
+	}
+	// The head is sent once with a sequence ID, which has to be kept for its replay
+	head, err := spool.Peek()
+	if err != nil {
+		t.Fatal(err)
+	}
+	head.SequenceID = 42
+	if err := spool.Update(head); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	spool.Close()
+
+	spool = newTestSpool(t, dir, SpoolDropOldest, 10)
+	defer spool.Close()
+
+	tests := []struct {
+		key            string
+		wantSequenceID int64
+	}{
+		{key: "k1", wantSequenceID: 42},
+		{key: "k2"},
+		{key: "k3"},
+	}
+	for _, tt := range tests {
+		record, err := spool.Peek()
+		if err != nil {
+			t.Fatalf("Peek() error = %v", err)
+		}
+		if record == nil || record.Key != tt.key || record.SequenceID != tt.wantSequenceID {
+			t.Fatalf("Peek() = %+v, want key %s with sequence ID %d", record, tt.key, tt.wantSequenceID)
+		}
+		if err := spool.Remove(record); err != nil {
+			t.Fatalf("Remove() error = %v", err)
+		}
+	}
+	if record, err := spool.Peek(); record != nil || err != nil {
+		t.Errorf("Peek() on an empty spool = %+v, %v, want nil, nil", record, err)
+	}
+}
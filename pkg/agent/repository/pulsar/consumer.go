@@ -2,15 +2,20 @@ package pulsar
 
 import (
 	"context"
-	"encoding/json"
 
 	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/ryo-arima/circulator/pkg/agent/repository/local"
 	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/codec"
 	"github.com/ryo-arima/circulator/pkg/entity/model"
 ) // ConsumerRepository defines the interface for Pulsar consumer operations from agent
 type ConsumerRepository interface {
 	ConsumeCommands(ctx context.Context, handler func(*model.Command) error) error
 	ConsumeServerEvents(ctx context.Context, handler func(*model.ServerEvent) error) error
+	ConsumeStreamData(ctx context.Context, handler func(*model.IncomingStreamData) error) error
+	// CloseStreamData closes the stream data consumer, so readings it has prefetched but not
+	// acknowledged go to the other consumers of the subscription. Call it once ConsumeStreamData returned.
+	CloseStreamData()
 	Close() error
 }
 
@@ -20,6 +25,9 @@ type consumerRepository struct {
 	client          pulsar.Client
 	commandConsumer pulsar.Consumer
 	eventConsumer   pulsar.Consumer
+	streamConsumer  pulsar.Consumer
+	codec           codec.Codec
+	dedup           local.DedupRepository
 }
 
 // NewConsumerRepository creates a new Pulsar consumer repository for agent
@@ -39,11 +47,27 @@ func NewConsumerRepository(c *config.BaseConfig, agentID string) (ConsumerReposi
 		return nil, err
 	}
 
+	messageCodec := codec.NewCodec(*c, "agent")
+
+	// Idempotency store so redelivered commands and stream data are not applied twice
+	var dedup local.DedupRepository
+	if c.YamlConfig.Pulsar.Dedup.Enabled {
+		dedup, err = local.NewDedupRepository(c, c.YamlConfig.Application.Agent.DataDir)
+		if err != nil {
+			c.Logger.ERROR(config.ARCERR, "Failed to create dedup repository", map[string]interface{}{
+				"error": err.Error(),
+			})
+			client.Close()
+			return nil, err
+		}
+	}
+
 	// Create consumer for commands directed to this agent
 	commandConsumer, err := client.Subscribe(pulsar.ConsumerOptions{
 		Topic:            "agent-commands",
 		SubscriptionName: "agent-" + agentID,
 		Type:             pulsar.Exclusive,
+		Schema:           messageCodec.Schema("agent-commands"),
 	})
 	if err != nil {
 		c.Logger.ERROR(config.ARCERR, "Failed to create command consumer", map[string]interface{}{
@@ -58,6 +82,7 @@ func NewConsumerRepository(c *config.BaseConfig, agentID string) (ConsumerReposi
 		Topic:            "server-events",
 		SubscriptionName: "agent-events-" + agentID,
 		Type:             pulsar.Shared,
+		Schema:           messageCodec.Schema("server-events"),
 	})
 	if err != nil {
 		c.Logger.ERROR(config.ARCERR, "Failed to create event consumer", map[string]interface{}{
@@ -68,11 +93,30 @@ func NewConsumerRepository(c *config.BaseConfig, agentID string) (ConsumerReposi
 		return nil, err
 	}
 
+	// Create consumer for external sensor data
+	streamTopic := c.YamlConfig.Pulsar.Topics.ExternalSensorData
+	streamOptions := c.YamlConfig.GetPulsarConsumerOptions(streamTopic)
+	streamOptions.Schema = messageCodec.Schema(streamTopic)
+	streamConsumer, err := client.Subscribe(streamOptions)
+	if err != nil {
+		c.Logger.ERROR(config.ARCERR, "Failed to create stream data consumer", map[string]interface{}{
+			"error": err.Error(),
+			"topic": streamTopic,
+		})
+		eventConsumer.Close()
+		commandConsumer.Close()
+		client.Close()
+		return nil, err
+	}
+
 	repo := &consumerRepository{
 		config:          c,
 		client:          client,
 		commandConsumer: commandConsumer,
 		eventConsumer:   eventConsumer,
+		streamConsumer:  streamConsumer,
+		codec:           messageCodec,
+		dedup:           dedup,
 	}
 
 	c.Logger.DEBUG(config.ARCSUCC, "Agent Pulsar consumer initialized successfully", map[string]interface{}{
@@ -104,7 +148,8 @@ func (r *consumerRepository) ConsumeCommands(ctx context.Context, handler func(*
 			})
 
 			var command model.Command
-			if err := json.Unmarshal(msg.Payload(), &command); err != nil {
+			envelope, err := r.codec.Decode(msg.Payload(), msg.Properties(), &command)
+			if err != nil {
 				r.config.Logger.ERROR(config.ARCERR, "Failed to unmarshal command", map[string]interface{}{
 					"error": err.Error(),
 				})
@@ -116,8 +161,19 @@ func (r *consumerRepository) ConsumeCommands(ctx context.Context, handler func(*
 				"command_id":   command.ID,
 				"command_type": command.Type,
 				"target":       command.Target,
+				"trace_id":     envelope.TraceID,
 			})
 
+			key := codec.IdempotencyKey(model.MessageTypeCommand, command.ID, msg.ID())
+			if r.isProcessed(key) {
+				r.config.Logger.INFO(config.ARCDUP, "Agent skipping already processed command", map[string]interface{}{
+					"command_id": command.ID,
+					"message_id": msg.ID().String(),
+				})
+				r.commandConsumer.Ack(msg)
+				continue
+			}
+
 			if err := handler(&command); err != nil {
 				r.config.Logger.ERROR(config.ARCERR, "Failed to process command", map[string]interface{}{
 					"error":        err.Error(),
@@ -128,6 +184,7 @@ func (r *consumerRepository) ConsumeCommands(ctx context.Context, handler func(*
 				continue
 			}
 
+			r.markProcessed(key)
 			r.commandConsumer.Ack(msg)
 			r.config.Logger.DEBUG(config.ARCSUCC, "Agent processed command successfully", map[string]interface{}{
 				"command_id": command.ID,
@@ -159,7 +216,8 @@ func (r *consumerRepository) ConsumeServerEvents(ctx context.Context, handler fu
 			})
 
 			var event model.ServerEvent
-			if err := json.Unmarshal(msg.Payload(), &event); err != nil {
+			envelope, err := r.codec.Decode(msg.Payload(), msg.Properties(), &event)
+			if err != nil {
 				r.config.Logger.ERROR(config.ARCERR, "Failed to unmarshal server event", map[string]interface{}{
 					"error": err.Error(),
 				})
@@ -171,6 +229,7 @@ func (r *consumerRepository) ConsumeServerEvents(ctx context.Context, handler fu
 				"event_id":   event.ID,
 				"event_type": event.Type,
 				"agent_id":   event.AgentID,
+				"trace_id":   envelope.TraceID,
 			})
 
 			if err := handler(&event); err != nil {
@@ -191,6 +250,93 @@ func (r *consumerRepository) ConsumeServerEvents(ctx context.Context, handler fu
 	}
 }
 
+// ConsumeStreamData consumes external sensor data from Pulsar
+func (r *consumerRepository) ConsumeStreamData(ctx context.Context, handler func(*model.IncomingStreamData) error) error {
+	r.config.Logger.DEBUG(config.ARCCONS, "Agent starting stream data consumption", nil)
+
+	for {
+		select {
+		case <-ctx.Done():
+			r.config.Logger.DEBUG(config.ARCSTOP, "Agent stopping stream data consumption", nil)
+			return ctx.Err()
+		default:
+			msg, err := r.streamConsumer.Receive(ctx)
+			if err != nil {
+				r.config.Logger.ERROR(config.ARCERR, "Failed to receive stream data message", map[string]interface{}{
+					"error": err.Error(),
+				})
+				continue
+			}
+
+			var data model.IncomingStreamData
+			envelope, err := r.codec.Decode(msg.Payload(), msg.Properties(), &data)
+			if err != nil {
+				r.config.Logger.ERROR(config.ARCERR, "Failed to unmarshal stream data", map[string]interface{}{
+					"error":      err.Error(),
+					"message_id": msg.ID().String(),
+				})
+				r.streamConsumer.Nack(msg)
+				continue
+			}
+
+			r.config.Logger.DEBUG(config.ARCPROC, "Agent processing stream data", map[string]interface{}{
+				"uuid":        data.UUID,
+				"source":      data.Source,
+				"sensor_type": data.SensorType,
+				"trace_id":    envelope.TraceID,
+			})
+
+			key := codec.IdempotencyKey(model.MessageTypeIncomingStreamData, data.UUID, msg.ID())
+			if r.isProcessed(key) {
+				r.config.Logger.INFO(config.ARCDUP, "Agent skipping already processed stream data", map[string]interface{}{
+					"uuid":       data.UUID,
+					"message_id": msg.ID().String(),
+				})
+				r.streamConsumer.Ack(msg)
+				continue
+			}
+
+			if err := handler(&data); err != nil {
+				r.config.Logger.ERROR(config.ARCERR, "Failed to process stream data", map[string]interface{}{
+					"error": err.Error(),
+					"uuid":  data.UUID,
+				})
+				r.streamConsumer.Nack(msg)
+				continue
+			}
+
+			r.markProcessed(key)
+			r.streamConsumer.Ack(msg)
+		}
+	}
+}
+
+func (r *consumerRepository) CloseStreamData() {
+	if r.streamConsumer != nil {
+		r.streamConsumer.Close()
+		r.streamConsumer = nil
+	}
+}
+
+// isProcessed reports whether key was already handled by this agent
+func (r *consumerRepository) isProcessed(key string) bool {
+	return r.dedup != nil && r.dedup.IsProcessed(key)
+}
+
+// markProcessed records key before the message is acknowledged, so a crash in
+// between results in a redelivery that is recognised as a duplicate
+func (r *consumerRepository) markProcessed(key string) {
+	if r.dedup == nil {
+		return
+	}
+	if err := r.dedup.MarkProcessed(key); err != nil {
+		r.config.Logger.WARN(config.ARCERR, "Failed to record processed message", map[string]interface{}{
+			"error": err.Error(),
+			"key":   key,
+		})
+	}
+}
+
 // Close closes all consumers and client
 func (r *consumerRepository) Close() error {
 	r.config.Logger.DEBUG(config.ARCCLOSE, "Closing Agent Pulsar consumer", nil)
@@ -201,6 +347,12 @@ func (r *consumerRepository) Close() error {
 	if r.eventConsumer != nil {
 		r.eventConsumer.Close()
 	}
+	if r.streamConsumer != nil {
+		r.streamConsumer.Close()
+	}
+	if r.dedup != nil {
+		r.dedup.Close()
+	}
 	if r.client != nil {
 		r.client.Close()
 	}
@@ -3,25 +3,69 @@ package pulsar
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/agent/repository/local"
 	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/codec"
 	"github.com/ryo-arima/circulator/pkg/entity/model"
 )
 
+// errProducerUnavailable is returned while the agent has no connected producer
+var errProducerUnavailable = errors.New("pulsar producer unavailable")
+
+// reportsTopic carries agent reports, notifications and heartbeats
+const reportsTopic = "agent-reports"
+
+// Outputs of the agent. Each has its own named producer, so sequence IDs on one never
+// overtake messages still waiting in the spool for another.
+const (
+	outputReports    = "reports"
+	outputHeartbeats = "heartbeats"
+	outputProcessed  = "processed"
+	outputAlerts     = "alerts"
+)
+
 // ProducerRepository defines the interface for Pulsar producer operations from agent
 type ProducerRepository interface {
 	PublishReport(report *model.AgentReport) error
 	PublishNotification(notification *model.Notification) error
+	PublishProcessedData(data *model.ProcessedStreamData) error
+	PublishAlert(alert *model.AlertData) error
+	PublishHeartbeat(agentID, status string) error
+	Backlog() local.SpoolStats
 	Close() error
 }
 
-// producerRepository implements ProducerRepository
+// outputProducer is the Pulsar producer of one output and the last sequence ID it used
+type outputProducer struct {
+	topic      string
+	name       string
+	producer   pulsar.Producer
+	sequenceID int64
+}
+
+// producerRepository implements ProducerRepository. When a spool is configured, messages
+// that cannot be sent are written to disk and replayed in order once Pulsar is reachable.
 type producerRepository struct {
-	config   *config.BaseConfig
-	client   pulsar.Client
-	producer pulsar.Producer
+	config     *config.BaseConfig
+	client     pulsar.Client
+	mu         sync.Mutex
+	outputs    map[string]*outputProducer
+	sendMu     sync.Mutex
+	ownsClient bool
+	codec      codec.Codec
+	spool      local.SpoolRepository
+	wake       chan struct{}
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
 }
 
 // NewProducerRepository creates a new Pulsar producer repository for agent
@@ -40,28 +84,129 @@ func NewProducerRepository(c *config.BaseConfig) (ProducerRepository, error) {
 		return nil, err
 	}
 
-	producer, err := client.CreateProducer(pulsar.ProducerOptions{
-		Topic:       "agent-reports",
-		SendTimeout: time.Duration(c.YamlConfig.Pulsar.Producer.SendTimeout) * time.Second,
-	})
+	// Stable producer names let the broker match sequence IDs across reconnects and
+	// restarts, so retried sends are dropped when namespace deduplication is enabled
+	instance := ""
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		instance = hostname
+	}
+
+	repo, err := newProducerRepository(c, client, instance)
 	if err != nil {
-		c.Logger.ERROR(config.ARPERR, "Failed to create Pulsar producer", map[string]interface{}{
-			"error": err.Error(),
-		})
 		client.Close()
 		return nil, err
 	}
+	repo.ownsClient = true
+	return repo, nil
+}
+
+// NewProducerRepositoryWithClient creates a producer repository on a shared Pulsar client.
+// Many agents can run in one process this way, each naming its producers after its own
+// instance; the client is left open by Close.
+func NewProducerRepositoryWithClient(c *config.BaseConfig, client pulsar.Client, instance string) (ProducerRepository, error) {
+	c.Logger.DEBUG(config.ARPPINIT, "Initializing Agent Pulsar producer", map[string]interface{}{
+		"instance": instance,
+	})
+	return newProducerRepository(c, client, instance)
+}
+
+func newProducerRepository(c *config.BaseConfig, client pulsar.Client, instance string) (*producerRepository, error) {
+	topics := c.YamlConfig.Pulsar.Topics
+	outputs := map[string]*outputProducer{
+		outputReports:    {topic: reportsTopic, name: "agent-reports"},
+		outputHeartbeats: {topic: reportsTopic, name: "agent-heartbeats"},
+		outputProcessed:  {topic: topics.ProcessedSensorData, name: "agent-processed"},
+		outputAlerts:     {topic: topics.AlertData, name: "agent-alerts"},
+	}
+	if instance != "" {
+		for _, output := range outputs {
+			output.name = output.name + "-" + instance
+		}
+	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	repo := &producerRepository{
-		config:   c,
-		client:   client,
-		producer: producer,
+		config:  c,
+		client:  client,
+		outputs: outputs,
+		codec:   codec.NewCodec(*c, "agent"),
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	spoolConfig := c.YamlConfig.Application.Agent.Spool
+	if spoolConfig.Enabled {
+		spool, err := local.NewSpoolRepository(c, c.YamlConfig.Application.Agent.DataDir)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		repo.spool = spool
+	}
+
+	// Without a spool there is nowhere to keep output, so the report producer must connect
+	// now. With one, the agent starts buffering and the replayer keeps trying to connect.
+	// The other outputs connect on first use.
+	if _, err := repo.connect(outputReports); err != nil {
+		if repo.spool == nil {
+			repo.closeProducers()
+			cancel()
+			return nil, err
+		}
+		c.Logger.WARN(config.ARPSPL, "Pulsar unavailable, agent output will be spooled", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	if repo.spool != nil {
+		repo.wg.Add(1)
+		go repo.replayLoop(spoolRetryInterval(c))
 	}
 
 	c.Logger.DEBUG(config.ARPSUCC, "Agent Pulsar producer initialized successfully", nil)
 	return repo, nil
 }
 
+// connect returns the Pulsar producer of output, creating it on first use
+func (r *producerRepository) connect(output string) (pulsar.Producer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Close cancels ctx before closing the producers, so none is created after it
+	if r.ctx.Err() != nil {
+		return nil, errProducerUnavailable
+	}
+	target := r.outputs[output]
+	if target.producer != nil {
+		return target.producer, nil
+	}
+
+	producer, err := r.client.CreateProducer(pulsar.ProducerOptions{
+		Topic:       target.topic,
+		Name:        target.name,
+		SendTimeout: time.Duration(r.config.YamlConfig.Pulsar.Producer.SendTimeout) * time.Second,
+		Schema:      r.codec.Schema(target.topic),
+	})
+	if err != nil {
+		r.config.Logger.ERROR(config.ARPERR, "Failed to create Pulsar producer", map[string]interface{}{
+			"error": err.Error(),
+			"topic": target.topic,
+		})
+		return nil, err
+	}
+
+	target.producer = producer
+	observeSequenceID(&target.sequenceID, producer.LastSequenceID())
+
+	r.config.Logger.DEBUG(config.ARPSEQ, "Agent Pulsar producer sequence restored", map[string]interface{}{
+		"producer_name":    target.name,
+		"topic":            target.topic,
+		"last_sequence_id": producer.LastSequenceID(),
+	})
+	return producer, nil
+}
+
 // PublishReport publishes an agent report to Pulsar
 func (r *producerRepository) PublishReport(report *model.AgentReport) error {
 	r.config.Logger.DEBUG(config.ARPREP, "Agent publishing report to Pulsar", map[string]interface{}{
@@ -70,28 +215,19 @@ func (r *producerRepository) PublishReport(report *model.AgentReport) error {
 		"report_type": report.Type,
 	})
 
-	data, err := json.Marshal(report)
+	msg, err := r.codec.Encode(reportsTopic, model.MessageTypeAgentReport, "", report)
 	if err != nil {
 		r.config.Logger.ERROR(config.ARPERR, "Failed to marshal agent report", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return err
 	}
+	msg.Key = report.AgentID
+	msg.Properties["type"] = "agent_report"
+	msg.Properties["agent_id"] = report.AgentID
+	msg.Properties["report_id"] = report.ID
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	_, err = r.producer.Send(ctx, &pulsar.ProducerMessage{
-		Payload: data,
-		Key:     report.AgentID,
-		Properties: map[string]string{
-			"type":      "agent_report",
-			"agent_id":  report.AgentID,
-			"report_id": report.ID,
-		},
-	})
-
-	if err != nil {
+	if err := r.send(outputReports, msg); err != nil {
 		r.config.Logger.ERROR(config.ARPERR, "Failed to publish agent report", map[string]interface{}{
 			"error":    err.Error(),
 			"agent_id": report.AgentID,
@@ -115,28 +251,19 @@ func (r *producerRepository) PublishNotification(notification *model.Notificatio
 		"type":            notification.Type,
 	})
 
-	data, err := json.Marshal(notification)
+	msg, err := r.codec.Encode(reportsTopic, model.MessageTypeNotification, "", notification)
 	if err != nil {
 		r.config.Logger.ERROR(config.ARPERR, "Failed to marshal notification", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return err
 	}
+	msg.Key = notification.AgentID
+	msg.Properties["type"] = "notification"
+	msg.Properties["agent_id"] = notification.AgentID
+	msg.Properties["notification_id"] = notification.ID
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	_, err = r.producer.Send(ctx, &pulsar.ProducerMessage{
-		Payload: data,
-		Key:     notification.AgentID,
-		Properties: map[string]string{
-			"type":            "notification",
-			"agent_id":        notification.AgentID,
-			"notification_id": notification.ID,
-		},
-	})
-
-	if err != nil {
+	if err := r.send(outputReports, msg); err != nil {
 		r.config.Logger.ERROR(config.ARPERR, "Failed to publish notification", map[string]interface{}{
 			"error":    err.Error(),
 			"agent_id": notification.AgentID,
@@ -152,17 +279,323 @@ func (r *producerRepository) PublishNotification(notification *model.Notificatio
 	return nil
 }
 
-// Close closes the producer and client
+// PublishProcessedData publishes the processing result of a sensor reading
+func (r *producerRepository) PublishProcessedData(data *model.ProcessedStreamData) error {
+	r.config.Logger.DEBUG(config.ARPPUB, "Agent publishing processed data to Pulsar", map[string]interface{}{
+		"uuid":       data.UUID,
+		"agent_uuid": data.AgentUUID,
+		"anomaly":    data.Anomaly,
+	})
+
+	output := r.outputs[outputProcessed]
+	msg, err := r.codec.Encode(output.topic, model.MessageTypeProcessedStreamData, "", data)
+	if err != nil {
+		r.config.Logger.ERROR(config.ARPERR, "Failed to marshal processed data", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+	msg.Key = data.AgentUUID
+
+	if err := r.send(outputProcessed, msg); err != nil {
+		r.config.Logger.ERROR(config.ARPERR, "Failed to publish processed data", map[string]interface{}{
+			"error": err.Error(),
+			"uuid":  data.UUID,
+		})
+		return err
+	}
+	return nil
+}
+
+// PublishAlert publishes an alert raised for an anomalous reading
+func (r *producerRepository) PublishAlert(alert *model.AlertData) error {
+	r.config.Logger.DEBUG(config.ARPPUB, "Agent publishing alert to Pulsar", map[string]interface{}{
+		"uuid":       alert.UUID,
+		"agent_uuid": alert.AgentUUID,
+		"severity":   alert.Severity,
+	})
+
+	output := r.outputs[outputAlerts]
+	msg, err := r.codec.Encode(output.topic, model.MessageTypeAlertData, "", alert)
+	if err != nil {
+		r.config.Logger.ERROR(config.ARPERR, "Failed to marshal alert", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+	msg.Key = alert.AgentUUID
+
+	if err := r.send(outputAlerts, msg); err != nil {
+		r.config.Logger.ERROR(config.ARPERR, "Failed to publish alert", map[string]interface{}{
+			"error": err.Error(),
+			"uuid":  alert.UUID,
+		})
+		return err
+	}
+	return nil
+}
+
+// PublishHeartbeat publishes a heartbeat report carrying the spool backlog. Heartbeats
+// describe the present, so they are sent directly and never spooled.
+func (r *producerRepository) PublishHeartbeat(agentID, status string) error {
+	backlog := r.Backlog()
+
+	r.config.Logger.DEBUG(config.ARPHB, "Agent publishing heartbeat to Pulsar", map[string]interface{}{
+		"agent_id":       agentID,
+		"status":         status,
+		"spool_messages": backlog.Messages,
+		"spool_bytes":    backlog.Bytes,
+	})
+
+	data, err := json.Marshal(backlog)
+	if err != nil {
+		return err
+	}
+	report := &model.AgentReport{
+		ID:        uuid.New().String(),
+		AgentID:   agentID,
+		Type:      model.ReportTypeHeartbeat,
+		Status:    status,
+		Data:      string(data),
+		Timestamp: time.Now(),
+	}
+
+	msg, err := r.codec.Encode(reportsTopic, model.MessageTypeAgentReport, "", report)
+	if err != nil {
+		r.config.Logger.ERROR(config.ARPERR, "Failed to marshal heartbeat", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+	msg.Key = agentID
+	msg.Properties["type"] = "agent_report"
+	msg.Properties["agent_id"] = agentID
+	msg.Properties["report_id"] = report.ID
+
+	if err := r.sendNow(outputHeartbeats, msg); err != nil {
+		r.config.Logger.WARN(config.ARPERR, "Failed to publish heartbeat", map[string]interface{}{
+			"error":    err.Error(),
+			"agent_id": agentID,
+		})
+		return err
+	}
+	return nil
+}
+
+// Backlog returns the number and size of messages waiting in the spool
+func (r *producerRepository) Backlog() local.SpoolStats {
+	if r.spool == nil {
+		return local.SpoolStats{}
+	}
+	return r.spool.Stats()
+}
+
+// send delivers msg, falling back to the spool when Pulsar is unavailable. While the spool
+// holds a backlog new messages queue behind it so delivery order is preserved. Sends are
+// serialised so sequence IDs reach the broker in the order they were assigned.
+func (r *producerRepository) send(output string, msg *pulsar.ProducerMessage) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	if r.spool == nil {
+		return r.sendNow(output, msg)
+	}
+
+	if r.spool.Stats().Messages == 0 {
+		err := r.sendNow(output, msg)
+		if err == nil {
+			return nil
+		}
+		r.config.Logger.WARN(config.ARPSPL, "Send failed, spooling message", map[string]interface{}{
+			"error": err.Error(),
+			"key":   msg.Key,
+		})
+	}
+
+	// A message that failed after a sequence ID was assigned may still have reached the
+	// broker, so it keeps that ID for the replay to be recognised as a duplicate
+	record := &local.SpoolRecord{
+		Output:     output,
+		Key:        msg.Key,
+		Properties: msg.Properties,
+		Payload:    msg.Payload,
+		SpooledAt:  time.Now(),
+	}
+	if msg.SequenceID != nil {
+		record.SequenceID = *msg.SequenceID
+	}
+	if err := r.spool.Append(record); err != nil {
+		return err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// sendNow sends msg synchronously on the output's producer. A message without a sequence
+// ID is given the next one; a message that already has one is sent with it unchanged.
+func (r *producerRepository) sendNow(output string, msg *pulsar.ProducerMessage) error {
+	producer, err := r.connect(output)
+	if err != nil {
+		return errProducerUnavailable
+	}
+	target := r.outputs[output]
+
+	if msg.SequenceID == nil {
+		next := atomic.AddInt64(&target.sequenceID, 1)
+		msg.SequenceID = &next
+	} else {
+		observeSequenceID(&target.sequenceID, *msg.SequenceID)
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, 30*time.Second)
+	defer cancel()
+
+	_, err = producer.Send(ctx, msg)
+	return err
+}
+
+// replayLoop drains the spool whenever a message is spooled and every interval until Close
+func (r *producerRepository) replayLoop(interval time.Duration) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+		r.replay()
+	}
+}
+
+// replay sends spooled messages oldest first, stopping at the first failure so that
+// order is kept and the rest are retried on the next pass
+func (r *producerRepository) replay() {
+	if r.spool.Stats().Messages == 0 {
+		return
+	}
+
+	replayed := 0
+	for r.ctx.Err() == nil {
+		record, err := r.spool.Peek()
+		if err != nil {
+			r.config.Logger.ERROR(config.ARPERR, "Failed to read spooled message", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		if record == nil {
+			break
+		}
+
+		// Records spooled by earlier versions carry no output and were all reports
+		output := record.Output
+		if _, ok := r.outputs[output]; !ok {
+			output = outputReports
+		}
+
+		// Connecting restores the sequence from the broker before any ID is assigned
+		if _, err := r.connect(output); err != nil {
+			return
+		}
+
+		msg := &pulsar.ProducerMessage{
+			Key:        record.Key,
+			Properties: record.Properties,
+			Payload:    record.Payload,
+		}
+		if record.SequenceID == 0 {
+			// Assign the ID before the first attempt and keep it with the record, so a
+			// send that times out after reaching the broker is retried under the same ID
+			record.SequenceID = atomic.AddInt64(&r.outputs[output].sequenceID, 1)
+			if err := r.spool.Update(record); err != nil {
+				r.config.Logger.ERROR(config.ARPERR, "Failed to record sequence ID of spooled message", map[string]interface{}{
+					"error": err.Error(),
+				})
+				return
+			}
+		}
+		sequenceID := record.SequenceID
+		msg.SequenceID = &sequenceID
+
+		if err := r.sendNow(output, msg); err != nil {
+			r.config.Logger.WARN(config.ARPRPL, "Spool replay interrupted", map[string]interface{}{
+				"error":    err.Error(),
+				"replayed": replayed,
+			})
+			return
+		}
+		if err := r.spool.Remove(record); err != nil {
+			r.config.Logger.ERROR(config.ARPERR, "Failed to remove replayed message from spool", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		replayed++
+	}
+
+	r.config.Logger.INFO(config.ARPRPL, "Spooled messages replayed", map[string]interface{}{
+		"replayed": replayed,
+	})
+}
+
+// spoolRetryInterval returns how often the replayer retries while Pulsar is unavailable
+func spoolRetryInterval(c *config.BaseConfig) time.Duration {
+	if c.YamlConfig.Application.Agent.Spool.RetryInterval <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(c.YamlConfig.Application.Agent.Spool.RetryInterval) * time.Second
+}
+
+// observeSequenceID raises *last to id, so IDs assigned later continue after the last one
+// the broker acknowledged or that a spooled message already carries
+func observeSequenceID(last *int64, id int64) {
+	for {
+		current := atomic.LoadInt64(last)
+		if id <= current || atomic.CompareAndSwapInt64(last, current, id) {
+			return
+		}
+	}
+}
+
+// Close stops the replayer and cancels in-flight sends, then closes the spool, the
+// producers and, unless it is shared, the client. Messages still in the spool are
+// replayed on the next start.
 func (r *producerRepository) Close() error {
 	r.config.Logger.DEBUG(config.ARPCLOSE, "Closing Agent Pulsar producer", nil)
 
-	if r.producer != nil {
-		r.producer.Close()
+	r.cancel()
+	r.wg.Wait()
+
+	if r.spool != nil {
+		r.spool.Close()
 	}
-	if r.client != nil {
+	r.closeProducers()
+	if r.ownsClient {
 		r.client.Close()
 	}
 
 	r.config.Logger.DEBUG(config.ARPSUCC, "Agent Pulsar producer closed successfully", nil)
 	return nil
 }
+
+// closeProducers closes every connected producer
+func (r *producerRepository) closeProducers() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, output := range r.outputs {
+		if output.producer != nil {
+			output.producer.Close()
+			output.producer = nil
+		}
+	}
+}
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/ryo-arima/circulator/pkg/agent/repository/api"
@@ -12,8 +13,15 @@ import (
 
 // AgentUsecase handles stream processing business logic
 type AgentUsecase struct {
-	config config.BaseConfig
-	repo   api.APIAgentRepository
+	config    config.BaseConfig
+	agentUUID string
+	repo      api.APIAgentRepository
+
+	// processingConfig is fetched on first use, fetched again once it is older than
+	// ConfigRefreshInterval and replaced right away by ReloadProcessingConfig
+	mu               sync.Mutex
+	processingConfig *model.AgentProcessingConfig
+	fetchedAt        time.Time
 }
 
 // AgentRepositoryInterface defines the interface for agent data operations
@@ -23,10 +31,11 @@ type AgentRepositoryInterface interface {
 }
 
 // NewAgentUsecase creates a new AgentUsecase instance
-func NewAgentUsecase(conf config.BaseConfig, repo api.APIAgentRepository) *AgentUsecase {
+func NewAgentUsecase(conf config.BaseConfig, agentUUID string, repo api.APIAgentRepository) *AgentUsecase {
 	return &AgentUsecase{
-		config: conf,
-		repo:   repo,
+		config:    conf,
+		agentUUID: agentUUID,
+		repo:      repo,
 	}
 }
 
@@ -50,12 +59,64 @@ func (u *AgentUsecase) GetProcessingConfig(ctx context.Context, agentUUID string
 	return resp.Config, nil
 }
 
-// ProcessAgentData processes incoming stream data
-func (u *AgentUsecase) ProcessAgentData(ctx context.Context, data config.IncomingAgentData) (*config.ProcessedAgentData, error) {
-	startTime := time.Now()
+// ReloadProcessingConfig fetches the processing configuration from the server and uses it
+// for subsequent readings. The previous configuration stays in use if the fetch fails.
+func (u *AgentUsecase) ReloadProcessingConfig(ctx context.Context) error {
+	u.config.Logger.INFO(config.AUARPC, "Reloading processing config", nil)
+	processingConfig, err := u.fetchProcessingConfig(ctx)
+	if err != nil {
+		return err
+	}
+	u.mu.Lock()
+	u.processingConfig = processingConfig
+	u.fetchedAt = time.Now()
+	u.mu.Unlock()
+	return nil
+}
 
-	// Get processing configuration for this agent
-	processingConfigResp, err := u.repo.GetProcessingConfig(ctx, "current-agent-uuid") // Would get from context
+// currentProcessingConfig returns the loaded processing configuration, fetching it first
+// if needed. When a refresh fails the previous configuration stays in use until the next
+// interval, so a server outage does not stop processing.
+func (u *AgentUsecase) currentProcessingConfig(ctx context.Context) (*model.AgentProcessingConfig, error) {
+	u.mu.Lock()
+	processingConfig, fetchedAt := u.processingConfig, u.fetchedAt
+	u.mu.Unlock()
+	if processingConfig != nil && time.Since(fetchedAt) < u.refreshInterval() {
+		return processingConfig, nil
+	}
+
+	fetched, err := u.fetchProcessingConfig(ctx)
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err != nil {
+		if u.processingConfig == nil {
+			return nil, err
+		}
+		u.config.Logger.WARN(config.AUARPE, "Keeping stale processing config", map[string]interface{}{
+			"error":      err.Error(),
+			"agent_uuid": u.agentUUID,
+		})
+		u.fetchedAt = time.Now()
+		return u.processingConfig, nil
+	}
+	// A reload that finished during the fetch is at least as recent
+	if u.processingConfig == nil || !u.fetchedAt.After(fetchedAt) {
+		u.processingConfig = fetched
+		u.fetchedAt = time.Now()
+	}
+	return u.processingConfig, nil
+}
+
+func (u *AgentUsecase) refreshInterval() time.Duration {
+	seconds := u.config.YamlConfig.Application.Agent.ConfigRefreshInterval
+	if seconds <= 0 {
+		seconds = 60
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func (u *AgentUsecase) fetchProcessingConfig(ctx context.Context) (*model.AgentProcessingConfig, error) {
+	processingConfigResp, err := u.repo.GetProcessingConfig(ctx, u.agentUUID)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +128,18 @@ func (u *AgentUsecase) ProcessAgentData(ctx context.Context, data config.Incomin
 			ProcessingRules: []map[string]interface{}{},
 		}
 	}
+	return processingConfig, nil
+}
+
+// ProcessAgentData processes incoming stream data
+func (u *AgentUsecase) ProcessAgentData(ctx context.Context, data config.IncomingAgentData) (*config.ProcessedAgentData, error) {
+	startTime := time.Now()
+
+	// Get processing configuration for this agent
+	processingConfig, err := u.currentProcessingConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	// Apply processing rules
 	processedValue := u.applyProcessingRules(data.Value, processingConfig.ProcessingRules)
@@ -76,7 +149,8 @@ func (u *AgentUsecase) ProcessAgentData(ctx context.Context, data config.Incomin
 	processingTime := time.Since(startTime).Microseconds()
 
 	result := &config.ProcessedAgentData{
-		AgentUUID:      "current-agent-uuid", // Would be retrieved from context
+		UUID:           data.UUID,
+		AgentUUID:      u.agentUUID,
 		OriginalValue:  data.Value,
 		ProcessedValue: processedValue,
 		Anomaly:        isAnomaly,
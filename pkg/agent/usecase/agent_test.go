@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/agent/repository/api"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+// fakeAPIAgentRepository serves a processing config named after the fetch count, or err
+type fakeAPIAgentRepository struct {
+	api.APIAgentRepository
+	fetches   int
+	requested []string
+	err       error
+}
+
+func (r *fakeAPIAgentRepository) GetProcessingConfig(ctx context.Context, agentUUID string) (*response.ProcessingConfigResponse, error) {
+	r.requested = append(r.requested, agentUUID)
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.fetches++
+	return &response.ProcessingConfigResponse{
+		Config: &model.AgentProcessingConfig{UUID: strconv.Itoa(r.fetches), AgentUUID: agentUUID},
+	}, nil
+}
+
+func TestCurrentProcessingConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		cached   string
+		age      time.Duration
+		err      error
+		want     string
+		wantErr  bool
+		wantSent int
+	}{
+		{name: "first use", want: "1", wantSent: 1},
+		{name: "fresh", cached: "0", age: 30 * time.Second, want: "0"},
+		{name: "expired", cached: "0", age: 2 * time.Minute, want: "1", wantSent: 1},
+		{name: "refresh fails", cached: "0", age: 2 * time.Minute, err: errors.New("connection refused"), want: "0", wantSent: 1},
+		{name: "first fetch fails", err: errors.New("connection refused"), wantErr: true, wantSent: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := config.BaseConfig{}
+			conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+			conf.YamlConfig.Application.Agent.ConfigRefreshInterval = 60
+
+			repo := &fakeAPIAgentRepository{err: tt.err}
+			u := NewAgentUsecase(conf, "agent-1", repo)
+			if tt.cached != "" {
+				u.processingConfig = &model.AgentProcessingConfig{UUID: tt.cached}
+				u.fetchedAt = time.Now().Add(-tt.age)
+			}
+
+			got, err := u.currentProcessingConfig(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("currentProcessingConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.UUID != tt.want {
+				t.Errorf("config = %q, want %q", got.UUID, tt.want)
+			}
+			if len(repo.requested) != tt.wantSent {
+				t.Fatalf("fetched %d times, want %d", len(repo.requested), tt.wantSent)
+			}
+			for _, uuid := range repo.requested {
+				if uuid != "agent-1" {
+					t.Errorf("fetched the config of %q, want agent-1", uuid)
+				}
+			}
+
+			// A failed refresh keeps the stale config for another interval
+			if tt.cached != "" && tt.err != nil {
+				if _, err := u.currentProcessingConfig(context.Background()); err != nil || len(repo.requested) != 1 {
+					t.Errorf("fetched again right after a failed refresh: %d fetches, error %v", len(repo.requested), err)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessAgentDataUsesAgentUUID(t *testing.T) {
+	conf := config.BaseConfig{}
+	conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+	u := NewAgentUsecase(conf, "agent-1", &fakeAPIAgentRepository{})
+
+	result, err := u.ProcessAgentData(context.Background(), config.IncomingAgentData{UUID: "reading-1", Value: 21.5})
+	if err != nil {
+		t.Fatalf("ProcessAgentData() error = %v", err)
+	}
+	if result.AgentUUID != "agent-1" {
+		t.Errorf("AgentUUID = %q, want agent-1", result.AgentUUID)
+	}
+}
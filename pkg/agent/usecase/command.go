@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+
+	agentpulsar "github.com/ryo-arima/circulator/pkg/agent/repository/pulsar"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// Agent statuses reported in heartbeats
+const (
+	AgentStatusOnline   = "online"
+	AgentStatusDraining = "draining"
+)
+
+// CommandUsecase applies the commands sent to this agent over Pulsar
+type CommandUsecase struct {
+	config       config.BaseConfig
+	agentUUID    string
+	agentUsecase *AgentUsecase
+	producer     agentpulsar.ProducerRepository
+
+	drainOnce sync.Once
+	drained   chan struct{}
+}
+
+// NewCommandUsecase creates a new CommandUsecase instance
+func NewCommandUsecase(conf config.BaseConfig, agentUUID string, agentUsecase *AgentUsecase, producer agentpulsar.ProducerRepository) *CommandUsecase {
+	return &CommandUsecase{
+		config:       conf,
+		agentUUID:    agentUUID,
+		agentUsecase: agentUsecase,
+		producer:     producer,
+		drained:      make(chan struct{}),
+	}
+}
+
+// HandleCommand applies a command addressed to this agent. Every agent reads the command
+// topic, so commands for other agents are ignored. A returned error leaves the message
+// unacknowledged so Pulsar redelivers it.
+func (u *CommandUsecase) HandleCommand(ctx context.Context, command *model.Command) error {
+	if command.Target != "agent" {
+		return nil
+	}
+	if target, _ := command.Payload["agent_uuid"].(string); target != "" && target != u.agentUUID {
+		return nil
+	}
+
+	u.config.Logger.INFO(config.AUCMD, "Handling agent command", map[string]interface{}{
+		"command_id": command.ID,
+		"type":       command.Type,
+		"action":     command.Action,
+	})
+
+	switch {
+	case command.Type == model.CommandTypeConfig && command.Action == model.CommandActionReload:
+		return u.agentUsecase.ReloadProcessingConfig(ctx)
+	case command.Type == model.CommandTypeAgent && command.Action == model.CommandActionDrain:
+		u.drain()
+		return nil
+	default:
+		// Redelivering a command this agent does not know would not change the outcome
+		u.config.Logger.WARN(config.AUCMDU, "Ignoring unknown agent command", map[string]interface{}{
+			"command_id": command.ID,
+			"type":       command.Type,
+			"action":     command.Action,
+		})
+		return nil
+	}
+}
+
+// drain stops the agent taking sensor readings from Pulsar and reports it right away, so
+// the fleet shows the agent as draining without waiting for the next heartbeat
+func (u *CommandUsecase) drain() {
+	u.drainOnce.Do(func() {
+		u.config.Logger.INFO(config.AUCMDR, "Draining agent", map[string]interface{}{
+			"agent_uuid": u.agentUUID,
+		})
+		close(u.drained)
+		// Failures are logged by the producer; the next heartbeat reports the status again
+		u.producer.PublishHeartbeat(u.agentUUID, AgentStatusDraining)
+	})
+}
+
+// Drained is closed once the agent has been told to drain
+func (u *CommandUsecase) Drained() <-chan struct{} {
+	return u.drained
+}
+
+// Status returns the status the agent reports in heartbeats
+func (u *CommandUsecase) Status() string {
+	select {
+	case <-u.drained:
+		return AgentStatusDraining
+	default:
+		return AgentStatusOnline
+	}
+}
@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	agentpulsar "github.com/ryo-arima/circulator/pkg/agent/repository/pulsar"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// StreamUsecase handles sensor readings from Pulsar and the gRPC stream endpoint and
+// publishes the results
+type StreamUsecase struct {
+	config       config.BaseConfig
+	agentUUID    string
+	agentUsecase *AgentUsecase
+	producer     agentpulsar.ProducerRepository
+}
+
+// NewStreamUsecase creates a new StreamUsecase instance
+func NewStreamUsecase(conf config.BaseConfig, agentUUID string, agentUsecase *AgentUsecase, producer agentpulsar.ProducerRepository) *StreamUsecase {
+	return &StreamUsecase{
+		config:       conf,
+		agentUUID:    agentUUID,
+		agentUsecase: agentUsecase,
+		producer:     producer,
+	}
+}
+
+// HandleStreamData processes one reading consumed from Pulsar. A returned error leaves the
+// message unacknowledged so Pulsar redelivers it.
+func (u *StreamUsecase) HandleStreamData(ctx context.Context, data *model.IncomingStreamData) error {
+	_, err := u.Process(ctx, config.IncomingAgentData{
+		UUID:       data.UUID,
+		Source:     data.Source,
+		SensorType: data.SensorType,
+		Value:      data.Value,
+		RawPayload: data.RawPayload,
+	})
+	return err
+}
+
+// Process processes one reading and publishes the processed data, plus an alert when the
+// reading is anomalous. Both echo the reading's UUID so results can be matched to it.
+func (u *StreamUsecase) Process(ctx context.Context, data config.IncomingAgentData) (*model.ProcessedStreamData, error) {
+	result, err := u.agentUsecase.ProcessAgentData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	processed := &model.ProcessedStreamData{
+		UUID:           result.UUID,
+		AgentUUID:      u.agentUUID,
+		OriginalValue:  result.OriginalValue,
+		ProcessedValue: result.ProcessedValue,
+		Anomaly:        result.Anomaly,
+		Confidence:     result.Confidence,
+		ProcessingTime: result.ProcessingTime,
+		Timestamp:      time.Now(),
+		SensorType:     data.SensorType,
+	}
+	if err := u.producer.PublishProcessedData(processed); err != nil {
+		return nil, err
+	}
+	if !result.Anomaly {
+		return processed, nil
+	}
+
+	severity := "medium"
+	if result.Confidence >= 0.9 {
+		severity = "high"
+	}
+	err = u.producer.PublishAlert(&model.AlertData{
+		UUID:           result.UUID,
+		AgentUUID:      u.agentUUID,
+		SensorType:     data.SensorType,
+		OriginalValue:  result.OriginalValue,
+		ProcessedValue: result.ProcessedValue,
+		Severity:       severity,
+		Message:        fmt.Sprintf("Anomalous %s reading %.2f", data.SensorType, result.ProcessedValue),
+		Timestamp:      processed.Timestamp,
+		Rule:           "outlier_detection",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return processed, nil
+}
@@ -1,7 +1,11 @@
 package client
 
 import (
+	"os"
+	"strings"
+
 	"github.com/ryo-arima/circulator/pkg/client/controller"
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
 	"github.com/ryo-arima/circulator/pkg/config"
 	"github.com/spf13/cobra"
 )
@@ -15,24 +19,43 @@ type BaseCmd struct {
 	Config    config.BaseConfig // Dependency injection for config
 }
 
-// InitRootCmd creates the root command with config dependency injection
-func InitRootCmd(baseConfig config.BaseConfig) *cobra.Command {
+// InitRootCmd creates the root command with config dependency injection. contextErr is the
+// failure to select the context, reported by every command except config.
+func InitRootCmd(baseConfig config.BaseConfig, contextErr error) *cobra.Command {
 	var output string
+	var contextName string
+	var tableOptions usecase.TableOptions
+	var noPager bool
 	rootCmd := &cobra.Command{
 		Use:   "circulator",
 		Short: "'circulator' is a CLI tool to manage circulator resources",
 		Long:  `''`,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			controller.SetOutputFormat(output)
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if contextErr != nil && !isConfigCmd(cmd) {
+				cmd.SilenceUsage = true
+				return contextErr
+			}
+			if err := controller.SetOutputFormat(output); err != nil {
+				return err
+			}
+			controller.SetTableOptions(tableOptions, !noPager)
 			// Log command execution
 			baseConfig.Logger.INFO(config.CBCE, "Command executed", map[string]interface{}{
 				"command": cmd.Name(),
 				"args":    args,
 				"output":  output,
+				"context": baseConfig.YamlConfig.Application.Client.Context,
 			})
+			return nil
 		},
 	}
-	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "table", "Output format: table|json|yaml")
+	// Read by Client before the commands are built, registered here for parsing and help
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "Context of the client config file to use, see `config --help`")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "table", "Output format: "+strings.Join(usecase.OutputFormats, "|"))
+	rootCmd.PersistentFlags().StringSliceVar(&tableOptions.Columns, "columns", nil, "Table columns by header or JSON path, e.g. uuid,status,metadata.group")
+	rootCmd.PersistentFlags().StringVar(&tableOptions.SortBy, "sort-by", "", "Sort table rows by a column header or JSON path")
+	rootCmd.PersistentFlags().BoolVar(&tableOptions.NoHeaders, "no-headers", false, "Omit the table header row")
+	rootCmd.PersistentFlags().BoolVar(&noPager, "no-pager", false, "Do not page long tables through $PAGER")
 	return rootCmd
 }
 
@@ -101,7 +124,9 @@ func InitBaseCmd(baseConfig config.BaseConfig) BaseCmd {
 func Client(conf config.BaseConfig) {
 	conf.Logger.INFO(config.CBSCA, "Starting client application")
 
-	rootCmd := InitRootCmd(conf)
+	// Commands copy the config when they are built, so the context is selected before flags are parsed
+	contextErr := usecase.SelectContext(&conf, contextFlag(os.Args[1:]))
+	rootCmd := InitRootCmd(conf, contextErr)
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
 	baseCmd := InitBaseCmd(conf)
 
@@ -111,7 +136,76 @@ func Client(conf config.BaseConfig) {
 	rootCmd.AddCommand(baseCmd.Get)
 	rootCmd.AddCommand(baseCmd.Update)
 	rootCmd.AddCommand(baseCmd.Delete)
+	rootCmd.AddCommand(controller.InitAgentCmd(conf))
+	rootCmd.AddCommand(controller.InitStreamCmd(conf))
+	rootCmd.AddCommand(controller.InitCommandCmd(conf))
+	rootCmd.AddCommand(controller.InitAckCmd(conf))
+	rootCmd.AddCommand(controller.InitResolveCmd(conf))
+	rootCmd.AddCommand(controller.InitApplyCmd(conf))
+	rootCmd.AddCommand(controller.InitDiffCmd(conf))
+	rootCmd.AddCommand(controller.InitEventsCmd(conf))
+	rootCmd.AddCommand(controller.InitDashboardCmd(conf))
+	rootCmd.AddCommand(controller.InitConfigCmd(conf))
+	rootCmd.AddCommand(controller.InitCommonLoginCmd(conf))
+	rootCmd.AddCommand(controller.InitCommonRefreshTokenCmd(conf))
+	rootCmd.AddCommand(controller.InitCommonLogoutCmd(conf))
+
+	baseCmd.Get.AddCommand(controller.InitGetAgentsCmd(conf))
+	baseCmd.Get.AddCommand(controller.InitGetAgentInfoCmd(conf))
+	baseCmd.Create.AddCommand(controller.InitCreateAgentInfoCmd(conf))
+	baseCmd.Update.AddCommand(controller.InitUpdateAgentInfoCmd(conf))
+	baseCmd.Delete.AddCommand(controller.InitDeleteAgentInfoCmd(conf))
+	baseCmd.Get.AddCommand(controller.InitGetAgentSystemCmd(conf))
+	baseCmd.Create.AddCommand(controller.InitCreateAgentSystemCmd(conf))
+	baseCmd.Update.AddCommand(controller.InitUpdateAgentSystemCmd(conf))
+	baseCmd.Delete.AddCommand(controller.InitDeleteAgentSystemCmd(conf))
+	baseCmd.Get.AddCommand(controller.InitGetAgentConfigCmd(conf))
+	baseCmd.Create.AddCommand(controller.InitCreateAgentConfigCmd(conf))
+	baseCmd.Update.AddCommand(controller.InitUpdateAgentConfigCmd(conf))
+	baseCmd.Delete.AddCommand(controller.InitDeleteAgentConfigCmd(conf))
+	baseCmd.Get.AddCommand(controller.InitGetProcessingRulesCmd(conf))
+	baseCmd.Create.AddCommand(controller.InitCreateProcessingRuleCmd(conf))
+	baseCmd.Update.AddCommand(controller.InitUpdateProcessingRuleCmd(conf))
+	baseCmd.Delete.AddCommand(controller.InitDeleteProcessingRuleCmd(conf))
+
+	baseCmd.Get.AddCommand(controller.InitGetAlertsCmd(conf))
+	baseCmd.Get.AddCommand(controller.InitGetSilencesCmd(conf))
+	baseCmd.Get.AddCommand(controller.InitGetMaintenanceWindowsCmd(conf))
+	baseCmd.Create.AddCommand(controller.InitCreateSilenceCmd(conf))
+	baseCmd.Create.AddCommand(controller.InitCreateMaintenanceWindowCmd(conf))
+	baseCmd.Delete.AddCommand(controller.InitDeleteSilenceCmd(conf))
+	baseCmd.Delete.AddCommand(controller.InitDeleteMaintenanceWindowCmd(conf))
+	baseCmd.Get.AddCommand(controller.InitGetAlertRulesCmd(conf))
+	baseCmd.Create.AddCommand(controller.InitCreateAlertRuleCmd(conf))
+	baseCmd.Update.AddCommand(controller.InitUpdateAlertRuleCmd(conf))
+	baseCmd.Delete.AddCommand(controller.InitDeleteAlertRuleCmd(conf))
 
 	conf.Logger.DEBUG(config.CBACR, "All commands registered")
 	rootCmd.Execute()
 }
+
+// contextFlag finds the value of --context in the command line arguments
+func contextFlag(args []string) string {
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return ""
+		case arg == "--context" && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(arg, "--context="):
+			return strings.TrimPrefix(arg, "--context=")
+		}
+	}
+	return ""
+}
+
+// isConfigCmd reports whether cmd is config or one of its subcommands, which must work
+// while the selected context is broken
+func isConfigCmd(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Name() == "config" && c.Parent() != nil && !c.Parent().HasParent() {
+			return true
+		}
+	}
+	return false
+}
@@ -28,26 +28,21 @@ func InitAgentCmd(conf config.BaseConfig) *cobra.Command {
 }
 
 func bootstrapAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
-	var format string
-
 	cmd := &cobra.Command{
 		Use:   "bootstrap",
 		Short: "Bootstrap agent data",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			req := request.AgentRequest{}
-			result := agentUsecase.Bootstrap(req, format)
-			cmd.Printf("%s\n", result)
+			result := agentUsecase.Bootstrap(req, GetOutputFormat())
+			printOutput(result)
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVar(&format, "format", "json", "Output format (json, yaml, table)")
-
 	return cmd
 }
 
 func getAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
-	var format string
 	var uuid string
 
 	cmd := &cobra.Command{
@@ -55,19 +50,17 @@ func getAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
 		Short: "Get agent data",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			req := request.AgentRequest{UUID: uuid}
-			result := agentUsecase.Get(req, format)
-			cmd.Printf("%s\n", result)
+			result := agentUsecase.Get(req, GetOutputFormat())
+			printOutput(result)
 			return nil
 		},
 	}
 	cmd.Flags().StringVarP(&uuid, "uuid", "u", "", "UUID filter (optional)")
-	cmd.Flags().StringVar(&format, "format", "json", "Output format (json, yaml, table)")
 
 	return cmd
 }
 
 func createAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
-	var format string
 	var name string
 
 	cmd := &cobra.Command{
@@ -75,20 +68,17 @@ func createAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
 		Short: "Create a new agent",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			req := request.AgentRequest{Name: name}
-			result := agentUsecase.Create(req, format)
-			cmd.Printf("%s\n", result)
+			result := agentUsecase.Create(req, GetOutputFormat())
+			printOutput(result)
 			return nil
 		},
 	}
 	cmd.Flags().StringVarP(&name, "name", "n", "", "Name (optional)")
 
-	cmd.Flags().StringVar(&format, "format", "json", "Output format (json, yaml, table)")
-
 	return cmd
 }
 
 func updateAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
-	var format string
 	var uuid string
 	var name string
 
@@ -97,8 +87,8 @@ func updateAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
 		Short: "Update an agent",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			req := request.AgentRequest{UUID: uuid, Name: name}
-			result := agentUsecase.Update(req, format)
-			cmd.Printf("%s\n", result)
+			result := agentUsecase.Update(req, GetOutputFormat())
+			printOutput(result)
 			return nil
 		},
 	}
@@ -106,13 +96,10 @@ func updateAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
 	cmd.MarkFlagRequired("uuid")
 	cmd.Flags().StringVarP(&name, "name", "n", "", "Name (optional)")
 
-	cmd.Flags().StringVar(&format, "format", "json", "Output format (json, yaml, table)")
-
 	return cmd
 }
 
 func deleteAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
-	var format string
 	var uuid string
 
 	cmd := &cobra.Command{
@@ -120,15 +107,13 @@ func deleteAgentCmd(agentUsecase usecase.AgentUsecase) *cobra.Command {
 		Short: "Delete an agent",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			req := request.AgentRequest{UUID: uuid}
-			result := agentUsecase.Delete(req, format)
-			cmd.Printf("%s\n", result)
+			result := agentUsecase.Delete(req, GetOutputFormat())
+			printOutput(result)
 			return nil
 		},
 	}
 	cmd.Flags().StringVarP(&uuid, "uuid", "u", "", "UUID (required)")
 	cmd.MarkFlagRequired("uuid")
 
-	cmd.Flags().StringVar(&format, "format", "json", "Output format (json, yaml, table)")
-
 	return cmd
 }
@@ -0,0 +1,511 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// InitGetAgentsCmd creates the `get agents` command
+func InitGetAgentsCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	watchUsecase := usecase.NewWatchUsecase(conf)
+	var watch bool
+
+	cmd := &cobra.Command{
+		Use:   "agents",
+		Short: "List registered agents",
+		Long: `List registered agents. With --watch the list is kept up to date until interrupted: on a
+terminal the table is redrawn in place as agents are added, change or go away, otherwise
+each change is printed as it arrives.`,
+		Example: `  circulator get agents --watch
+  circulator get agents --watch -o ndjson | jq 'select(.type == "MODIFIED") | .agent.status'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !watch {
+				printOutput(agentUsecase.ListAgents(GetOutputFormat()))
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			cmd.SilenceUsage = true
+			return watchUsecase.WatchAgents(ctx, usecase.WatchOptions{
+				Format: GetOutputFormat(),
+				Live:   isTableFormat() && term.IsTerminal(int(os.Stdout.Fd())),
+			}, os.Stdout)
+		},
+	}
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Watch for changes after listing the agents")
+
+	return cmd
+}
+
+// ============ AGENT INFO ============
+
+// InitGetAgentInfoCmd creates the `get agent-info` command
+func InitGetAgentInfoCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+
+	cmd := &cobra.Command{
+		Use:   "agent-info",
+		Short: "Show the registration info of an agent",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.GetAgentInfo(agentUUID, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+
+	return cmd
+}
+
+// InitCreateAgentInfoCmd creates the `create agent-info` command
+func InitCreateAgentInfoCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+	var req request.AgentInfoRequest
+
+	cmd := &cobra.Command{
+		Use:     "agent-info",
+		Short:   "Register the info of an agent",
+		Example: `  circulator create agent-info --agent 7c0e... --hostname edge-01 --ip 10.0.0.5 --port 8081 --capability temperature --meta group=plant-a`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.CreateAgentInfo(agentUUID, req, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+	addAgentInfoFlags(cmd, &req)
+	cmd.MarkFlagRequired("hostname")
+	cmd.MarkFlagRequired("ip")
+	cmd.MarkFlagRequired("port")
+
+	return cmd
+}
+
+// InitUpdateAgentInfoCmd creates the `update agent-info` command
+func InitUpdateAgentInfoCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+	var changes request.AgentInfoRequest
+
+	cmd := &cobra.Command{
+		Use:   "agent-info",
+		Short: "Update the info of an agent",
+		Long:  "Update the info of an agent. Only the given flags change; --meta replaces the whole metadata map.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flags := cmd.Flags()
+			printOutput(agentUsecase.UpdateAgentInfo(agentUUID, func(req *request.AgentInfoRequest) {
+				if flags.Changed("hostname") {
+					req.Hostname = changes.Hostname
+				}
+				if flags.Changed("ip") {
+					req.IPAddress = changes.IPAddress
+				}
+				if flags.Changed("port") {
+					req.Port = changes.Port
+				}
+				if flags.Changed("threads") {
+					req.ThreadCount = changes.ThreadCount
+				}
+				if flags.Changed("max-threads") {
+					req.MaxThreadCount = changes.MaxThreadCount
+				}
+				if flags.Changed("version") {
+					req.Version = changes.Version
+				}
+				if flags.Changed("capability") {
+					req.Capabilities = changes.Capabilities
+				}
+				if flags.Changed("meta") {
+					req.Metadata = changes.Metadata
+				}
+			}, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+	addAgentInfoFlags(cmd, &changes)
+
+	return cmd
+}
+
+// InitDeleteAgentInfoCmd creates the `delete agent-info` command
+func InitDeleteAgentInfoCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+
+	cmd := &cobra.Command{
+		Use:   "agent-info",
+		Short: "Delete the info of an agent",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.DeleteAgentInfo(agentUUID, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+
+	return cmd
+}
+
+func addAgentInfoFlags(cmd *cobra.Command, req *request.AgentInfoRequest) {
+	cmd.Flags().StringVar(&req.Hostname, "hostname", "", "Agent hostname")
+	cmd.Flags().StringVar(&req.IPAddress, "ip", "", "Agent IP address")
+	cmd.Flags().IntVar(&req.Port, "port", 0, "Agent API port")
+	cmd.Flags().IntVar(&req.ThreadCount, "threads", 0, "Current processing thread count")
+	cmd.Flags().IntVar(&req.MaxThreadCount, "max-threads", 0, "Maximum processing thread count")
+	cmd.Flags().StringVar(&req.Version, "version", "", "Agent version")
+	cmd.Flags().StringSliceVar(&req.Capabilities, "capability", nil, "Capability, repeatable or comma separated")
+	cmd.Flags().StringToStringVar(&req.Metadata, "meta", nil, "Metadata key=value, e.g. group=plant-a")
+}
+
+// ============ AGENT SYSTEM ============
+
+// InitGetAgentSystemCmd creates the `get agent-system` command
+func InitGetAgentSystemCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+
+	cmd := &cobra.Command{
+		Use:   "agent-system",
+		Short: "Show the host system of an agent",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.GetAgentSystem(agentUUID, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+
+	return cmd
+}
+
+// InitCreateAgentSystemCmd creates the `create agent-system` command
+func InitCreateAgentSystemCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+	var req request.AgentSystemRequest
+
+	cmd := &cobra.Command{
+		Use:     "agent-system",
+		Short:   "Record the host system of an agent",
+		Example: `  circulator create agent-system --agent 7c0e... --hostname edge-01 --os linux --arch arm64 --cpus 4`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.CreateAgentSystem(agentUUID, req, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+	addAgentSystemFlags(cmd, &req)
+
+	return cmd
+}
+
+// InitUpdateAgentSystemCmd creates the `update agent-system` command
+func InitUpdateAgentSystemCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+	var changes request.AgentSystemRequest
+
+	cmd := &cobra.Command{
+		Use:   "agent-system",
+		Short: "Update the host system of an agent",
+		Long:  "Update the host system of an agent. Only the given flags change.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flags := cmd.Flags()
+			printOutput(agentUsecase.UpdateAgentSystem(agentUUID, func(req *request.AgentSystemRequest) {
+				if flags.Changed("hostname") {
+					req.Hostname = changes.Hostname
+				}
+				if flags.Changed("os") {
+					req.OS = changes.OS
+				}
+				if flags.Changed("arch") {
+					req.Architecture = changes.Architecture
+				}
+				if flags.Changed("cpus") {
+					req.CPUCount = changes.CPUCount
+				}
+			}, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+	addAgentSystemFlags(cmd, &changes)
+
+	return cmd
+}
+
+// InitDeleteAgentSystemCmd creates the `delete agent-system` command
+func InitDeleteAgentSystemCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+
+	cmd := &cobra.Command{
+		Use:   "agent-system",
+		Short: "Delete the host system of an agent",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.DeleteAgentSystem(agentUUID, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+
+	return cmd
+}
+
+func addAgentSystemFlags(cmd *cobra.Command, req *request.AgentSystemRequest) {
+	cmd.Flags().StringVar(&req.Hostname, "hostname", "", "Host name")
+	cmd.Flags().StringVar(&req.OS, "os", "", "Operating system, e.g. linux")
+	cmd.Flags().StringVar(&req.Architecture, "arch", "", "CPU architecture, e.g. amd64")
+	cmd.Flags().IntVar(&req.CPUCount, "cpus", 0, "Number of CPUs")
+}
+
+// ============ AGENT CONFIG ============
+
+// InitGetAgentConfigCmd creates the `get agent-config` command
+func InitGetAgentConfigCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+
+	cmd := &cobra.Command{
+		Use:   "agent-config",
+		Short: "Show the stream processing config of an agent",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.GetAgentConfig(agentUUID, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+
+	return cmd
+}
+
+// InitCreateAgentConfigCmd creates the `create agent-config` command
+func InitCreateAgentConfigCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+	var req request.AgentConfigRequest
+
+	cmd := &cobra.Command{
+		Use:     "agent-config",
+		Short:   "Create the stream processing config of an agent",
+		Long:    "Create the stream processing config of an agent. Add processing rules with `create rule` afterwards.",
+		Example: `  circulator create agent-config --agent 7c0e... --sensor-type temperature --output-stream processed-temperature`,
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.CreateAgentConfig(agentUUID, req, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+	addAgentConfigFlags(cmd, &req)
+	cmd.MarkFlagRequired("sensor-type")
+
+	return cmd
+}
+
+// InitUpdateAgentConfigCmd creates the `update agent-config` command
+func InitUpdateAgentConfigCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+	var changes request.AgentConfigRequest
+
+	cmd := &cobra.Command{
+		Use:   "agent-config",
+		Short: "Update the stream processing config of an agent",
+		Long:  "Update the stream processing config of an agent. Only the given flags change; processing rules are managed with the rule verbs.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			flags := cmd.Flags()
+			printOutput(agentUsecase.UpdateAgentConfig(agentUUID, func(req *request.AgentConfigRequest) {
+				if flags.Changed("sensor-type") {
+					req.SensorType = changes.SensorType
+				}
+				if flags.Changed("output-stream") {
+					req.OutputStreams = changes.OutputStreams
+				}
+			}, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+	addAgentConfigFlags(cmd, &changes)
+
+	return cmd
+}
+
+// InitDeleteAgentConfigCmd creates the `delete agent-config` command
+func InitDeleteAgentConfigCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+
+	cmd := &cobra.Command{
+		Use:   "agent-config",
+		Short: "Delete the stream processing config of an agent",
+		Long:  "Delete the stream processing config of an agent together with its processing rules.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.DeleteAgentConfig(agentUUID, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+
+	return cmd
+}
+
+func addAgentConfigFlags(cmd *cobra.Command, req *request.AgentConfigRequest) {
+	cmd.Flags().StringVar(&req.SensorType, "sensor-type", "", "Sensor type the agent processes")
+	cmd.Flags().StringSliceVar(&req.OutputStreams, "output-stream", nil, "Output stream, repeatable or comma separated")
+}
+
+// ============ PROCESSING RULES ============
+
+// InitGetProcessingRulesCmd creates the `get rules` command
+func InitGetProcessingRulesCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+
+	cmd := &cobra.Command{
+		Use:     "rules",
+		Aliases: []string{"rule"},
+		Short:   "List the processing rules of an agent",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.ListProcessingRules(agentUUID, GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+
+	return cmd
+}
+
+// InitCreateProcessingRuleCmd creates the `create rule` command
+func InitCreateProcessingRuleCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+	var req request.AgentConfigRulesRequest
+	var params []string
+
+	cmd := &cobra.Command{
+		Use:   "rule",
+		Short: "Add a processing rule to the config of an agent",
+		Example: `  circulator create rule --agent 7c0e... --name outlier_detection --param threshold_sigma=3
+  circulator create rule --agent 7c0e... --name moving_average --param window=10 --enabled=false`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsed, err := parseRuleParams(params)
+			if err != nil {
+				return err
+			}
+			req.Params = parsed
+			printOutput(agentUsecase.CreateProcessingRule(agentUUID, req, GetOutputFormat()))
+			return nil
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+	cmd.Flags().StringVar(&req.Name, "name", "", "Rule name, e.g. outlier_detection (required)")
+	cmd.Flags().BoolVar(&req.Enabled, "enabled", true, "Whether the agent applies the rule")
+	cmd.Flags().StringArrayVar(&params, "param", nil, "Rule parameter key=value, repeatable; values are parsed as JSON when possible")
+	cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+// InitUpdateProcessingRuleCmd creates the `update rule` command
+func InitUpdateProcessingRuleCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+	var changes request.AgentConfigRulesRequest
+	var params, removeParams []string
+
+	cmd := &cobra.Command{
+		Use:     "rule <rule-uuid>",
+		Short:   "Update a processing rule of an agent",
+		Long:    "Update a processing rule of an agent. --param sets or overrides single parameters and --remove-param drops them; the others are kept.",
+		Example: `  circulator update rule 3f1a... --agent 7c0e... --param threshold_sigma=2.5 --enabled=false`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsed, err := parseRuleParams(params)
+			if err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			printOutput(agentUsecase.UpdateProcessingRule(agentUUID, args[0], func(req *request.AgentConfigRulesRequest) {
+				if flags.Changed("name") {
+					req.Name = changes.Name
+				}
+				if flags.Changed("enabled") {
+					req.Enabled = changes.Enabled
+				}
+				if req.Params == nil {
+					req.Params = map[string]interface{}{}
+				}
+				for key, value := range parsed {
+					req.Params[key] = value
+				}
+				for _, key := range removeParams {
+					delete(req.Params, key)
+				}
+			}, GetOutputFormat()))
+			return nil
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+	cmd.Flags().StringVar(&changes.Name, "name", "", "New rule name")
+	cmd.Flags().BoolVar(&changes.Enabled, "enabled", true, "Whether the agent applies the rule")
+	cmd.Flags().StringArrayVar(&params, "param", nil, "Rule parameter key=value to set, repeatable")
+	cmd.Flags().StringSliceVar(&removeParams, "remove-param", nil, "Rule parameter to remove, repeatable")
+
+	return cmd
+}
+
+// InitDeleteProcessingRuleCmd creates the `delete rule` command
+func InitDeleteProcessingRuleCmd(conf config.BaseConfig) *cobra.Command {
+	agentUsecase := usecase.NewAgentResourceUsecase(conf)
+	var agentUUID string
+
+	cmd := &cobra.Command{
+		Use:   "rule <rule-uuid>",
+		Short: "Delete a processing rule of an agent",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(agentUsecase.DeleteProcessingRule(agentUUID, args[0], GetOutputFormat()))
+		},
+	}
+	addAgentFlag(cmd, &agentUUID)
+
+	return cmd
+}
+
+func addAgentFlag(cmd *cobra.Command, agentUUID *string) {
+	cmd.Flags().StringVarP(agentUUID, "agent", "a", "", "Agent UUID (required)")
+	cmd.MarkFlagRequired("agent")
+}
+
+// parseRuleParams turns key=value pairs into rule params. Values that are valid JSON keep
+// their type, so threshold_sigma=3 is a number and fields=["a","b"] a list; others are strings.
+func parseRuleParams(pairs []string) (map[string]interface{}, error) {
+	params := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		key, raw, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --param %q, expected key=value", pair)
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		params[key] = value
+	}
+	return params, nil
+}
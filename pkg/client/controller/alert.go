@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+// InitGetAlertsCmd creates the `get alerts` command
+func InitGetAlertsCmd(conf config.BaseConfig) *cobra.Command {
+	alertUsecase := usecase.NewAlertUsecase(conf)
+	var req request.AlertListRequest
+
+	cmd := &cobra.Command{
+		Use:     "alerts",
+		Aliases: []string{"alert"},
+		Short:   "List alerts",
+		Long:    "List alerts, most recently seen first. Without --status only unresolved alerts are listed.",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(alertUsecase.List(req, GetOutputFormat()))
+		},
+	}
+	cmd.Flags().StringVar(&req.Status, "status", "", "Status filter: open|acknowledged|resolved")
+	cmd.Flags().StringVarP(&req.AgentUUID, "agent", "a", "", "Agent UUID filter")
+	cmd.Flags().StringVar(&req.SensorType, "sensor-type", "", "Sensor type filter")
+	cmd.Flags().StringVar(&req.Severity, "severity", "", "Severity filter: low|medium|high|critical")
+	cmd.Flags().IntVar(&req.Limit, "limit", 0, "Maximum number of alerts (0 for no limit)")
+
+	return cmd
+}
+
+// InitAckCmd creates the `ack` command
+func InitAckCmd(conf config.BaseConfig) *cobra.Command {
+	alertUsecase := usecase.NewAlertUsecase(conf)
+	var req request.AlertActionRequest
+
+	cmd := &cobra.Command{
+		Use:   "ack <alert-uuid>",
+		Short: "Acknowledge an alert",
+		Long:  "Acknowledge an open alert, assigning it to --assignee or to the logged in user",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			req.UUID = args[0]
+			printOutput(alertUsecase.Acknowledge(req, GetOutputFormat()))
+		},
+	}
+	cmd.Flags().StringVar(&req.Assignee, "assignee", "", "Assignee (defaults to the logged in user)")
+	cmd.Flags().StringVarP(&req.Note, "note", "m", "", "Note to record with the acknowledgement")
+
+	return cmd
+}
+
+// InitResolveCmd creates the `resolve` command
+func InitResolveCmd(conf config.BaseConfig) *cobra.Command {
+	alertUsecase := usecase.NewAlertUsecase(conf)
+	var req request.AlertActionRequest
+
+	cmd := &cobra.Command{
+		Use:   "resolve <alert-uuid>",
+		Short: "Resolve an alert",
+		Long:  "Resolve an alert. A repeat of the same alert afterwards opens a new one.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			req.UUID = args[0]
+			printOutput(alertUsecase.Resolve(req, GetOutputFormat()))
+		},
+	}
+	cmd.Flags().StringVarP(&req.Note, "note", "m", "", "Note to record with the resolution")
+
+	return cmd
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+const manifestLong = `Manifests are YAML documents describing the desired stream processing config of agents:
+
+  kind: AgentConfig
+  agents: [7c0e..., 91ab...]     # or agent: 7c0e...
+  sensor_type: temperature
+  output_streams: [processed-temperature]
+  processing_rules:
+    - name: outlier_detection
+      params:
+        threshold_sigma: 3
+    - name: moving_average
+      enabled: false             # defaults to true
+      params:
+        window: 10
+
+Rules are matched to the agent's rules by name. Rules on the server that are not in the
+manifest are kept unless --prune is given.`
+
+// InitApplyCmd creates the `apply` command
+func InitApplyCmd(conf config.BaseConfig) *cobra.Command {
+	applyUsecase := usecase.NewApplyUsecase(conf)
+	var files []string
+	var prune, dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "apply -f <file|dir>",
+		Short: "Bring agent configs and rules in line with manifests",
+		Long:  manifestLong,
+		Example: `  circulator apply -f manifests/
+  circulator apply -f plant-a.yaml --prune`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifests, err := applyUsecase.LoadManifests(files)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			plan, err := applyUsecase.Plan(manifests, prune)
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				printOutput(formatPlan(plan, false))
+				return nil
+			}
+
+			failed := applyUsecase.Apply(plan)
+			printOutput(formatPlan(plan, true))
+			if failed > 0 {
+				return fmt.Errorf("%d of %d changes failed", failed, plan.Pending())
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&files, "filename", "f", nil, "Manifest file or directory of .yaml files, repeatable; - reads stdin (required)")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Delete rules that are not in the manifest")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the changes, like diff")
+	cmd.MarkFlagRequired("filename")
+
+	return cmd
+}
+
+// InitDiffCmd creates the `diff` command
+func InitDiffCmd(conf config.BaseConfig) *cobra.Command {
+	applyUsecase := usecase.NewApplyUsecase(conf)
+	var files []string
+	var prune bool
+
+	cmd := &cobra.Command{
+		Use:   "diff -f <file|dir>",
+		Short: "Preview the changes apply would make",
+		Long:  manifestLong + "\n\nLines start with + for creates, ~ for updates, - for deletes and ? for rules apply keeps.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifests, err := applyUsecase.LoadManifests(files)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			plan, err := applyUsecase.Plan(manifests, prune)
+			if err != nil {
+				return err
+			}
+			printOutput(formatPlan(plan, false))
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVarP(&files, "filename", "f", nil, "Manifest file or directory of .yaml files, repeatable; - reads stdin (required)")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Show rules that are not in the manifest as deletes")
+	cmd.MarkFlagRequired("filename")
+
+	return cmd
+}
+
+// formatPlan renders the plan as a diff for table output, or as data for the other formats
+func formatPlan(plan *usecase.ApplyPlan, applied bool) string {
+	if isTableFormat() {
+		return usecase.RenderPlan(plan, applied)
+	}
+	return usecase.Format(GetOutputFormat(), plan)
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func InitCommandCmd(conf config.BaseConfig) *cobra.Command {
+	commandCmd := &cobra.Command{
+		Use:   "command",
+		Short: "Agent command operations",
+		Long:  "Send commands to agents over Pulsar",
+	}
+
+	// Initialize usecase
+	commandUsecase := usecase.NewCommandUsecase(conf)
+
+	// Add subcommands
+	commandCmd.AddCommand(sendCommandCmd(commandUsecase))
+
+	return commandCmd
+}
+
+func sendCommandCmd(commandUsecase usecase.CommandUsecase) *cobra.Command {
+	var opts usecase.SendCommandOptions
+	var agentUUID string
+	var params []string
+
+	cmd := &cobra.Command{
+		Use:   "send",
+		Short: "Send a command to agents",
+		Long: `Publish a command given by flags, or the Command JSON messages of --file, one per line
+and "-" for stdin, to the agent command topic and print the message ID the broker assigned
+to each. Commands without an id, target or timestamp get a new UUID, the agent target and
+the current time.`,
+		Example: `  circulator command send --type config --action reload --agent 7c0e...
+  circulator command send --type processing --action set_threads --param threads=8
+  circulator command send -f commands.ndjson -o ndjson`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.Path == "" {
+				if opts.Command.Type == "" || opts.Command.Action == "" {
+					return fmt.Errorf("either --file or --type and --action are required")
+				}
+				payload, err := parseRuleParams(params)
+				if err != nil {
+					return err
+				}
+				if agentUUID != "" {
+					payload["agent_uuid"] = agentUUID
+				}
+				opts.Command.Payload = payload
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			cmd.SilenceUsage = true
+
+			opts.Format = GetOutputFormat()
+			sent, err := commandUsecase.Send(ctx, opts, os.Stdout)
+			if err != nil {
+				return err
+			}
+			if opts.Path != "" {
+				cmd.PrintErrf("Sent %d commands\n", sent)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Path, "file", "f", "", "NDJSON file of commands to send, - for stdin")
+	cmd.Flags().StringVar(&opts.Command.Type, "type", "", "Command type, e.g. config")
+	cmd.Flags().StringVar(&opts.Command.Action, "action", "", "Command action, e.g. reload")
+	cmd.Flags().StringVar(&opts.Command.Target, "target", "agent", "Command target: agent|server|client")
+	cmd.Flags().StringVar(&opts.Command.ID, "id", "", "Command ID (defaults to a new UUID)")
+	cmd.Flags().StringVarP(&agentUUID, "agent", "a", "", "Agent UUID the command is meant for, sent as payload agent_uuid")
+	cmd.Flags().StringArrayVar(&params, "param", nil, "Payload key=value, repeatable; values are parsed as JSON when possible")
+	cmd.MarkFlagsMutuallyExclusive("file", "type")
+	cmd.MarkFlagsMutuallyExclusive("file", "action")
+
+	return cmd
+}
@@ -3,68 +3,90 @@ package controller
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/ryo-arima/circulator/pkg/client/usecase"
 	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
 	"github.com/ryo-arima/circulator/pkg/entity/request"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
-// global output format (table/json/yaml)
+// global output format, see usecase.OutputFormats
 var outputFormat = "table"
 
-// SetOutputFormat sets global output format
-func SetOutputFormat(format string) {
-	format = strings.ToLower(strings.TrimSpace(format))
-	switch format {
-	case "table", "json", "yaml":
-		outputFormat = format
-	default:
-		outputFormat = "table"
+// SetOutputFormat sets global output format, rejecting unknown formats and invalid templates
+func SetOutputFormat(format string) error {
+	format = strings.TrimSpace(format)
+	if format == "" {
+		format = "table"
 	}
+	if err := usecase.ValidateFormat(format); err != nil {
+		return err
+	}
+	outputFormat = format
+	return nil
 }
 
 // GetOutputFormat returns current output format
 func GetOutputFormat() string { return outputFormat }
 
-// PrintMessage prints message as per current format via usecase formatter
-func PrintMessage(msg string) {
-	type message struct {
-		Message string `json:"message" yaml:"message"`
+// paging sends table output longer than the terminal through $PAGER
+var paging = true
+
+// SetTableOptions sets the table options, truncating rows to the terminal width. COLUMNS
+// overrides the width and also applies when stdout is not a terminal.
+func SetTableOptions(opts usecase.TableOptions, enablePaging bool) {
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		opts.Width = columns
+	} else if term.IsTerminal(int(os.Stdout.Fd())) {
+		if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			opts.Width = width
+		}
 	}
-	fmt.Print(usecase.Format(GetOutputFormat(), message{Message: msg}))
+	usecase.SetTableOptions(opts)
+	paging = enablePaging
 }
 
-// saveTokenPairToFiles writes access & refresh tokens under etc/.circulator/client/{profile}/
-func saveTokenPairToFiles(profile string, access, refresh string) {
-	if profile == "" {
-		profile = "app"
-	}
-	baseDir := filepath.Join("etc", ".circulator", "client", profile)
-	_ = os.MkdirAll(baseDir, 0o755)
-	if access != "" {
-		_ = os.WriteFile(filepath.Join(baseDir, "access_token"), []byte(access), 0o600)
-	}
-	if refresh != "" {
-		_ = os.WriteFile(filepath.Join(baseDir, "refresh_token"), []byte(refresh), 0o600)
+// printOutput prints command output, paging tables that do not fit the terminal
+func printOutput(out string) {
+	if paging && isTableFormat() && term.IsTerminal(int(os.Stdout.Fd())) {
+		_, height, err := term.GetSize(int(os.Stdout.Fd()))
+		if err == nil && strings.Count(out, "\n") >= height && pageOutput(out) == nil {
+			return
+		}
 	}
+	fmt.Print(out)
+}
+
+// isTableFormat reports whether the output is a table meant for people
+func isTableFormat() bool {
+	name, _, _ := strings.Cut(outputFormat, "=")
+	return strings.EqualFold(name, "table") || strings.EqualFold(name, "wide")
 }
 
-// loadRefreshTokenFromFiles tries base then app directory
-func loadRefreshTokenFromFiles() string {
-	candidates := []string{
-		filepath.Join("etc", ".circulator", "client", "base", "refresh_token"),
-		filepath.Join("etc", ".circulator", "client", "app", "refresh_token"),
+// pageOutput runs $PAGER, or less when it is unset, on out
+func pageOutput(out string) error {
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less", "-FRX"}
 	}
-	for _, p := range candidates {
-		b, err := os.ReadFile(p)
-		if err == nil && len(b) > 0 {
-			return strings.TrimSpace(string(b))
-		}
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = strings.NewReader(out)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// PrintMessage prints message as per current format via usecase formatter
+func PrintMessage(msg string) {
+	type message struct {
+		Message string `json:"message" yaml:"message"`
 	}
-	return ""
+	fmt.Print(usecase.Format(GetOutputFormat(), message{Message: msg}))
 }
 
 // isBaseEmail checks email against config base emails
@@ -84,7 +106,7 @@ func InitCommonLoginCmd(conf config.BaseConfig) *cobra.Command {
 		Use:   "login",
 		Short: "login with email and password",
 		Long:  "authenticate user and receive JWT tokens",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			email, err := cmd.Flags().GetString("email")
 			if err != nil {
 				conf.Logger.FATAL(config.CCLCE1, "Failed to get email flag", map[string]interface{}{
@@ -100,7 +122,7 @@ func InitCommonLoginCmd(conf config.BaseConfig) *cobra.Command {
 
 			if email == "" || password == "" {
 				PrintMessage("Email and password are required")
-				return
+				return nil
 			}
 
 			loginResponse := uc.Login(request.LoginRequest{
@@ -108,23 +130,24 @@ func InitCommonLoginCmd(conf config.BaseConfig) *cobra.Command {
 				Password: password,
 			})
 
-			// Save tokens to environment variables or files for later use
+			// Keep the tokens in the token store of the context or login profile
 			if loginResponse.TokenPair != nil {
-				os.Setenv("STREAM_MANAGER_ACCESS_TOKEN", loginResponse.TokenPair.AccessToken)
-				os.Setenv("STREAM_MANAGER_REFRESH_TOKEN", loginResponse.TokenPair.RefreshToken)
 				profile := "app"
 				if loginResponse.User != nil && isBaseEmail(conf, loginResponse.User.Email) {
 					profile = "base"
 				}
-				saveTokenPairToFiles(profile, loginResponse.TokenPair.AccessToken, loginResponse.TokenPair.RefreshToken)
+				if err := uc.SaveTokens(profile, *loginResponse.TokenPair); err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
 			}
 
 			fmt.Print(usecase.Format(GetOutputFormat(), loginResponse))
+			return nil
 		},
 	}
-	loginCmd.Flags().StringP("email", "e", "", "user email")
+	loginCmd.Flags().StringP("email", "e", conf.YamlConfig.Application.Client.UserEmail, "user email, defaults to the user of the context")
 	loginCmd.Flags().StringP("password", "p", "", "user password")
-	loginCmd.MarkFlagRequired("email")
 	loginCmd.MarkFlagRequired("password")
 	return loginCmd
 }
@@ -136,7 +159,7 @@ func InitCommonRefreshTokenCmd(conf config.BaseConfig) *cobra.Command {
 		Use:   "refresh",
 		Short: "refresh access token using refresh token",
 		Long:  "refresh the access token using the stored refresh token",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			refreshToken, err := cmd.Flags().GetString("refresh-token")
 			if err != nil {
 				conf.Logger.FATAL(config.CCRTCE1, "Failed to get refresh-token flag", map[string]interface{}{
@@ -149,28 +172,38 @@ func InitCommonRefreshTokenCmd(conf config.BaseConfig) *cobra.Command {
 				refreshToken = os.Getenv("STREAM_MANAGER_REFRESH_TOKEN")
 			}
 
-			// If still empty, try file system
+			// If still empty, try the token store
+			profile := "app"
 			if refreshToken == "" {
-				refreshToken = loadRefreshTokenFromFiles()
+				var stored model.TokenPair
+				if profile, stored, err = uc.StoredTokens(); err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
+				refreshToken = stored.RefreshToken
 			}
 
 			if refreshToken == "" {
 				PrintMessage("Refresh token is required. Provide via --refresh-token flag, env var, or login first")
-				return
+				return nil
 			}
 
 			refreshResponse := uc.RefreshToken(refreshToken)
 
-			// Update environment variables & files
+			// Replace the stored tokens of the profile the refresh token came from
 			if refreshResponse.TokenPair != nil {
-				os.Setenv("STREAM_MANAGER_ACCESS_TOKEN", refreshResponse.TokenPair.AccessToken)
-				os.Setenv("STREAM_MANAGER_REFRESH_TOKEN", refreshResponse.TokenPair.RefreshToken)
-				// Cannot know which profile with only token; keep both for convenience
-				saveTokenPairToFiles("base", refreshResponse.TokenPair.AccessToken, refreshResponse.TokenPair.RefreshToken)
-				saveTokenPairToFiles("app", refreshResponse.TokenPair.AccessToken, refreshResponse.TokenPair.RefreshToken)
+				tokens := *refreshResponse.TokenPair
+				if tokens.RefreshToken == "" {
+					tokens.RefreshToken = refreshToken
+				}
+				if err := uc.SaveTokens(profile, tokens); err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
 			}
 
 			fmt.Print(usecase.Format(GetOutputFormat(), refreshResponse))
+			return nil
 		},
 	}
 	refreshCmd.Flags().StringP("refresh-token", "r", "", "refresh token")
@@ -183,8 +216,8 @@ func InitCommonLogoutCmd(conf config.BaseConfig) *cobra.Command {
 	logoutCmd := &cobra.Command{
 		Use:   "logout",
 		Short: "logout and invalidate tokens",
-		Long:  "logout user and optionally invalidate tokens",
-		Run: func(cmd *cobra.Command, args []string) {
+		Long:  "logout user and remove the stored tokens",
+		RunE: func(cmd *cobra.Command, args []string) error {
 			accessToken, err := cmd.Flags().GetString("access-token")
 			if err != nil {
 				conf.Logger.FATAL(config.CCLOCE1, "Failed to get access-token flag", map[string]interface{}{
@@ -197,15 +230,23 @@ func InitCommonLogoutCmd(conf config.BaseConfig) *cobra.Command {
 				accessToken = os.Getenv("STREAM_MANAGER_ACCESS_TOKEN")
 			}
 
+			// If still empty, use the stored token
+			if accessToken == "" {
+				if _, stored, err := uc.StoredTokens(); err == nil {
+					accessToken = stored.AccessToken
+				}
+			}
+
 			logoutResponse := uc.Logout(accessToken)
 
-			// Clear environment variables on success
-			if logoutResponse.Code == "" || logoutResponse.Code == "SERVER_CONTROLLER_LOGOUT_SUCCESS" {
-				os.Unsetenv("STREAM_MANAGER_ACCESS_TOKEN")
-				os.Unsetenv("STREAM_MANAGER_REFRESH_TOKEN")
+			// The stored tokens are removed even when the server rejects the logout
+			if err := uc.ClearTokens(); err != nil {
+				cmd.SilenceUsage = true
+				return err
 			}
 
 			fmt.Print(usecase.Format(GetOutputFormat(), logoutResponse))
+			return nil
 		},
 	}
 	logoutCmd.Flags().StringP("access-token", "a", "", "access token")
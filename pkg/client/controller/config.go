@@ -0,0 +1,152 @@
+package controller
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+const configLong = `Manage the named contexts of the client config file, $CIRCULATOR_CONFIG or
+$XDG_CONFIG_HOME/circulator/config.yaml (~/.config/circulator/config.yaml). A context holds the
+server endpoint, user, CA and token store of one environment. Commands use the context given
+by --context, then $CIRCULATOR_CONTEXT, then current-context; without any, etc/app.yaml is read
+from the working directory.`
+
+// InitConfigCmd creates the `config` command and its context subcommands
+func InitConfigCmd(conf config.BaseConfig) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage client contexts",
+		Long:  configLong,
+		Example: `  circulator config set-context prod --server https://circulator.example.com --user ops@example.com --certificate-authority ~/certs/prod-ca.pem
+  circulator config use-context prod
+  circulator --context staging get agents`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	configCmd.AddCommand(initGetContextsCmd(conf))
+	configCmd.AddCommand(initCurrentContextCmd(conf))
+	configCmd.AddCommand(initUseContextCmd(conf))
+	configCmd.AddCommand(initSetContextCmd(conf))
+	configCmd.AddCommand(initDeleteContextCmd(conf))
+	return configCmd
+}
+
+func initGetContextsCmd(conf config.BaseConfig) *cobra.Command {
+	contextUsecase := usecase.NewContextUsecase(conf)
+
+	return &cobra.Command{
+		Use:   "get-contexts",
+		Short: "List the contexts, marking current-context",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(contextUsecase.GetContexts(GetOutputFormat()))
+		},
+	}
+}
+
+func initCurrentContextCmd(conf config.BaseConfig) *cobra.Command {
+	contextUsecase := usecase.NewContextUsecase(conf)
+
+	return &cobra.Command{
+		Use:   "current-context",
+		Short: "Show the context commands use",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(contextUsecase.CurrentContext(GetOutputFormat()))
+		},
+	}
+}
+
+func initUseContextCmd(conf config.BaseConfig) *cobra.Command {
+	contextUsecase := usecase.NewContextUsecase(conf)
+
+	return &cobra.Command{
+		Use:   "use-context <name>",
+		Short: "Set current-context",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(contextUsecase.UseContext(args[0], GetOutputFormat()))
+		},
+	}
+}
+
+func initSetContextCmd(conf config.BaseConfig) *cobra.Command {
+	contextUsecase := usecase.NewContextUsecase(conf)
+	var changes config.ClientContext
+
+	cmd := &cobra.Command{
+		Use:   "set-context <name>",
+		Short: "Create a context or change the given fields of one",
+		Long: `Create a context or change the given fields of one. New contexts need --server.
+Paths given here are relative to the working directory.
+
+Tokens are kept by --token-backend:
+  auto            keyring where available, encrypted-file otherwise
+  keyring         Secret Service over D-Bus, macOS Keychain or Windows Credential Manager
+  encrypted-file  files encrypted with $CIRCULATOR_TOKEN_PASSPHRASE, asked on the terminal when unset
+  file            plain files, only when chosen explicitly
+Files go to tokens/<name> next to the config file unless --token-store is given.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			flags := cmd.Flags()
+			printOutput(contextUsecase.SetContext(args[0], func(ctx *config.ClientContext) {
+				if flags.Changed("server") {
+					ctx.Server = changes.Server
+				}
+				if flags.Changed("user") {
+					ctx.User = changes.User
+				}
+				if flags.Changed("certificate-authority") {
+					ctx.CertificateAuthority = absPath(changes.CertificateAuthority)
+				}
+				if flags.Changed("insecure-skip-tls-verify") {
+					ctx.InsecureSkipTLSVerify = changes.InsecureSkipTLSVerify
+				}
+				if flags.Changed("token-store") {
+					ctx.TokenStore = absPath(changes.TokenStore)
+				}
+				if flags.Changed("token-backend") {
+					ctx.TokenBackend = changes.TokenBackend
+				}
+			}, GetOutputFormat()))
+		},
+	}
+	cmd.Flags().StringVar(&changes.Server, "server", "", "Server endpoint, e.g. https://circulator.example.com")
+	cmd.Flags().StringVar(&changes.User, "user", "", "User email used to log in")
+	cmd.Flags().StringVar(&changes.CertificateAuthority, "certificate-authority", "", "PEM file of the CA that signed the server certificate")
+	cmd.Flags().BoolVar(&changes.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "Do not verify the server certificate")
+	cmd.Flags().StringVar(&changes.TokenStore, "token-store", "", "Directory of encrypted or plain token files")
+	cmd.Flags().StringVar(&changes.TokenBackend, "token-backend", "", "Where tokens are kept: "+strings.Join(config.TokenBackends, "|")+" (default auto)")
+
+	return cmd
+}
+
+func initDeleteContextCmd(conf config.BaseConfig) *cobra.Command {
+	contextUsecase := usecase.NewContextUsecase(conf)
+
+	return &cobra.Command{
+		Use:   "delete-context <name>",
+		Short: "Delete a context, keeping its token store",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(contextUsecase.DeleteContext(args[0], GetOutputFormat()))
+		},
+	}
+}
+
+// absPath makes a path given on the command line independent of the working directory;
+// ~ is kept and expanded when the context is used
+func absPath(path string) string {
+	if path == "" || path == "~" || strings.HasPrefix(path, "~/") {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// InitDashboardCmd creates the `dashboard` command
+func InitDashboardCmd(conf config.BaseConfig) *cobra.Command {
+	opts := usecase.DashboardOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: "Full-screen dashboard of the fleet",
+		Long: `Show the agents with their status, heartbeat age, thread counts, open alerts and a
+sparkline of a metric, refreshed every --refresh. Enter opens an agent's config, rules and
+alerts; tab lists all open alerts.
+
+Keys: ↑/↓ or j/k select, enter open, esc back, tab alerts, R refresh, q quit.
+Actions ask for confirmation: a acknowledges the selected alert, r sends a config reload
+command to the selected agent and d a drain command.`,
+		Example: `  circulator dashboard
+  circulator dashboard --refresh 10s --sensor-type temperature --metric processed_value --window 1h`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stdin, stdout := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+			if !term.IsTerminal(stdin) || !term.IsTerminal(stdout) {
+				return fmt.Errorf("dashboard needs a terminal")
+			}
+			cmd.SilenceUsage = true
+
+			// Logs would draw over the screen, so unless they go to a file they are dropped
+			switch conf.YamlConfig.Logger.Output {
+			case "", "stdout", "stderr":
+				loggerConfig := conf.YamlConfig.Logger
+				loggerConfig.Output = os.DevNull
+				conf.Logger = config.NewLogger(loggerConfig, &conf)
+			}
+
+			state, err := term.MakeRaw(stdin)
+			if err != nil {
+				return fmt.Errorf("failed to set up the terminal: %w", err)
+			}
+			defer term.Restore(stdin, state)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			opts.Size = func() (int, int) {
+				width, height, err := term.GetSize(stdout)
+				if err != nil {
+					return 0, 0
+				}
+				return width, height
+			}
+			return usecase.NewDashboardUsecase(conf).Run(ctx, opts, os.Stdin, os.Stdout)
+		},
+	}
+	cmd.Flags().DurationVar(&opts.Refresh, "refresh", 5*time.Second, "How often agents, alerts and metrics are fetched")
+	cmd.Flags().StringVar(&opts.SensorType, "sensor-type", "system", "Sensor type of the sparkline metric")
+	cmd.Flags().StringVar(&opts.Metric, "metric", "cpu_usage", "Metric drawn as a sparkline, empty for none")
+	cmd.Flags().DurationVar(&opts.Window, "window", 30*time.Minute, "Time span of the sparkline")
+
+	return cmd
+}
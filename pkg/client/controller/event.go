@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+// InitEventsCmd creates the `events` command
+func InitEventsCmd(conf config.BaseConfig) *cobra.Command {
+	watchUsecase := usecase.NewWatchUsecase(conf)
+	var req request.EventListRequest
+
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Tail server events",
+		Long: `Print the events the server publishes, such as agent_status_changed, agent_config_updated
+or alert_opened, as they happen until interrupted. Tables summarize the fields a change
+touched; other formats print each event as sent. A dropped connection resumes after the
+last event printed.`,
+		Example: `  circulator events --type agent_status_changed --type alert_opened
+  circulator events --agent 7c0e... --since 1h
+  circulator events -o ndjson`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			cmd.SilenceUsage = true
+			return watchUsecase.TailEvents(ctx, req, GetOutputFormat(), os.Stdout)
+		},
+	}
+	cmd.Flags().StringSliceVar(&req.Types, "type", nil, "Only these event types (repeatable)")
+	cmd.Flags().StringVarP(&req.AgentUUID, "agent", "a", "", "Only events of this agent UUID")
+	cmd.Flags().StringVar(&req.Since, "since", "", "Start with the events of this long ago (e.g. 10m), an RFC3339 time or unix seconds instead of new events")
+
+	return cmd
+}
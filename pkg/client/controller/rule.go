@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+const alertRuleLong = `Server-side alert rules are evaluated periodically over stored metrics and raise alerts
+through the same lifecycle as agent alerts. Kinds:
+  threshold     every sample of --metric over the last --for breaches --operator --threshold
+  absence       an agent that reported --sensor-type stopped reporting it for --for
+  anomaly_rate  the percentage of anomalous samples in scope breaches --threshold over --for`
+
+// InitGetAlertRulesCmd creates the `get alert-rules` command
+func InitGetAlertRulesCmd(conf config.BaseConfig) *cobra.Command {
+	ruleUsecase := usecase.NewRuleUsecase(conf)
+
+	return &cobra.Command{
+		Use:     "alert-rules",
+		Aliases: []string{"alert-rule"},
+		Short:   "List server-side alert rules",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(ruleUsecase.List(GetOutputFormat()))
+		},
+	}
+}
+
+// InitCreateAlertRuleCmd creates the `create alert-rule` command
+func InitCreateAlertRuleCmd(conf config.BaseConfig) *cobra.Command {
+	ruleUsecase := usecase.NewRuleUsecase(conf)
+	var req request.AlertRuleRequest
+	var disabled bool
+
+	cmd := &cobra.Command{
+		Use:   "alert-rule",
+		Short: "Create a server-side alert rule",
+		Long:  alertRuleLong,
+		Example: `  circulator create alert-rule --name high_cpu --kind threshold --sensor-type system --metric cpu_usage --operator ">" --threshold 90 --for 5m
+  circulator create alert-rule --name temp_silent --kind absence --sensor-type temperature --for 10m --severity high
+  circulator create alert-rule --name plant_a_anomalies --kind anomaly_rate --group plant-a --threshold 5 --for 15m`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			req.Enabled = enabledFlag(disabled)
+			printOutput(ruleUsecase.Create(req, GetOutputFormat()))
+		},
+	}
+	addAlertRuleFlags(cmd, &req, &disabled)
+
+	return cmd
+}
+
+// InitUpdateAlertRuleCmd creates the `update alert-rule` command
+func InitUpdateAlertRuleCmd(conf config.BaseConfig) *cobra.Command {
+	ruleUsecase := usecase.NewRuleUsecase(conf)
+	var req request.AlertRuleRequest
+	var disabled bool
+
+	cmd := &cobra.Command{
+		Use:   "alert-rule <rule-uuid>",
+		Short: "Replace a server-side alert rule",
+		Long:  alertRuleLong + "\n\nThe flags replace the whole definition; renaming a rule starts new alerts.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			req.Enabled = enabledFlag(disabled)
+			printOutput(ruleUsecase.Update(args[0], req, GetOutputFormat()))
+		},
+	}
+	addAlertRuleFlags(cmd, &req, &disabled)
+
+	return cmd
+}
+
+// InitDeleteAlertRuleCmd creates the `delete alert-rule` command
+func InitDeleteAlertRuleCmd(conf config.BaseConfig) *cobra.Command {
+	ruleUsecase := usecase.NewRuleUsecase(conf)
+
+	return &cobra.Command{
+		Use:   "alert-rule <rule-uuid>",
+		Short: "Delete a server-side alert rule",
+		Long:  "Delete a server-side alert rule. Alerts it raised are kept.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(ruleUsecase.Delete(args[0], GetOutputFormat()))
+		},
+	}
+}
+
+func addAlertRuleFlags(cmd *cobra.Command, req *request.AlertRuleRequest, disabled *bool) {
+	cmd.Flags().StringVar(&req.Name, "name", "", "Rule name, used as the alert rule (required)")
+	cmd.Flags().StringVar(&req.Kind, "kind", "", "Rule kind: threshold|absence|anomaly_rate (required)")
+	cmd.Flags().StringVar(&req.SensorType, "sensor-type", "", "Sensor type, \"system\" for host metrics")
+	cmd.Flags().StringVar(&req.Metric, "metric", "", "Metric name for threshold rules, e.g. cpu_usage or processed_value")
+	cmd.Flags().StringVar(&req.Operator, "operator", "", "Comparison: >, >=, < or <=")
+	cmd.Flags().Float64Var(&req.Threshold, "threshold", 0, "Threshold, a percentage for anomaly_rate rules")
+	cmd.Flags().StringVar(&req.For, "for", "", "Window the condition must hold, e.g. 5m (default 5m)")
+	cmd.Flags().StringVarP(&req.AgentUUID, "agent", "a", "", "Only evaluate this agent")
+	cmd.Flags().StringVar(&req.Group, "group", "", "Only evaluate agents of this metadata group")
+	cmd.Flags().StringVar(&req.Severity, "severity", "", "Alert severity: low|medium|high|critical (default medium)")
+	cmd.Flags().StringVarP(&req.Message, "message", "m", "", "Text prefixed to the alert message")
+	cmd.Flags().BoolVar(disabled, "disabled", false, "Store the rule without evaluating it")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("kind")
+}
+
+func enabledFlag(disabled bool) *bool {
+	enabled := !disabled
+	return &enabled
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+// InitCreateSilenceCmd creates the `create silence` command
+func InitCreateSilenceCmd(conf config.BaseConfig) *cobra.Command {
+	silenceUsecase := usecase.NewSilenceUsecase(conf)
+	var req request.SilenceRequest
+	var start, end string
+
+	cmd := &cobra.Command{
+		Use:   "silence",
+		Short: "Silence notifications",
+		Long: "Suppress notifications about alerts and agents matching every --match until the silence ends.\n" +
+			"Alerts are still recorded while silenced. Matchers: agent_uuid, group, sensor_type, rule, event.",
+		Example: "  circulator create silence --match agent_uuid=1b4e... --match rule=high_temp --duration 2h -m \"replacing sensor\"",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if req.StartsAt, err = parseFlagTime("start", start); err != nil {
+				return err
+			}
+			if req.EndsAt, err = parseFlagTime("end", end); err != nil {
+				return err
+			}
+			if req.EndsAt == nil && req.Duration == "" {
+				return fmt.Errorf("--duration or --end is required")
+			}
+			printOutput(silenceUsecase.Create(req, GetOutputFormat()))
+			return nil
+		},
+	}
+	cmd.Flags().StringToStringVar(&req.Matchers, "match", nil, "Matcher key=value, repeatable (required)")
+	cmd.Flags().StringVar(&req.Duration, "duration", "", "How long the silence lasts, e.g. 30m or 2h")
+	cmd.Flags().StringVar(&start, "start", "", "Start time in RFC 3339 (defaults to now)")
+	cmd.Flags().StringVar(&end, "end", "", "End time in RFC 3339, instead of --duration")
+	cmd.Flags().StringVarP(&req.Comment, "comment", "m", "", "Why the notifications are silenced")
+	cmd.MarkFlagRequired("match")
+
+	return cmd
+}
+
+// parseFlagTime parses an optional RFC 3339 flag value
+func parseFlagTime(flag, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("--%s must be an RFC 3339 time such as 2026-01-02T15:04:05Z", flag)
+	}
+	return &parsed, nil
+}
+
+// InitGetSilencesCmd creates the `get silences` command
+func InitGetSilencesCmd(conf config.BaseConfig) *cobra.Command {
+	silenceUsecase := usecase.NewSilenceUsecase(conf)
+	var req request.SilenceListRequest
+
+	cmd := &cobra.Command{
+		Use:     "silences",
+		Aliases: []string{"silence"},
+		Short:   "List silences",
+		Long:    "List active and upcoming silences. With --all ended silences are listed too.",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(silenceUsecase.List(req, GetOutputFormat()))
+		},
+	}
+	cmd.Flags().BoolVar(&req.All, "all", false, "Include ended silences")
+
+	return cmd
+}
+
+// InitDeleteSilenceCmd creates the `delete silence` command
+func InitDeleteSilenceCmd(conf config.BaseConfig) *cobra.Command {
+	silenceUsecase := usecase.NewSilenceUsecase(conf)
+
+	return &cobra.Command{
+		Use:   "silence <silence-uuid>",
+		Short: "Expire a silence",
+		Long:  "End a silence now. The silence is kept and still listed by get silences --all.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(silenceUsecase.Expire(args[0], GetOutputFormat()))
+		},
+	}
+}
+
+// InitCreateMaintenanceWindowCmd creates the `create maintenance-window` command
+func InitCreateMaintenanceWindowCmd(conf config.BaseConfig) *cobra.Command {
+	silenceUsecase := usecase.NewSilenceUsecase(conf)
+	var req request.MaintenanceWindowRequest
+
+	cmd := &cobra.Command{
+		Use:     "maintenance-window",
+		Aliases: []string{"mw"},
+		Short:   "Create a recurring maintenance window",
+		Long: "Suppress all notifications about the agents of a group, including agents going offline, during a recurring window.\n" +
+			"Agents belong to the group named by the \"group\" key of their metadata.",
+		Example: "  circulator create maintenance-window --group plant-a --days sat,sun --start 02:00 --duration 3h --timezone Asia/Tokyo",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(silenceUsecase.CreateMaintenanceWindow(req, GetOutputFormat()))
+		},
+	}
+	cmd.Flags().StringVar(&req.Name, "name", "", "Window name")
+	cmd.Flags().StringVar(&req.Group, "group", "", "Agent group (required)")
+	cmd.Flags().StringSliceVar(&req.Weekdays, "days", nil, "Weekdays the window starts on, e.g. mon,wed (default every day)")
+	cmd.Flags().StringVar(&req.StartTime, "start", "", "Start time of day as HH:MM (required)")
+	cmd.Flags().StringVar(&req.Duration, "duration", "", "Window length, e.g. 3h (required)")
+	cmd.Flags().StringVar(&req.Timezone, "timezone", "UTC", "IANA timezone of --start")
+	cmd.Flags().StringVarP(&req.Comment, "comment", "m", "", "Comment")
+	cmd.MarkFlagRequired("group")
+	cmd.MarkFlagRequired("start")
+	cmd.MarkFlagRequired("duration")
+
+	return cmd
+}
+
+// InitGetMaintenanceWindowsCmd creates the `get maintenance-windows` command
+func InitGetMaintenanceWindowsCmd(conf config.BaseConfig) *cobra.Command {
+	silenceUsecase := usecase.NewSilenceUsecase(conf)
+
+	return &cobra.Command{
+		Use:     "maintenance-windows",
+		Aliases: []string{"maintenance-window", "mw"},
+		Short:   "List maintenance windows",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(silenceUsecase.ListMaintenanceWindows(GetOutputFormat()))
+		},
+	}
+}
+
+// InitDeleteMaintenanceWindowCmd creates the `delete maintenance-window` command
+func InitDeleteMaintenanceWindowCmd(conf config.BaseConfig) *cobra.Command {
+	silenceUsecase := usecase.NewSilenceUsecase(conf)
+
+	return &cobra.Command{
+		Use:     "maintenance-window <window-uuid>",
+		Aliases: []string{"mw"},
+		Short:   "Delete a maintenance window",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printOutput(silenceUsecase.DeleteMaintenanceWindow(args[0], GetOutputFormat()))
+		},
+	}
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/client/usecase"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+func InitStreamCmd(conf config.BaseConfig) *cobra.Command {
+	streamCmd := &cobra.Command{
+		Use:   "stream",
+		Short: "Sensor stream operations",
+		Long:  "Record, replay, tail and publish sensor data streams",
+	}
+
+	// Initialize usecase
+	streamUsecase := usecase.NewStreamUsecase(conf)
+
+	// Add subcommands
+	streamCmd.AddCommand(recordStreamCmd(streamUsecase))
+	streamCmd.AddCommand(replayStreamCmd(streamUsecase))
+	streamCmd.AddCommand(tailStreamCmd(streamUsecase))
+	streamCmd.AddCommand(publishStreamCmd(streamUsecase))
+
+	return streamCmd
+}
+
+func recordStreamCmd(streamUsecase usecase.StreamUsecase) *cobra.Command {
+	var opts usecase.RecordOptions
+
+	cmd := &cobra.Command{
+		Use:   "record",
+		Short: "Record external sensor data into an archive",
+		Long:  "Read the external sensor data topic without a subscription and write a gzip-compressed, timestamped archive",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			summary, err := streamUsecase.Record(ctx, opts)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Recorded %d messages to %s in %s (%d skipped)\n",
+				summary.Messages, opts.Path, summary.Elapsed.Round(time.Millisecond), summary.Skipped)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Path, "file", "f", "", "Archive file to write (required)")
+	cmd.Flags().DurationVar(&opts.Duration, "duration", 0, "Stop after this long, 0 to record until interrupted")
+	cmd.Flags().Int64Var(&opts.Count, "count", 0, "Stop after this many messages, 0 for no limit")
+	cmd.Flags().StringSliceVar(&opts.Sources, "source", nil, "Only record these sources (repeatable)")
+	cmd.Flags().BoolVar(&opts.FromEarliest, "from-earliest", false, "Start from the oldest retained message instead of new messages")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func replayStreamCmd(streamUsecase usecase.StreamUsecase) *cobra.Command {
+	var opts usecase.ReplayOptions
+	var asFast bool
+
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Replay a recorded archive",
+		Long:  "Send a recorded archive to the external sensor data topic or directly to an agent, at real speed, N times faster or as fast as possible",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if asFast {
+				opts.Speed = 0
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			summary, err := streamUsecase.Replay(ctx, opts)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Replayed %d messages from %s in %s (%d errors)\n",
+				summary.Messages, opts.Path, summary.Elapsed.Round(time.Millisecond), summary.Errors)
+			if opts.Target == usecase.ReplayTargetGRPC {
+				cmd.Printf("Agent processed %d, flagged %d anomalies\n", summary.Processed, summary.Anomalies)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Path, "file", "f", "", "Archive file to replay (required)")
+	cmd.Flags().Float64Var(&opts.Speed, "speed", 1, "Replay speed multiplier, 1 for real time")
+	cmd.Flags().BoolVar(&asFast, "as-fast-as-possible", false, "Ignore recorded timing")
+	cmd.Flags().StringToStringVar(&opts.Remap, "remap", nil, "Rename sources, e.g. --remap plant-a-t1=test-t1")
+	cmd.Flags().StringVar(&opts.Target, "target", usecase.ReplayTargetPulsar, "Replay target: pulsar|grpc")
+	cmd.Flags().StringVar(&opts.AgentAddr, "agent-addr", "localhost:50051", "Agent gRPC address for --target grpc")
+	cmd.Flags().BoolVar(&opts.KeepTimestamps, "keep-timestamps", false, "Send recorded timestamps instead of shifting them to the replay time")
+	cmd.Flags().BoolVar(&opts.NewIDs, "new-ids", false, "Give replayed messages new UUIDs")
+	cmd.Flags().StringVar(&opts.ResultsPath, "results", "", "Write agent results as JSON lines (grpc target)")
+	cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func tailStreamCmd(streamUsecase usecase.StreamUsecase) *cobra.Command {
+	var opts usecase.TailOptions
+
+	cmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Print the messages of a stream topic as they arrive",
+		Long: `Read a topic without a subscription, so tailing never takes messages from agents or the
+server, and print each message decoded by its type: processed sensor data, alerts, system
+metrics and the other stream models. Tables summarize each message; other formats print
+it whole, -o ndjson one message per line.
+
+--filter compares message fields by their JSON names with ==, !=, >, >=, <, <=, =~ and !~
+(regular expression), joined by && and ||. A bare field tests that it is set and true.`,
+		Example: `  circulator stream tail --topic processed_sensor_data --agent 7c0e... --sensor-type temp
+  circulator stream tail --topic alert_data --filter 'severity =~ high|critical'
+  circulator stream tail --filter 'anomaly && confidence >= 0.9' --sample 10 -o ndjson`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			cmd.SilenceUsage = true
+
+			opts.Format = GetOutputFormat()
+			summary, err := streamUsecase.Tail(ctx, opts, os.Stdout)
+			if err != nil {
+				return err
+			}
+			cmd.PrintErrf("Printed %d messages in %s (%d skipped)\n",
+				summary.Messages, summary.Elapsed.Round(time.Millisecond), summary.Skipped)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Topic, "topic", "processed_sensor_data", "Topic key (external_sensor_data, processed_sensor_data, system_metrics, alert_data, processing_results) or topic name")
+	cmd.Flags().StringVarP(&opts.Agent, "agent", "a", "", "Only messages of this agent UUID")
+	cmd.Flags().StringVar(&opts.SensorType, "sensor-type", "", "Only messages of this sensor type")
+	cmd.Flags().StringVar(&opts.Filter, "filter", "", "Only messages matching this expression, e.g. 'processed_value > 30 && anomaly'")
+	cmd.Flags().Int64Var(&opts.Sample, "sample", 0, "Print one in every N matching messages")
+	cmd.Flags().Float64Var(&opts.Rate, "rate", 0, "Print at most this many messages per second, 0 for no limit")
+	cmd.Flags().Int64Var(&opts.Count, "count", 0, "Stop after printing this many messages, 0 for no limit")
+	cmd.Flags().BoolVar(&opts.FromEarliest, "from-earliest", false, "Start from the oldest retained message instead of new messages")
+
+	return cmd
+}
+
+func publishStreamCmd(streamUsecase usecase.StreamUsecase) *cobra.Command {
+	var opts usecase.PublishOptions
+	var timestamp string
+
+	cmd := &cobra.Command{
+		Use:   "publish",
+		Short: "Publish sensor data to a stream topic",
+		Long: `Publish one reading given by flags, or the IncomingStreamData JSON messages of --file,
+one per line and "-" for stdin, and print the message ID the broker assigned to each.
+Messages without a uuid or timestamp get a new UUID and the current time.`,
+		Example: `  circulator stream publish --source plant-a-t1 --sensor-type temp --value 31.5
+  circulator stream publish -f readings.ndjson
+  generate-readings | circulator stream publish -f - -o ndjson`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.Path == "" && !cmd.Flags().Changed("value") {
+				return fmt.Errorf("either --file or --source and --value are required")
+			}
+			if opts.Path == "" && opts.Data.Source == "" {
+				return fmt.Errorf("--source is required with --value")
+			}
+			if timestamp != "" {
+				t, err := time.Parse(time.RFC3339Nano, timestamp)
+				if err != nil {
+					return fmt.Errorf("invalid --timestamp: %w", err)
+				}
+				opts.Data.Timestamp = t
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			cmd.SilenceUsage = true
+
+			opts.Format = GetOutputFormat()
+			summary, err := streamUsecase.Publish(ctx, opts, os.Stdout)
+			if err != nil {
+				return err
+			}
+			if opts.Path != "" {
+				cmd.PrintErrf("Published %d messages in %s\n", summary.Messages, summary.Elapsed.Round(time.Millisecond))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Topic, "topic", "external_sensor_data", "Topic key (external_sensor_data, processed_sensor_data, ...) or topic name")
+	cmd.Flags().StringVarP(&opts.Path, "file", "f", "", "NDJSON file of messages to publish, - for stdin")
+	cmd.Flags().StringVar(&opts.Data.Source, "source", "", "Source of the reading")
+	cmd.Flags().StringVar(&opts.Data.SensorType, "sensor-type", "", "Sensor type of the reading")
+	cmd.Flags().Float64Var(&opts.Data.Value, "value", 0, "Value of the reading")
+	cmd.Flags().StringVar(&opts.Data.UUID, "uuid", "", "UUID of the reading (defaults to a new UUID)")
+	cmd.Flags().StringVar(&timestamp, "timestamp", "", "RFC3339 time of the reading (defaults to now)")
+	cmd.MarkFlagsMutuallyExclusive("file", "source")
+	cmd.MarkFlagsMutuallyExclusive("file", "value")
+
+	return cmd
+}
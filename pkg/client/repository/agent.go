@@ -22,10 +22,11 @@ type AgentRepository interface {
 
 type agentRepository struct {
 	BaseConfig config.BaseConfig
+	client     *http.Client
 }
 
 func NewAgentRepository(conf config.BaseConfig) AgentRepository {
-	return &agentRepository{BaseConfig: conf}
+	return &agentRepository{BaseConfig: conf, client: httpClient(conf)}
 }
 
 func (r *agentRepository) BootstrapAgentForDB(req request.AgentRequest) interface{} {
@@ -34,13 +35,11 @@ func (r *agentRepository) BootstrapAgentForDB(req request.AgentRequest) interfac
 
 func (r *agentRepository) GetAgent(req request.AgentRequest) interface{} {
 	url := fmt.Sprintf("%s/v1/agents", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
-	client := &http.Client{}
 	httpReq, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
-	bearer(httpReq)
-	resp, err := client.Do(httpReq)
+	resp, err := doAuthorized(r.client, httpReq)
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
@@ -62,14 +61,12 @@ func (r *agentRepository) CreateAgent(req request.AgentRequest) interface{} {
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
-	client := &http.Client{}
 	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	bearer(httpReq)
-	resp, err := client.Do(httpReq)
+	resp, err := doAuthorized(r.client, httpReq)
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
@@ -95,14 +92,12 @@ func (r *agentRepository) UpdateAgent(req request.AgentRequest) interface{} {
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
-	client := &http.Client{}
 	httpReq, err := http.NewRequest("PUT", url, bytes.NewBuffer(b))
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
-	bearer(httpReq)
-	resp, err := client.Do(httpReq)
+	resp, err := doAuthorized(r.client, httpReq)
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
@@ -124,13 +119,11 @@ func (r *agentRepository) DeleteAgent(req request.AgentRequest) interface{} {
 		return map[string]any{"code": "error", "message": "delete requires uuid; not provided in request"}
 	}
 	url := fmt.Sprintf("%s/v1/agent/%s", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint, id)
-	client := &http.Client{}
 	httpReq, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
-	bearer(httpReq)
-	resp, err := client.Do(httpReq)
+	resp, err := doAuthorized(r.client, httpReq)
 	if err != nil {
 		return map[string]any{"code": "error", "message": err.Error()}
 	}
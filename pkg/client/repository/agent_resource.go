@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+// AgentResourceRepository is the typed client for agents and their info, system, config
+// and processing rule subresources. Non-2xx answers are returned as *APIError.
+type AgentResourceRepository interface {
+	GetAgents() (response.AgentDetailListResponse, error)
+
+	GetAgentInfo(agentUUID string) (response.AgentInfoResponse, error)
+	CreateAgentInfo(agentUUID string, req request.AgentInfoRequest) (response.AgentInfoResponse, error)
+	UpdateAgentInfo(agentUUID string, req request.AgentInfoRequest) (response.AgentInfoResponse, error)
+	DeleteAgentInfo(agentUUID string) (response.AgentInfoResponse, error)
+
+	GetAgentSystem(agentUUID string) (response.AgentSystemResponse, error)
+	CreateAgentSystem(agentUUID string, req request.AgentSystemRequest) (response.AgentSystemResponse, error)
+	UpdateAgentSystem(agentUUID string, req request.AgentSystemRequest) (response.AgentSystemResponse, error)
+	DeleteAgentSystem(agentUUID string) (response.AgentSystemResponse, error)
+
+	GetAgentConfig(agentUUID string) (response.AgentConfigResponse, error)
+	CreateAgentConfig(agentUUID string, req request.AgentConfigRequest) (response.AgentConfigResponse, error)
+	UpdateAgentConfig(agentUUID string, req request.AgentConfigRequest) (response.AgentConfigResponse, error)
+	DeleteAgentConfig(agentUUID string) (response.AgentConfigResponse, error)
+
+	GetProcessingRules(agentUUID string) (response.AgentConfigRulesListResponse, error)
+	CreateProcessingRule(agentUUID string, req request.AgentConfigRulesRequest) (response.AgentConfigRulesResponse, error)
+	UpdateProcessingRule(agentUUID, ruleUUID string, req request.AgentConfigRulesRequest) (response.AgentConfigRulesResponse, error)
+	DeleteProcessingRule(agentUUID, ruleUUID string) (response.AgentConfigRulesResponse, error)
+
+	GetAgentMetrics(agentUUID string, req request.AgentMetricsRequest) (response.AgentMetricsResponse, error)
+}
+
+type agentResourceRepository struct {
+	BaseConfig config.BaseConfig
+	client     *http.Client
+}
+
+func NewAgentResourceRepository(conf config.BaseConfig) AgentResourceRepository {
+	return &agentResourceRepository{BaseConfig: conf, client: httpClient(conf)}
+}
+
+// endpoint builds /v1/agent/<uuid>/<subresource...> on the configured server
+func (r *agentResourceRepository) endpoint(agentUUID string, parts ...string) string {
+	endpoint := fmt.Sprintf("%s/v1/agent/%s", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint, url.PathEscape(agentUUID))
+	for _, part := range parts {
+		endpoint += "/" + url.PathEscape(part)
+	}
+	return endpoint
+}
+
+func (r *agentResourceRepository) GetAgents() (response.AgentDetailListResponse, error) {
+	endpoint := fmt.Sprintf("%s/v1/agents", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	var out response.AgentDetailListResponse
+	err := requestJSON(r.client, "GET", endpoint, nil, &out)
+	return out, err
+}
+
+// ============ AGENT INFO ============
+
+func (r *agentResourceRepository) GetAgentInfo(agentUUID string) (response.AgentInfoResponse, error) {
+	var out response.AgentInfoResponse
+	err := requestJSON(r.client, "GET", r.endpoint(agentUUID, "info"), nil, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) CreateAgentInfo(agentUUID string, req request.AgentInfoRequest) (response.AgentInfoResponse, error) {
+	// The server keys agent info by the UUID in the body
+	req.UUID = agentUUID
+	var out response.AgentInfoResponse
+	err := requestJSON(r.client, "POST", r.endpoint(agentUUID, "info"), req, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) UpdateAgentInfo(agentUUID string, req request.AgentInfoRequest) (response.AgentInfoResponse, error) {
+	req.UUID = agentUUID
+	var out response.AgentInfoResponse
+	err := requestJSON(r.client, "PUT", r.endpoint(agentUUID, "info"), req, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) DeleteAgentInfo(agentUUID string) (response.AgentInfoResponse, error) {
+	var out response.AgentInfoResponse
+	err := requestJSON(r.client, "DELETE", r.endpoint(agentUUID, "info"), nil, &out)
+	return out, err
+}
+
+// ============ AGENT SYSTEM ============
+
+func (r *agentResourceRepository) GetAgentSystem(agentUUID string) (response.AgentSystemResponse, error) {
+	var out response.AgentSystemResponse
+	err := requestJSON(r.client, "GET", r.endpoint(agentUUID, "system"), nil, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) CreateAgentSystem(agentUUID string, req request.AgentSystemRequest) (response.AgentSystemResponse, error) {
+	req.AgentUUID = agentUUID
+	var out response.AgentSystemResponse
+	err := requestJSON(r.client, "POST", r.endpoint(agentUUID, "system"), req, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) UpdateAgentSystem(agentUUID string, req request.AgentSystemRequest) (response.AgentSystemResponse, error) {
+	req.AgentUUID = agentUUID
+	var out response.AgentSystemResponse
+	err := requestJSON(r.client, "PUT", r.endpoint(agentUUID, "system"), req, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) DeleteAgentSystem(agentUUID string) (response.AgentSystemResponse, error) {
+	var out response.AgentSystemResponse
+	err := requestJSON(r.client, "DELETE", r.endpoint(agentUUID, "system"), nil, &out)
+	return out, err
+}
+
+// ============ AGENT CONFIG ============
+
+func (r *agentResourceRepository) GetAgentConfig(agentUUID string) (response.AgentConfigResponse, error) {
+	var out response.AgentConfigResponse
+	err := requestJSON(r.client, "GET", r.endpoint(agentUUID, "config"), nil, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) CreateAgentConfig(agentUUID string, req request.AgentConfigRequest) (response.AgentConfigResponse, error) {
+	req.AgentUUID = agentUUID
+	var out response.AgentConfigResponse
+	err := requestJSON(r.client, "POST", r.endpoint(agentUUID, "config"), req, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) UpdateAgentConfig(agentUUID string, req request.AgentConfigRequest) (response.AgentConfigResponse, error) {
+	req.AgentUUID = agentUUID
+	var out response.AgentConfigResponse
+	err := requestJSON(r.client, "PUT", r.endpoint(agentUUID, "config"), req, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) DeleteAgentConfig(agentUUID string) (response.AgentConfigResponse, error) {
+	var out response.AgentConfigResponse
+	err := requestJSON(r.client, "DELETE", r.endpoint(agentUUID, "config"), nil, &out)
+	return out, err
+}
+
+// ============ PROCESSING RULES ============
+
+func (r *agentResourceRepository) GetProcessingRules(agentUUID string) (response.AgentConfigRulesListResponse, error) {
+	var out response.AgentConfigRulesListResponse
+	err := requestJSON(r.client, "GET", r.endpoint(agentUUID, "config", "rules"), nil, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) CreateProcessingRule(agentUUID string, req request.AgentConfigRulesRequest) (response.AgentConfigRulesResponse, error) {
+	var out response.AgentConfigRulesResponse
+	err := requestJSON(r.client, "POST", r.endpoint(agentUUID, "config", "rules"), req, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) UpdateProcessingRule(agentUUID, ruleUUID string, req request.AgentConfigRulesRequest) (response.AgentConfigRulesResponse, error) {
+	var out response.AgentConfigRulesResponse
+	err := requestJSON(r.client, "PUT", r.endpoint(agentUUID, "config", "rules", ruleUUID), req, &out)
+	return out, err
+}
+
+func (r *agentResourceRepository) DeleteProcessingRule(agentUUID, ruleUUID string) (response.AgentConfigRulesResponse, error) {
+	var out response.AgentConfigRulesResponse
+	err := requestJSON(r.client, "DELETE", r.endpoint(agentUUID, "config", "rules", ruleUUID), nil, &out)
+	return out, err
+}
+
+// ============ METRICS ============
+
+func (r *agentResourceRepository) GetAgentMetrics(agentUUID string, req request.AgentMetricsRequest) (response.AgentMetricsResponse, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"sensor_type": req.SensorType,
+		"name":        req.Name,
+		"from":        req.From,
+		"to":          req.To,
+		"step":        req.Step,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	endpoint := r.endpoint(agentUUID, "metrics")
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var out response.AgentMetricsResponse
+	err := requestJSON(r.client, "GET", endpoint, nil, &out)
+	return out, err
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+type AlertRepository interface {
+	GetAlerts(req request.AlertListRequest) interface{}
+	AcknowledgeAlert(req request.AlertActionRequest) interface{}
+	ResolveAlert(req request.AlertActionRequest) interface{}
+}
+
+type alertRepository struct {
+	BaseConfig config.BaseConfig
+	client     *http.Client
+}
+
+func NewAlertRepository(conf config.BaseConfig) AlertRepository {
+	return &alertRepository{BaseConfig: conf, client: httpClient(conf)}
+}
+
+func (r *alertRepository) GetAlerts(req request.AlertListRequest) interface{} {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"status":      req.Status,
+		"agent_uuid":  req.AgentUUID,
+		"sensor_type": req.SensorType,
+		"severity":    req.Severity,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.Itoa(req.Limit))
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/alerts", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	var out response.AlertListResponse
+	return sendJSON(r.client, "GET", endpoint, nil, &out)
+}
+
+func (r *alertRepository) AcknowledgeAlert(req request.AlertActionRequest) interface{} {
+	return r.action(req, "ack")
+}
+
+func (r *alertRepository) ResolveAlert(req request.AlertActionRequest) interface{} {
+	return r.action(req, "resolve")
+}
+
+func (r *alertRepository) action(req request.AlertActionRequest, action string) interface{} {
+	if req.UUID == "" {
+		return map[string]any{"code": "error", "message": action + " requires an alert uuid"}
+	}
+	endpoint := fmt.Sprintf("%s/v1/alert/%s/%s", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint, url.PathEscape(req.UUID), action)
+	var out response.AlertResponse
+	return sendJSON(r.client, "POST", endpoint, req, &out)
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// StreamArchiveVersion is the archive format version written by this build
+const StreamArchiveVersion = 1
+
+// ArchiveWriter appends records to a gzip-compressed JSON-lines stream archive
+type ArchiveWriter interface {
+	Write(record *model.StreamArchiveRecord) error
+	Close() error
+}
+
+// ArchiveReader reads records from a stream archive in recorded order
+type ArchiveReader interface {
+	Header() model.StreamArchiveHeader
+	// Next returns the next record, or io.EOF at the end of the archive
+	Next() (*model.StreamArchiveRecord, error)
+	Close() error
+}
+
+type archiveWriter struct {
+	config  config.BaseConfig
+	file    *os.File
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+}
+
+// NewArchiveWriter creates the archive at path and writes its header line
+func NewArchiveWriter(conf config.BaseConfig, path string, header model.StreamArchiveHeader) (ArchiveWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		conf.Logger.ERROR(config.CRAERR, "Failed to create stream archive", map[string]interface{}{
+			"error": err.Error(),
+			"path":  path,
+		})
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	zw := gzip.NewWriter(file)
+	w := &archiveWriter{
+		config:  conf,
+		file:    file,
+		gzip:    zw,
+		encoder: json.NewEncoder(zw),
+	}
+
+	header.Version = StreamArchiveVersion
+	if err := w.encoder.Encode(header); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to write archive header: %w", err)
+	}
+
+	conf.Logger.DEBUG(config.CRAOPEN, "Stream archive created", map[string]interface{}{
+		"path":  path,
+		"topic": header.Topic,
+	})
+	return w, nil
+}
+
+func (w *archiveWriter) Write(record *model.StreamArchiveRecord) error {
+	return w.encoder.Encode(record)
+}
+
+// Close flushes the compressed stream; an archive that is not closed is truncated
+func (w *archiveWriter) Close() error {
+	if err := w.gzip.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+type archiveReader struct {
+	config  config.BaseConfig
+	file    *os.File
+	gzip    *gzip.Reader
+	decoder *json.Decoder
+	header  model.StreamArchiveHeader
+}
+
+// NewArchiveReader opens the archive at path and reads its header line
+func NewArchiveReader(conf config.BaseConfig, path string) (ArchiveReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		conf.Logger.ERROR(config.CRAERR, "Failed to open stream archive", map[string]interface{}{
+			"error": err.Error(),
+			"path":  path,
+		})
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	zr, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read archive %s: %w", path, err)
+	}
+
+	r := &archiveReader{
+		config:  conf,
+		file:    file,
+		gzip:    zr,
+		decoder: json.NewDecoder(zr),
+	}
+	if err := r.decoder.Decode(&r.header); err != nil {
+		r.Close()
+		return nil, fmt.Errorf("failed to read archive header: %w", err)
+	}
+	if r.header.Version > StreamArchiveVersion {
+		r.Close()
+		return nil, fmt.Errorf("unsupported archive version %d", r.header.Version)
+	}
+
+	conf.Logger.DEBUG(config.CRAOPEN, "Stream archive opened", map[string]interface{}{
+		"path":        path,
+		"topic":       r.header.Topic,
+		"recorded_at": r.header.RecordedAt,
+	})
+	return r, nil
+}
+
+func (r *archiveReader) Header() model.StreamArchiveHeader {
+	return r.header
+}
+
+func (r *archiveReader) Next() (*model.StreamArchiveRecord, error) {
+	var record model.StreamArchiveRecord
+	if err := r.decoder.Decode(&record); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read archive record: %w", err)
+	}
+	return &record, nil
+}
+
+func (r *archiveReader) Close() error {
+	r.gzip.Close()
+	return r.file.Close()
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+func newTestConfig() config.BaseConfig {
+	conf := config.BaseConfig{}
+	conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+	return conf
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	conf := newTestConfig()
+	path := filepath.Join(t.TempDir(), "plant-a.jsonl.gz")
+	recordedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []*model.StreamArchiveRecord{
+		{At: recordedAt, Data: model.IncomingStreamData{UUID: "r1", Source: "sensor-1", SensorType: "temperature", Value: 21.5, Timestamp: recordedAt}},
+		{At: recordedAt.Add(time.Second), Data: model.IncomingStreamData{UUID: "r2", Source: "sensor-2", SensorType: "pressure", Value: -3, Timestamp: recordedAt.Add(time.Second)}},
+		{At: recordedAt.Add(1500 * time.Millisecond), Data: model.IncomingStreamData{UUID: "r3", Source: "sensor-1", Value: 22, Timestamp: recordedAt, RawPayload: []byte(`{"value": 12.`)}},
+	}
+
+	// The writer sets the version of this build
+	writer, err := NewArchiveWriter(conf, path, model.StreamArchiveHeader{Version: 99, Topic: "sensor-data", RecordedAt: recordedAt})
+	if err != nil {
+		t.Fatalf("NewArchiveWriter() error = %v", err)
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reader, err := NewArchiveReader(conf, path)
+	if err != nil {
+		t.Fatalf("NewArchiveReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	want := model.StreamArchiveHeader{Version: StreamArchiveVersion, Topic: "sensor-data", RecordedAt: recordedAt}
+	if header := reader.Header(); !reflect.DeepEqual(header, want) {
+		t.Errorf("Header() = %+v, want %+v", header, want)
+	}
+	for i, want := range records {
+		got, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next() record %d error = %v", i, err)
+		}
+		if !got.At.Equal(want.At) || !got.Data.Timestamp.Equal(want.Data.Timestamp) {
+			t.Errorf("record %d times = %v/%v, want %v/%v", i, got.At, got.Data.Timestamp, want.At, want.Data.Timestamp)
+		}
+		got.At, got.Data.Timestamp = want.At, want.Data.Timestamp
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("record %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("Next() after the last record error = %v, want io.EOF", err)
+	}
+}
+
+// writeRawArchive gzips lines into a file as an archive written by another build would be
+func writeRawArchive(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.jsonl.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zw := gzip.NewWriter(file)
+	for _, line := range lines {
+		io.WriteString(zw, line+"\n")
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewArchiveReader(t *testing.T) {
+	header := func(version int) string {
+		data, _ := json.Marshal(model.StreamArchiveHeader{Version: version, Topic: "sensor-data"})
+		return string(data)
+	}
+	plain := filepath.Join(t.TempDir(), "plain.jsonl")
+	if err := os.WriteFile(plain, []byte(header(1)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{name: "current version", path: writeRawArchive(t, header(StreamArchiveVersion))},
+		{name: "older version", path: writeRawArchive(t, header(0))},
+		{name: "newer version", path: writeRawArchive(t, header(StreamArchiveVersion+1)), wantErr: "unsupported archive version"},
+		{name: "missing", path: filepath.Join(t.TempDir(), "missing.jsonl.gz"), wantErr: "failed to open archive"},
+		{name: "not gzip", path: plain, wantErr: "failed to read archive"},
+		{name: "no header", path: writeRawArchive(t), wantErr: "failed to read archive header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := NewArchiveReader(newTestConfig(), tt.path)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewArchiveReader() error = %v", err)
+				}
+				reader.Close()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewArchiveReader() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArchiveReaderBadRecord(t *testing.T) {
+	path := writeRawArchive(t, `{"version":1,"topic":"sensor-data"}`, `{"at":"2025-06-01T12:00:00Z","data":{"uuid":"r1"}}`, `{"at": 12`)
+	reader, err := NewArchiveReader(newTestConfig(), path)
+	if err != nil {
+		t.Fatalf("NewArchiveReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	if record, err := reader.Next(); err != nil || record.Data.UUID != "r1" {
+		t.Fatalf("Next() = %+v, %v", record, err)
+	}
+	if _, err := reader.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Next() on a broken record error = %v, want a read error", err)
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/ryo-arima/circulator/pkg/config"
@@ -26,40 +24,17 @@ type CommonRepository interface {
 
 type commonRepository struct {
 	BaseConfig config.BaseConfig
+	client     *http.Client
 }
 
 func NewCommonRepository(conf config.BaseConfig) CommonRepository {
 	return &commonRepository{
 		BaseConfig: conf,
+		client:     httpClient(conf),
 	}
 }
 
 // --- shared helpers for repository package ---
-// loadAccessTokenFromFiles tries token files used by locker-style clients
-func loadAccessTokenFromFiles() string {
-	paths := []string{
-		filepath.Join("etc", ".circulator", "client", "base", "access_token"),
-		filepath.Join("etc", ".circulator", "client", "app", "access_token"),
-	}
-	for _, p := range paths {
-		if b, err := os.ReadFile(p); err == nil && len(b) > 0 {
-			return strings.TrimSpace(string(b))
-		}
-	}
-	return ""
-}
-
-// bearer sets Authorization header from env or token files
-func bearer(req *http.Request) {
-	token := os.Getenv("STREAM_MANAGER_ACCESS_TOKEN")
-	if token == "" {
-		token = loadAccessTokenFromFiles()
-	}
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-}
-
 // extractUUID tries to pull `uuid` from any request struct via JSON tags
 func extractUUID(v any) string {
 	b, err := json.Marshal(v)
@@ -84,7 +59,7 @@ func (r *commonRepository) Login(req request.LoginRequest) (response.LoginRespon
 		return response.LoginResponse{}, err
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := r.client.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return response.LoginResponse{}, err
 	}
@@ -111,7 +86,7 @@ func (r *commonRepository) RefreshToken(req request.RefreshTokenRequest) (respon
 		return response.RefreshTokenResponse{}, err
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := r.client.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return response.RefreshTokenResponse{}, err
 	}
@@ -133,14 +108,13 @@ func (r *commonRepository) RefreshToken(req request.RefreshTokenRequest) (respon
 func (r *commonRepository) ValidateToken(token string) (response.ValidateResponse, error) {
 	url := fmt.Sprintf("%s/v1/common/tokens/validate", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
 
-	client := &http.Client{}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return response.ValidateResponse{}, err
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	resp, err := client.Do(req)
+	resp, err := r.client.Do(req)
 	if err != nil {
 		return response.ValidateResponse{}, err
 	}
@@ -162,7 +136,6 @@ func (r *commonRepository) ValidateToken(token string) (response.ValidateRespons
 func (r *commonRepository) Logout(token string) (response.CommonResponse, error) {
 	url := fmt.Sprintf("%s/v1/common/tokens", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
 
-	client := &http.Client{}
 	req, err := http.NewRequest("DELETE", url, nil)
 	if err != nil {
 		return response.CommonResponse{}, err
@@ -172,7 +145,7 @@ func (r *commonRepository) Logout(token string) (response.CommonResponse, error)
 		req.Header.Set("Authorization", "Bearer "+token)
 	}
 
-	resp, err := client.Do(req)
+	resp, err := r.client.Do(req)
 	if err != nil {
 		return response.CommonResponse{}, err
 	}
@@ -194,14 +167,13 @@ func (r *commonRepository) Logout(token string) (response.CommonResponse, error)
 func (r *commonRepository) GetUserInfo(token string) (response.CommonResponse, error) {
 	url := fmt.Sprintf("%s/v1/common/tokens/user", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
 
-	client := &http.Client{}
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return response.CommonResponse{}, err
 	}
 	req.Header.Set("Authorization", "Bearer "+token)
 
-	resp, err := client.Do(req)
+	resp, err := r.client.Do(req)
 	if err != nil {
 		return response.CommonResponse{}, err
 	}
@@ -219,3 +191,109 @@ func (r *commonRepository) GetUserInfo(token string) (response.CommonResponse, e
 
 	return result, nil
 }
+
+// sendJSON performs the request and decodes the envelope into out, returning an error map on failure
+func sendJSON(client *http.Client, method, endpoint string, body interface{}, out interface{}) interface{} {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return map[string]any{"code": "error", "message": err.Error()}
+		}
+		reader = bytes.NewBuffer(b)
+	}
+	httpReq, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return map[string]any{"code": "error", "message": err.Error()}
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := doAuthorized(client, httpReq)
+	if err != nil {
+		return map[string]any{"code": "error", "message": err.Error()}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return map[string]any{"code": "error", "message": err.Error()}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return map[string]any{"code": "error", "message": err.Error()}
+	}
+	return out
+}
+
+// APIError is returned by requestJSON when the server answers with a non-2xx status
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s (%d): %s", e.Code, e.Status, e.Message)
+	}
+	return fmt.Sprintf("HTTP %d: %s", e.Status, e.Message)
+}
+
+// newAPIError builds the error of a non-2xx response from its envelope message
+func newAPIError(status int, data []byte) *APIError {
+	var envelope struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	apiErr := &APIError{Status: status, Message: strings.TrimSpace(string(data))}
+	if json.Unmarshal(data, &envelope) == nil {
+		apiErr.Code = envelope.Code
+		if envelope.Message != "" {
+			apiErr.Message = envelope.Message
+		} else if envelope.Error != "" {
+			apiErr.Message = envelope.Error
+		}
+	}
+	return apiErr
+}
+
+// requestJSON performs the request and decodes a 2xx body into out, which may be nil.
+// Other statuses are returned as *APIError carrying the envelope message.
+func requestJSON(client *http.Client, method, endpoint string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewBuffer(b)
+	}
+	httpReq, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := doAuthorized(client, httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newAPIError(resp.StatusCode, data)
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
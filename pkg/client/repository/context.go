@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+)
+
+// UseContext gives conf an HTTP client with the TLS settings of the selected context, or of
+// etc/app.yaml without one, and points the token store at it. Commands copy conf after this,
+// so every client repository shares that client.
+func UseContext(conf *config.BaseConfig) error {
+	client, err := NewHTTPClient(*conf)
+	conf.HTTPClient = client
+	session.use(*conf)
+	return err
+}
+
+// NewHTTPClient builds a client with its own transport, so the TLS settings of one context
+// never leak into http.DefaultTransport or another context
+func NewHTTPClient(conf config.BaseConfig) (*http.Client, error) {
+	client := conf.YamlConfig.Application.Client
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if client.CertificateAuthority == "" && !client.InsecureSkipTLSVerify {
+		return &http.Client{Transport: transport}, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: client.InsecureSkipTLSVerify}
+	if client.CertificateAuthority != "" {
+		pem, err := os.ReadFile(client.CertificateAuthority)
+		if err != nil {
+			return nil, fmt.Errorf("context %s: %w", client.Context, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("context %s: no certificates in %s", client.Context, client.CertificateAuthority)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+// httpClient returns the client UseContext built for conf, or the default one without it
+func httpClient(conf config.BaseConfig) *http.Client {
+	if conf.HTTPClient != nil {
+		return conf.HTTPClient
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyPath := filepath.Join(t.TempDir(), "empty.crt")
+	if err := os.WriteFile(emptyPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		client    config.Client
+		wantErr   bool
+		wantTrust bool
+	}{
+		{name: "system roots", client: config.Client{Context: "prod"}},
+		{name: "certificate authority", client: config.Client{Context: "staging", CertificateAuthority: caPath}, wantTrust: true},
+		{name: "insecure", client: config.Client{Context: "dev", InsecureSkipTLSVerify: true}, wantTrust: true},
+		{name: "missing file", client: config.Client{Context: "staging", CertificateAuthority: filepath.Join(t.TempDir(), "missing.crt")}, wantErr: true},
+		{name: "no certificates", client: config.Client{Context: "staging", CertificateAuthority: emptyPath}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := config.BaseConfig{}
+			conf.YamlConfig.Application.Client = tt.client
+
+			client, err := NewHTTPClient(conf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHTTPClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if client.Transport == http.DefaultTransport {
+				t.Error("client shares http.DefaultTransport")
+			}
+
+			resp, err := client.Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if trusted := err == nil; trusted != tt.wantTrust {
+				t.Errorf("request trusted = %v (error %v), want %v", trusted, err, tt.wantTrust)
+			}
+			if tlsConfig := http.DefaultTransport.(*http.Transport).TLSClientConfig; tlsConfig != nil && (tlsConfig.RootCAs != nil || tlsConfig.InsecureSkipVerify) {
+				t.Error("http.DefaultTransport TLS settings were changed")
+			}
+		})
+	}
+}
@@ -2,95 +2,125 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/codec"
 	"github.com/ryo-arima/circulator/pkg/entity/model"
 )
 
+// CommandTopic is the topic agents read commands from
+const CommandTopic = "agent-commands"
+
 // PulsarRepository handles Pulsar messaging for Client
 type PulsarRepository struct {
-	config   config.BaseConfig
-	client   pulsar.Client
-	producer pulsar.Producer
-	consumer pulsar.Consumer
+	config    config.BaseConfig
+	client    pulsar.Client
+	codec     codec.Codec
+	mu        sync.Mutex
+	producers map[string]pulsar.Producer
+	consumer  pulsar.Consumer
 }
 
-// NewPulsarRepository creates a new PulsarRepository instance
+// NewPulsarRepository creates a new PulsarRepository instance. Producers and the
+// notification consumer are created on first use, so reading a topic takes nothing from
+// the shared subscription.
 func NewPulsarRepository(cfg config.BaseConfig, pulsarURL string) (*PulsarRepository, error) {
 	client, err := pulsar.NewClient(pulsar.ClientOptions{
-		URL: pulsarURL,
+		URL:               pulsarURL,
+		ConnectionTimeout: time.Duration(cfg.YamlConfig.Pulsar.ConnectionTimeout) * time.Second,
+		OperationTimeout:  time.Duration(cfg.YamlConfig.Pulsar.OperationTimeout) * time.Second,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create pulsar client: %w", err)
 	}
 
-	// Create producer
-	producer, err := client.CreateProducer(pulsar.ProducerOptions{
-		Topic: "client-commands",
-		Name:  "client-producer",
+	repo := &PulsarRepository{
+		config:    cfg,
+		client:    client,
+		codec:     codec.NewCodec(cfg, "client"),
+		producers: map[string]pulsar.Producer{},
+	}
+
+	cfg.Logger.INFO(config.CRPINIT, "Client Pulsar repository initialized", map[string]interface{}{
+		"pulsar_url": pulsarURL,
+	})
+
+	return repo, nil
+}
+
+func (r *PulsarRepository) getProducer(topic string) (pulsar.Producer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if producer, ok := r.producers[topic]; ok {
+		return producer, nil
+	}
+	producer, err := r.client.CreateProducer(pulsar.ProducerOptions{
+		Topic:  topic,
+		Schema: r.codec.Schema(topic),
 	})
 	if err != nil {
-		client.Close()
+		r.config.Logger.ERROR(config.CRPERR, "Failed to create producer", map[string]interface{}{
+			"error": err.Error(),
+			"topic": topic,
+		})
 		return nil, fmt.Errorf("failed to create pulsar producer: %w", err)
 	}
+	r.producers[topic] = producer
+	return producer, nil
+}
 
-	// Create consumer
-	consumer, err := client.Subscribe(pulsar.ConsumerOptions{
+func (r *PulsarRepository) getConsumer() (pulsar.Consumer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.consumer != nil {
+		return r.consumer, nil
+	}
+	consumer, err := r.client.Subscribe(pulsar.ConsumerOptions{
 		Topic:            "client-notifications",
 		SubscriptionName: "client-consumer",
 		Type:             pulsar.Shared,
+		Schema:           r.codec.Schema("client-notifications"),
 	})
 	if err != nil {
-		producer.Close()
-		client.Close()
 		return nil, fmt.Errorf("failed to create pulsar consumer: %w", err)
 	}
-
-	repo := &PulsarRepository{
-		config:   cfg,
-		client:   client,
-		producer: producer,
-		consumer: consumer,
-	}
-
-	cfg.Logger.INFO(config.CRPINIT, "Client Pulsar repository initialized", map[string]interface{}{
-		"pulsar_url": pulsarURL,
-	})
-
-	return repo, nil
+	r.consumer = consumer
+	return consumer, nil
 }
 
-// PublishCommand publishes a command to Pulsar
-func (r *PulsarRepository) PublishCommand(ctx context.Context, command *model.Command) error {
+// PublishCommand publishes a command to the agents' command topic and returns the ID
+// of the message the broker stored
+func (r *PulsarRepository) PublishCommand(ctx context.Context, command *model.Command) (string, error) {
 	r.config.Logger.DEBUG(config.CRPPUB, "Publishing command to Pulsar", map[string]interface{}{
 		"command_type": command.Type,
 		"command_id":   command.ID,
 	})
 
-	payload, err := json.Marshal(command)
+	msg, err := r.codec.Encode(CommandTopic, model.MessageTypeCommand, "", command)
 	if err != nil {
-		return fmt.Errorf("failed to marshal command: %w", err)
+		return "", fmt.Errorf("failed to marshal command: %w", err)
 	}
+	msg.Key = command.ID
+	msg.Properties["type"] = command.Type
 
-	msgID, err := r.producer.Send(ctx, &pulsar.ProducerMessage{
-		Payload: payload,
-		Key:     command.ID,
-		Properties: map[string]string{
-			"type":      command.Type,
-			"timestamp": time.Now().UTC().Format(time.RFC3339),
-		},
-	})
+	producer, err := r.getProducer(CommandTopic)
+	if err != nil {
+		return "", err
+	}
+	msgID, err := producer.Send(ctx, msg)
 	if err != nil {
 		r.config.Logger.ERROR(config.CRPERR, "Failed to publish command", map[string]interface{}{
 			"error":        err.Error(),
 			"command_type": command.Type,
 			"command_id":   command.ID,
 		})
-		return fmt.Errorf("failed to publish command: %w", err)
+		return "", fmt.Errorf("failed to publish command: %w", err)
 	}
 
 	r.config.Logger.INFO(config.CRPSUCC, "Command published successfully", map[string]interface{}{
@@ -99,11 +129,48 @@ func (r *PulsarRepository) PublishCommand(ctx context.Context, command *model.Co
 		"command_id":   command.ID,
 	})
 
-	return nil
+	return msgID.String(), nil
+}
+
+// PublishStreamData publishes sensor data to topic, keyed by its source, and returns the
+// ID of the message the broker stored
+func (r *PulsarRepository) PublishStreamData(ctx context.Context, topic string, data *model.IncomingStreamData) (string, error) {
+	msg, err := r.codec.Encode(topic, model.MessageTypeIncomingStreamData, "", data)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sensor data: %w", err)
+	}
+	msg.Key = data.Source
+	msg.EventTime = data.Timestamp
+
+	producer, err := r.getProducer(topic)
+	if err != nil {
+		return "", err
+	}
+	msgID, err := producer.Send(ctx, msg)
+	if err != nil {
+		r.config.Logger.ERROR(config.CRPERR, "Failed to publish sensor data", map[string]interface{}{
+			"error":  err.Error(),
+			"topic":  topic,
+			"source": data.Source,
+		})
+		return "", fmt.Errorf("failed to publish sensor data: %w", err)
+	}
+
+	r.config.Logger.DEBUG(config.CRPSUCC, "Sensor data published successfully", map[string]interface{}{
+		"message_id": msgID.String(),
+		"topic":      topic,
+		"uuid":       data.UUID,
+	})
+
+	return msgID.String(), nil
 }
 
 // ConsumeNotifications consumes notifications from Pulsar
 func (r *PulsarRepository) ConsumeNotifications(ctx context.Context, handler func(*model.Notification) error) error {
+	consumer, err := r.getConsumer()
+	if err != nil {
+		return err
+	}
 	r.config.Logger.INFO(config.CRPCONS, "Starting notification consumption from Pulsar", nil)
 
 	for {
@@ -112,7 +179,7 @@ func (r *PulsarRepository) ConsumeNotifications(ctx context.Context, handler fun
 			r.config.Logger.INFO(config.CRPSTOP, "Stopping notification consumption", nil)
 			return ctx.Err()
 		default:
-			msg, err := r.consumer.Receive(ctx)
+			msg, err := consumer.Receive(ctx)
 			if err != nil {
 				r.config.Logger.ERROR(config.CRPERR, "Failed to receive message", map[string]interface{}{
 					"error": err.Error(),
@@ -121,12 +188,13 @@ func (r *PulsarRepository) ConsumeNotifications(ctx context.Context, handler fun
 			}
 
 			var notification model.Notification
-			if err := json.Unmarshal(msg.Payload(), &notification); err != nil {
+			envelope, err := r.codec.Decode(msg.Payload(), msg.Properties(), &notification)
+			if err != nil {
 				r.config.Logger.ERROR(config.CRPERR, "Failed to unmarshal notification", map[string]interface{}{
 					"error":      err.Error(),
 					"message_id": msg.ID().String(),
 				})
-				r.consumer.Ack(msg)
+				consumer.Ack(msg)
 				continue
 			}
 
@@ -134,6 +202,7 @@ func (r *PulsarRepository) ConsumeNotifications(ctx context.Context, handler fun
 				"notification_type": notification.Type,
 				"notification_id":   notification.ID,
 				"message_id":        msg.ID().String(),
+				"trace_id":          envelope.TraceID,
 			})
 
 			if err := handler(&notification); err != nil {
@@ -142,11 +211,11 @@ func (r *PulsarRepository) ConsumeNotifications(ctx context.Context, handler fun
 					"notification_type": notification.Type,
 					"notification_id":   notification.ID,
 				})
-				r.consumer.Nack(msg)
+				consumer.Nack(msg)
 				continue
 			}
 
-			r.consumer.Ack(msg)
+			consumer.Ack(msg)
 			r.config.Logger.DEBUG(config.CRPSUCC, "Notification processed successfully", map[string]interface{}{
 				"notification_type": notification.Type,
 				"notification_id":   notification.ID,
@@ -155,13 +224,145 @@ func (r *PulsarRepository) ConsumeNotifications(ctx context.Context, handler fun
 	}
 }
 
+// ResolveTopic maps a configured topic key such as processed_sensor_data to its topic
+// name. Other names are taken as topic names.
+func (r *PulsarRepository) ResolveTopic(name string) string {
+	topics := r.config.YamlConfig.Pulsar.Topics
+	switch name {
+	case "external_sensor_data":
+		return topics.ExternalSensorData
+	case "processed_sensor_data":
+		return topics.ProcessedSensorData
+	case "system_metrics":
+		return topics.SystemMetrics
+	case "alert_data":
+		return topics.AlertData
+	case "processing_results":
+		return topics.ProcessingResults
+	}
+	return name
+}
+
+// TailTopic reads topic with a non-durable reader, so tailing never takes messages from
+// subscribers, and calls handler with each message decoded by its message type until ctx
+// is cancelled. Messages without a type are decoded by the model the topic carries.
+func (r *PulsarRepository) TailTopic(ctx context.Context, topic string, fromEarliest bool, handler func(msg *model.TopicMessage) error) error {
+	start := pulsar.LatestMessageID()
+	if fromEarliest {
+		start = pulsar.EarliestMessageID()
+	}
+
+	reader, err := r.client.CreateReader(pulsar.ReaderOptions{
+		Topic:          topic,
+		StartMessageID: start,
+	})
+	if err != nil {
+		r.config.Logger.ERROR(config.CRPERR, "Failed to create topic reader", map[string]interface{}{
+			"error": err.Error(),
+			"topic": topic,
+		})
+		return fmt.Errorf("failed to create reader: %w", err)
+	}
+	defer reader.Close()
+
+	r.config.Logger.INFO(config.CRPCONS, "Tailing topic", map[string]interface{}{
+		"topic":         topic,
+		"from_earliest": fromEarliest,
+	})
+
+	for {
+		msg, err := reader.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read topic %s: %w", topic, err)
+		}
+
+		msgType := msg.Properties()[codec.PropertyType]
+		if msgType == "" {
+			msgType = r.topicMessageType(topic)
+		}
+		data := newTopicModel(msgType)
+		envelope, err := r.codec.Decode(msg.Payload(), msg.Properties(), data)
+		if err != nil {
+			r.config.Logger.WARN(config.CRPERR, "Skipping undecodable message", map[string]interface{}{
+				"error":      err.Error(),
+				"topic":      topic,
+				"message_id": msg.ID().String(),
+			})
+			continue
+		}
+		if envelope.Type != "" {
+			msgType = envelope.Type
+		}
+
+		err = handler(&model.TopicMessage{
+			Topic:       topic,
+			MessageID:   msg.ID().String(),
+			PublishTime: msg.PublishTime(),
+			Type:        msgType,
+			TraceID:     envelope.TraceID,
+			Data:        data,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// topicMessageType is the message type of the configured topic, for messages that
+// predate the envelope
+func (r *PulsarRepository) topicMessageType(topic string) string {
+	topics := r.config.YamlConfig.Pulsar.Topics
+	switch topic {
+	case topics.ExternalSensorData:
+		return model.MessageTypeIncomingStreamData
+	case topics.ProcessedSensorData:
+		return model.MessageTypeProcessedStreamData
+	case topics.SystemMetrics:
+		return model.MessageTypeSystemMetrics
+	case topics.AlertData:
+		return model.MessageTypeAlertData
+	case topics.ProcessingResults:
+		return model.MessageTypeStreamProcessingResult
+	}
+	return ""
+}
+
+// newTopicModel returns the model a message type decodes into, a generic map for JSON
+// messages of other types
+func newTopicModel(msgType string) interface{} {
+	switch msgType {
+	case model.MessageTypeIncomingStreamData:
+		return &model.IncomingStreamData{}
+	case model.MessageTypeCommand:
+		return &model.Command{}
+	case model.MessageTypeNotification:
+		return &model.Notification{}
+	case model.MessageTypeServerEvent:
+		return &model.ServerEvent{}
+	case model.MessageTypeAgentReport:
+		return &model.AgentReport{}
+	case model.MessageTypeProcessedStreamData:
+		return &model.ProcessedStreamData{}
+	case model.MessageTypeSystemMetrics:
+		return &model.SystemMetrics{}
+	case model.MessageTypeAlertData:
+		return &model.AlertData{}
+	case model.MessageTypeStreamProcessingResult:
+		return &model.StreamProcessingResult{}
+	}
+	return &map[string]interface{}{}
+}
+
 // Close closes the Pulsar repository
 func (r *PulsarRepository) Close() {
 	if r.consumer != nil {
 		r.consumer.Close()
 	}
-	if r.producer != nil {
-		r.producer.Close()
+	for _, producer := range r.producers {
+		producer.Close()
 	}
 	if r.client != nil {
 		r.client.Close()
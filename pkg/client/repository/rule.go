@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+type RuleRepository interface {
+	GetRules() interface{}
+	CreateRule(req request.AlertRuleRequest) interface{}
+	UpdateRule(ruleUUID string, req request.AlertRuleRequest) interface{}
+	DeleteRule(ruleUUID string) interface{}
+}
+
+type ruleRepository struct {
+	BaseConfig config.BaseConfig
+	client     *http.Client
+}
+
+func NewRuleRepository(conf config.BaseConfig) RuleRepository {
+	return &ruleRepository{BaseConfig: conf, client: httpClient(conf)}
+}
+
+func (r *ruleRepository) GetRules() interface{} {
+	endpoint := fmt.Sprintf("%s/v1/alert-rules", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	var out response.AlertRuleListResponse
+	return sendJSON(r.client, "GET", endpoint, nil, &out)
+}
+
+func (r *ruleRepository) CreateRule(req request.AlertRuleRequest) interface{} {
+	endpoint := fmt.Sprintf("%s/v1/alert-rule", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	var out response.AlertRuleResponse
+	return sendJSON(r.client, "POST", endpoint, req, &out)
+}
+
+func (r *ruleRepository) UpdateRule(ruleUUID string, req request.AlertRuleRequest) interface{} {
+	endpoint := fmt.Sprintf("%s/v1/alert-rule/%s", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint, url.PathEscape(ruleUUID))
+	var out response.AlertRuleResponse
+	return sendJSON(r.client, "PUT", endpoint, req, &out)
+}
+
+func (r *ruleRepository) DeleteRule(ruleUUID string) interface{} {
+	endpoint := fmt.Sprintf("%s/v1/alert-rule/%s", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint, url.PathEscape(ruleUUID))
+	var out response.AlertRuleResponse
+	return sendJSON(r.client, "DELETE", endpoint, nil, &out)
+}
@@ -26,7 +26,7 @@ func NewServerRepository(config config.BaseConfig, baseURL string) *ServerReposi
 	return &ServerRepository{
 		config:  config,
 		baseURL: baseURL,
-		client:  &http.Client{},
+		client:  httpClient(config),
 	}
 }
 
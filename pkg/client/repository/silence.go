@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+type SilenceRepository interface {
+	GetSilences(req request.SilenceListRequest) interface{}
+	CreateSilence(req request.SilenceRequest) interface{}
+	ExpireSilence(silenceUUID string) interface{}
+
+	GetMaintenanceWindows() interface{}
+	CreateMaintenanceWindow(req request.MaintenanceWindowRequest) interface{}
+	DeleteMaintenanceWindow(windowUUID string) interface{}
+}
+
+type silenceRepository struct {
+	BaseConfig config.BaseConfig
+	client     *http.Client
+}
+
+func NewSilenceRepository(conf config.BaseConfig) SilenceRepository {
+	return &silenceRepository{BaseConfig: conf, client: httpClient(conf)}
+}
+
+func (r *silenceRepository) GetSilences(req request.SilenceListRequest) interface{} {
+	endpoint := fmt.Sprintf("%s/v1/silences", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	if req.All {
+		endpoint += "?all=true"
+	}
+	var out response.SilenceListResponse
+	return sendJSON(r.client, "GET", endpoint, nil, &out)
+}
+
+func (r *silenceRepository) CreateSilence(req request.SilenceRequest) interface{} {
+	endpoint := fmt.Sprintf("%s/v1/silence", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	var out response.SilenceResponse
+	return sendJSON(r.client, "POST", endpoint, req, &out)
+}
+
+func (r *silenceRepository) ExpireSilence(silenceUUID string) interface{} {
+	endpoint := fmt.Sprintf("%s/v1/silence/%s", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint, url.PathEscape(silenceUUID))
+	var out response.SilenceResponse
+	return sendJSON(r.client, "DELETE", endpoint, nil, &out)
+}
+
+func (r *silenceRepository) GetMaintenanceWindows() interface{} {
+	endpoint := fmt.Sprintf("%s/v1/maintenance-windows", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	var out response.MaintenanceWindowListResponse
+	return sendJSON(r.client, "GET", endpoint, nil, &out)
+}
+
+func (r *silenceRepository) CreateMaintenanceWindow(req request.MaintenanceWindowRequest) interface{} {
+	endpoint := fmt.Sprintf("%s/v1/maintenance-window", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	var out response.MaintenanceWindowResponse
+	return sendJSON(r.client, "POST", endpoint, req, &out)
+}
+
+func (r *silenceRepository) DeleteMaintenanceWindow(windowUUID string) interface{} {
+	endpoint := fmt.Sprintf("%s/v1/maintenance-window/%s", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint, url.PathEscape(windowUUID))
+	var out response.MaintenanceWindowResponse
+	return sendJSON(r.client, "DELETE", endpoint, nil, &out)
+}
@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	pb "github.com/ryo-arima/circulator/pkg/agent/gengrpc"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/codec"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// StreamRepository reads and writes sensor data on the external sensor data topic
+type StreamRepository interface {
+	// ReadSensorData reads without a subscription, so recording never takes messages from agents
+	ReadSensorData(ctx context.Context, fromEarliest bool, handler func(publishTime time.Time, data *model.IncomingStreamData) error) error
+	PublishSensorData(ctx context.Context, data *model.IncomingStreamData) error
+	Topic() string
+	Close()
+}
+
+type streamRepository struct {
+	config   config.BaseConfig
+	client   pulsar.Client
+	codec    codec.Codec
+	topic    string
+	mu       sync.Mutex
+	producer pulsar.Producer
+}
+
+// NewStreamRepository creates a StreamRepository. The producer is created on first publish.
+func NewStreamRepository(conf config.BaseConfig) (StreamRepository, error) {
+	client, err := config.NewPulsarClient(conf.YamlConfig)
+	if err != nil {
+		conf.Logger.ERROR(config.CRSTERR, "Failed to create Pulsar client", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to create pulsar client: %w", err)
+	}
+
+	topic := conf.YamlConfig.Pulsar.Topics.ExternalSensorData
+	conf.Logger.DEBUG(config.CRSTINIT, "Client stream repository initialized", map[string]interface{}{
+		"topic": topic,
+	})
+
+	return &streamRepository{
+		config: conf,
+		client: client,
+		codec:  codec.NewCodec(conf, "client"),
+		topic:  topic,
+	}, nil
+}
+
+func (r *streamRepository) Topic() string {
+	return r.topic
+}
+
+func (r *streamRepository) ReadSensorData(ctx context.Context, fromEarliest bool, handler func(publishTime time.Time, data *model.IncomingStreamData) error) error {
+	start := pulsar.LatestMessageID()
+	if fromEarliest {
+		start = pulsar.EarliestMessageID()
+	}
+
+	reader, err := r.client.CreateReader(pulsar.ReaderOptions{
+		Topic:          r.topic,
+		StartMessageID: start,
+	})
+	if err != nil {
+		r.config.Logger.ERROR(config.CRSTERR, "Failed to create sensor data reader", map[string]interface{}{
+			"error": err.Error(),
+			"topic": r.topic,
+		})
+		return fmt.Errorf("failed to create reader: %w", err)
+	}
+	defer reader.Close()
+
+	r.config.Logger.INFO(config.CRSTREAD, "Reading sensor data", map[string]interface{}{
+		"topic":         r.topic,
+		"from_earliest": fromEarliest,
+	})
+
+	for {
+		msg, err := reader.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read sensor data: %w", err)
+		}
+
+		var data model.IncomingStreamData
+		if _, err := r.codec.Decode(msg.Payload(), msg.Properties(), &data); err != nil {
+			r.config.Logger.WARN(config.CRSTERR, "Skipping undecodable sensor data", map[string]interface{}{
+				"error":      err.Error(),
+				"message_id": msg.ID().String(),
+			})
+			continue
+		}
+		if err := handler(msg.PublishTime(), &data); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *streamRepository) PublishSensorData(ctx context.Context, data *model.IncomingStreamData) error {
+	producer, err := r.getProducer()
+	if err != nil {
+		return err
+	}
+
+	msg, err := r.codec.Encode(r.topic, model.MessageTypeIncomingStreamData, "", data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sensor data: %w", err)
+	}
+	msg.Key = data.Source
+	msg.EventTime = data.Timestamp
+
+	if _, err := producer.Send(ctx, msg); err != nil {
+		r.config.Logger.ERROR(config.CRSTERR, "Failed to publish sensor data", map[string]interface{}{
+			"error":  err.Error(),
+			"source": data.Source,
+		})
+		return fmt.Errorf("failed to publish sensor data: %w", err)
+	}
+	return nil
+}
+
+func (r *streamRepository) getProducer() (pulsar.Producer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.producer != nil {
+		return r.producer, nil
+	}
+	producer, err := r.client.CreateProducer(pulsar.ProducerOptions{
+		Topic:  r.topic,
+		Schema: r.codec.Schema(r.topic),
+	})
+	if err != nil {
+		r.config.Logger.ERROR(config.CRSTERR, "Failed to create sensor data producer", map[string]interface{}{
+			"error": err.Error(),
+			"topic": r.topic,
+		})
+		return nil, fmt.Errorf("failed to create producer: %w", err)
+	}
+	r.producer = producer
+	return producer, nil
+}
+
+func (r *streamRepository) Close() {
+	if r.producer != nil {
+		r.producer.Close()
+	}
+	r.client.Close()
+	r.config.Logger.DEBUG(config.CRSTCLOSE, "Client stream repository closed", nil)
+}
+
+// AgentStreamRepository sends sensor data straight to an agent's gRPC StreamService
+type AgentStreamRepository interface {
+	Send(data *model.IncomingStreamData) error
+	// Results yields the agent's result for every reading sent, closed when the stream ends
+	Results() <-chan *model.ProcessedStreamData
+	Close() error
+}
+
+type agentStreamRepository struct {
+	config  config.BaseConfig
+	conn    *grpc.ClientConn
+	stream  pb.StreamService_ProcessStreamClient
+	results chan *model.ProcessedStreamData
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewAgentStreamRepository opens a ProcessStream call to the agent at addr
+func NewAgentStreamRepository(conf config.BaseConfig, addr string) (AgentStreamRepository, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		conf.Logger.ERROR(config.CRSTERR, "Failed to connect to agent", map[string]interface{}{
+			"error":      err.Error(),
+			"agent_addr": addr,
+		})
+		return nil, fmt.Errorf("failed to connect to agent: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := pb.NewStreamServiceClient(conn).ProcessStream(ctx)
+	if err != nil {
+		conf.Logger.ERROR(config.CRSTERR, "Failed to open agent stream", map[string]interface{}{
+			"error":      err.Error(),
+			"agent_addr": addr,
+		})
+		cancel()
+		conn.Close()
+		return nil, fmt.Errorf("failed to open agent stream: %w", err)
+	}
+
+	r := &agentStreamRepository{
+		config:  conf,
+		conn:    conn,
+		stream:  stream,
+		results: make(chan *model.ProcessedStreamData, 1024),
+		cancel:  cancel,
+	}
+	r.wg.Add(1)
+	go r.receive()
+	return r, nil
+}
+
+func (r *agentStreamRepository) Send(data *model.IncomingStreamData) error {
+	return r.stream.Send(&pb.IncomingStreamData{
+		Uuid:       data.UUID,
+		Source:     data.Source,
+		SensorType: data.SensorType,
+		Value:      data.Value,
+		Timestamp:  timestamppb.New(data.Timestamp),
+		RawPayload: data.RawPayload,
+	})
+}
+
+func (r *agentStreamRepository) Results() <-chan *model.ProcessedStreamData {
+	return r.results
+}
+
+func (r *agentStreamRepository) receive() {
+	defer r.wg.Done()
+	defer close(r.results)
+
+	for {
+		out, err := r.stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			r.config.Logger.WARN(config.CRSTERR, "Agent stream closed with error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		r.results <- &model.ProcessedStreamData{
+			UUID:           out.GetUuid(),
+			AgentUUID:      out.GetAgentUuid(),
+			OriginalValue:  out.GetOriginalValue(),
+			ProcessedValue: out.GetProcessedValue(),
+			Anomaly:        out.GetAnomaly(),
+			Confidence:     out.GetConfidence(),
+			ProcessingTime: out.GetProcessingTime(),
+			Timestamp:      out.GetTimestamp().AsTime(),
+			SensorType:     out.GetSensorType(),
+		}
+	}
+}
+
+// Close half-closes the stream and waits for results of everything already sent
+func (r *agentStreamRepository) Close() error {
+	err := r.stream.CloseSend()
+	r.wg.Wait()
+	r.cancel()
+	r.conn.Close()
+	return err
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+)
+
+// tokenSession holds the tokens shared by every client repository. A context has a single
+// token store; without one the base then app login profile is used.
+type tokenSession struct {
+	mu      sync.Mutex
+	conf    config.BaseConfig
+	stores  map[string]TokenStore // by login profile, "" for a context
+	profile string                // profile the cached tokens belong to
+	tokens  model.TokenPair
+	loaded  bool
+}
+
+var session = &tokenSession{}
+
+func (s *tokenSession) use(conf config.BaseConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conf, s.stores = conf, nil
+	s.profile, s.tokens, s.loaded = "", model.TokenPair{}, false
+}
+
+func (s *tokenSession) profiles() []string {
+	if s.conf.YamlConfig.Application.Client.Context != "" {
+		return []string{""}
+	}
+	return []string{"base", "app"}
+}
+
+func (s *tokenSession) store(profile string) (TokenStore, error) {
+	if s.conf.YamlConfig.Application.Client.Context != "" {
+		profile = ""
+	}
+	if store, ok := s.stores[profile]; ok {
+		return store, nil
+	}
+	store, err := NewTokenStore(s.conf, profile)
+	if err != nil {
+		return nil, err
+	}
+	if s.stores == nil {
+		s.stores = map[string]TokenStore{}
+	}
+	s.stores[profile] = store
+	return store, nil
+}
+
+// load returns the cached tokens, reading the first profile that has some on first use
+func (s *tokenSession) load() (string, model.TokenPair, error) {
+	if s.loaded {
+		return s.profile, s.tokens, nil
+	}
+	for _, profile := range s.profiles() {
+		store, err := s.store(profile)
+		if err != nil {
+			return "", model.TokenPair{}, err
+		}
+		tokens, err := store.Load()
+		if err != nil {
+			return "", model.TokenPair{}, err
+		}
+		if tokens.AccessToken != "" || tokens.RefreshToken != "" {
+			s.profile, s.tokens = profile, tokens
+			break
+		}
+	}
+	s.loaded = true
+	return s.profile, s.tokens, nil
+}
+
+func (s *tokenSession) save(profile string, tokens model.TokenPair) error {
+	store, err := s.store(profile)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(tokens); err != nil {
+		return err
+	}
+	s.profile, s.tokens, s.loaded = profile, tokens, true
+	return nil
+}
+
+// refresh exchanges the stored refresh token after the server rejected stale. It returns
+// the new access token, or "" when the tokens cannot be refreshed.
+func (s *tokenSession) refresh(stale string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, tokens, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	if tokens.AccessToken != "" && tokens.AccessToken != stale {
+		// Another request refreshed them meanwhile
+		return tokens.AccessToken, nil
+	}
+	if tokens.RefreshToken == "" {
+		return "", nil
+	}
+
+	resp, err := NewCommonRepository(s.conf).RefreshToken(request.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	if err != nil || resp.TokenPair == nil || resp.TokenPair.AccessToken == "" {
+		// The refresh token expired or was revoked; the caller keeps the 401
+		return "", nil
+	}
+	next := *resp.TokenPair
+	if next.RefreshToken == "" {
+		next.RefreshToken = tokens.RefreshToken
+	}
+	if err := s.save(profile, next); err != nil {
+		return "", err
+	}
+	return next.AccessToken, nil
+}
+
+// StoredTokens returns the stored tokens and the login profile they belong to
+func StoredTokens() (string, model.TokenPair, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.load()
+}
+
+// SaveTokens stores the tokens of a login profile; a context keeps one pair for all profiles
+func SaveTokens(profile string, tokens model.TokenPair) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.save(profile, tokens)
+}
+
+// ClearTokens removes the stored tokens of every profile
+func ClearTokens() error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, profile := range session.profiles() {
+		store, err := session.store(profile)
+		if err != nil {
+			return err
+		}
+		if err := store.Clear(); err != nil {
+			return err
+		}
+	}
+	session.profile, session.tokens, session.loaded = "", model.TokenPair{}, true
+	return nil
+}
+
+// accessToken returns $STREAM_MANAGER_ACCESS_TOKEN or the stored access token and whether it
+// may be refreshed
+func accessToken() (string, bool, error) {
+	if token := os.Getenv("STREAM_MANAGER_ACCESS_TOKEN"); token != "" {
+		return token, false, nil
+	}
+	_, tokens, err := StoredTokens()
+	return tokens.AccessToken, true, err
+}
+
+// doAuthorized sends the request with the access token. When the server answers 401 the
+// stored refresh token is exchanged once and the request is retried with the new token.
+func doAuthorized(client *http.Client, req *http.Request) (*http.Response, error) {
+	token, refreshable, err := accessToken()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || !refreshable {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	fresh, err := session.refresh(token)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if fresh == "" {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+fresh)
+	return client.Do(retry)
+}
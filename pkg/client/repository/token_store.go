@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/99designs/keyring"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"golang.org/x/term"
+)
+
+// TokenStore keeps the access and refresh token of one context or login profile
+type TokenStore interface {
+	// Load returns the stored tokens, an empty pair when none are stored
+	Load() (model.TokenPair, error)
+	Save(tokens model.TokenPair) error
+	Clear() error
+}
+
+// NewTokenStore opens the token backend of the config for a context, or for the login
+// profile (base or app) when no context is selected
+func NewTokenStore(conf config.BaseConfig, profile string) (TokenStore, error) {
+	client := conf.YamlConfig.Application.Client
+	dir, key := client.TokenDir, "context:"+client.Context
+	if client.Context == "" {
+		if profile == "" {
+			profile = "app"
+		}
+		dir, key = filepath.Join("etc", ".circulator", "client", profile), "profile:"+profile
+	}
+
+	switch backend := client.TokenBackend; backend {
+	case "", config.TokenBackendAuto:
+		if store, err := newKeyringTokenStore(key); err == nil {
+			return store, nil
+		}
+		return newEncryptedFileTokenStore(dir)
+	case config.TokenBackendKeyring:
+		return newKeyringTokenStore(key)
+	case config.TokenBackendEncryptedFile:
+		return newEncryptedFileTokenStore(dir)
+	case config.TokenBackendFile:
+		return &fileTokenStore{dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown token backend %q, expected one of %s", backend, strings.Join(config.TokenBackends, ", "))
+	}
+}
+
+// keyringTokenStore keeps the token pair as one JSON item of a keyring
+type keyringTokenStore struct {
+	ring    keyring.Keyring
+	key     string
+	backend string
+}
+
+func newKeyringTokenStore(key string) (TokenStore, error) {
+	ring, err := keyring.Open(keyring.Config{
+		ServiceName:             "circulator",
+		AllowedBackends:         []keyring.BackendType{keyring.SecretServiceBackend, keyring.KeychainBackend, keyring.WinCredBackend},
+		LibSecretCollectionName: "login",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("no OS keyring available: %w", err)
+	}
+	return &keyringTokenStore{ring: ring, key: key, backend: config.TokenBackendKeyring}, nil
+}
+
+// newEncryptedFileTokenStore encrypts the tokens in dir with the passphrase from
+// $CIRCULATOR_TOKEN_PASSPHRASE, asked on the terminal when unset
+func newEncryptedFileTokenStore(dir string) (TokenStore, error) {
+	ring, err := keyring.Open(keyring.Config{
+		AllowedBackends:  []keyring.BackendType{keyring.FileBackend},
+		FileDir:          dir,
+		FilePasswordFunc: tokenPassphrase,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &keyringTokenStore{ring: ring, key: "tokens", backend: config.TokenBackendEncryptedFile}, nil
+}
+
+var errNoPassphrase = errors.New("tokens are encrypted: set CIRCULATOR_TOKEN_PASSPHRASE or run from a terminal")
+
+func tokenPassphrase(prompt string) (string, error) {
+	if passphrase := os.Getenv("CIRCULATOR_TOKEN_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errNoPassphrase
+	}
+	// The prompt goes to stderr so that it never mixes with command output
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(passphrase) == 0 {
+		return "", errors.New("empty token passphrase")
+	}
+	return string(passphrase), nil
+}
+
+func (s *keyringTokenStore) Load() (model.TokenPair, error) {
+	var tokens model.TokenPair
+	item, err := s.ring.Get(s.key)
+	if errors.Is(err, keyring.ErrKeyNotFound) {
+		return tokens, nil
+	}
+	if err != nil && s.backend == config.TokenBackendEncryptedFile && !errors.Is(err, errNoPassphrase) {
+		return tokens, fmt.Errorf("failed to decrypt tokens, wrong passphrase? %w", err)
+	}
+	if err != nil {
+		return tokens, fmt.Errorf("failed to read tokens from %s: %w", s.backend, err)
+	}
+	if err := json.Unmarshal(item.Data, &tokens); err != nil {
+		return tokens, fmt.Errorf("failed to decode tokens from %s: %w", s.backend, err)
+	}
+	return tokens, nil
+}
+
+func (s *keyringTokenStore) Save(tokens model.TokenPair) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	err = s.ring.Set(keyring.Item{
+		Key:   s.key,
+		Data:  data,
+		Label: "circulator tokens (" + s.key + ")",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save tokens to %s: %w", s.backend, err)
+	}
+	return nil
+}
+
+func (s *keyringTokenStore) Clear() error {
+	err := s.ring.Remove(s.key)
+	if err != nil && !errors.Is(err, keyring.ErrKeyNotFound) && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove tokens from %s: %w", s.backend, err)
+	}
+	return nil
+}
+
+// fileTokenStore keeps the tokens as plain access_token and refresh_token files
+type fileTokenStore struct {
+	dir string
+}
+
+func (s *fileTokenStore) Load() (model.TokenPair, error) {
+	var tokens model.TokenPair
+	for name, token := range map[string]*string{"access_token": &tokens.AccessToken, "refresh_token": &tokens.RefreshToken} {
+		b, err := os.ReadFile(filepath.Join(s.dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return tokens, err
+		}
+		*token = strings.TrimSpace(string(b))
+	}
+	return tokens, nil
+}
+
+func (s *fileTokenStore) Save(tokens model.TokenPair) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, "access_token"), []byte(tokens.AccessToken), 0o600); err != nil {
+		return err
+	}
+	if tokens.RefreshToken == "" {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(s.dir, "refresh_token"), []byte(tokens.RefreshToken), 0o600)
+}
+
+func (s *fileTokenStore) Clear() error {
+	for _, name := range []string{"access_token", "refresh_token"} {
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+// Delays between reconnects of a dropped stream, doubled per failed attempt
+const (
+	watchInitialBackoff = time.Second
+	watchMaxBackoff     = 30 * time.Second
+)
+
+type WatchRepository interface {
+	// WatchAgents calls connected each time the stream (re)connects, after which the server
+	// sends every agent again as ADDED, and handle for each change, until ctx is cancelled
+	WatchAgents(ctx context.Context, connected func(), handle func(event response.AgentWatchEvent) error) error
+	// TailEvents calls handle for each server event matching req until ctx is cancelled,
+	// resuming after the last event received when the stream reconnects
+	TailEvents(ctx context.Context, req request.EventListRequest, handle func(event model.ServerEvent) error) error
+}
+
+type watchRepository struct {
+	BaseConfig config.BaseConfig
+	client     *http.Client
+}
+
+func NewWatchRepository(conf config.BaseConfig) WatchRepository {
+	return &watchRepository{BaseConfig: conf, client: httpClient(conf)}
+}
+
+func (r *watchRepository) WatchAgents(ctx context.Context, connected func(), handle func(event response.AgentWatchEvent) error) error {
+	endpoint := fmt.Sprintf("%s/v1/agents/watch", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	return r.stream(ctx, endpoint, connected, func(event sseEvent) error {
+		var change response.AgentWatchEvent
+		if err := json.Unmarshal(event.Data, &change); err != nil {
+			return fmt.Errorf("failed to decode agent change: %w", err)
+		}
+		return handle(change)
+	})
+}
+
+func (r *watchRepository) TailEvents(ctx context.Context, req request.EventListRequest, handle func(event model.ServerEvent) error) error {
+	query := url.Values{}
+	for _, eventType := range req.Types {
+		query.Add("type", eventType)
+	}
+	if req.AgentUUID != "" {
+		query.Set("agent_uuid", req.AgentUUID)
+	}
+	if req.Since != "" {
+		query.Set("since", req.Since)
+	}
+	endpoint := fmt.Sprintf("%s/v1/events", r.BaseConfig.YamlConfig.Application.Client.ServerEndpoint)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	return r.stream(ctx, endpoint, nil, func(event sseEvent) error {
+		var serverEvent model.ServerEvent
+		if err := json.Unmarshal(event.Data, &serverEvent); err != nil {
+			return fmt.Errorf("failed to decode server event: %w", err)
+		}
+		return handle(serverEvent)
+	})
+}
+
+// sseEvent is one server-sent event
+type sseEvent struct {
+	ID   string
+	Name string
+	Data []byte
+}
+
+// errStreamFailed marks an error event sent by the server before it closed the stream
+var errStreamFailed = errors.New("stream failed on the server")
+
+// stream reads server-sent events from endpoint until ctx is cancelled. Dropped connections,
+// server errors and error events are retried with backoff, sending the id of the last event
+// received as Last-Event-ID; other error statuses end the stream.
+func (r *watchRepository) stream(ctx context.Context, endpoint string, connected func(), handle func(event sseEvent) error) error {
+	lastEventID := ""
+	backoff := watchInitialBackoff
+	for {
+		err := r.read(ctx, endpoint, &lastEventID, func() {
+			backoff = watchInitialBackoff
+			r.BaseConfig.Logger.DEBUG(config.CRWOPEN, "Client watch stream connected", map[string]interface{}{
+				"endpoint":      endpoint,
+				"last_event_id": lastEventID,
+			})
+			if connected != nil {
+				connected()
+			}
+		}, handle)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.Status < http.StatusInternalServerError {
+			return err
+		}
+		var handleErr handlerError
+		if errors.As(err, &handleErr) {
+			return handleErr.err
+		}
+
+		r.BaseConfig.Logger.WARN(config.CRWERR, "Client watch stream dropped, reconnecting", map[string]interface{}{
+			"endpoint": endpoint,
+			"error":    fmt.Sprint(err),
+			"backoff":  backoff.String(),
+		})
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watchMaxBackoff)
+	}
+}
+
+// handlerError wraps a failure of the caller's handler, which is not retried
+type handlerError struct {
+	err error
+}
+
+func (e handlerError) Error() string { return e.err.Error() }
+
+// read makes one connection and dispatches its events until it ends
+func (r *watchRepository) read(ctx context.Context, endpoint string, lastEventID *string, connected func(), handle func(event sseEvent) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return handlerError{err}
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if *lastEventID != "" {
+		req.Header.Set("Last-Event-ID", *lastEventID)
+	}
+	resp, err := doAuthorized(r.client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return newAPIError(resp.StatusCode, data)
+	}
+	connected()
+
+	reader := bufio.NewReader(resp.Body)
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if len(event.Data) > 0 {
+				if event.Name == "error" {
+					var envelope response.CommonResponse
+					json.Unmarshal(event.Data, &envelope)
+					return fmt.Errorf("%w: %s", errStreamFailed, envelope.Message)
+				}
+				if err := handle(event); err != nil {
+					return handlerError{err}
+				}
+			}
+			if event.ID != "" {
+				*lastEventID = event.ID
+			}
+			event = sseEvent{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // keep-alive comment
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Name = value
+		case "data":
+			if len(event.Data) > 0 {
+				event.Data = append(event.Data, '\n')
+			}
+			event.Data = append(event.Data, value...)
+		}
+	}
+}
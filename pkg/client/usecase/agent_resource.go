@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+)
+
+// AgentResourceUsecase formats agents and their subresources for the CLI. The server replaces
+// a subresource on PUT, so updates read the current record and apply only the given changes.
+type AgentResourceUsecase interface {
+	ListAgents(format string) string
+
+	GetAgentInfo(agentUUID string, format string) string
+	CreateAgentInfo(agentUUID string, req request.AgentInfoRequest, format string) string
+	UpdateAgentInfo(agentUUID string, apply func(*request.AgentInfoRequest), format string) string
+	DeleteAgentInfo(agentUUID string, format string) string
+
+	GetAgentSystem(agentUUID string, format string) string
+	CreateAgentSystem(agentUUID string, req request.AgentSystemRequest, format string) string
+	UpdateAgentSystem(agentUUID string, apply func(*request.AgentSystemRequest), format string) string
+	DeleteAgentSystem(agentUUID string, format string) string
+
+	GetAgentConfig(agentUUID string, format string) string
+	CreateAgentConfig(agentUUID string, req request.AgentConfigRequest, format string) string
+	UpdateAgentConfig(agentUUID string, apply func(*request.AgentConfigRequest), format string) string
+	DeleteAgentConfig(agentUUID string, format string) string
+
+	ListProcessingRules(agentUUID string, format string) string
+	CreateProcessingRule(agentUUID string, req request.AgentConfigRulesRequest, format string) string
+	UpdateProcessingRule(agentUUID, ruleUUID string, apply func(*request.AgentConfigRulesRequest), format string) string
+	DeleteProcessingRule(agentUUID, ruleUUID string, format string) string
+}
+
+type agentResourceUsecase struct {
+	config config.BaseConfig
+	repo   repository.AgentResourceRepository
+}
+
+func NewAgentResourceUsecase(conf config.BaseConfig) AgentResourceUsecase {
+	return &agentResourceUsecase{
+		config: conf,
+		repo:   repository.NewAgentResourceRepository(conf),
+	}
+}
+
+// formatResult formats resp, or the error envelope used across the CLI when err is set
+func formatResult(format string, resp interface{}, err error) string {
+	if err != nil {
+		return Format(format, map[string]any{"code": "error", "message": err.Error()})
+	}
+	return Format(format, resp)
+}
+
+func (u *agentResourceUsecase) ListAgents(format string) string {
+	resp, err := u.repo.GetAgents()
+	return formatResult(format, resp, err)
+}
+
+// ============ AGENT INFO ============
+
+func (u *agentResourceUsecase) GetAgentInfo(agentUUID string, format string) string {
+	resp, err := u.repo.GetAgentInfo(agentUUID)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) CreateAgentInfo(agentUUID string, req request.AgentInfoRequest, format string) string {
+	resp, err := u.repo.CreateAgentInfo(agentUUID, req)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) UpdateAgentInfo(agentUUID string, apply func(*request.AgentInfoRequest), format string) string {
+	current, err := u.repo.GetAgentInfo(agentUUID)
+	if err != nil {
+		return formatResult(format, nil, err)
+	}
+	if current.Data == nil {
+		return formatResult(format, nil, fmt.Errorf("agent %s has no info", agentUUID))
+	}
+
+	info := current.Data
+	req := request.AgentInfoRequest{
+		Hostname:       info.Hostname,
+		IPAddress:      info.IPAddress,
+		Port:           info.Port,
+		ThreadCount:    info.ThreadCount,
+		MaxThreadCount: info.MaxThreadCount,
+		Version:        info.Version,
+		Capabilities:   info.Capabilities,
+		Metadata:       info.Metadata,
+	}
+	apply(&req)
+
+	resp, err := u.repo.UpdateAgentInfo(agentUUID, req)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) DeleteAgentInfo(agentUUID string, format string) string {
+	resp, err := u.repo.DeleteAgentInfo(agentUUID)
+	return formatResult(format, resp, err)
+}
+
+// ============ AGENT SYSTEM ============
+
+func (u *agentResourceUsecase) GetAgentSystem(agentUUID string, format string) string {
+	resp, err := u.repo.GetAgentSystem(agentUUID)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) CreateAgentSystem(agentUUID string, req request.AgentSystemRequest, format string) string {
+	resp, err := u.repo.CreateAgentSystem(agentUUID, req)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) UpdateAgentSystem(agentUUID string, apply func(*request.AgentSystemRequest), format string) string {
+	current, err := u.repo.GetAgentSystem(agentUUID)
+	if err != nil {
+		return formatResult(format, nil, err)
+	}
+	if current.Data == nil {
+		return formatResult(format, nil, fmt.Errorf("agent %s has no system info", agentUUID))
+	}
+
+	system := current.Data
+	req := request.AgentSystemRequest{
+		Hostname:     system.Hostname,
+		OS:           system.OS,
+		Architecture: system.Architecture,
+		CPUCount:     system.CPUCount,
+	}
+	apply(&req)
+
+	resp, err := u.repo.UpdateAgentSystem(agentUUID, req)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) DeleteAgentSystem(agentUUID string, format string) string {
+	resp, err := u.repo.DeleteAgentSystem(agentUUID)
+	return formatResult(format, resp, err)
+}
+
+// ============ AGENT CONFIG ============
+
+func (u *agentResourceUsecase) GetAgentConfig(agentUUID string, format string) string {
+	resp, err := u.repo.GetAgentConfig(agentUUID)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) CreateAgentConfig(agentUUID string, req request.AgentConfigRequest, format string) string {
+	resp, err := u.repo.CreateAgentConfig(agentUUID, req)
+	return formatResult(format, resp, err)
+}
+
+// UpdateAgentConfig changes the sensor type and output streams; rules have their own verbs
+func (u *agentResourceUsecase) UpdateAgentConfig(agentUUID string, apply func(*request.AgentConfigRequest), format string) string {
+	current, err := u.repo.GetAgentConfig(agentUUID)
+	if err != nil {
+		return formatResult(format, nil, err)
+	}
+	if current.Data == nil {
+		return formatResult(format, nil, fmt.Errorf("agent %s has no config", agentUUID))
+	}
+
+	req := request.AgentConfigRequest{
+		SensorType:    current.Data.SensorType,
+		OutputStreams: current.Data.OutputStreams,
+	}
+	apply(&req)
+
+	resp, err := u.repo.UpdateAgentConfig(agentUUID, req)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) DeleteAgentConfig(agentUUID string, format string) string {
+	resp, err := u.repo.DeleteAgentConfig(agentUUID)
+	return formatResult(format, resp, err)
+}
+
+// ============ PROCESSING RULES ============
+
+func (u *agentResourceUsecase) ListProcessingRules(agentUUID string, format string) string {
+	resp, err := u.repo.GetProcessingRules(agentUUID)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) CreateProcessingRule(agentUUID string, req request.AgentConfigRulesRequest, format string) string {
+	resp, err := u.repo.CreateProcessingRule(agentUUID, req)
+	return formatResult(format, resp, err)
+}
+
+func (u *agentResourceUsecase) UpdateProcessingRule(agentUUID, ruleUUID string, apply func(*request.AgentConfigRulesRequest), format string) string {
+	current, err := u.repo.GetProcessingRules(agentUUID)
+	if err != nil {
+		return formatResult(format, nil, err)
+	}
+
+	for _, rule := range current.Data {
+		if rule.UUID != ruleUUID {
+			continue
+		}
+		req := request.AgentConfigRulesRequest{
+			Name:    rule.Name,
+			Enabled: rule.Enabled,
+			Params:  rule.Params,
+		}
+		apply(&req)
+
+		resp, err := u.repo.UpdateProcessingRule(agentUUID, ruleUUID, req)
+		return formatResult(format, resp, err)
+	}
+	return formatResult(format, nil, fmt.Errorf("processing rule %s not found on agent %s", ruleUUID, agentUUID))
+}
+
+func (u *agentResourceUsecase) DeleteProcessingRule(agentUUID, ruleUUID string, format string) string {
+	resp, err := u.repo.DeleteProcessingRule(agentUUID, ruleUUID)
+	return formatResult(format, resp, err)
+}
@@ -0,0 +1,40 @@
+package usecase
+
+import (
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+)
+
+type AlertUsecase interface {
+	List(req request.AlertListRequest, format string) string
+	Acknowledge(req request.AlertActionRequest, format string) string
+	Resolve(req request.AlertActionRequest, format string) string
+}
+
+type alertUsecase struct {
+	config config.BaseConfig
+	repo   repository.AlertRepository
+}
+
+func NewAlertUsecase(conf config.BaseConfig) AlertUsecase {
+	return &alertUsecase{
+		config: conf,
+		repo:   repository.NewAlertRepository(conf),
+	}
+}
+
+func (u *alertUsecase) List(req request.AlertListRequest, format string) string {
+	resp := u.repo.GetAlerts(req)
+	return Format(format, resp)
+}
+
+func (u *alertUsecase) Acknowledge(req request.AlertActionRequest, format string) string {
+	resp := u.repo.AcknowledgeAlert(req)
+	return Format(format, resp)
+}
+
+func (u *alertUsecase) Resolve(req request.AlertActionRequest, format string) string {
+	resp := u.repo.ResolveAlert(req)
+	return Format(format, resp)
+}
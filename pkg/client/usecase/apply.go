@@ -0,0 +1,473 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"gopkg.in/yaml.v3"
+)
+
+// Actions of an apply change
+const (
+	ApplyActionCreate = "create"
+	ApplyActionUpdate = "update"
+	ApplyActionDelete = "delete"
+	// ApplyActionUnmanaged marks server rules missing from the manifest; they are only deleted with --prune
+	ApplyActionUnmanaged = "unmanaged"
+)
+
+// Resources an apply change acts on
+const (
+	ApplyResourceConfig = "config"
+	ApplyResourceRule   = "rule"
+)
+
+// ApplyChange is one step from the server state towards a manifest
+type ApplyChange struct {
+	Agent    string   `json:"agent" yaml:"agent"`
+	Resource string   `json:"resource" yaml:"resource"`
+	Name     string   `json:"name" yaml:"name"` // sensor type for configs, rule name for rules
+	Action   string   `json:"action" yaml:"action"`
+	RuleUUID string   `json:"rule_uuid,omitempty" yaml:"rule_uuid,omitempty"`
+	Diff     []string `json:"diff,omitempty" yaml:"diff,omitempty"`
+	Error    string   `json:"error,omitempty" yaml:"error,omitempty"`
+
+	config *request.AgentConfigRequest
+	rule   *request.AgentConfigRulesRequest
+}
+
+// ApplyPlan holds the changes for a set of manifests, ordered by agent
+type ApplyPlan struct {
+	Prune     bool          `json:"prune" yaml:"prune"`
+	Changes   []ApplyChange `json:"changes" yaml:"changes"`
+	Unchanged []string      `json:"unchanged,omitempty" yaml:"unchanged,omitempty"` // agents already in the desired state
+}
+
+// Pending returns the number of changes Apply would execute
+func (p *ApplyPlan) Pending() int {
+	pending := 0
+	for _, change := range p.Changes {
+		if change.Action != ApplyActionUnmanaged {
+			pending++
+		}
+	}
+	return pending
+}
+
+type ApplyUsecase interface {
+	// LoadManifests reads manifests from files and directories; "-" reads stdin
+	LoadManifests(paths []string) ([]request.AgentConfigManifest, error)
+	// Plan compares the manifests with the server and returns the changes to reach them
+	Plan(manifests []request.AgentConfigManifest, prune bool) (*ApplyPlan, error)
+	// Apply executes the plan, recording errors on its changes, and returns the number of failures
+	Apply(plan *ApplyPlan) int
+}
+
+type applyUsecase struct {
+	config config.BaseConfig
+	repo   repository.AgentResourceRepository
+}
+
+func NewApplyUsecase(conf config.BaseConfig) ApplyUsecase {
+	return &applyUsecase{
+		config: conf,
+		repo:   repository.NewAgentResourceRepository(conf),
+	}
+}
+
+func (u *applyUsecase) LoadManifests(paths []string) ([]request.AgentConfigManifest, error) {
+	var files []string
+	for _, path := range paths {
+		if path == "-" {
+			files = append(files, path)
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no manifests found in %s", strings.Join(paths, ", "))
+	}
+
+	var manifests []request.AgentConfigManifest
+	for _, file := range files {
+		loaded, err := loadManifestFile(file)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, loaded...)
+	}
+	return manifests, nil
+}
+
+// loadManifestFile decodes every YAML document of file, rejecting unknown fields
+func loadManifestFile(file string) ([]request.AgentConfigManifest, error) {
+	var reader io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	decoder := yaml.NewDecoder(reader)
+	decoder.KnownFields(true)
+
+	var manifests []request.AgentConfigManifest
+	for doc := 1; ; doc++ {
+		var manifest request.AgentConfigManifest
+		err := decoder.Decode(&manifest)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s document %d: %w", file, doc, err)
+		}
+		if manifest.Kind == "" && manifest.Agent == "" && len(manifest.Agents) == 0 {
+			// empty document, e.g. a trailing ---
+			continue
+		}
+		manifest.Source = fmt.Sprintf("%s document %d", file, doc)
+		if err := validateManifest(manifest); err != nil {
+			return nil, fmt.Errorf("%s: %w", manifest.Source, err)
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+func validateManifest(manifest request.AgentConfigManifest) error {
+	if manifest.Kind != request.ManifestKindAgentConfig {
+		return fmt.Errorf("unsupported kind %q, expected %s", manifest.Kind, request.ManifestKindAgentConfig)
+	}
+	if manifest.Agent == "" && len(manifest.Agents) == 0 {
+		return fmt.Errorf("agent or agents is required")
+	}
+	if manifest.SensorType == "" {
+		return fmt.Errorf("sensor_type is required")
+	}
+	names := make(map[string]bool, len(manifest.ProcessingRules))
+	for _, rule := range manifest.ProcessingRules {
+		if rule.Name == "" {
+			return fmt.Errorf("processing rule without name")
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("processing rule %s is listed twice", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+func (u *applyUsecase) Plan(manifests []request.AgentConfigManifest, prune bool) (*ApplyPlan, error) {
+	desired := make(map[string]request.AgentConfigManifest)
+	for _, manifest := range manifests {
+		agents := manifest.Agents
+		if manifest.Agent != "" {
+			agents = append([]string{manifest.Agent}, agents...)
+		}
+		for _, agentUUID := range agents {
+			if previous, ok := desired[agentUUID]; ok {
+				return nil, fmt.Errorf("agent %s is described by both %s and %s", agentUUID, previous.Source, manifest.Source)
+			}
+			desired[agentUUID] = manifest
+		}
+	}
+
+	plan := &ApplyPlan{Prune: prune}
+	for _, agentUUID := range sortedAgents(desired) {
+		changes, err := u.planAgent(agentUUID, desired[agentUUID], prune)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", agentUUID, err)
+		}
+		if len(changes) == 0 {
+			plan.Unchanged = append(plan.Unchanged, agentUUID)
+		}
+		plan.Changes = append(plan.Changes, changes...)
+	}
+	return plan, nil
+}
+
+func (u *applyUsecase) planAgent(agentUUID string, manifest request.AgentConfigManifest, prune bool) ([]ApplyChange, error) {
+	var changes []ApplyChange
+	var current *response.AgentConfig
+
+	resp, err := u.repo.GetAgentConfig(agentUUID)
+	var apiErr *repository.APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound:
+		changes = append(changes, ApplyChange{
+			Agent:    agentUUID,
+			Resource: ApplyResourceConfig,
+			Name:     manifest.SensorType,
+			Action:   ApplyActionCreate,
+			Diff:     []string{"output_streams: " + jsonValue(nonNil(manifest.OutputStreams))},
+			config:   &request.AgentConfigRequest{SensorType: manifest.SensorType, OutputStreams: manifest.OutputStreams},
+		})
+	case err != nil:
+		return nil, err
+	default:
+		current = resp.Data
+	}
+
+	if current != nil {
+		var diff []string
+		if current.SensorType != manifest.SensorType {
+			diff = append(diff, fmt.Sprintf("sensor_type: %s -> %s", current.SensorType, manifest.SensorType))
+		}
+		before, after := jsonValue(nonNil(current.OutputStreams)), jsonValue(nonNil(manifest.OutputStreams))
+		if before != after {
+			diff = append(diff, fmt.Sprintf("output_streams: %s -> %s", before, after))
+		}
+		if len(diff) > 0 {
+			changes = append(changes, ApplyChange{
+				Agent:    agentUUID,
+				Resource: ApplyResourceConfig,
+				Name:     manifest.SensorType,
+				Action:   ApplyActionUpdate,
+				Diff:     diff,
+				config:   &request.AgentConfigRequest{SensorType: manifest.SensorType, OutputStreams: manifest.OutputStreams},
+			})
+		}
+	}
+
+	// The first server rule of each name is managed; further duplicates count as missing from the manifest
+	existing := make(map[string]response.AgentConfigRules)
+	var extra []response.AgentConfigRules
+	if current != nil {
+		for _, rule := range current.ProcessingRules {
+			if _, ok := existing[rule.Name]; ok {
+				extra = append(extra, rule)
+				continue
+			}
+			existing[rule.Name] = rule
+		}
+	}
+
+	for _, rule := range manifest.ProcessingRules {
+		want := request.AgentConfigRulesRequest{Name: rule.Name, Enabled: rule.Enabled == nil || *rule.Enabled, Params: rule.Params}
+		have, ok := existing[rule.Name]
+		if !ok {
+			changes = append(changes, ApplyChange{
+				Agent:    agentUUID,
+				Resource: ApplyResourceRule,
+				Name:     rule.Name,
+				Action:   ApplyActionCreate,
+				Diff:     ruleDiff(nil, want),
+				rule:     &want,
+			})
+			continue
+		}
+		delete(existing, rule.Name)
+		if diff := ruleDiff(&have, want); len(diff) > 0 {
+			changes = append(changes, ApplyChange{
+				Agent:    agentUUID,
+				Resource: ApplyResourceRule,
+				Name:     rule.Name,
+				Action:   ApplyActionUpdate,
+				RuleUUID: have.UUID,
+				Diff:     diff,
+				rule:     &want,
+			})
+		}
+	}
+
+	for _, rule := range existing {
+		extra = append(extra, rule)
+	}
+	sort.Slice(extra, func(i, j int) bool {
+		if extra[i].Name != extra[j].Name {
+			return extra[i].Name < extra[j].Name
+		}
+		return extra[i].UUID < extra[j].UUID
+	})
+	for _, rule := range extra {
+		action := ApplyActionUnmanaged
+		if prune {
+			action = ApplyActionDelete
+		}
+		changes = append(changes, ApplyChange{
+			Agent:    agentUUID,
+			Resource: ApplyResourceRule,
+			Name:     rule.Name,
+			Action:   action,
+			RuleUUID: rule.UUID,
+		})
+	}
+	return changes, nil
+}
+
+// ruleDiff lists the fields of want that differ from have, or all of them when have is nil
+func ruleDiff(have *response.AgentConfigRules, want request.AgentConfigRulesRequest) []string {
+	var diff []string
+	if have == nil {
+		diff = append(diff, fmt.Sprintf("enabled: %t", want.Enabled))
+		for _, key := range sortedKeys(want.Params) {
+			diff = append(diff, fmt.Sprintf("params.%s: %s", key, jsonValue(want.Params[key])))
+		}
+		return diff
+	}
+
+	if have.Enabled != want.Enabled {
+		diff = append(diff, fmt.Sprintf("enabled: %t -> %t", have.Enabled, want.Enabled))
+	}
+	keys := make(map[string]interface{}, len(have.Params)+len(want.Params))
+	for key := range have.Params {
+		keys[key] = nil
+	}
+	for key := range want.Params {
+		keys[key] = nil
+	}
+	for _, key := range sortedKeys(keys) {
+		before, after := "<unset>", "<unset>"
+		if value, ok := have.Params[key]; ok {
+			before = jsonValue(value)
+		}
+		if value, ok := want.Params[key]; ok {
+			after = jsonValue(value)
+		}
+		if before != after {
+			diff = append(diff, fmt.Sprintf("params.%s: %s -> %s", key, before, after))
+		}
+	}
+	return diff
+}
+
+func (u *applyUsecase) Apply(plan *ApplyPlan) int {
+	failed := 0
+	// A config that could not be created leaves nothing to attach rules to
+	skipped := make(map[string]bool)
+
+	for i := range plan.Changes {
+		change := &plan.Changes[i]
+		if change.Action == ApplyActionUnmanaged {
+			continue
+		}
+		if skipped[change.Agent] {
+			change.Error = "skipped, the agent config could not be created"
+			failed++
+			continue
+		}
+
+		var err error
+		switch {
+		case change.Resource == ApplyResourceConfig && change.Action == ApplyActionCreate:
+			_, err = u.repo.CreateAgentConfig(change.Agent, *change.config)
+			if err != nil {
+				skipped[change.Agent] = true
+			}
+		case change.Resource == ApplyResourceConfig && change.Action == ApplyActionUpdate:
+			_, err = u.repo.UpdateAgentConfig(change.Agent, *change.config)
+		case change.Action == ApplyActionCreate:
+			_, err = u.repo.CreateProcessingRule(change.Agent, *change.rule)
+		case change.Action == ApplyActionUpdate:
+			_, err = u.repo.UpdateProcessingRule(change.Agent, change.RuleUUID, *change.rule)
+		case change.Action == ApplyActionDelete:
+			_, err = u.repo.DeleteProcessingRule(change.Agent, change.RuleUUID)
+		}
+		if err != nil {
+			change.Error = err.Error()
+			failed++
+		}
+	}
+	return failed
+}
+
+// RenderPlan prints a plan as a readable diff; applied adds the outcome of each change
+func RenderPlan(plan *ApplyPlan, applied bool) string {
+	var b strings.Builder
+	symbols := map[string]string{
+		ApplyActionCreate:    "+",
+		ApplyActionUpdate:    "~",
+		ApplyActionDelete:    "-",
+		ApplyActionUnmanaged: "?",
+	}
+
+	agent := ""
+	for _, change := range plan.Changes {
+		if change.Agent != agent {
+			agent = change.Agent
+			fmt.Fprintf(&b, "agent %s\n", agent)
+		}
+		fmt.Fprintf(&b, "  %s %s %s", symbols[change.Action], change.Resource, change.Name)
+		switch {
+		case change.Action == ApplyActionUnmanaged:
+			b.WriteString("  not in manifest, kept (use --prune to delete)")
+		case applied && change.Error != "":
+			fmt.Fprintf(&b, "  FAILED: %s", change.Error)
+		case applied:
+			fmt.Fprintf(&b, "  %sd", change.Action)
+		}
+		b.WriteString("\n")
+		for _, line := range change.Diff {
+			fmt.Fprintf(&b, "      %s\n", line)
+		}
+	}
+	for _, agentUUID := range plan.Unchanged {
+		fmt.Fprintf(&b, "agent %s unchanged\n", agentUUID)
+	}
+	return b.String()
+}
+
+func sortedAgents(desired map[string]request.AgentConfigManifest) []string {
+	agents := make([]string, 0, len(desired))
+	for agentUUID := range desired {
+		agents = append(agents, agentUUID)
+	}
+	sort.Strings(agents)
+	return agents
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonValue renders a value compactly; maps come out with sorted keys, so equal values render equally
+func jsonValue(value interface{}) string {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(b)
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
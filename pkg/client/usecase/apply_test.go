@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+// fakeAgentResourceRepository serves agent configs from memory; agents without one get a 404
+type fakeAgentResourceRepository struct {
+	repository.AgentResourceRepository
+	configs map[string]*response.AgentConfig
+}
+
+func (r *fakeAgentResourceRepository) GetAgentConfig(agentUUID string) (response.AgentConfigResponse, error) {
+	current, ok := r.configs[agentUUID]
+	if !ok {
+		return response.AgentConfigResponse{}, &repository.APIError{Status: http.StatusNotFound, Message: "not found"}
+	}
+	return response.AgentConfigResponse{Data: current}, nil
+}
+
+func TestApplyPlan(t *testing.T) {
+	disabled := false
+	manifest := func(agents ...string) request.AgentConfigManifest {
+		return request.AgentConfigManifest{
+			Kind:          request.ManifestKindAgentConfig,
+			Agents:        agents,
+			SensorType:    "system",
+			OutputStreams: []string{"reports"},
+			ProcessingRules: []request.ProcessingRuleManifest{
+				{Name: "threshold", Params: map[string]interface{}{"limit": 90}},
+				{Name: "smoothing", Enabled: &disabled},
+			},
+			Source: "agents.yaml document 1",
+		}
+	}
+	current := func(rules ...response.AgentConfigRules) *response.AgentConfig {
+		return &response.AgentConfig{SensorType: "system", OutputStreams: []string{"reports"}, ProcessingRules: rules}
+	}
+	threshold := response.AgentConfigRules{UUID: "r1", Name: "threshold", Enabled: true, Params: map[string]interface{}{"limit": 90}}
+	smoothing := response.AgentConfigRules{UUID: "r2", Name: "smoothing", Enabled: false}
+
+	tests := []struct {
+		name          string
+		manifests     []request.AgentConfigManifest
+		configs       map[string]*response.AgentConfig
+		prune         bool
+		wantChanges   []ApplyChange
+		wantUnchanged []string
+		wantErr       string
+	}{
+		{
+			name:      "missing config is created with its rules",
+			manifests: []request.AgentConfigManifest{manifest("a")},
+			wantChanges: []ApplyChange{
+				{Agent: "a", Resource: ApplyResourceConfig, Name: "system", Action: ApplyActionCreate, Diff: []string{`output_streams: ["reports"]`}},
+				{Agent: "a", Resource: ApplyResourceRule, Name: "threshold", Action: ApplyActionCreate, Diff: []string{"enabled: true", "params.limit: 90"}},
+				{Agent: "a", Resource: ApplyResourceRule, Name: "smoothing", Action: ApplyActionCreate, Diff: []string{"enabled: false"}},
+			},
+		},
+		{
+			name:          "agent in the desired state is unchanged",
+			manifests:     []request.AgentConfigManifest{manifest("a")},
+			configs:       map[string]*response.AgentConfig{"a": current(threshold, smoothing)},
+			wantUnchanged: []string{"a"},
+		},
+		{
+			name:      "changed fields are diffed",
+			manifests: []request.AgentConfigManifest{manifest("a")},
+			configs: map[string]*response.AgentConfig{"a": {
+				SensorType: "system",
+				ProcessingRules: []response.AgentConfigRules{
+					{UUID: "r1", Name: "threshold", Enabled: false, Params: map[string]interface{}{"limit": 80, "window": 5}},
+					smoothing,
+				},
+			}},
+			wantChanges: []ApplyChange{
+				{Agent: "a", Resource: ApplyResourceConfig, Name: "system", Action: ApplyActionUpdate, Diff: []string{`output_streams: [] -> ["reports"]`}},
+				{Agent: "a", Resource: ApplyResourceRule, Name: "threshold", Action: ApplyActionUpdate, RuleUUID: "r1", Diff: []string{"enabled: false -> true", "params.limit: 80 -> 90", "params.window: 5 -> <unset>"}},
+			},
+		},
+		{
+			name:      "rules missing from the manifest are kept without prune",
+			manifests: []request.AgentConfigManifest{manifest("a")},
+			configs:   map[string]*response.AgentConfig{"a": current(threshold, smoothing, response.AgentConfigRules{UUID: "r3", Name: "legacy"})},
+			wantChanges: []ApplyChange{
+				{Agent: "a", Resource: ApplyResourceRule, Name: "legacy", Action: ApplyActionUnmanaged, RuleUUID: "r3"},
+			},
+		},
+		{
+			name:      "rules missing from the manifest are deleted with prune",
+			manifests: []request.AgentConfigManifest{manifest("a")},
+			configs:   map[string]*response.AgentConfig{"a": current(threshold, smoothing, response.AgentConfigRules{UUID: "r3", Name: "legacy"})},
+			prune:     true,
+			wantChanges: []ApplyChange{
+				{Agent: "a", Resource: ApplyResourceRule, Name: "legacy", Action: ApplyActionDelete, RuleUUID: "r3"},
+			},
+		},
+		{
+			name:      "duplicate server rules beyond the first are pruned",
+			manifests: []request.AgentConfigManifest{manifest("a")},
+			configs: map[string]*response.AgentConfig{"a": current(threshold, smoothing,
+				response.AgentConfigRules{UUID: "r4", Name: "threshold", Enabled: true, Params: map[string]interface{}{"limit": 90}})},
+			prune: true,
+			wantChanges: []ApplyChange{
+				{Agent: "a", Resource: ApplyResourceRule, Name: "threshold", Action: ApplyActionDelete, RuleUUID: "r4"},
+			},
+		},
+		{
+			name:          "agents are planned in order",
+			manifests:     []request.AgentConfigManifest{manifest("c", "b")},
+			configs:       map[string]*response.AgentConfig{"b": current(threshold, smoothing), "c": current(threshold, smoothing)},
+			wantUnchanged: []string{"b", "c"},
+		},
+		{
+			name:      "agent described twice",
+			manifests: []request.AgentConfigManifest{manifest("a"), manifest("b", "a")},
+			wantErr:   "agent a is described by both",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &applyUsecase{repo: &fakeAgentResourceRepository{configs: tt.configs}}
+			plan, err := u.Plan(tt.manifests, tt.prune)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Plan() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Plan() error = %v", err)
+			}
+
+			// The requests Apply sends are not part of the rendered plan
+			changes := plan.Changes
+			for i := range changes {
+				changes[i].config, changes[i].rule = nil, nil
+			}
+			if !reflect.DeepEqual(changes, tt.wantChanges) {
+				t.Errorf("Plan() changes = %+v, want %+v", changes, tt.wantChanges)
+			}
+			if !reflect.DeepEqual(plan.Unchanged, tt.wantUnchanged) {
+				t.Errorf("Plan() unchanged = %v, want %v", plan.Unchanged, tt.wantUnchanged)
+			}
+		})
+	}
+}
+
+func TestApplyPlanPending(t *testing.T) {
+	plan := &ApplyPlan{Changes: []ApplyChange{
+		{Action: ApplyActionCreate},
+		{Action: ApplyActionUpdate},
+		{Action: ApplyActionDelete},
+		{Action: ApplyActionUnmanaged},
+	}}
+	if got := plan.Pending(); got != 3 {
+		t.Errorf("Pending() = %d, want 3", got)
+	}
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// SendCommandOptions controls command sending. Commands are read from Path, NDJSON with
+// "-" for stdin, or Command is sent when Path is empty.
+type SendCommandOptions struct {
+	Path    string
+	Command model.Command
+	Format  string
+}
+
+type CommandUsecase interface {
+	// Send publishes commands to the agents' command topic and prints the message ID of
+	// each, returning how many were sent
+	Send(ctx context.Context, opts SendCommandOptions, out io.Writer) (int64, error)
+}
+
+type commandUsecase struct {
+	config config.BaseConfig
+}
+
+func NewCommandUsecase(conf config.BaseConfig) CommandUsecase {
+	return &commandUsecase{
+		config: conf,
+	}
+}
+
+// Send fills in a new ID, the current time and the agent target for commands without them
+func (u *commandUsecase) Send(ctx context.Context, opts SendCommandOptions, out io.Writer) (int64, error) {
+	repo, err := repository.NewPulsarRepository(u.config, u.config.YamlConfig.Pulsar.URL)
+	if err != nil {
+		return 0, err
+	}
+	defer repo.Close()
+
+	u.config.Logger.INFO(config.CUCMDSEND, "Sending commands", map[string]interface{}{
+		"topic": repository.CommandTopic,
+		"path":  opts.Path,
+	})
+
+	printer := &streamPrinter{format: opts.Format}
+	var sent int64
+	send := func(command *model.Command) error {
+		if command.Type == "" || command.Action == "" {
+			return fmt.Errorf("command needs a type and an action")
+		}
+		if command.ID == "" {
+			command.ID = uuid.New().String()
+		}
+		if command.Target == "" {
+			command.Target = "agent"
+		}
+		if command.Timestamp.IsZero() {
+			command.Timestamp = time.Now().UTC()
+		}
+		msgID, err := repo.PublishCommand(ctx, command)
+		if err != nil {
+			return err
+		}
+		sent++
+		_, err = io.WriteString(out, printer.print(publishedMessage{ID: command.ID, Topic: repository.CommandTopic, MessageID: msgID}))
+		return err
+	}
+
+	if opts.Path == "" {
+		err = send(&opts.Command)
+	} else {
+		err = decodeMessages(opts.Path, send)
+	}
+
+	u.config.Logger.INFO(config.CUCMDDONE, "Commands sent", map[string]interface{}{
+		"commands": sent,
+	})
+	return sent, err
+}
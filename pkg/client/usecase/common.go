@@ -5,6 +5,7 @@ import (
 
 	"github.com/ryo-arima/circulator/pkg/client/repository"
 	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
 	"github.com/ryo-arima/circulator/pkg/entity/request"
 	"github.com/ryo-arima/circulator/pkg/entity/response"
 )
@@ -16,6 +17,10 @@ type CommonUsecase interface {
 	Logout(accessToken string) response.CommonResponse
 	ValidateToken(accessToken string) response.ValidateResponse
 	GetUserInfo(accessToken string) response.CommonResponse
+	// Token storage of the selected context or login profile
+	StoredTokens() (string, model.TokenPair, error)
+	SaveTokens(profile string, tokens model.TokenPair) error
+	ClearTokens() error
 }
 
 type commonUsecase struct {
@@ -86,3 +91,15 @@ func (u *commonUsecase) GetUserInfo(accessToken string) response.CommonResponse
 	}
 	return res
 }
+
+func (u *commonUsecase) StoredTokens() (string, model.TokenPair, error) {
+	return repository.StoredTokens()
+}
+
+func (u *commonUsecase) SaveTokens(profile string, tokens model.TokenPair) error {
+	return repository.SaveTokens(profile, tokens)
+}
+
+func (u *commonUsecase) ClearTokens() error {
+	return repository.ClearTokens()
+}
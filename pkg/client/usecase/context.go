@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+type ContextUsecase interface {
+	GetContexts(format string) string
+	CurrentContext(format string) string
+	UseContext(name string, format string) string
+	SetContext(name string, apply func(*config.ClientContext), format string) string
+	DeleteContext(name string, format string) string
+}
+
+type contextUsecase struct {
+	config config.BaseConfig
+	path   string
+}
+
+// NewContextUsecase manages the contexts of the client config file
+func NewContextUsecase(conf config.BaseConfig) ContextUsecase {
+	return &contextUsecase{
+		config: conf,
+		path:   config.ClientConfigPath(),
+	}
+}
+
+// SelectContext loads the client config file and points conf at the context named by the
+// --context flag, $CIRCULATOR_CONTEXT or current-context. Without any, etc/app.yaml is used.
+func SelectContext(conf *config.BaseConfig, name string) error {
+	path := config.ClientConfigPath()
+	file, err := config.LoadClientConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if name = file.ResolveContextName(name); name != "" {
+		ctx, ok := file.Context(name)
+		if !ok {
+			return fmt.Errorf("context %q not found in %s", name, path)
+		}
+		if ctx.Server == "" {
+			return fmt.Errorf("context %q has no server", name)
+		}
+		conf.UseClientContext(ctx, path)
+	}
+	return repository.UseContext(conf)
+}
+
+func (u *contextUsecase) GetContexts(format string) string {
+	file, err := config.LoadClientConfigFile(u.path)
+	if err != nil {
+		return formatResult(format, nil, err)
+	}
+
+	resp := response.ClientContextListResponse{Code: "SUCCESS", Contexts: []response.ClientContext{}}
+	for _, ctx := range file.Contexts {
+		resp.Contexts = append(resp.Contexts, u.describe(ctx, ctx.Name == file.CurrentContext))
+	}
+	return Format(format, resp)
+}
+
+// CurrentContext shows the context in use, which --context or $CIRCULATOR_CONTEXT may override
+func (u *contextUsecase) CurrentContext(format string) string {
+	name := u.config.YamlConfig.Application.Client.Context
+	if name == "" {
+		return formatResult(format, nil, errors.New("no context is selected, see `config use-context`"))
+	}
+	file, err := config.LoadClientConfigFile(u.path)
+	if err != nil {
+		return formatResult(format, nil, err)
+	}
+	ctx, _ := file.Context(name)
+	described := u.describe(ctx, name == file.CurrentContext)
+	return Format(format, response.ClientContextResponse{Code: "SUCCESS", Data: &described})
+}
+
+func (u *contextUsecase) UseContext(name string, format string) string {
+	file, err := config.LoadClientConfigFile(u.path)
+	if err != nil {
+		return formatResult(format, nil, err)
+	}
+	ctx, ok := file.Context(name)
+	if !ok {
+		return formatResult(format, nil, fmt.Errorf("context %q not found in %s", name, u.path))
+	}
+
+	file.CurrentContext = name
+	if err := file.Save(u.path); err != nil {
+		return formatResult(format, nil, err)
+	}
+	described := u.describe(ctx, true)
+	return Format(format, response.ClientContextResponse{
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Switched to context %q.", name),
+		Data:    &described,
+	})
+}
+
+// SetContext creates the context or changes the fields apply sets on it
+func (u *contextUsecase) SetContext(name string, apply func(*config.ClientContext), format string) string {
+	file, err := config.LoadClientConfigFile(u.path)
+	if err != nil {
+		return formatResult(format, nil, err)
+	}
+	ctx, exists := file.Context(name)
+	ctx.Name = name
+	apply(&ctx)
+	if ctx.Server == "" {
+		return formatResult(format, nil, fmt.Errorf("context %q needs a server", name))
+	}
+	if ctx.TokenBackend != "" && !slices.Contains(config.TokenBackends, ctx.TokenBackend) {
+		return formatResult(format, nil, fmt.Errorf("unknown token backend %q, expected one of %s", ctx.TokenBackend, strings.Join(config.TokenBackends, ", ")))
+	}
+
+	file.SetContext(ctx)
+	if err := file.Save(u.path); err != nil {
+		return formatResult(format, nil, err)
+	}
+	message := fmt.Sprintf("Context %q modified.", name)
+	if !exists {
+		message = fmt.Sprintf("Context %q created.", name)
+	}
+	described := u.describe(ctx, name == file.CurrentContext)
+	return Format(format, response.ClientContextResponse{Code: "SUCCESS", Message: message, Data: &described})
+}
+
+// DeleteContext removes the context; its token store is left on disk
+func (u *contextUsecase) DeleteContext(name string, format string) string {
+	file, err := config.LoadClientConfigFile(u.path)
+	if err != nil {
+		return formatResult(format, nil, err)
+	}
+	if !file.DeleteContext(name) {
+		return formatResult(format, nil, fmt.Errorf("context %q not found in %s", name, u.path))
+	}
+	if err := file.Save(u.path); err != nil {
+		return formatResult(format, nil, err)
+	}
+	return Format(format, response.ClientContextResponse{
+		Code:    "SUCCESS",
+		Message: fmt.Sprintf("Deleted context %q.", name),
+	})
+}
+
+func (u *contextUsecase) describe(ctx config.ClientContext, current bool) response.ClientContext {
+	described := response.ClientContext{
+		Current:               current,
+		Name:                  ctx.Name,
+		Server:                ctx.Server,
+		User:                  ctx.User,
+		CertificateAuthority:  ctx.CertificateAuthority,
+		InsecureSkipTLSVerify: ctx.InsecureSkipTLSVerify,
+		TokenStore:            ctx.TokenDir(u.path),
+		TokenBackend:          ctx.TokenBackend,
+	}
+	if described.TokenBackend == "" {
+		described.TokenBackend = config.TokenBackendAuto
+	}
+	return described
+}
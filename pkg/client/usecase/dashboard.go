@@ -0,0 +1,862 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+// How often relative times on the dashboard are redrawn, and how many agents' metrics are
+// fetched at once
+const (
+	dashboardTick            = time.Second
+	dashboardMetricWorkers   = 8
+	dashboardSparklinePoints = 30
+)
+
+// DashboardOptions controls the dashboard
+type DashboardOptions struct {
+	Refresh    time.Duration // how often agents, alerts and metrics are fetched
+	SensorType string        // sensor type of the metric drawn as a sparkline, e.g. system
+	Metric     string        // name of the metric drawn as a sparkline, e.g. cpu_usage
+	Window     time.Duration // time span of the sparkline
+	Size       func() (width, height int)
+}
+
+type DashboardUsecase interface {
+	// Run draws the dashboard on out and handles the keys read from in, a terminal in raw
+	// mode, until q is pressed or ctx is cancelled
+	Run(ctx context.Context, opts DashboardOptions, in io.Reader, out io.Writer) error
+}
+
+type dashboardUsecase struct {
+	config config.BaseConfig
+	agents repository.AgentResourceRepository
+	alerts repository.AlertRepository
+
+	mu       sync.Mutex
+	commands *repository.PulsarRepository
+}
+
+func NewDashboardUsecase(conf config.BaseConfig) DashboardUsecase {
+	return &dashboardUsecase{
+		config: conf,
+		agents: repository.NewAgentResourceRepository(conf),
+		alerts: repository.NewAlertRepository(conf),
+	}
+}
+
+// dashboardView is the screen the dashboard shows
+type dashboardView int
+
+const (
+	viewFleet dashboardView = iota
+	viewAlerts
+	viewAgent
+)
+
+// dashboardFleet is one fetch of the agents, their open alerts and metrics
+type dashboardFleet struct {
+	Agents  []model.Agent
+	Alerts  []model.Alert // open alerts, most recently seen first
+	Metrics map[string][]float64
+	Fetched time.Time
+}
+
+// dashboardAction is an action waiting for the user to confirm it
+type dashboardAction struct {
+	prompt string
+	run    func() (string, error)
+}
+
+// dashboard is the state of a running dashboard. It is only touched by the Run loop;
+// fetches and actions running in the background send their results as updates.
+type dashboard struct {
+	opts     DashboardOptions
+	view     dashboardView
+	fleet    *dashboardFleet
+	fetchErr error
+	fetching bool
+
+	agentRow  int    // selected agent in the fleet view
+	alertRow  int    // selected alert in the alerts and agent views
+	agentUUID string // agent shown in the agent view
+	config    *response.AgentConfig
+	configErr error
+
+	confirm *dashboardAction
+	status  string
+}
+
+func (u *dashboardUsecase) Run(ctx context.Context, opts DashboardOptions, in io.Reader, out io.Writer) error {
+	defer u.closeCommands()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keys := make(chan string)
+	go readKeys(ctx, in, keys)
+	updates := make(chan func(d *dashboard), 16)
+	update := func(apply func(d *dashboard)) {
+		select {
+		case updates <- apply:
+		case <-ctx.Done():
+		}
+	}
+
+	if opts.Refresh <= 0 {
+		opts.Refresh = 5 * time.Second
+	}
+	d := &dashboard{opts: opts}
+	refresh := func() {
+		if d.fetching {
+			return
+		}
+		d.fetching = true
+		agentUUID := ""
+		if d.view == viewAgent {
+			agentUUID = d.agentUUID
+		}
+		go func() {
+			fleet, err := u.fetchFleet(opts)
+			update(func(d *dashboard) {
+				d.fetching = false
+				d.fetchErr = err
+				if err == nil {
+					d.fleet = fleet
+					d.clampRows()
+				}
+			})
+			if agentUUID != "" {
+				u.fetchConfig(agentUUID, update)
+			}
+		}()
+	}
+	// act runs an action in the background and reports its outcome on the status line
+	act := func(action *dashboardAction) {
+		d.status = action.prompt + " …"
+		go func() {
+			result, err := action.run()
+			update(func(d *dashboard) {
+				if err != nil {
+					d.status = "Error: " + err.Error()
+					return
+				}
+				d.status = result
+				refresh()
+			})
+		}()
+	}
+
+	io.WriteString(out, "\033[?1049h\033[?25l")
+	defer io.WriteString(out, "\033[?25h\033[?1049l")
+
+	refresh()
+	fetchTicker := time.NewTicker(opts.Refresh)
+	defer fetchTicker.Stop()
+	drawTicker := time.NewTicker(dashboardTick)
+	defer drawTicker.Stop()
+	for {
+		if _, err := io.WriteString(out, d.render()); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-fetchTicker.C:
+			refresh()
+		case <-drawTicker.C:
+		case apply := <-updates:
+			apply(d)
+		case key, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			if d.confirm != nil {
+				action := d.confirm
+				d.confirm = nil
+				if key == "y" || key == "Y" {
+					act(action)
+				} else {
+					d.status = "Cancelled"
+				}
+				continue
+			}
+			if quit := d.handleKey(key, u, refresh, update); quit {
+				return nil
+			}
+		}
+	}
+}
+
+// handleKey applies a key press, reporting whether the dashboard should quit
+func (d *dashboard) handleKey(key string, u *dashboardUsecase, refresh func(), update func(func(d *dashboard))) bool {
+	d.status = ""
+	switch key {
+	case "q", "ctrl+c":
+		return true
+	case "up", "k":
+		d.move(-1)
+	case "down", "j":
+		d.move(1)
+	case "pgup":
+		d.move(-10)
+	case "pgdown":
+		d.move(10)
+	case "home", "g":
+		d.move(-math.MaxInt32)
+	case "end", "G":
+		d.move(math.MaxInt32)
+	case "tab":
+		if d.view == viewFleet {
+			d.view, d.alertRow = viewAlerts, 0
+		} else {
+			d.view = viewFleet
+		}
+	case "esc", "backspace", "left", "h":
+		d.view = viewFleet
+	case "enter", "right", "l":
+		agent := d.selectedAgent()
+		if d.view == viewAlerts {
+			if alert := d.selectedAlert(); alert != nil {
+				agent = d.findAgent(alert.AgentUUID)
+				if agent == nil {
+					agent = &model.Agent{UUID: alert.AgentUUID}
+				}
+			}
+		}
+		if agent != nil && d.view != viewAgent {
+			d.view, d.agentUUID, d.alertRow = viewAgent, agent.UUID, 0
+			d.config, d.configErr = nil, nil
+			go u.fetchConfig(agent.UUID, update)
+		}
+	case "R":
+		d.status = "Refreshing …"
+		refresh()
+	case "a":
+		if alert := d.selectedAlert(); alert != nil && d.view != viewFleet {
+			d.confirm = &dashboardAction{
+				prompt: fmt.Sprintf("Acknowledge %s alert %s on %s", alert.Severity, alert.Rule, d.agentName(alert.AgentUUID)),
+				run:    func() (string, error) { return u.acknowledge(alert.UUID) },
+			}
+		}
+	case "r":
+		if agent := d.selectedAgent(); agent != nil && d.view != viewAlerts {
+			d.confirm = &dashboardAction{
+				prompt: "Reload the config of " + d.agentName(agent.UUID),
+				run: func() (string, error) {
+					return u.sendCommand(agent.UUID, model.CommandTypeConfig, model.CommandActionReload)
+				},
+			}
+		}
+	case "d":
+		if agent := d.selectedAgent(); agent != nil && d.view != viewAlerts {
+			d.confirm = &dashboardAction{
+				prompt: "Drain " + d.agentName(agent.UUID),
+				run: func() (string, error) {
+					return u.sendCommand(agent.UUID, model.CommandTypeAgent, model.CommandActionDrain)
+				},
+			}
+		}
+	}
+	return false
+}
+
+// move changes the selection of the current view by delta rows
+func (d *dashboard) move(delta int) {
+	if d.view == viewFleet {
+		d.agentRow += delta
+	} else {
+		d.alertRow += delta
+	}
+	d.clampRows()
+}
+
+func (d *dashboard) clampRows() {
+	if d.fleet == nil {
+		return
+	}
+	d.agentRow = max(0, min(d.agentRow, len(d.fleet.Agents)-1))
+	d.alertRow = max(0, min(d.alertRow, len(d.visibleAlerts())-1))
+}
+
+// selectedAgent is the agent the fleet view selects or the agent view shows
+func (d *dashboard) selectedAgent() *model.Agent {
+	if d.view == viewAgent {
+		if agent := d.findAgent(d.agentUUID); agent != nil {
+			return agent
+		}
+		return &model.Agent{UUID: d.agentUUID}
+	}
+	if d.fleet == nil || d.agentRow >= len(d.fleet.Agents) {
+		return nil
+	}
+	return &d.fleet.Agents[d.agentRow]
+}
+
+func (d *dashboard) selectedAlert() *model.Alert {
+	alerts := d.visibleAlerts()
+	if d.alertRow >= len(alerts) {
+		return nil
+	}
+	return &alerts[d.alertRow]
+}
+
+// visibleAlerts are all open alerts, or those of the agent in the agent view
+func (d *dashboard) visibleAlerts() []model.Alert {
+	if d.fleet == nil {
+		return nil
+	}
+	if d.view != viewAgent {
+		return d.fleet.Alerts
+	}
+	var alerts []model.Alert
+	for _, alert := range d.fleet.Alerts {
+		if alert.AgentUUID == d.agentUUID {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+func (d *dashboard) findAgent(agentUUID string) *model.Agent {
+	if d.fleet == nil {
+		return nil
+	}
+	for i := range d.fleet.Agents {
+		if d.fleet.Agents[i].UUID == agentUUID {
+			return &d.fleet.Agents[i]
+		}
+	}
+	return nil
+}
+
+// agentName is the hostname of an agent, or its UUID when it has none or is unknown
+func (d *dashboard) agentName(agentUUID string) string {
+	if agent := d.findAgent(agentUUID); agent != nil && agent.Hostname != "" {
+		return agent.Hostname
+	}
+	return agentUUID
+}
+
+// fetchFleet reads the agents, their open alerts and each agent's metric over the window
+func (u *dashboardUsecase) fetchFleet(opts DashboardOptions) (*dashboardFleet, error) {
+	agents, err := u.agents.GetAgents()
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := alertList(u.alerts.GetAlerts(request.AlertListRequest{Status: model.AlertStatusOpen}))
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := &dashboardFleet{
+		Agents:  agents.Agents,
+		Alerts:  alerts,
+		Metrics: map[string][]float64{},
+		Fetched: time.Now(),
+	}
+	if opts.Metric == "" {
+		return fleet, nil
+	}
+
+	step := max((opts.Window / dashboardSparklinePoints).Truncate(time.Minute), time.Minute)
+	req := request.AgentMetricsRequest{
+		SensorType: opts.SensorType,
+		Name:       opts.Metric,
+		From:       fleet.Fetched.Add(-opts.Window).UTC().Format(time.RFC3339),
+		Step:       step.String(),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, dashboardMetricWorkers)
+	for _, agent := range fleet.Agents {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(agentUUID string) {
+			defer func() { <-workers; wg.Done() }()
+			resp, err := u.agents.GetAgentMetrics(agentUUID, req)
+			if err != nil || resp.Data == nil {
+				return // the agent is listed without a sparkline
+			}
+			if len(resp.Data.Series) == 0 {
+				return
+			}
+			// Without a sensor type several series may match; the first is drawn
+			var values []float64
+			for _, point := range resp.Data.Series[0].Points {
+				values = append(values, point.Avg)
+			}
+			mu.Lock()
+			fleet.Metrics[agentUUID] = values
+			mu.Unlock()
+		}(agent.UUID)
+	}
+	wg.Wait()
+	return fleet, nil
+}
+
+// fetchConfig reads the config and rules of the agent shown in the agent view
+func (u *dashboardUsecase) fetchConfig(agentUUID string, update func(func(d *dashboard))) {
+	resp, err := u.agents.GetAgentConfig(agentUUID)
+	var apiErr *repository.APIError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		err = nil
+	}
+	update(func(d *dashboard) {
+		if d.agentUUID != agentUUID {
+			return
+		}
+		d.config, d.configErr = resp.Data, err
+	})
+}
+
+func (u *dashboardUsecase) acknowledge(alertUUID string) (string, error) {
+	result := u.alerts.AcknowledgeAlert(request.AlertActionRequest{UUID: alertUUID})
+	if resp, ok := result.(*response.AlertResponse); ok && strings.EqualFold(resp.Code, "SUCCESS") {
+		return "Acknowledged alert " + alertUUID, nil
+	}
+	return "", resultError(result)
+}
+
+// sendCommand publishes a command for one agent, connecting to Pulsar on first use
+func (u *dashboardUsecase) sendCommand(agentUUID, commandType, action string) (string, error) {
+	u.mu.Lock()
+	if u.commands == nil {
+		repo, err := repository.NewPulsarRepository(u.config, u.config.YamlConfig.Pulsar.URL)
+		if err != nil {
+			u.mu.Unlock()
+			return "", err
+		}
+		u.commands = repo
+	}
+	commands := u.commands
+	u.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(u.config.YamlConfig.Pulsar.OperationTimeout)*time.Second+5*time.Second)
+	defer cancel()
+	msgID, err := commands.PublishCommand(ctx, &model.Command{
+		ID:        uuid.New().String(),
+		Type:      commandType,
+		Target:    "agent",
+		Action:    action,
+		Payload:   map[string]interface{}{"agent_uuid": agentUUID},
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Sent %s %s to %s as message %s", commandType, action, agentUUID, msgID), nil
+}
+
+func (u *dashboardUsecase) closeCommands() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.commands != nil {
+		u.commands.Close()
+		u.commands = nil
+	}
+}
+
+// alertList takes the alerts out of an AlertRepository result
+func alertList(result interface{}) ([]model.Alert, error) {
+	if resp, ok := result.(*response.AlertListResponse); ok && strings.EqualFold(resp.Code, "SUCCESS") {
+		alerts := resp.Data
+		sort.SliceStable(alerts, func(i, j int) bool { return alerts[i].LastSeenAt.After(alerts[j].LastSeenAt) })
+		return alerts, nil
+	}
+	return nil, resultError(result)
+}
+
+// resultError describes an AlertRepository result that is not a success
+func resultError(result interface{}) error {
+	found := findRows(result)
+	if found.message != "" {
+		return errors.New(found.message)
+	}
+	return fmt.Errorf("unexpected response %v", result)
+}
+
+// render draws the whole screen, overwriting the previous one
+func (d *dashboard) render() string {
+	width, height := 80, 24
+	if d.opts.Size != nil {
+		if w, h := d.opts.Size(); w > 0 && h > 0 {
+			width, height = w, h
+		}
+	}
+
+	var header []string
+	var body []string
+	var help string
+	switch d.view {
+	case viewFleet:
+		header = d.fleetHeader()
+		body = d.fleetBody(width, height-len(header)-2)
+		help = "↑/↓ select  enter agent  tab alerts  r reload config  d drain  R refresh  q quit"
+	case viewAlerts:
+		header = d.fleetHeader()
+		body = d.alertsBody(width, height-len(header)-2, true)
+		help = "↑/↓ select  a acknowledge  enter agent  tab agents  R refresh  q quit"
+	case viewAgent:
+		header = d.agentHeader()
+		body = d.agentBody(width, height-len(header)-2)
+		help = "↑/↓ select alert  a acknowledge  r reload config  d drain  esc back  R refresh  q quit"
+	}
+
+	status := d.status
+	switch {
+	case d.confirm != nil:
+		status = d.confirm.prompt + "? [y/N]"
+	case status == "" && d.fetchErr != nil:
+		status = "Error: " + d.fetchErr.Error()
+	}
+
+	// Errors may span lines, which would push the help off the screen
+	status = strings.Join(strings.Fields(status), " ")
+
+	lines := append(header, body...)
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+	lines = append(lines[:max(0, height-2)], status, "\033[2m"+help+"\033[0m")
+
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for i, line := range lines {
+		b.WriteString(truncateText(line, width))
+		b.WriteString("\033[K")
+		if i < len(lines)-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	b.WriteString("\033[J")
+	return b.String()
+}
+
+func (d *dashboard) fleetHeader() []string {
+	if d.fleet == nil {
+		return []string{"\033[1mcirculator dashboard\033[0m", "Loading …", ""}
+	}
+	online := 0
+	for _, agent := range d.fleet.Agents {
+		if agent.Status == "online" {
+			online++
+		}
+	}
+	return []string{
+		"\033[1mcirculator dashboard\033[0m",
+		fmt.Sprintf("%d agents, %d online   %d open alerts   updated %s",
+			len(d.fleet.Agents), online, len(d.fleet.Alerts), d.fleet.Fetched.Format("15:04:05")),
+		"",
+	}
+}
+
+func (d *dashboard) fleetBody(width, height int) []string {
+	if d.fleet == nil {
+		return nil
+	}
+	openAlerts := map[string]int{}
+	for _, alert := range d.fleet.Alerts {
+		openAlerts[alert.AgentUUID]++
+	}
+
+	now := time.Now()
+	metric := "METRIC"
+	if d.opts.Metric != "" {
+		metric = strings.ToUpper(d.opts.Metric) + " (" + shortDuration(d.opts.Window) + ")"
+	}
+	cells := [][]string{{"UUID", "HOSTNAME", "STATUS", "HEARTBEAT", "THREADS", "ALERTS", metric}}
+	for _, agent := range d.fleet.Agents {
+		heartbeat := time.Time{}
+		if agent.HeartbeatAt != nil {
+			heartbeat = *agent.HeartbeatAt
+		}
+		cells = append(cells, []string{
+			shortUUID(agent.UUID),
+			agent.Hostname,
+			agent.Status,
+			relativeTime(heartbeat, now),
+			fmt.Sprintf("%d/%d", agent.ThreadCount, agent.MaxThreadCount),
+			fmt.Sprint(openAlerts[agent.UUID]),
+			sparkline(d.fleet.Metrics[agent.UUID]),
+		})
+	}
+	if len(cells) == 1 {
+		return []string{"No agents registered"}
+	}
+	return selectableTable(cells, d.agentRow, width, height)
+}
+
+func (d *dashboard) alertsBody(width, height int, withAgent bool) []string {
+	alerts := d.visibleAlerts()
+	if len(alerts) == 0 {
+		return []string{"No open alerts"}
+	}
+
+	now := time.Now()
+	cells := [][]string{{"SEVERITY", "RULE", "SENSOR", "COUNT", "LAST SEEN", "MESSAGE"}}
+	if withAgent {
+		cells[0] = append([]string{"AGENT"}, cells[0]...)
+	}
+	for _, alert := range alerts {
+		row := []string{alert.Severity, alert.Rule, alert.SensorType, fmt.Sprint(alert.Count), relativeTime(alert.LastSeenAt, now), alert.Message}
+		if withAgent {
+			row = append([]string{d.agentName(alert.AgentUUID)}, row...)
+		}
+		cells = append(cells, row)
+	}
+	return selectableTable(cells, d.alertRow, width, height)
+}
+
+func (d *dashboard) agentHeader() []string {
+	agent := d.selectedAgent()
+	title := "\033[1mAgent " + d.agentName(agent.UUID) + "\033[0m  " + agent.UUID
+	if agent.Hostname == "" && agent.Status == "" {
+		return []string{title, "Not in the agent list", ""}
+	}
+	heartbeat := time.Time{}
+	if agent.HeartbeatAt != nil {
+		heartbeat = *agent.HeartbeatAt
+	}
+	line := fmt.Sprintf("%s, heartbeat %s, threads %d/%d, version %s, %s:%d",
+		agent.Status, relativeTime(heartbeat, time.Now()), agent.ThreadCount, agent.MaxThreadCount, agent.Version, agent.IpAddress, agent.Port)
+	lines := []string{title, line}
+	if d.opts.Metric != "" && d.fleet != nil {
+		values := d.fleet.Metrics[agent.UUID]
+		lines = append(lines, fmt.Sprintf("%s (%s) %s", d.opts.Metric, shortDuration(d.opts.Window), sparkline(values)))
+	}
+	return append(lines, "")
+}
+
+func (d *dashboard) agentBody(width, height int) []string {
+	var lines []string
+	switch {
+	case d.configErr != nil:
+		lines = append(lines, "Config: error: "+d.configErr.Error())
+	case d.config == nil:
+		lines = append(lines, "Config: none")
+	default:
+		lines = append(lines, fmt.Sprintf("Config: sensor type %s, output streams %s", d.config.SensorType, strings.Join(d.config.OutputStreams, ", ")))
+		if len(d.config.ProcessingRules) == 0 {
+			lines = append(lines, "Rules: none")
+		} else {
+			cells := [][]string{{"RULE", "ENABLED", "PARAMS"}}
+			for _, rule := range d.config.ProcessingRules {
+				cells = append(cells, []string{rule.Name, fmt.Sprint(rule.Enabled), cellText(rule.Params)})
+			}
+			for _, line := range strings.Split(strings.TrimRight(layoutTable(cells, width-2), "\n"), "\n") {
+				lines = append(lines, "  "+line)
+			}
+		}
+	}
+	lines = append(lines, "", "\033[1mOpen alerts\033[0m")
+	return append(lines, d.alertsBody(width, height-len(lines), false)...)
+}
+
+// selectableTable lays out cells, the first row the header, within width and height,
+// scrolled so the selected row is visible and highlighted
+func selectableTable(cells [][]string, selected, width, height int) []string {
+	lines := strings.Split(strings.TrimRight(layoutTable(cells, width-2), "\n"), "\n")
+	header, rows := lines[0], lines[1:]
+
+	visible := max(1, height-1)
+	offset := 0
+	if selected >= visible {
+		offset = selected - visible + 1
+	}
+	out := []string{"  " + header}
+	for i := offset; i < len(rows) && i < offset+visible; i++ {
+		if i == selected {
+			out = append(out, "\033[7m> "+rows[i]+"\033[0m")
+		} else {
+			out = append(out, "  "+rows[i])
+		}
+	}
+	return out
+}
+
+// sparkline draws values scaled between their minimum and maximum, followed by the last
+// value, keeping the most recent points
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return "<none>"
+	}
+	if len(values) > dashboardSparklinePoints {
+		values = values[len(values)-dashboardSparklinePoints:]
+	}
+	const bars = "▁▂▃▄▅▆▇█"
+	levels := []rune(bars)
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low, high = math.Min(low, v), math.Max(high, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if high > low {
+			level = int((v - low) / (high - low) * float64(len(levels)-1))
+		}
+		b.WriteRune(levels[level])
+	}
+	return fmt.Sprintf("%s %.1f", b.String(), values[len(values)-1])
+}
+
+// readKeys sends the keys read from in until it fails or ctx is cancelled, then closes keys
+func readKeys(ctx context.Context, in io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := in.Read(buf)
+		for _, key := range parseKeys(buf[:n]) {
+			select {
+			case keys <- key:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// escapeKeys are the escape sequences of the keys the dashboard handles
+var escapeKeys = map[string]string{
+	"\033[A": "up", "\033OA": "up",
+	"\033[B": "down", "\033OB": "down",
+	"\033[C": "right", "\033OC": "right",
+	"\033[D": "left", "\033OD": "left",
+	"\033[H": "home", "\033[1~": "home", "\033OH": "home",
+	"\033[F": "end", "\033[4~": "end", "\033OF": "end",
+	"\033[5~": "pgup",
+	"\033[6~": "pgdown",
+}
+
+// parseKeys splits terminal input into key names: escape sequences such as up, control
+// keys such as enter and ctrl+c, and other characters as themselves
+func parseKeys(input []byte) []string {
+	var keys []string
+	for len(input) > 0 {
+		if input[0] == 0x1b {
+			if len(input) == 1 || (input[1] != '[' && input[1] != 'O') {
+				keys = append(keys, "esc")
+				input = input[1:]
+				continue
+			}
+			// A sequence ends with its first letter or ~ after the introducer
+			end := 2
+			for end < len(input) && !(input[end] >= 'A' && input[end] <= 'Z' || input[end] >= 'a' && input[end] <= 'z' || input[end] == '~') {
+				end++
+			}
+			end = min(end+1, len(input))
+			if key, ok := escapeKeys[string(input[:end])]; ok {
+				keys = append(keys, key)
+			}
+			input = input[end:]
+			continue
+		}
+
+		switch input[0] {
+		case '\r', '\n':
+			keys = append(keys, "enter")
+		case '\t':
+			keys = append(keys, "tab")
+		case 0x7f, 0x08:
+			keys = append(keys, "backspace")
+		case 0x03:
+			keys = append(keys, "ctrl+c")
+		default:
+			r, size := utf8.DecodeRune(input)
+			keys = append(keys, string(r))
+			input = input[size:]
+			continue
+		}
+		input = input[1:]
+	}
+	return keys
+}
+
+// truncateText cuts text to width visible runes, marking the cut with …. Escape
+// sequences take no width and a cut resets the text attributes.
+func truncateText(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	var b strings.Builder
+	visible := 0
+	for i := 0; i < len(text); {
+		if text[i] == 0x1b {
+			end := i + 1
+			for end < len(text) && !(text[end] >= 'A' && text[end] <= 'Z' || text[end] >= 'a' && text[end] <= 'z') {
+				end++
+			}
+			end = min(end+1, len(text))
+			b.WriteString(text[i:end])
+			i = end
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if visible == width-1 && utf8.RuneCountInString(stripEscapes(text[i:])) > 1 {
+			b.WriteString("…\033[0m")
+			return b.String()
+		}
+		b.WriteRune(r)
+		visible++
+		i += size
+	}
+	return b.String()
+}
+
+// stripEscapes removes the escape sequences of text
+func stripEscapes(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		if text[i] == 0x1b {
+			i++
+			for i < len(text) && !(text[i] >= 'A' && text[i] <= 'Z' || text[i] >= 'a' && text[i] <= 'z') {
+				i++
+			}
+			i++
+			continue
+		}
+		b.WriteByte(text[i])
+		i++
+	}
+	return b.String()
+}
+
+func shortUUID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// shortDuration formats d without zero units, e.g. 30m instead of 30m0s
+func shortDuration(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// filterOperators in the order they are matched, longer operators first
+var filterOperators = []string{"==", "!=", ">=", "<=", "=~", "!~", ">", "<"}
+
+// filterExpr is a parsed filter expression: comparisons joined by && within a group and
+// || between groups, so && binds tighter
+type filterExpr [][]filterClause
+
+// filterClause compares the value at a field path with a literal
+type filterClause struct {
+	path     string
+	operator string
+	literal  string
+	pattern  *regexp.Regexp
+}
+
+// parseFilter parses expressions such as `processed_value > 30 && anomaly == true` or
+// `severity =~ high|critical || cpu_usage >= 90`. Literals may be quoted, and a bare
+// field tests that the value is set and not false or zero.
+func parseFilter(text string) (filterExpr, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	var expr filterExpr
+	for _, group := range splitOutsideQuotes(text, "||") {
+		var clauses []filterClause
+		for _, part := range splitOutsideQuotes(group, "&&") {
+			clause, err := parseFilterClause(strings.TrimSpace(part))
+			if err != nil {
+				return nil, fmt.Errorf("invalid filter %q: %w", text, err)
+			}
+			clauses = append(clauses, clause)
+		}
+		expr = append(expr, clauses)
+	}
+	return expr, nil
+}
+
+func parseFilterClause(text string) (filterClause, error) {
+	if text == "" {
+		return filterClause{}, fmt.Errorf("empty comparison")
+	}
+	index, operator := -1, ""
+	for _, op := range filterOperators {
+		if i := strings.Index(text, op); i >= 0 && (index < 0 || i < index) {
+			index, operator = i, op
+		}
+	}
+	if index < 0 {
+		if strings.ContainsAny(text, " \t'\"") {
+			return filterClause{}, fmt.Errorf("no operator in %q", text)
+		}
+		return filterClause{path: text}, nil
+	}
+
+	clause := filterClause{
+		path:     strings.TrimSpace(text[:index]),
+		operator: operator,
+		literal:  unquote(strings.TrimSpace(text[index+len(operator):])),
+	}
+	if clause.path == "" {
+		return filterClause{}, fmt.Errorf("no field in %q", text)
+	}
+	if operator == "=~" || operator == "!~" {
+		pattern, err := regexp.Compile(clause.literal)
+		if err != nil {
+			return filterClause{}, err
+		}
+		clause.pattern = pattern
+	}
+	return clause, nil
+}
+
+// match reports whether the row, looked up through lookup, satisfies the expression. An
+// empty expression matches everything.
+func (e filterExpr) match(lookup func(path string) any) bool {
+	if len(e) == 0 {
+		return true
+	}
+	for _, group := range e {
+		matched := true
+		for _, clause := range group {
+			if !clause.match(lookup(clause.path)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (c filterClause) match(value any) bool {
+	// Rows decoded by toRow keep numbers as json.Number
+	if number, ok := value.(json.Number); ok {
+		if f, err := number.Float64(); err == nil {
+			value = f
+		}
+	}
+	if c.operator == "" {
+		switch v := value.(type) {
+		case nil:
+			return false
+		case bool:
+			return v
+		case float64:
+			return v != 0
+		case string:
+			return v != ""
+		}
+		return true
+	}
+	if value == nil {
+		return c.operator == "!=" || c.operator == "!~"
+	}
+	if c.pattern != nil {
+		return c.pattern.MatchString(cellText(value)) == (c.operator == "=~")
+	}
+
+	var cmp int
+	switch v := value.(type) {
+	case float64:
+		literal, err := strconv.ParseFloat(c.literal, 64)
+		if err != nil {
+			return c.operator == "!="
+		}
+		switch {
+		case v < literal:
+			cmp = -1
+		case v > literal:
+			cmp = 1
+		}
+	case bool:
+		literal, err := strconv.ParseBool(c.literal)
+		if err != nil || (c.operator != "==" && c.operator != "!=") {
+			return c.operator == "!="
+		}
+		if v != literal {
+			cmp = 1
+		}
+	default:
+		cmp = strings.Compare(cellText(value), c.literal)
+	}
+
+	switch c.operator {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// splitOutsideQuotes splits text at sep where it is not inside single or double quotes
+func splitOutsideQuotes(text, sep string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch {
+		case quote != 0:
+			if text[i] == quote {
+				quote = 0
+			}
+		case text[i] == '\'' || text[i] == '"':
+			quote = text[i]
+		case strings.HasPrefix(text[i:], sep):
+			parts = append(parts, text[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+func unquote(text string) string {
+	if len(text) >= 2 && (text[0] == '\'' || text[0] == '"') && text[len(text)-1] == text[0] {
+		return text[1 : len(text)-1]
+	}
+	return text
+}
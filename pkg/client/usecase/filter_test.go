@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	// Rows come from toRow, as in the stream tail
+	row, err := toRow(map[string]any{
+		"agent_uuid":      "a1",
+		"severity":        "critical",
+		"processed_value": 100,
+		"original_value":  0,
+		"anomaly":         true,
+		"smoothed":        false,
+		"message":         "cpu > 90 && rising",
+		"metadata":        map[string]any{"group": "plant-a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lookup := func(path string) any { return lookupPath(row, path) }
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{filter: "", want: true},
+		{filter: "processed_value > 30", want: true},
+		{filter: "processed_value > 200", want: false},
+		{filter: "processed_value >= 100", want: true},
+		{filter: "processed_value<100", want: false},
+		{filter: "processed_value <= 100.0", want: true},
+		{filter: "processed_value == 1e2", want: true},
+		{filter: "processed_value == high", want: false},
+		{filter: "processed_value != high", want: true},
+		{filter: "anomaly == true", want: true},
+		{filter: "anomaly != true", want: false},
+		{filter: "anomaly > false", want: false},
+		{filter: "severity == critical", want: true},
+		{filter: "severity == 'critical'", want: true},
+		{filter: `severity != "critical"`, want: false},
+		{filter: "severity =~ high|critical", want: true},
+		{filter: "severity !~ ^crit", want: false},
+		{filter: "metadata.group == plant-a", want: true},
+		{filter: "message == 'cpu > 90 && rising'", want: true},
+		{filter: "missing == x", want: false},
+		{filter: "missing != x", want: true},
+		{filter: "missing !~ x", want: true},
+		{filter: "anomaly", want: true},
+		{filter: "smoothed", want: false},
+		{filter: "original_value", want: false},
+		{filter: "processed_value", want: true},
+		{filter: "missing", want: false},
+		{filter: "anomaly && processed_value > 30", want: true},
+		{filter: "smoothed && processed_value > 30", want: false},
+		{filter: "smoothed || processed_value > 30", want: true},
+		{filter: "smoothed || missing", want: false},
+		{filter: "smoothed && anomaly || severity == critical", want: true},
+		{filter: "severity == low || anomaly && processed_value < 30", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			expr, err := parseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseFilter(%q) error = %v", tt.filter, err)
+			}
+			if got := expr.match(lookup); got != tt.want {
+				t.Errorf("parseFilter(%q).match() = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		filter  string
+		wantErr string
+	}{
+		{filter: "anomaly &&", wantErr: "empty comparison"},
+		{filter: "|| anomaly", wantErr: "empty comparison"},
+		{filter: "== 3", wantErr: "no field"},
+		{filter: "severity critical", wantErr: "no operator"},
+		{filter: "severity =~ (", wantErr: "missing closing )"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			_, err := parseFilter(tt.filter)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseFilter(%q) error = %v, want %q", tt.filter, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,25 +1,86 @@
 package usecase
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
-	"reflect"
+	"os"
 	"strings"
+	"text/template"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// OutputFormats lists the values accepted by -o; jsonpath, go-template and go-template-file
+// take their argument after =, e.g. -o jsonpath='{.agents[*].uuid}'
+var OutputFormats = []string{"table", "wide", "json", "yaml", "csv", "ndjson", "jsonpath=<template>", "go-template=<template>", "go-template-file=<path>"}
+
+// splitFormat separates a format name from its argument, dropping quotes around the argument
+func splitFormat(outputFormat string) (string, string) {
+	name, arg, _ := strings.Cut(strings.TrimSpace(outputFormat), "=")
+	if len(arg) >= 2 && (arg[0] == '\'' || arg[0] == '"') && arg[len(arg)-1] == arg[0] {
+		arg = arg[1 : len(arg)-1]
+	}
+	return strings.ToLower(name), arg
+}
+
+// ValidateFormat checks that outputFormat is known and that its template, if any, parses
+func ValidateFormat(outputFormat string) error {
+	name, arg := splitFormat(outputFormat)
+	switch name {
+	case "table", "wide", "json", "yaml", "csv", "ndjson":
+		return nil
+	case "jsonpath":
+		if arg == "" {
+			return fmt.Errorf("jsonpath output requires a template, e.g. -o jsonpath='{.agents[*].uuid}'")
+		}
+		_, err := parseJSONPath(arg)
+		return err
+	case "go-template":
+		if arg == "" {
+			return fmt.Errorf("go-template output requires a template, e.g. -o go-template='{{range .agents}}{{.uuid}} {{end}}'")
+		}
+		_, err := template.New("output").Funcs(templateFuncs).Parse(arg)
+		return err
+	case "go-template-file":
+		text, err := os.ReadFile(arg)
+		if err != nil {
+			return err
+		}
+		_, err = template.New(arg).Funcs(templateFuncs).Parse(string(text))
+		return err
+	}
+	return fmt.Errorf("unknown output format %q, expected one of %s", outputFormat, strings.Join(OutputFormats, ", "))
+}
+
 // Format formats data according to the specified output format
 func Format(outputFormat string, data interface{}) string {
-	switch strings.ToLower(outputFormat) {
+	name, arg := splitFormat(outputFormat)
+	switch name {
 	case "json":
 		return formatJSON(data)
 	case "yaml":
 		return formatYAML(data)
-	case "table":
-		return formatTable(data)
+	case "wide":
+		return formatTable(data, true)
+	case "csv":
+		return formatCSV(data)
+	case "ndjson":
+		return formatNDJSON(data)
+	case "jsonpath":
+		return formatJSONPath(arg, data)
+	case "go-template":
+		return formatGoTemplate(arg, data)
+	case "go-template-file":
+		text, err := os.ReadFile(arg)
+		if err != nil {
+			return fmt.Sprintf("Error reading template: %v\n", err)
+		}
+		return formatGoTemplate(string(text), data)
 	default:
-		return formatTable(data)
+		return formatTable(data, false)
 	}
 }
 
@@ -39,32 +100,129 @@ func formatYAML(data interface{}) string {
 	return string(yamlBytes)
 }
 
-func formatTable(data interface{}) string {
-	// Simple table formatting - in a real implementation this would be more sophisticated
-	val := reflect.ValueOf(data)
-	if val.Kind() == reflect.Ptr {
-		val = val.Elem()
+// formatCSV writes the rows with the wide columns, or --columns, and absolute times
+func formatCSV(data interface{}) string {
+	found := findRows(data)
+	if found.failed {
+		return fmt.Sprintf("Error: %s\n", found.message)
 	}
+	if !found.rows.IsValid() {
+		return formatNDJSON(data)
+	}
+	rows, columns, err := tableRows(found, true)
+	if err != nil {
+		return fmt.Sprintf("Error formatting CSV: %v\n", err)
+	}
+
+	var b bytes.Buffer
+	writer := csv.NewWriter(&b)
+	if !tableOptions.NoHeaders {
+		header := make([]string, len(columns))
+		for i, column := range columns {
+			header[i] = column.Path
+		}
+		writer.Write(header)
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = cellText(lookupPath(row, column.Path))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Sprintf("Error formatting CSV: %v\n", err)
+	}
+	return b.String()
+}
 
-	if val.Kind() == reflect.Struct {
-		var result strings.Builder
-		typ := val.Type()
+// formatNDJSON writes one compact JSON document per row, or the whole data when it holds no
+// rows, so streaming commands can print each event on its own line
+func formatNDJSON(data interface{}) string {
+	found := findRows(data)
+	if found.failed || !found.rows.IsValid() {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Sprintf("Error formatting NDJSON: %v\n", err)
+		}
+		return string(b) + "\n"
+	}
 
-		for i := 0; i < val.NumField(); i++ {
-			field := typ.Field(i)
-			value := val.Field(i)
+	var b strings.Builder
+	for i := 0; i < found.rows.Len(); i++ {
+		line, err := json.Marshal(found.rows.Index(i).Interface())
+		if err != nil {
+			return fmt.Sprintf("Error formatting NDJSON: %v\n", err)
+		}
+		b.Write(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
 
-			// Skip unexported fields
-			if !field.IsExported() {
-				continue
-			}
+func formatJSONPath(text string, data interface{}) string {
+	tmpl, err := parseJSONPath(text)
+	if err != nil {
+		return fmt.Sprintf("Error parsing jsonpath: %v\n", err)
+	}
+	generic, err := toGeneric(data)
+	if err != nil {
+		return fmt.Sprintf("Error formatting jsonpath: %v\n", err)
+	}
+	out, err := tmpl.Execute(generic)
+	if err != nil {
+		return fmt.Sprintf("Error formatting jsonpath: %v\n", err)
+	}
+	return out
+}
 
-			result.WriteString(fmt.Sprintf("%-15s: %v\n", field.Name, value.Interface()))
+// templateFuncs are available to go-template output: json renders a value as compact JSON
+// and since renders an RFC3339 time like the table, e.g. 5m ago
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"since": func(value any) string {
+		text := fmt.Sprint(value)
+		t, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return text
 		}
+		return relativeTime(t, time.Now())
+	},
+}
 
-		return result.String()
+// formatGoTemplate executes a text/template over the JSON form of data, so fields are
+// addressed by their JSON names as in -o json, e.g. {{range .agents}}{{.uuid}}{{"\n"}}{{end}}
+func formatGoTemplate(text string, data interface{}) string {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return fmt.Sprintf("Error parsing go-template: %v\n", err)
+	}
+	generic, err := toGeneric(data)
+	if err != nil {
+		return fmt.Sprintf("Error formatting go-template: %v\n", err)
 	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, generic); err != nil {
+		return fmt.Sprintf("Error formatting go-template: %v\n", err)
+	}
+	return b.String()
+}
 
-	// Fallback to simple string representation
-	return fmt.Sprintf("%+v\n", data)
+// toGeneric converts data to its JSON form of maps, lists, strings and json.Number values
+func toGeneric(data interface{}) (any, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var generic any
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
 }
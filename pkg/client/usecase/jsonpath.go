@@ -0,0 +1,458 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathTemplate is a parsed kubectl-style JSONPath template such as
+// {.agents[*].uuid} or {range .data[*]}{.name}{"\t"}{.status}{"\n"}{end}.
+// Supported are fields, ['quoted'] fields, [n] indexes, [*], ..recursive descent and
+// [?(@.path op value)] filters with ==, !=, <, <=, > and >=.
+type jsonPathTemplate struct {
+	nodes []jsonPathNode
+}
+
+type jsonPathNode struct {
+	text   string         // literal text, used when path and body are nil
+	path   []jsonPathStep // expression to print
+	isPath bool           // the node prints path, even when empty ({.} or {@})
+	body   []jsonPathNode // range body, printed for every result of path
+	isLoop bool
+}
+
+type jsonPathStep struct {
+	field     string // map key, also used by recursive descent
+	index     *int
+	wildcard  bool
+	recursive bool
+	filter    *jsonPathFilter
+}
+
+type jsonPathFilter struct {
+	path  []jsonPathStep
+	op    string // empty tests that path exists
+	value any
+}
+
+func parseJSONPath(template string) (*jsonPathTemplate, error) {
+	var stack [][]jsonPathNode
+	var nodes []jsonPathNode
+	var loops []jsonPathNode
+
+	for len(template) > 0 {
+		open := strings.IndexByte(template, '{')
+		if open < 0 {
+			nodes = append(nodes, jsonPathNode{text: template})
+			break
+		}
+		if open > 0 {
+			nodes = append(nodes, jsonPathNode{text: template[:open]})
+		}
+		end := closingBrace(template, open)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed { in jsonpath %q", template)
+		}
+		expr := strings.TrimSpace(template[open+1 : end])
+		template = template[end+1:]
+
+		switch {
+		case strings.HasPrefix(expr, "range "):
+			path, err := parseJSONPathSteps(strings.TrimSpace(strings.TrimPrefix(expr, "range ")))
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, nodes)
+			loops = append(loops, jsonPathNode{path: path, isLoop: true})
+			nodes = nil
+		case expr == "end":
+			if len(loops) == 0 {
+				return nil, fmt.Errorf("{end} without {range} in jsonpath")
+			}
+			loop := loops[len(loops)-1]
+			loops = loops[:len(loops)-1]
+			loop.body = nodes
+			nodes = append(stack[len(stack)-1], loop)
+			stack = stack[:len(stack)-1]
+		case strings.HasPrefix(expr, `"`) || strings.HasPrefix(expr, "'"):
+			text, err := unquoteJSONPath(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid literal %s in jsonpath: %w", expr, err)
+			}
+			nodes = append(nodes, jsonPathNode{text: text})
+		default:
+			path, err := parseJSONPathSteps(expr)
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, jsonPathNode{path: path, isPath: true})
+		}
+	}
+	if len(loops) > 0 {
+		return nil, fmt.Errorf("{range} without {end} in jsonpath")
+	}
+	return &jsonPathTemplate{nodes: nodes}, nil
+}
+
+// closingBrace returns the index of the } closing the { at open, skipping quoted text
+func closingBrace(s string, open int) int {
+	var quote byte
+	for i := open + 1; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == '\\':
+			i++
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+func unquoteJSONPath(s string) (string, error) {
+	if strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") && len(s) >= 2 {
+		return s[1 : len(s)-1], nil
+	}
+	return strconv.Unquote(s)
+}
+
+// parseJSONPathSteps parses a path like .agents[*].metadata.group or $..uuid
+func parseJSONPathSteps(path string) ([]jsonPathStep, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), "@")
+	var steps []jsonPathStep
+
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			recursive := strings.HasPrefix(path[i:], "..")
+			if recursive {
+				i += 2
+			} else {
+				i++
+			}
+			name := readJSONPathName(path[i:])
+			i += len(name)
+			switch {
+			case recursive:
+				steps = append(steps, jsonPathStep{field: name, recursive: true})
+			case name == "*":
+				steps = append(steps, jsonPathStep{wildcard: true})
+			case name != "":
+				steps = append(steps, jsonPathStep{field: name})
+			}
+		case '[':
+			end := closingBracket(path, i)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in jsonpath %q", path)
+			}
+			step, err := parseJSONPathBracket(strings.TrimSpace(path[i+1 : end]))
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			i = end + 1
+		default:
+			name := readJSONPathName(path[i:])
+			if name == "" {
+				return nil, fmt.Errorf("unexpected %q in jsonpath %q", path[i], path)
+			}
+			steps = append(steps, jsonPathStep{field: name})
+			i += len(name)
+		}
+	}
+	return steps, nil
+}
+
+func readJSONPathName(s string) string {
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		return s
+	}
+	return s[:end]
+}
+
+// closingBracket returns the index of the ] closing the [ at open, skipping quotes and parentheses
+func closingBracket(s string, open int) int {
+	var quote byte
+	depth := 0
+	for i := open + 1; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '(':
+			depth++
+		case s[i] == ')':
+			depth--
+		case s[i] == ']' && depth == 0:
+			return i
+		}
+	}
+	return -1
+}
+
+func parseJSONPathBracket(inner string) (jsonPathStep, error) {
+	switch {
+	case inner == "*":
+		return jsonPathStep{wildcard: true}, nil
+	case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+		filter, err := parseJSONPathFilter(strings.TrimSpace(inner[2 : len(inner)-1]))
+		if err != nil {
+			return jsonPathStep{}, err
+		}
+		return jsonPathStep{filter: filter}, nil
+	case strings.HasPrefix(inner, "'") || strings.HasPrefix(inner, `"`):
+		name, err := unquoteJSONPath(inner)
+		if err != nil {
+			return jsonPathStep{}, fmt.Errorf("invalid field %s in jsonpath: %w", inner, err)
+		}
+		return jsonPathStep{field: name}, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return jsonPathStep{}, fmt.Errorf("unsupported [%s] in jsonpath", inner)
+	}
+	return jsonPathStep{index: &index}, nil
+}
+
+func parseJSONPathFilter(expr string) (*jsonPathFilter, error) {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		left, right, found := strings.Cut(expr, op)
+		if !found {
+			continue
+		}
+		path, err := parseJSONPathSteps(strings.TrimSpace(left))
+		if err != nil {
+			return nil, err
+		}
+		right = strings.TrimSpace(right)
+		var value any = right
+		switch {
+		case strings.HasPrefix(right, "'") || strings.HasPrefix(right, `"`):
+			if value, err = unquoteJSONPath(right); err != nil {
+				return nil, fmt.Errorf("invalid literal %s in jsonpath filter: %w", right, err)
+			}
+		case right == "true" || right == "false":
+			value = right == "true"
+		default:
+			if number, err := strconv.ParseFloat(right, 64); err == nil {
+				value = number
+			}
+		}
+		return &jsonPathFilter{path: path, op: op, value: value}, nil
+	}
+
+	path, err := parseJSONPathSteps(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonPathFilter{path: path}, nil
+}
+
+// Execute prints the template for data, the JSON form of a response; several results of
+// one expression are separated by spaces
+func (t *jsonPathTemplate) Execute(data any) (string, error) {
+	var b strings.Builder
+	if err := executeJSONPath(&b, t.nodes, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func executeJSONPath(b *strings.Builder, nodes []jsonPathNode, data any) error {
+	for _, node := range nodes {
+		switch {
+		case node.isLoop:
+			results := evalJSONPath(node.path, []any{data})
+			if len(results) == 1 {
+				if list, ok := results[0].([]any); ok {
+					results = list
+				}
+			}
+			for _, result := range results {
+				if err := executeJSONPath(b, node.body, result); err != nil {
+					return err
+				}
+			}
+		case node.isPath:
+			results := evalJSONPath(node.path, []any{data})
+			for i, result := range results {
+				if i > 0 {
+					b.WriteString(" ")
+				}
+				b.WriteString(jsonPathText(result))
+			}
+		default:
+			b.WriteString(node.text)
+		}
+	}
+	return nil
+}
+
+func evalJSONPath(steps []jsonPathStep, values []any) []any {
+	for _, step := range steps {
+		var next []any
+		for _, value := range values {
+			next = append(next, evalJSONPathStep(step, value)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func evalJSONPathStep(step jsonPathStep, value any) []any {
+	switch {
+	case step.recursive:
+		var found []any
+		walkJSON(value, func(v any) {
+			if step.field == "*" {
+				found = append(found, jsonChildren(v)...)
+			} else if m, ok := v.(map[string]any); ok {
+				if child, ok := m[step.field]; ok {
+					found = append(found, child)
+				}
+			}
+		})
+		return found
+	case step.wildcard:
+		return jsonChildren(value)
+	case step.index != nil:
+		list, ok := value.([]any)
+		if !ok {
+			return nil
+		}
+		index := *step.index
+		if index < 0 {
+			index += len(list)
+		}
+		if index < 0 || index >= len(list) {
+			return nil
+		}
+		return []any{list[index]}
+	case step.filter != nil:
+		var matched []any
+		candidates, ok := value.([]any)
+		if !ok {
+			candidates = []any{value}
+		}
+		for _, candidate := range candidates {
+			if step.filter.matches(candidate) {
+				matched = append(matched, candidate)
+			}
+		}
+		return matched
+	}
+
+	if m, ok := value.(map[string]any); ok {
+		if child, ok := m[step.field]; ok {
+			return []any{child}
+		}
+	}
+	return nil
+}
+
+func (f *jsonPathFilter) matches(value any) bool {
+	results := evalJSONPath(f.path, []any{value})
+	if f.op == "" {
+		return len(results) > 0
+	}
+	if len(results) == 0 {
+		return false
+	}
+
+	left := results[0]
+	if number, ok := left.(json.Number); ok {
+		if l, err := number.Float64(); err == nil {
+			if r, ok := f.value.(float64); ok {
+				return compareOrdered(l, r, f.op)
+			}
+		}
+	}
+	if l, ok := left.(bool); ok {
+		if r, ok := f.value.(bool); ok {
+			switch f.op {
+			case "==":
+				return l == r
+			case "!=":
+				return l != r
+			}
+			return false
+		}
+	}
+	return compareOrdered(jsonPathText(left), fmt.Sprint(f.value), f.op)
+}
+
+func compareOrdered[T float64 | string](l, r T, op string) bool {
+	switch op {
+	case "==":
+		return l == r
+	case "!=":
+		return l != r
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case ">=":
+		return l >= r
+	}
+	return false
+}
+
+// jsonChildren returns the elements of a list or the values of a map ordered by key
+func jsonChildren(value any) []any {
+	switch v := value.(type) {
+	case []any:
+		return v
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		children := make([]any, len(keys))
+		for i, key := range keys {
+			children[i] = v[key]
+		}
+		return children
+	}
+	return nil
+}
+
+func walkJSON(value any, visit func(any)) {
+	visit(value)
+	for _, child := range jsonChildren(value) {
+		walkJSON(child, visit)
+	}
+}
+
+// jsonPathText prints strings and numbers as they are and objects as compact JSON
+func jsonPathText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case map[string]any, []any:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
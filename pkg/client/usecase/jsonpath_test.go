@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONPathExecute(t *testing.T) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(`{
+		"code": "SUCCESS",
+		"agents": [
+			{"uuid": "a1", "status": "online", "threads": 4, "enabled": true, "metadata": {"group": "plant-a", "rack.id": "r1"}},
+			{"uuid": "a2", "status": "offline", "threads": 12, "enabled": false, "metadata": {"group": "plant-b"}},
+			{"uuid": "a3", "status": "online", "threads": 8, "enabled": true}
+		]
+	}`)))
+	decoder.UseNumber()
+	var data any
+	if err := decoder.Decode(&data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "field", template: "{.code}", want: "SUCCESS"},
+		{name: "without the leading dot", template: "{code}", want: "SUCCESS"},
+		{name: "root markers", template: "{$.agents[0].uuid} {@.agents[1].uuid}", want: "a1 a2"},
+		{name: "wildcard results are space separated", template: "{.agents[*].uuid}", want: "a1 a2 a3"},
+		{name: "negative index", template: "{.agents[-1].uuid}", want: "a3"},
+		{name: "index out of range", template: "{.agents[5].uuid}", want: ""},
+		{name: "missing fields print nothing", template: "{.agents[*].metadata.group}", want: "plant-a plant-b"},
+		{name: "quoted field", template: "{.agents[0].metadata['rack.id']}", want: "r1"},
+		{name: "wildcard over a map is ordered by key", template: "{.agents[0].metadata.*}", want: "plant-a r1"},
+		{name: "recursive descent", template: "{..group}", want: "plant-a plant-b"},
+		{name: "objects print as JSON", template: "{.agents[1].metadata}", want: `{"group":"plant-b"}`},
+		{name: "literal text around expressions", template: "agents: {.agents[0].uuid}!", want: "agents: a1!"},
+		{name: "quoted literals", template: `{.code}{"\t"}{'x}y'}`, want: "SUCCESS\tx}y"},
+		{
+			name:     "range",
+			template: `{range .agents[*]}{.uuid}{"="}{.status}{"\n"}{end}`,
+			want:     "a1=online\na2=offline\na3=online\n",
+		},
+		{
+			name:     "range over a list",
+			template: `{range .agents}{.threads},{end}`,
+			want:     "4,12,8,",
+		},
+		{
+			name:     "nested range",
+			template: `{range .agents[*]}{range .metadata.*}{.}|{end}{end}`,
+			want:     "plant-a|r1|plant-b|",
+		},
+		{name: "filter on text", template: `{.agents[?(@.status=="online")].uuid}`, want: "a1 a3"},
+		{name: "filter on text with single quotes", template: `{.agents[?(@.status != 'online')].uuid}`, want: "a2"},
+		{name: "filter on numbers", template: `{.agents[?(@.threads > 5)].uuid}`, want: "a2 a3"},
+		{name: "filter compares numbers numerically", template: `{.agents[?(@.threads<=8)].uuid}`, want: "a1 a3"},
+		{name: "filter on booleans", template: `{.agents[?(@.enabled==false)].uuid}`, want: "a2"},
+		{name: "filter on a nested field", template: `{.agents[?(@.metadata.group=="plant-b")].threads}`, want: "12"},
+		{name: "filter on existence", template: `{.agents[?(@.metadata)].uuid}`, want: "a1 a2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := parseJSONPath(tt.template)
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q) error = %v", tt.template, err)
+			}
+			got, err := template.Execute(data)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Execute(%q) = %q, want %q", tt.template, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  string
+	}{
+		{template: "{.agents", wantErr: "unclosed {"},
+		{template: "{.agents[0}", wantErr: "unclosed ["},
+		{template: "{.agents[x]}", wantErr: "unsupported [x]"},
+		{template: "{range .agents[*]}{.uuid}", wantErr: "{range} without {end}"},
+		{template: "{.uuid}{end}", wantErr: "{end} without {range}"},
+		{template: `{"\q"}`, wantErr: "invalid literal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			_, err := parseJSONPath(tt.template)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseJSONPath(%q) error = %v, want %q", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
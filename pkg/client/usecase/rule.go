@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+)
+
+type RuleUsecase interface {
+	List(format string) string
+	Create(req request.AlertRuleRequest, format string) string
+	Update(ruleUUID string, req request.AlertRuleRequest, format string) string
+	Delete(ruleUUID string, format string) string
+}
+
+type ruleUsecase struct {
+	config config.BaseConfig
+	repo   repository.RuleRepository
+}
+
+func NewRuleUsecase(conf config.BaseConfig) RuleUsecase {
+	return &ruleUsecase{
+		config: conf,
+		repo:   repository.NewRuleRepository(conf),
+	}
+}
+
+func (u *ruleUsecase) List(format string) string {
+	resp := u.repo.GetRules()
+	return Format(format, resp)
+}
+
+func (u *ruleUsecase) Create(req request.AlertRuleRequest, format string) string {
+	resp := u.repo.CreateRule(req)
+	return Format(format, resp)
+}
+
+func (u *ruleUsecase) Update(ruleUUID string, req request.AlertRuleRequest, format string) string {
+	resp := u.repo.UpdateRule(ruleUUID, req)
+	return Format(format, resp)
+}
+
+func (u *ruleUsecase) Delete(ruleUUID string, format string) string {
+	resp := u.repo.DeleteRule(ruleUUID)
+	return Format(format, resp)
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+)
+
+type SilenceUsecase interface {
+	List(req request.SilenceListRequest, format string) string
+	Create(req request.SilenceRequest, format string) string
+	Expire(silenceUUID string, format string) string
+
+	ListMaintenanceWindows(format string) string
+	CreateMaintenanceWindow(req request.MaintenanceWindowRequest, format string) string
+	DeleteMaintenanceWindow(windowUUID string, format string) string
+}
+
+type silenceUsecase struct {
+	config config.BaseConfig
+	repo   repository.SilenceRepository
+}
+
+func NewSilenceUsecase(conf config.BaseConfig) SilenceUsecase {
+	return &silenceUsecase{
+		config: conf,
+		repo:   repository.NewSilenceRepository(conf),
+	}
+}
+
+func (u *silenceUsecase) List(req request.SilenceListRequest, format string) string {
+	resp := u.repo.GetSilences(req)
+	return Format(format, resp)
+}
+
+func (u *silenceUsecase) Create(req request.SilenceRequest, format string) string {
+	resp := u.repo.CreateSilence(req)
+	return Format(format, resp)
+}
+
+func (u *silenceUsecase) Expire(silenceUUID string, format string) string {
+	resp := u.repo.ExpireSilence(silenceUUID)
+	return Format(format, resp)
+}
+
+func (u *silenceUsecase) ListMaintenanceWindows(format string) string {
+	resp := u.repo.GetMaintenanceWindows()
+	return Format(format, resp)
+}
+
+func (u *silenceUsecase) CreateMaintenanceWindow(req request.MaintenanceWindowRequest, format string) string {
+	resp := u.repo.CreateMaintenanceWindow(req)
+	return Format(format, resp)
+}
+
+func (u *silenceUsecase) DeleteMaintenanceWindow(windowUUID string, format string) string {
+	resp := u.repo.DeleteMaintenanceWindow(windowUUID)
+	return Format(format, resp)
+}
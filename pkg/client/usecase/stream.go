@@ -0,0 +1,527 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// Replay targets
+const (
+	ReplayTargetPulsar = "pulsar"
+	ReplayTargetGRPC   = "grpc"
+)
+
+// RecordOptions controls stream recording
+type RecordOptions struct {
+	Path         string
+	Duration     time.Duration // 0 records until interrupted
+	Count        int64         // 0 for no limit
+	Sources      []string      // only record these sources, all when empty
+	FromEarliest bool          // start from the oldest retained message instead of new ones
+}
+
+// ReplayOptions controls stream replay
+type ReplayOptions struct {
+	Path           string
+	Speed          float64 // 1 is real time, N is N times faster, 0 is as fast as possible
+	Remap          map[string]string
+	Target         string
+	AgentAddr      string
+	KeepTimestamps bool // send the recorded timestamps instead of shifting them to now
+	NewIDs         bool
+	ResultsPath    string // write agent results as JSON lines, grpc target only
+}
+
+// TailOptions controls stream tailing
+type TailOptions struct {
+	Topic        string  // configured topic key such as processed_sensor_data, or a topic name
+	Agent        string  // only messages of this agent UUID
+	SensorType   string  // only messages of this sensor type
+	Filter       string  // filter expression over the message fields, see parseFilter
+	Sample       int64   // print one in every Sample matching messages, 0 or 1 for all
+	Rate         float64 // print at most this many messages per second, 0 for no limit
+	Count        int64   // stop after printing this many messages, 0 for no limit
+	FromEarliest bool    // start from the oldest retained message instead of new ones
+	Format       string
+}
+
+// PublishOptions controls stream publishing. Messages are read from Path, NDJSON with
+// "-" for stdin, or Data is sent when Path is empty.
+type PublishOptions struct {
+	Topic  string // configured topic key such as external_sensor_data, or a topic name
+	Path   string
+	Data   model.IncomingStreamData
+	Format string
+}
+
+// StreamSummary describes a finished record or replay
+type StreamSummary struct {
+	Messages  int64
+	Skipped   int64
+	Errors    int64
+	Processed int64
+	Anomalies int64
+	Elapsed   time.Duration
+}
+
+type StreamUsecase interface {
+	Record(ctx context.Context, opts RecordOptions) (*StreamSummary, error)
+	Replay(ctx context.Context, opts ReplayOptions) (*StreamSummary, error)
+	Tail(ctx context.Context, opts TailOptions, out io.Writer) (*StreamSummary, error)
+	Publish(ctx context.Context, opts PublishOptions, out io.Writer) (*StreamSummary, error)
+}
+
+type streamUsecase struct {
+	config config.BaseConfig
+}
+
+func NewStreamUsecase(conf config.BaseConfig) StreamUsecase {
+	return &streamUsecase{
+		config: conf,
+	}
+}
+
+// Record reads external sensor data into an archive until the duration or count is reached or ctx is cancelled
+func (u *streamUsecase) Record(ctx context.Context, opts RecordOptions) (*StreamSummary, error) {
+	repo, err := repository.NewStreamRepository(u.config)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Close()
+
+	writer, err := repository.NewArchiveWriter(u.config, opts.Path, model.StreamArchiveHeader{
+		Topic:      repo.Topic(),
+		RecordedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	sources := map[string]bool{}
+	for _, source := range opts.Sources {
+		sources[source] = true
+	}
+
+	u.config.Logger.INFO(config.CUSTREC, "Recording sensor data", map[string]interface{}{
+		"path":     opts.Path,
+		"topic":    repo.Topic(),
+		"duration": opts.Duration.String(),
+		"count":    opts.Count,
+	})
+
+	summary := &StreamSummary{}
+	started := time.Now()
+	err = repo.ReadSensorData(ctx, opts.FromEarliest, func(publishTime time.Time, data *model.IncomingStreamData) error {
+		if len(sources) > 0 && !sources[data.Source] {
+			summary.Skipped++
+			return nil
+		}
+		if err := writer.Write(&model.StreamArchiveRecord{At: publishTime, Data: *data}); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		summary.Messages++
+		if opts.Count > 0 && summary.Messages >= opts.Count {
+			stop()
+		}
+		return nil
+	})
+	summary.Elapsed = time.Since(started)
+
+	if closeErr := writer.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close archive: %w", closeErr)
+	}
+
+	u.config.Logger.INFO(config.CUSTDONE, "Sensor data recording finished", map[string]interface{}{
+		"path":     opts.Path,
+		"messages": summary.Messages,
+		"skipped":  summary.Skipped,
+	})
+	return summary, err
+}
+
+// Tail prints the messages of a topic that match the options until the count is reached
+// or ctx is cancelled. Messages left out by the filters or sampling count as skipped.
+func (u *streamUsecase) Tail(ctx context.Context, opts TailOptions, out io.Writer) (*StreamSummary, error) {
+	filter, err := parseFilter(opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := repository.NewPulsarRepository(u.config, u.config.YamlConfig.Pulsar.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Close()
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	topic := repo.ResolveTopic(opts.Topic)
+	u.config.Logger.INFO(config.CUSTTAIL, "Tailing stream topic", map[string]interface{}{
+		"topic":  topic,
+		"filter": opts.Filter,
+		"sample": opts.Sample,
+		"rate":   opts.Rate,
+	})
+
+	tabular := false
+	switch name, _ := splitFormat(opts.Format); name {
+	case "table", "wide", "csv":
+		tabular = true
+	}
+	var interval time.Duration
+	if opts.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / opts.Rate)
+	}
+
+	printer := &streamPrinter{format: opts.Format}
+	summary := &StreamSummary{}
+	var matched int64
+	var printed time.Time
+	started := time.Now()
+	err = repo.TailTopic(ctx, topic, opts.FromEarliest, func(msg *model.TopicMessage) error {
+		row, err := toRow(msg.Data)
+		if err != nil {
+			return err
+		}
+		lookup := func(path string) any {
+			if value := lookupPath(row, path); value != nil {
+				return value
+			}
+			switch path {
+			case "topic":
+				return msg.Topic
+			case "type":
+				return msg.Type
+			case "message_id":
+				return msg.MessageID
+			}
+			return nil
+		}
+		if (opts.Agent != "" && cellText(lookup("agent_uuid")) != opts.Agent) ||
+			(opts.SensorType != "" && cellText(lookup("sensor_type")) != opts.SensorType) ||
+			!filter.match(lookup) {
+			summary.Skipped++
+			return nil
+		}
+
+		matched++
+		if (opts.Sample > 1 && (matched-1)%opts.Sample != 0) ||
+			(interval > 0 && time.Since(printed) < interval) {
+			summary.Skipped++
+			return nil
+		}
+		printed = time.Now()
+
+		var item interface{} = msg
+		if tabular {
+			item = newTailRow(msg)
+		}
+		if _, err := io.WriteString(out, printer.print(item)); err != nil {
+			return err
+		}
+		summary.Messages++
+		if opts.Count > 0 && summary.Messages >= opts.Count {
+			stop()
+		}
+		return nil
+	})
+	summary.Elapsed = time.Since(started)
+
+	u.config.Logger.INFO(config.CUSTDONE, "Stream tail finished", map[string]interface{}{
+		"topic":    topic,
+		"messages": summary.Messages,
+		"skipped":  summary.Skipped,
+	})
+	return summary, err
+}
+
+// tailRow is a tailed message as a table row, its data summarized
+type tailRow struct {
+	Time    string `json:"time"`
+	Type    string `json:"type"`
+	Agent   string `json:"agent"`
+	Sensor  string `json:"sensor"`
+	Summary string `json:"summary"`
+}
+
+func newTailRow(msg *model.TopicMessage) tailRow {
+	row := tailRow{
+		Time: msg.PublishTime.Local().Format("2006-01-02 15:04:05.000"),
+		Type: msg.Type,
+	}
+	switch data := msg.Data.(type) {
+	case *model.ProcessedStreamData:
+		row.Agent, row.Sensor = data.AgentUUID, data.SensorType
+		row.Summary = fmt.Sprintf("%g → %g, confidence %.2f, %dµs", data.OriginalValue, data.ProcessedValue, data.Confidence, data.ProcessingTime)
+		if data.Anomaly {
+			row.Summary = "ANOMALY " + row.Summary
+		}
+	case *model.AlertData:
+		row.Agent, row.Sensor = data.AgentUUID, data.SensorType
+		row.Summary = fmt.Sprintf("[%s] %s (value %g, threshold %g)", data.Severity, data.Message, data.ProcessedValue, data.Threshold)
+	case *model.SystemMetrics:
+		row.Agent = data.AgentUUID
+		row.Summary = fmt.Sprintf("cpu %.1f%%, memory %.1f%%, disk %.1f%%", data.CPUUsage, data.MemoryUsage, data.DiskUsage)
+	case *model.IncomingStreamData:
+		row.Sensor = data.SensorType
+		row.Summary = fmt.Sprintf("%s: %g", data.Source, data.Value)
+	case *model.StreamProcessingResult:
+		row.Agent = data.AgentUUID
+		row.Summary = fmt.Sprintf("%s succeeded in %dµs", data.ProcessingType, data.ProcessingTime)
+		if !data.Success {
+			row.Summary = fmt.Sprintf("%s failed: %s", data.ProcessingType, data.ErrorMessage)
+		}
+	default:
+		b, _ := json.Marshal(data)
+		row.Summary = string(b)
+	}
+	return row
+}
+
+// Publish sends sensor data to a topic and prints the message ID of each. Messages
+// without a UUID or timestamp get a new UUID and the current time.
+func (u *streamUsecase) Publish(ctx context.Context, opts PublishOptions, out io.Writer) (*StreamSummary, error) {
+	repo, err := repository.NewPulsarRepository(u.config, u.config.YamlConfig.Pulsar.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Close()
+
+	topic := repo.ResolveTopic(opts.Topic)
+	u.config.Logger.INFO(config.CUSTPUB, "Publishing sensor data", map[string]interface{}{
+		"topic": topic,
+		"path":  opts.Path,
+	})
+
+	printer := &streamPrinter{format: opts.Format}
+	summary := &StreamSummary{}
+	started := time.Now()
+	publish := func(data *model.IncomingStreamData) error {
+		if data.UUID == "" {
+			data.UUID = uuid.New().String()
+		}
+		if data.Timestamp.IsZero() {
+			data.Timestamp = time.Now().UTC()
+		}
+		msgID, err := repo.PublishStreamData(ctx, topic, data)
+		if err != nil {
+			return err
+		}
+		summary.Messages++
+		_, err = io.WriteString(out, printer.print(publishedMessage{ID: data.UUID, Topic: topic, MessageID: msgID}))
+		return err
+	}
+
+	if opts.Path == "" {
+		err = publish(&opts.Data)
+	} else {
+		err = decodeMessages(opts.Path, publish)
+	}
+	summary.Elapsed = time.Since(started)
+
+	u.config.Logger.INFO(config.CUSTDONE, "Sensor data publishing finished", map[string]interface{}{
+		"topic":    topic,
+		"messages": summary.Messages,
+	})
+	return summary, err
+}
+
+// publishedMessage confirms a message the broker stored
+type publishedMessage struct {
+	ID        string `json:"id"`
+	Topic     string `json:"topic"`
+	MessageID string `json:"message_id"`
+}
+
+// decodeMessages calls handle with each JSON message of path, "-" for stdin, in turn,
+// rejecting unknown fields. Messages are usually one per line but may span lines.
+func decodeMessages[T any](path string, handle func(v *T) error) error {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	for n := 1; ; n++ {
+		var v T
+		err := decoder.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse %s message %d: %w", path, n, err)
+		}
+		if err := handle(&v); err != nil {
+			return fmt.Errorf("%s message %d: %w", path, n, err)
+		}
+	}
+}
+
+// Replay sends an archive's records with their recorded spacing divided by the speed
+func (u *streamUsecase) Replay(ctx context.Context, opts ReplayOptions) (*StreamSummary, error) {
+	reader, err := repository.NewArchiveReader(u.config, opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	summary := &StreamSummary{}
+	var send func(data *model.IncomingStreamData) error
+	var closeTarget func() error
+	var wg sync.WaitGroup
+
+	switch opts.Target {
+	case ReplayTargetPulsar:
+		repo, err := repository.NewStreamRepository(u.config)
+		if err != nil {
+			return nil, err
+		}
+		send = func(data *model.IncomingStreamData) error {
+			return repo.PublishSensorData(ctx, data)
+		}
+		closeTarget = func() error {
+			repo.Close()
+			return nil
+		}
+	case ReplayTargetGRPC:
+		repo, err := repository.NewAgentStreamRepository(u.config, opts.AgentAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		var results *json.Encoder
+		if opts.ResultsPath != "" {
+			file, err := os.Create(opts.ResultsPath)
+			if err != nil {
+				repo.Close()
+				return nil, fmt.Errorf("failed to create results file: %w", err)
+			}
+			defer file.Close()
+			buffered := bufio.NewWriter(file)
+			defer buffered.Flush()
+			results = json.NewEncoder(buffered)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range repo.Results() {
+				summary.Processed++
+				if result.Anomaly {
+					summary.Anomalies++
+				}
+				if results != nil {
+					results.Encode(result)
+				}
+			}
+		}()
+		send = repo.Send
+		closeTarget = repo.Close
+	default:
+		return nil, fmt.Errorf("unknown replay target: %s", opts.Target)
+	}
+
+	u.config.Logger.INFO(config.CUSTRPL, "Replaying sensor data", map[string]interface{}{
+		"path":   opts.Path,
+		"topic":  reader.Header().Topic,
+		"target": opts.Target,
+		"speed":  opts.Speed,
+	})
+
+	started := time.Now()
+	err = u.replayRecords(ctx, reader, opts, started, send, summary)
+
+	if closeErr := closeTarget(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	wg.Wait()
+	summary.Elapsed = time.Since(started)
+
+	u.config.Logger.INFO(config.CUSTDONE, "Sensor data replay finished", map[string]interface{}{
+		"path":      opts.Path,
+		"messages":  summary.Messages,
+		"errors":    summary.Errors,
+		"processed": summary.Processed,
+		"anomalies": summary.Anomalies,
+	})
+	return summary, err
+}
+
+// replayRecords paces and rewrites each record before sending it
+func (u *streamUsecase) replayRecords(ctx context.Context, reader repository.ArchiveReader, opts ReplayOptions, started time.Time, send func(*model.IncomingStreamData) error, summary *StreamSummary) error {
+	var first time.Time
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if first.IsZero() {
+			first = record.At
+		}
+
+		if opts.Speed > 0 {
+			due := started.Add(time.Duration(float64(record.At.Sub(first)) / opts.Speed))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(wait):
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		data := record.Data
+		if source, ok := opts.Remap[data.Source]; ok {
+			data.Source = source
+		}
+		if !opts.KeepTimestamps {
+			data.Timestamp = data.Timestamp.Add(started.Sub(first))
+		}
+		if opts.NewIDs {
+			data.UUID = uuid.New().String()
+		}
+
+		if err := send(&data); err != nil {
+			summary.Errors++
+			u.config.Logger.WARN(config.CUSTERR, "Failed to replay sensor data", map[string]interface{}{
+				"error":  err.Error(),
+				"source": data.Source,
+			})
+			continue
+		}
+		summary.Messages++
+	}
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+var recordedAt = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// openTestArchive records readings from sensor-1 and sensor-2 at 0, 100ms and 300ms
+func openTestArchive(t *testing.T, conf config.BaseConfig) repository.ArchiveReader {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "archive.jsonl.gz")
+	writer, err := repository.NewArchiveWriter(conf, path, model.StreamArchiveHeader{Topic: "sensor-data", RecordedAt: recordedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, offset := range []time.Duration{0, 100 * time.Millisecond, 300 * time.Millisecond} {
+		source := "sensor-1"
+		if i == 1 {
+			source = "sensor-2"
+		}
+		at := recordedAt.Add(offset)
+		if err := writer.Write(&model.StreamArchiveRecord{At: at, Data: model.IncomingStreamData{
+			UUID:       []string{"r1", "r2", "r3"}[i],
+			Source:     source,
+			SensorType: "temperature",
+			Value:      float64(20 + i),
+			Timestamp:  at.Add(-time.Second),
+		}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := repository.NewArchiveReader(conf, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reader.Close() })
+	return reader
+}
+
+// sentRecord is a replayed reading and when it was sent relative to the start
+type sentRecord struct {
+	data  model.IncomingStreamData
+	after time.Duration
+}
+
+func TestReplayRecords(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  ReplayOptions
+		check func(t *testing.T, sent []sentRecord, started time.Time)
+	}{
+		{
+			name: "real time",
+			opts: ReplayOptions{Speed: 1},
+			check: func(t *testing.T, sent []sentRecord, started time.Time) {
+				wantSpacing(t, sent, []time.Duration{0, 100 * time.Millisecond, 300 * time.Millisecond})
+			},
+		},
+		{
+			name: "faster",
+			opts: ReplayOptions{Speed: 2},
+			check: func(t *testing.T, sent []sentRecord, started time.Time) {
+				wantSpacing(t, sent, []time.Duration{0, 50 * time.Millisecond, 150 * time.Millisecond})
+			},
+		},
+		{
+			name: "as fast as possible",
+			check: func(t *testing.T, sent []sentRecord, started time.Time) {
+				if last := sent[len(sent)-1].after; last > 50*time.Millisecond {
+					t.Errorf("last reading sent after %v, want no pacing", last)
+				}
+			},
+		},
+		{
+			name: "remapped sources and shifted timestamps",
+			opts: ReplayOptions{Remap: map[string]string{"sensor-1": "plant-b-1"}},
+			check: func(t *testing.T, sent []sentRecord, started time.Time) {
+				for i, want := range []string{"plant-b-1", "sensor-2", "plant-b-1"} {
+					if sent[i].data.Source != want {
+						t.Errorf("reading %d source = %q, want %q", i, sent[i].data.Source, want)
+					}
+				}
+				// The first reading keeps its offset of -1s from when it was recorded
+				for i, offset := range []time.Duration{0, 100 * time.Millisecond, 300 * time.Millisecond} {
+					want := started.Add(offset - time.Second)
+					if got := sent[i].data.Timestamp; !got.Equal(want) {
+						t.Errorf("reading %d timestamp = %v, want %v", i, got, want)
+					}
+				}
+				if sent[0].data.UUID != "r1" {
+					t.Errorf("reading 0 uuid = %q, want the recorded one", sent[0].data.UUID)
+				}
+			},
+		},
+		{
+			name: "recorded timestamps and new ids",
+			opts: ReplayOptions{KeepTimestamps: true, NewIDs: true},
+			check: func(t *testing.T, sent []sentRecord, started time.Time) {
+				seen := map[string]bool{}
+				for i, offset := range []time.Duration{0, 100 * time.Millisecond, 300 * time.Millisecond} {
+					if want := recordedAt.Add(offset - time.Second); !sent[i].data.Timestamp.Equal(want) {
+						t.Errorf("reading %d timestamp = %v, want %v", i, sent[i].data.Timestamp, want)
+					}
+					if id := sent[i].data.UUID; id == "" || id == []string{"r1", "r2", "r3"}[i] || seen[id] {
+						t.Errorf("reading %d uuid = %q, want a new one", i, id)
+					}
+					seen[sent[i].data.UUID] = true
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := config.BaseConfig{}
+			conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+			u := &streamUsecase{config: conf}
+			reader := openTestArchive(t, conf)
+
+			var sent []sentRecord
+			started := time.Now()
+			summary := &StreamSummary{}
+			err := u.replayRecords(context.Background(), reader, tt.opts, started, func(data *model.IncomingStreamData) error {
+				sent = append(sent, sentRecord{data: *data, after: time.Since(started)})
+				return nil
+			}, summary)
+			if err != nil {
+				t.Fatalf("replayRecords() error = %v", err)
+			}
+			if len(sent) != 3 || summary.Messages != 3 {
+				t.Fatalf("sent %d readings, counted %d, want 3", len(sent), summary.Messages)
+			}
+			tt.check(t, sent, started)
+		})
+	}
+}
+
+// wantSpacing checks each reading was sent at its due time, allowing for scheduling delay
+func wantSpacing(t *testing.T, sent []sentRecord, due []time.Duration) {
+	t.Helper()
+	for i, want := range due {
+		if got := sent[i].after; got < want || got > want+80*time.Millisecond {
+			t.Errorf("reading %d sent after %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestReplayRecordsErrors(t *testing.T) {
+	conf := config.BaseConfig{}
+	conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+	u := &streamUsecase{config: conf}
+
+	summary := &StreamSummary{}
+	err := u.replayRecords(context.Background(), openTestArchive(t, conf), ReplayOptions{}, time.Now(), func(data *model.IncomingStreamData) error {
+		if data.Source == "sensor-2" {
+			return errors.New("agent unavailable")
+		}
+		return nil
+	}, summary)
+	if err != nil {
+		t.Fatalf("replayRecords() error = %v", err)
+	}
+	if summary.Messages != 2 || summary.Errors != 1 {
+		t.Errorf("summary = %d messages, %d errors, want 2 and 1", summary.Messages, summary.Errors)
+	}
+
+	// Cancelling stops the replay while it waits for the next reading
+	ctx, cancel := context.WithCancel(context.Background())
+	summary = &StreamSummary{}
+	var sent int
+	err = u.replayRecords(ctx, openTestArchive(t, conf), ReplayOptions{Speed: 0.1}, time.Now(), func(data *model.IncomingStreamData) error {
+		sent++
+		cancel()
+		return nil
+	}, summary)
+	if err != nil || sent != 1 {
+		t.Errorf("replayRecords() after cancel = %d sent, error %v, want 1 and none", sent, err)
+	}
+}
@@ -0,0 +1,586 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+// TableOptions control how lists are rendered by the table and wide formats
+type TableOptions struct {
+	Columns   []string // headers or JSON paths replacing the default columns, e.g. uuid,metadata.group
+	SortBy    string   // header or JSON path to sort the rows by
+	NoHeaders bool
+	Width     int // truncate rows to this many characters, 0 to disable
+}
+
+var tableOptions TableOptions
+
+// SetTableOptions sets the table options used by Format
+func SetTableOptions(opts TableOptions) {
+	tableOptions = opts
+}
+
+// tableColumn renders the value at a dotted JSON path of each row. Paths map over lists,
+// so processing_rules.name lists the name of every rule.
+type tableColumn struct {
+	Header   string
+	Path     string
+	Wide     bool // only shown with -o wide
+	Relative bool // time shown relative to now, e.g. 5m ago
+}
+
+// resourceColumns are the columns of resources with a curated table; other resources get
+// their scalar fields by default and the remaining ones in wide mode
+var resourceColumns = map[reflect.Type][]tableColumn{
+	reflect.TypeOf(model.Agent{}): {
+		{Header: "UUID", Path: "uuid"},
+		{Header: "HOSTNAME", Path: "hostname"},
+		{Header: "IP", Path: "ip_address"},
+		{Header: "STATUS", Path: "status"},
+		{Header: "VERSION", Path: "version"},
+		{Header: "HEARTBEAT", Path: "heartbeat_at", Relative: true},
+		{Header: "UPDATED", Path: "updated_at", Relative: true},
+		{Header: "PORT", Path: "port", Wide: true},
+		{Header: "THREADS", Path: "thread_count", Wide: true},
+		{Header: "MAX THREADS", Path: "max_thread_count", Wide: true},
+		{Header: "GROUP", Path: "metadata.group", Wide: true},
+		{Header: "CAPABILITIES", Path: "capabilities", Wide: true},
+	},
+	reflect.TypeOf(response.AgentWatchEvent{}): {
+		{Header: "EVENT", Path: "type"},
+		{Header: "UUID", Path: "agent.uuid"},
+		{Header: "HOSTNAME", Path: "agent.hostname"},
+		{Header: "IP", Path: "agent.ip_address"},
+		{Header: "STATUS", Path: "agent.status"},
+		{Header: "VERSION", Path: "agent.version"},
+		{Header: "HEARTBEAT", Path: "agent.heartbeat_at", Relative: true},
+		{Header: "PORT", Path: "agent.port", Wide: true},
+		{Header: "THREADS", Path: "agent.thread_count", Wide: true},
+		{Header: "MAX THREADS", Path: "agent.max_thread_count", Wide: true},
+		{Header: "GROUP", Path: "agent.metadata.group", Wide: true},
+	},
+	reflect.TypeOf(model.Alert{}): {
+		{Header: "UUID", Path: "uuid"},
+		{Header: "STATUS", Path: "status"},
+		{Header: "SEVERITY", Path: "severity"},
+		{Header: "AGENT", Path: "agent_uuid"},
+		{Header: "RULE", Path: "rule"},
+		{Header: "COUNT", Path: "count"},
+		{Header: "LAST SEEN", Path: "last_seen_at", Relative: true},
+		{Header: "SENSOR TYPE", Path: "sensor_type", Wide: true},
+		{Header: "VALUE", Path: "last_value", Wide: true},
+		{Header: "THRESHOLD", Path: "threshold", Wide: true},
+		{Header: "ASSIGNEE", Path: "assignee", Wide: true},
+		{Header: "FIRST SEEN", Path: "first_seen_at", Relative: true, Wide: true},
+		{Header: "MESSAGE", Path: "message", Wide: true},
+	},
+	reflect.TypeOf(model.AlertRule{}): {
+		{Header: "UUID", Path: "uuid"},
+		{Header: "NAME", Path: "name"},
+		{Header: "KIND", Path: "kind"},
+		{Header: "SENSOR TYPE", Path: "sensor_type"},
+		{Header: "METRIC", Path: "metric"},
+		{Header: "OPERATOR", Path: "operator"},
+		{Header: "THRESHOLD", Path: "threshold"},
+		{Header: "FOR", Path: "for"},
+		{Header: "ENABLED", Path: "enabled"},
+		{Header: "SEVERITY", Path: "severity", Wide: true},
+		{Header: "AGENT", Path: "agent_uuid", Wide: true},
+		{Header: "GROUP", Path: "group", Wide: true},
+		{Header: "EVALUATED", Path: "evaluated_at", Relative: true, Wide: true},
+		{Header: "LAST ERROR", Path: "last_error", Wide: true},
+	},
+	reflect.TypeOf(model.Silence{}): {
+		{Header: "UUID", Path: "uuid"},
+		{Header: "MATCHERS", Path: "matchers"},
+		{Header: "STARTS", Path: "starts_at", Relative: true},
+		{Header: "ENDS", Path: "ends_at", Relative: true},
+		{Header: "CREATED BY", Path: "created_by"},
+		{Header: "COMMENT", Path: "comment", Wide: true},
+	},
+	reflect.TypeOf(model.MaintenanceWindow{}): {
+		{Header: "UUID", Path: "uuid"},
+		{Header: "NAME", Path: "name"},
+		{Header: "GROUP", Path: "group"},
+		{Header: "WEEKDAYS", Path: "weekdays"},
+		{Header: "START", Path: "start_time"},
+		{Header: "DURATION", Path: "duration"},
+		{Header: "TIMEZONE", Path: "timezone"},
+		{Header: "CREATED BY", Path: "created_by", Wide: true},
+		{Header: "COMMENT", Path: "comment", Wide: true},
+	},
+	reflect.TypeOf(response.AgentConfig{}): {
+		{Header: "AGENT", Path: "agent_uuid"},
+		{Header: "SENSOR TYPE", Path: "sensor_type"},
+		{Header: "RULES", Path: "processing_rules.name"},
+		{Header: "OUTPUT STREAMS", Path: "output_streams"},
+		{Header: "UPDATED", Path: "updated_at", Relative: true},
+		{Header: "UUID", Path: "uuid", Wide: true},
+	},
+	reflect.TypeOf(response.ClientContext{}): {
+		{Header: "CURRENT", Path: "current"},
+		{Header: "NAME", Path: "name"},
+		{Header: "SERVER", Path: "server"},
+		{Header: "USER", Path: "user"},
+		{Header: "CA", Path: "certificate_authority", Wide: true},
+		{Header: "INSECURE", Path: "insecure_skip_tls_verify", Wide: true},
+		{Header: "TOKENS", Path: "token_backend", Wide: true},
+		{Header: "TOKEN STORE", Path: "token_store", Wide: true},
+	},
+	reflect.TypeOf(response.AgentConfigRules{}): {
+		{Header: "UUID", Path: "uuid"},
+		{Header: "NAME", Path: "name"},
+		{Header: "ENABLED", Path: "enabled"},
+		{Header: "PARAMS", Path: "params"},
+		{Header: "UPDATED", Path: "updated_at", Relative: true},
+	},
+}
+
+func formatTable(data interface{}, wide bool) string {
+	found := findRows(data)
+	switch {
+	case found.failed:
+		return fmt.Sprintf("Error: %s\n", found.message)
+	case found.rows.IsValid():
+		rows, columns, err := tableRows(found, wide)
+		if err != nil {
+			return fmt.Sprintf("Error formatting table: %v\n", err)
+		}
+		if len(rows) == 0 {
+			return "No resources found.\n"
+		}
+
+		now := time.Now()
+		var cells [][]string
+		if !tableOptions.NoHeaders {
+			cells = append(cells, columnHeaders(columns))
+		}
+		for _, row := range rows {
+			line := make([]string, len(columns))
+			for i, column := range columns {
+				line[i] = formatCell(lookupPath(row, column.Path), column.Relative, now)
+			}
+			cells = append(cells, line)
+		}
+		return layoutTable(cells, tableOptions.Width)
+	case found.message != "":
+		return found.message + "\n"
+	case found.value.Kind() == reflect.Struct:
+		return formatFields(found.value)
+	}
+
+	// Fallback to simple string representation
+	return fmt.Sprintf("%+v\n", data)
+}
+
+// foundRows is what findRows located in the data passed to Format
+type foundRows struct {
+	value   reflect.Value // data with pointers removed
+	rows    reflect.Value // list of rows, invalid when the data holds none
+	message string        // envelope message
+	failed  bool          // the data is an error response
+}
+
+// findRows locates the rows of data: a list, the first non-empty list of a response envelope
+// or its Data object as a single row. Envelopes with an error code are marked failed.
+func findRows(data interface{}) foundRows {
+	if m, ok := data.(map[string]any); ok && m["code"] == "error" {
+		return foundRows{message: fmt.Sprint(m["message"]), failed: true}
+	}
+
+	val := reflect.ValueOf(data)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return foundRows{}
+		}
+		val = val.Elem()
+	}
+
+	found := foundRows{value: val}
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		found.rows = val
+		return found
+	case reflect.Struct:
+	default:
+		return found
+	}
+
+	code, message := stringField(val, "Code"), stringField(val, "Message")
+	found.message = message
+	if code != "" && !strings.EqualFold(code, "SUCCESS") {
+		found.failed = true
+		return found
+	}
+
+	var emptyList reflect.Value
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		if !val.Type().Field(i).IsExported() || field.Kind() != reflect.Slice || !isStruct(field.Type().Elem()) {
+			continue
+		}
+		if field.Len() > 0 {
+			found.rows = field
+			return found
+		}
+		if !emptyList.IsValid() {
+			emptyList = field
+		}
+	}
+
+	if dataField := val.FieldByName("Data"); dataField.IsValid() && dataField.Kind() == reflect.Ptr && !dataField.IsNil() && isStruct(dataField.Type()) {
+		rows := reflect.MakeSlice(reflect.SliceOf(dataField.Type().Elem()), 0, 1)
+		found.rows = reflect.Append(rows, dataField.Elem())
+		return found
+	}
+	found.rows = emptyList
+	return found
+}
+
+// formatFields prints one line per field of structs that are not lists
+func formatFields(val reflect.Value) string {
+	var result strings.Builder
+	typ := val.Type()
+
+	for i := 0; i < val.NumField(); i++ {
+		field := typ.Field(i)
+		value := val.Field(i)
+
+		// Skip unexported fields
+		if !field.IsExported() {
+			continue
+		}
+
+		result.WriteString(fmt.Sprintf("%-15s: %v\n", field.Name, value.Interface()))
+	}
+
+	return result.String()
+}
+
+// tableRows converts the rows to their JSON form, sorted by --sort-by, and picks the columns
+func tableRows(found foundRows, wide bool) ([]map[string]any, []tableColumn, error) {
+	list := found.rows
+	elemType := list.Type().Elem()
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+
+	rows := make([]map[string]any, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		row, err := toRow(list.Index(i).Interface())
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	available := resourceColumns[elemType]
+	if available == nil {
+		available = derivedColumns(elemType)
+	}
+
+	var columns []tableColumn
+	if len(tableOptions.Columns) > 0 {
+		for _, spec := range tableOptions.Columns {
+			columns = append(columns, resolveColumn(available, spec))
+		}
+	} else {
+		for _, column := range available {
+			if wide || !column.Wide {
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	if tableOptions.SortBy != "" {
+		sortColumn := resolveColumn(available, tableOptions.SortBy)
+		sort.SliceStable(rows, func(i, j int) bool {
+			return lessValue(lookupPath(rows[i], sortColumn.Path), lookupPath(rows[j], sortColumn.Path))
+		})
+	}
+	return rows, columns, nil
+}
+
+func columnHeaders(columns []tableColumn) []string {
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Header
+	}
+	return header
+}
+
+// toRow converts a resource to its JSON form, so columns use the same names as -o json
+func toRow(v interface{}) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var row map[string]any
+	if err := decoder.Decode(&row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// derivedColumns shows the scalar JSON fields of a struct by default and the rest in wide mode
+func derivedColumns(typ reflect.Type) []tableColumn {
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var columns, wideColumns []tableColumn
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		isTime := fieldType == reflect.TypeOf(time.Time{})
+		column := tableColumn{
+			Header:   strings.ToUpper(strings.ReplaceAll(name, "_", " ")),
+			Path:     name,
+			Relative: isTime,
+		}
+		if isTime || isScalar(fieldType) {
+			columns = append(columns, column)
+		} else {
+			column.Wide = true
+			wideColumns = append(wideColumns, column)
+		}
+	}
+	return append(columns, wideColumns...)
+}
+
+// resolveColumn finds a column by header or path, or reads spec as a JSON path
+func resolveColumn(available []tableColumn, spec string) tableColumn {
+	normalized := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(spec))
+	for _, column := range available {
+		if strings.EqualFold(column.Path, spec) ||
+			strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(column.Header)) == normalized {
+			return column
+		}
+	}
+	return tableColumn{
+		Header:   strings.ToUpper(strings.ReplaceAll(spec, "_", " ")),
+		Path:     spec,
+		Relative: strings.HasSuffix(spec, "_at"),
+	}
+}
+
+// lookupPath walks a dotted path through a row, collecting the values of every list element
+func lookupPath(value any, path string) any {
+	if path == "" {
+		return value
+	}
+	key, rest, _ := strings.Cut(path, ".")
+	switch v := value.(type) {
+	case map[string]any:
+		return lookupPath(v[key], rest)
+	case []any:
+		values := make([]any, 0, len(v))
+		for _, item := range v {
+			if found := lookupPath(item, path); found != nil {
+				values = append(values, found)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// formatCell renders a value for the table, relative times and <none> for missing values
+func formatCell(value any, relative bool, now time.Time) string {
+	if text, ok := value.(string); ok && relative {
+		if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			return relativeTime(t, now)
+		}
+	}
+	if text := cellText(value); text != "" {
+		return text
+	}
+	return "<none>"
+}
+
+// cellText renders lists comma separated and maps as sorted key=value pairs
+func cellText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if text := cellText(item); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key + "=" + cellText(v[key])
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// relativeTime renders t like 45s ago, 5m ago, 3h ago or 2d ago, and future times as in 5m
+func relativeTime(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "<none>"
+	}
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var amount string
+	switch {
+	case d < time.Minute:
+		amount = fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		amount = fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		amount = fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		amount = fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+	if future {
+		return "in " + amount
+	}
+	return amount + " ago"
+}
+
+// lessValue orders numbers numerically, RFC3339 times chronologically and the rest as text.
+// Missing values sort last.
+func lessValue(a, b any) bool {
+	if a == nil || b == nil {
+		return a != nil
+	}
+	if x, ok := a.(json.Number); ok {
+		if y, ok := b.(json.Number); ok {
+			fx, errX := x.Float64()
+			fy, errY := y.Float64()
+			if errX == nil && errY == nil {
+				return fx < fy
+			}
+		}
+	}
+	if x, ok := a.(string); ok {
+		if y, ok := b.(string); ok {
+			tx, errX := time.Parse(time.RFC3339Nano, x)
+			ty, errY := time.Parse(time.RFC3339Nano, y)
+			if errX == nil && errY == nil {
+				return tx.Before(ty)
+			}
+			return x < y
+		}
+	}
+	return fmt.Sprintf("%v", a) < fmt.Sprintf("%v", b)
+}
+
+// layoutTable pads the cells into columns separated by three spaces. With a width, the widest
+// columns are narrowed until the rows fit and cut cells end in an ellipsis.
+func layoutTable(cells [][]string, width int) string {
+	if len(cells) == 0 {
+		return ""
+	}
+
+	const gap = 3
+	const minWidth = 6
+	widths := make([]int, len(cells[0]))
+	for _, line := range cells {
+		for i, cell := range line {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+
+	if width > 0 {
+		total := func() int {
+			sum := gap * (len(widths) - 1)
+			for _, w := range widths {
+				sum += w
+			}
+			return sum
+		}
+		for total() > width {
+			widest := 0
+			for i, w := range widths {
+				if w > widths[widest] {
+					widest = i
+				}
+			}
+			if widths[widest] <= minWidth {
+				break
+			}
+			widths[widest]--
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range cells {
+		for i, cell := range line {
+			runes := []rune(cell)
+			if len(runes) > widths[i] {
+				runes = append(runes[:widths[i]-1], '…')
+			}
+			b.WriteString(string(runes))
+			if i < len(line)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-len(runes)+gap))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func stringField(val reflect.Value, name string) string {
+	field := val.FieldByName(name)
+	if field.IsValid() && field.Kind() == reflect.String {
+		return field.String()
+	}
+	return ""
+}
+
+func isStruct(typ reflect.Type) bool {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct
+}
+
+func isScalar(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+func TestFormatTableSortBy(t *testing.T) {
+	at := func(minute int) *time.Time {
+		evaluated := time.Date(2025, 6, 2, 10, minute, 0, 0, time.UTC)
+		return &evaluated
+	}
+	rules := []model.AlertRule{
+		{Name: "b", SensorType: "system", Threshold: 10, EvaluatedAt: at(30)},
+		{Name: "c", SensorType: "disk", Threshold: 9},
+		{Name: "a", SensorType: "system", Threshold: 100, EvaluatedAt: at(5)},
+	}
+
+	tests := []struct {
+		name   string
+		sortBy string
+		want   string
+	}{
+		{name: "unsorted keeps the server order", want: "b   10\nc   9\na   100\n"},
+		{name: "text by path", sortBy: "name", want: "a   100\nb   10\nc   9\n"},
+		{name: "numbers numerically", sortBy: "threshold", want: "c   9\nb   10\na   100\n"},
+		{name: "times chronologically with missing values last", sortBy: "evaluated_at", want: "a   100\nb   10\nc   9\n"},
+		{name: "ties keep the server order", sortBy: "sensor-type", want: "c   9\nb   10\na   100\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetTableOptions(TableOptions{Columns: []string{"name", "threshold"}, SortBy: tt.sortBy, NoHeaders: true})
+			defer SetTableOptions(TableOptions{})
+
+			if got := formatTable(rules, false); got != tt.want {
+				t.Errorf("formatTable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLessValue(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b any
+		want bool
+	}{
+		{name: "numbers", a: json.Number("9"), b: json.Number("10"), want: true},
+		{name: "numbers reversed", a: json.Number("10"), b: json.Number("9"), want: false},
+		{name: "times", a: "2025-06-02T10:00:00+09:00", b: "2025-06-02T09:00:00Z", want: true},
+		{name: "text", a: "10", b: "9", want: true},
+		{name: "value before missing", a: "z", b: nil, want: true},
+		{name: "missing after value", a: nil, b: "a", want: false},
+		{name: "both missing", a: nil, b: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lessValue(tt.a, tt.b); got != tt.want {
+				t.Errorf("lessValue(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayoutTable(t *testing.T) {
+	cells := [][]string{
+		{"NAME", "MESSAGE"},
+		{"a", "disk almost full"},
+	}
+
+	tests := []struct {
+		name  string
+		width int
+		want  string
+	}{
+		{name: "no width", width: 0, want: "NAME   MESSAGE\na      disk almost full\n"},
+		{name: "wide enough", width: 23, want: "NAME   MESSAGE\na      disk almost full\n"},
+		{name: "widest column is cut", width: 20, want: "NAME   MESSAGE\na      disk almost …\n"},
+		{name: "columns keep a minimum width", width: 5, want: "NAME   MESSA…\na      disk …\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := layoutTable(cells, tt.width); got != tt.want {
+				t.Errorf("layoutTable(%d) = %q, want %q", tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{at: now.Add(-45 * time.Second), want: "45s ago"},
+		{at: now.Add(-5 * time.Minute), want: "5m ago"},
+		{at: now.Add(-47 * time.Hour), want: "47h ago"},
+		{at: now.Add(-72 * time.Hour), want: "3d ago"},
+		{at: now.Add(5 * time.Minute), want: "in 5m"},
+		{at: time.Time{}, want: "<none>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := relativeTime(tt.at, now); got != tt.want {
+				t.Errorf("relativeTime(%s) = %q, want %q", tt.at, got, tt.want)
+			}
+		})
+	}
+}
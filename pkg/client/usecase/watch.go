@@ -0,0 +1,299 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/client/repository"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+)
+
+// How often a live table is redrawn after changes, and at most how long relative times
+// such as heartbeat ages may go stale
+const (
+	liveRedrawDelay     = 200 * time.Millisecond
+	liveRefreshInterval = time.Second
+)
+
+// WatchOptions controls how streamed changes are printed
+type WatchOptions struct {
+	Format string
+	Live   bool // redraw the agent table in place, for a terminal
+}
+
+type WatchUsecase interface {
+	WatchAgents(ctx context.Context, opts WatchOptions, out io.Writer) error
+	TailEvents(ctx context.Context, req request.EventListRequest, format string, out io.Writer) error
+}
+
+type watchUsecase struct {
+	config config.BaseConfig
+	repo   repository.WatchRepository
+}
+
+func NewWatchUsecase(conf config.BaseConfig) WatchUsecase {
+	return &watchUsecase{
+		config: conf,
+		repo:   repository.NewWatchRepository(conf),
+	}
+}
+
+// WatchAgents prints the changes of the agent list until ctx is cancelled. A live table
+// is redrawn in place; otherwise each change is printed as it arrives, tables as rows
+// prefixed by the change.
+func (u *watchUsecase) WatchAgents(ctx context.Context, opts WatchOptions, out io.Writer) error {
+	if !opts.Live {
+		printer := &streamPrinter{format: opts.Format}
+		return u.repo.WatchAgents(ctx, nil, func(event response.AgentWatchEvent) error {
+			_, err := io.WriteString(out, printer.print(event))
+			return err
+		})
+	}
+
+	table := &liveAgentTable{format: opts.Format, out: out, agents: map[string]model.Agent{}}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- u.repo.WatchAgents(ctx, table.reset, table.apply)
+	}()
+
+	redraw := time.NewTicker(liveRedrawDelay)
+	defer redraw.Stop()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-redraw.C:
+			if err := table.draw(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// liveAgentTable holds the agents of a watch and redraws them over the previous table
+type liveAgentTable struct {
+	mu     sync.Mutex
+	format string
+	out    io.Writer
+	agents map[string]model.Agent
+	dirty  bool
+	drawn  time.Time
+}
+
+// reset forgets the agents when the stream reconnects, as the server sends them all again
+func (t *liveAgentTable) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.agents)
+	t.dirty = true
+}
+
+func (t *liveAgentTable) apply(event response.AgentWatchEvent) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if event.Type == response.WatchEventDeleted {
+		delete(t.agents, event.Agent.UUID)
+	} else {
+		t.agents[event.Agent.UUID] = event.Agent
+	}
+	t.dirty = true
+	return nil
+}
+
+// draw repaints the table when agents changed or its relative times are due a refresh
+func (t *liveAgentTable) draw() error {
+	t.mu.Lock()
+	if !t.dirty && time.Since(t.drawn) < liveRefreshInterval {
+		t.mu.Unlock()
+		return nil
+	}
+	agents := make([]model.Agent, 0, len(t.agents))
+	for _, agent := range t.agents {
+		agents = append(agents, agent)
+	}
+	first := t.drawn.IsZero()
+	t.dirty, t.drawn = false, time.Now()
+	t.mu.Unlock()
+
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	text := Format(t.format, response.AgentDetailListResponse{Agents: agents})
+
+	// Home the cursor and overwrite each line, clearing what is left of the previous table
+	var b strings.Builder
+	if first {
+		b.WriteString("\033[2J")
+	}
+	b.WriteString("\033[H")
+	b.WriteString(strings.ReplaceAll(text, "\n", "\033[K\n"))
+	b.WriteString("\033[J")
+	_, err := io.WriteString(t.out, b.String())
+	return err
+}
+
+// TailEvents prints the server events matching req until ctx is cancelled. req.Since
+// also accepts a duration such as 10m, counted back from now.
+func (u *watchUsecase) TailEvents(ctx context.Context, req request.EventListRequest, format string, out io.Writer) error {
+	if req.Since != "" {
+		if d, err := time.ParseDuration(req.Since); err == nil {
+			req.Since = strconv.FormatInt(time.Now().Add(-d).Unix(), 10)
+		}
+	}
+
+	tabular := false
+	switch name, _ := splitFormat(format); name {
+	case "table", "wide", "csv":
+		tabular = true
+	}
+
+	printer := &streamPrinter{format: format}
+	return u.repo.TailEvents(ctx, req, func(event model.ServerEvent) error {
+		var item interface{} = event
+		if tabular {
+			item = newEventRow(event)
+		}
+		_, err := io.WriteString(out, printer.print(item))
+		return err
+	})
+}
+
+// eventRow is a server event as a table row, its data summarized
+type eventRow struct {
+	Time    string `json:"time"`
+	Type    string `json:"type"`
+	Agent   string `json:"agent"`
+	Summary string `json:"summary"`
+	ID      string `json:"id"`
+}
+
+func newEventRow(event model.ServerEvent) eventRow {
+	return eventRow{
+		Time:    event.Timestamp.Local().Format("2006-01-02 15:04:05"),
+		Type:    event.Type,
+		Agent:   event.AgentID,
+		Summary: summarizeEvent(event),
+		ID:      event.ID,
+	}
+}
+
+// summarizeEvent describes a mutation event by the resource, action and changed fields,
+// e.g. "agent updated: status online → offline". Other data is shown as is.
+func summarizeEvent(event model.ServerEvent) string {
+	var change model.ResourceChange
+	if err := json.Unmarshal([]byte(event.Data), &change); err != nil || change.Resource == "" {
+		return strings.TrimSpace(event.Data)
+	}
+
+	summary := change.Resource + " " + change.Action
+	if change.Action != model.ChangeActionUpdated {
+		fields := change.After
+		if fields == nil {
+			fields = change.Before
+		}
+		if uuid := cellText(fields["uuid"]); uuid != "" {
+			summary += " " + uuid
+		}
+		return summary
+	}
+
+	var keys []string
+	for key := range change.Diff {
+		switch key {
+		case "id", "created_at", "updated_at":
+		default:
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		diff := change.Diff[key]
+		parts[i] = fmt.Sprintf("%s %s → %s", key, formatCell(diff.Before, false, time.Time{}), formatCell(diff.After, false, time.Time{}))
+	}
+	if len(parts) > 0 {
+		summary += ": " + strings.Join(parts, ", ")
+	}
+	return summary
+}
+
+// streamPrinter formats the items of a stream one at a time. Tables print their header
+// before the first row only and keep the column widths of earlier rows, so rows line up
+// while values fit; YAML items are separated as documents and ndjson prints one line each.
+type streamPrinter struct {
+	format string
+	widths []int
+	count  int
+}
+
+func (p *streamPrinter) print(item interface{}) string {
+	defer func() { p.count++ }()
+
+	rows := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(item)), 1, 1)
+	rows.Index(0).Set(reflect.ValueOf(item))
+	switch name, _ := splitFormat(p.format); name {
+	case "table", "wide":
+		return p.tableRow(rows.Interface(), name == "wide")
+	case "csv":
+		saved := tableOptions
+		defer func() { tableOptions = saved }()
+		tableOptions.NoHeaders = saved.NoHeaders || p.count > 0
+		return Format(p.format, rows.Interface())
+	case "yaml":
+		return "---\n" + Format(p.format, item)
+	default:
+		return Format(p.format, item)
+	}
+}
+
+func (p *streamPrinter) tableRow(data interface{}, wide bool) string {
+	rows, columns, err := tableRows(findRows(data), wide)
+	if err != nil {
+		return fmt.Sprintf("Error formatting table: %v\n", err)
+	}
+
+	var lines [][]string
+	if p.count == 0 && !tableOptions.NoHeaders {
+		lines = append(lines, columnHeaders(columns))
+	}
+	now := time.Now()
+	for _, row := range rows {
+		line := make([]string, len(columns))
+		for i, column := range columns {
+			line[i] = formatCell(lookupPath(row, column.Path), column.Relative, now)
+		}
+		lines = append(lines, line)
+	}
+
+	const gap = 3
+	if len(p.widths) != len(columns) {
+		p.widths = make([]int, len(columns))
+	}
+	for _, line := range lines {
+		for i, cell := range line {
+			p.widths[i] = max(p.widths[i], len([]rune(cell)))
+		}
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		for i, cell := range line {
+			b.WriteString(cell)
+			if i < len(line)-1 {
+				b.WriteString(strings.Repeat(" ", p.widths[i]-len([]rune(cell))+gap))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"net/http"
 	"os"
 	"time"
 
@@ -14,6 +15,7 @@ type BaseConfig struct {
 	DBConnection *gorm.DB
 	YamlConfig   YamlConfig
 	Logger       LoggerInterface // Dependency injection for logger
+	HTTPClient   *http.Client    // Client repositories' HTTP client, built for the selected context
 }
 
 type YamlConfig struct {
@@ -37,8 +39,85 @@ type Common struct {
 }
 
 type Server struct {
-	Base      Base   `yaml:"base"`
-	JWTSecret string `yaml:"jwt_secret"`
+	Base            Base          `yaml:"base"`
+	JWTSecret       string        `yaml:"jwt_secret"`
+	ShutdownTimeout int           `yaml:"shutdown_timeout"` // seconds
+	Outbox          Outbox        `yaml:"outbox"`
+	Retention       Retention     `yaml:"retention"`
+	Notifications   Notifications `yaml:"notifications"`
+	Rules           Rules         `yaml:"rules"`
+	Liveness        Liveness      `yaml:"liveness"`
+	Watch           Watch         `yaml:"watch"`
+}
+
+type Outbox struct {
+	PollInterval int `yaml:"poll_interval"` // milliseconds
+	BatchSize    int `yaml:"batch_size"`
+	Retention    int `yaml:"retention"` // hours published events are kept
+}
+
+// Retention configures the background compactor that deletes expired history
+type Retention struct {
+	Enabled   bool           `yaml:"enabled"`
+	Interval  int            `yaml:"interval"`   // seconds between compactor runs
+	BatchSize int            `yaml:"batch_size"` // rows deleted per statement
+	DryRun    bool           `yaml:"dry_run"`    // only count what would be deleted
+	Policies  map[string]int `yaml:"policies"`   // data class -> hours kept, 0 keeps forever
+}
+
+// Rules configures the evaluator of server-side alert rules
+type Rules struct {
+	Enabled  bool `yaml:"enabled"`
+	Interval int  `yaml:"interval"` // seconds between evaluation passes
+}
+
+// Liveness configures the check that marks agents offline after missed heartbeats
+type Liveness struct {
+	Enabled  bool `yaml:"enabled"`
+	Interval int  `yaml:"interval"` // seconds between checks
+	Timeout  int  `yaml:"timeout"`  // seconds without a heartbeat before an agent is offline
+}
+
+// Watch configures the streaming endpoints behind `get agents --watch` and `events`
+type Watch struct {
+	PollInterval int `yaml:"poll_interval"` // milliseconds between checks for agent changes and new events
+	KeepAlive    int `yaml:"keep_alive"`    // seconds between comments keeping idle streams open through proxies
+	Settle       int `yaml:"settle"`        // milliseconds an event is held back so earlier, slower transactions can commit
+}
+
+// Notifications routes alerts and client notifications to outbound channels
+type Notifications struct {
+	PollInterval   int                   `yaml:"poll_interval"`   // milliseconds between delivery polls
+	BatchSize      int                   `yaml:"batch_size"`      // deliveries attempted per poll
+	Timeout        int                   `yaml:"timeout"`         // seconds per delivery attempt
+	MaxAttempts    int                   `yaml:"max_attempts"`    // attempts before a delivery is marked failed
+	InitialBackoff int                   `yaml:"initial_backoff"` // seconds before the first retry, doubled per attempt
+	MaxBackoff     int                   `yaml:"max_backoff"`     // seconds
+	SMTP           SMTP                  `yaml:"smtp"`
+	Channels       []NotificationChannel `yaml:"channels"`
+}
+
+type SMTP struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"` // empty disables authentication
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// NotificationChannel is one outbound destination. A message is routed to every channel
+// whose filters all match; empty filters match everything.
+type NotificationChannel struct {
+	Name       string            `yaml:"name"`
+	Type       string            `yaml:"type"`       // webhook, slack or email
+	URL        string            `yaml:"url"`        // webhook and slack
+	Secret     string            `yaml:"secret"`     // webhook HMAC-SHA256 signing key
+	To         []string          `yaml:"to"`         // email recipients
+	Severities []string          `yaml:"severities"` // low, medium, high, critical
+	Events     []string          `yaml:"events"`     // server event or notification types
+	Labels     map[string]string `yaml:"labels"`     // e.g. agent_uuid, sensor_type, rule
+	Subject    string            `yaml:"subject"`    // text/template for the email subject
+	Template   string            `yaml:"template"`   // text/template for the message text
 }
 
 type Base struct {
@@ -48,16 +127,33 @@ type Base struct {
 type Client struct {
 	ServerEndpoint string `yaml:"ServerEndpoint"`
 	UserEmail      string `yaml:"UserEmail"`
+	TokenBackend   string `yaml:"TokenBackend"` // see TokenBackends, empty is auto
+	// Set from the selected context of the client config file, see UseClientContext
+	Context               string `yaml:"-"`
+	CertificateAuthority  string `yaml:"-"`
+	InsecureSkipTLSVerify bool   `yaml:"-"`
+	TokenDir              string `yaml:"-"` // empty keeps tokens under etc/.circulator/client/{profile}
 }
 
 type Agent struct {
-	ServerEndpoint            string `yaml:"ServerEndpoint"`
-	LoginEmail                string `yaml:"LoginEmail"`
-	LoginPassword             string `yaml:"LoginPassword"`
-	TokenCachePath            string `yaml:"TokenCachePath"`
-	RefreshIntervalMinutes    int    `yaml:"RefreshIntervalMinutes"`
-	RegistrationRetryInterval int    `yaml:"RegistrationRetryInterval"`
-	HealthCheckInterval       int    `yaml:"HealthCheckInterval"`
+	ServerEndpoint            string     `yaml:"ServerEndpoint"`
+	LoginEmail                string     `yaml:"LoginEmail"`
+	LoginPassword             string     `yaml:"LoginPassword"`
+	TokenCachePath            string     `yaml:"TokenCachePath"`
+	RefreshIntervalMinutes    int        `yaml:"RefreshIntervalMinutes"`
+	RegistrationRetryInterval int        `yaml:"RegistrationRetryInterval"`
+	HealthCheckInterval       int        `yaml:"HealthCheckInterval"`
+	ConfigRefreshInterval     int        `yaml:"ConfigRefreshInterval"` // seconds a fetched processing config is used
+	DataDir                   string     `yaml:"DataDir"`
+	Spool                     AgentSpool `yaml:"Spool"`
+}
+
+// AgentSpool configures the disk-backed buffer for agent output while Pulsar is unavailable
+type AgentSpool struct {
+	Enabled        bool   `yaml:"Enabled"`
+	MaxBytes       int64  `yaml:"MaxBytes"`
+	OverflowPolicy string `yaml:"OverflowPolicy"` // drop_oldest, drop_newest or block
+	RetryInterval  int    `yaml:"RetryInterval"`  // seconds
 }
 
 type MySQL struct {
@@ -75,6 +171,8 @@ type Pulsar struct {
 	Topics            PulsarTopics   `yaml:"topics"`
 	Consumer          PulsarConsumer `yaml:"consumer"`
 	Producer          PulsarProducer `yaml:"producer"`
+	Encoding          PulsarEncoding `yaml:"encoding"`
+	Dedup             PulsarDedup    `yaml:"dedup"`
 }
 
 type PulsarTopics struct {
@@ -94,6 +192,18 @@ type PulsarProducer struct {
 	SendTimeout int `yaml:"send_timeout"` // seconds
 }
 
+type PulsarEncoding struct {
+	Default        string            `yaml:"default"`         // json, protobuf
+	Topics         map[string]string `yaml:"topics"`          // per-topic override: topic name -> json|protobuf
+	RegisterSchema bool              `yaml:"register_schema"` // register protobuf schemas with the broker
+}
+
+type PulsarDedup struct {
+	Enabled    bool `yaml:"enabled"`
+	TTL        int  `yaml:"ttl"`         // seconds a processed message key is remembered
+	MaxEntries int  `yaml:"max_entries"` // bound for the agent local store
+}
+
 // type Redis struct {
 //   Host string `yaml:"host"`
 //   Port int    `yaml:"port"`
@@ -143,12 +253,58 @@ func loadYamlConfig() YamlConfig {
 			Application: Application{
 				Common: Common{Port: "8080"},
 				Server: Server{
-					JWTSecret: "your-secret-key",
-					Base:      Base{Emails: []string{"base@example.com"}},
+					JWTSecret:       "your-secret-key",
+					Base:            Base{Emails: []string{"base@example.com"}},
+					ShutdownTimeout: 10,
+					Outbox: Outbox{
+						PollInterval: 500,
+						BatchSize:    100,
+						Retention:    168,
+					},
+					Retention: Retention{
+						Enabled:   true,
+						Interval:  3600,
+						BatchSize: 10000,
+						Policies: map[string]int{
+							"metrics_raw":   168,
+							"metrics_1m":    2160,
+							"metrics_1h":    17520,
+							"system_info":   720,
+							"alerts":        2160,
+							"notifications": 720,
+						},
+					},
+					Notifications: Notifications{
+						PollInterval:   1000,
+						BatchSize:      10,
+						Timeout:        10,
+						MaxAttempts:    8,
+						InitialBackoff: 5,
+						MaxBackoff:     600,
+						SMTP:           SMTP{Port: 25},
+					},
+					Rules: Rules{
+						Enabled:  true,
+						Interval: 60,
+					},
+					Watch: Watch{
+						PollInterval: 1000,
+						KeepAlive:    15,
+						Settle:       2000,
+					},
 				},
 				Client: Client{
 					ServerEndpoint: "http://localhost:8080",
 				},
+				Agent: Agent{
+					DataDir: "/tmp/circulator-agent",
+					Spool: AgentSpool{
+						Enabled:        true,
+						MaxBytes:       64 * 1024 * 1024,
+						OverflowPolicy: "drop_oldest",
+						RetryInterval:  5,
+					},
+				},
 			},
 			MySQL: MySQL{
 				Host:     "localhost",
@@ -175,6 +331,14 @@ func loadYamlConfig() YamlConfig {
 				Producer: PulsarProducer{
 					SendTimeout: 30,
 				},
+				Encoding: PulsarEncoding{
+					Default: "json",
+				},
+				Dedup: PulsarDedup{
+					Enabled:    true,
+					TTL:        86400,
+					MaxEntries: 10000,
+				},
 			},
 			Logger: LoggerConfig{
 				Component:    "unknown",
@@ -249,6 +413,27 @@ func NewAgentConfig() BaseConfig {
 	return baseConfig
 }
 
+// NewSimulatorConfig creates a BaseConfig instance for the simulator
+func NewSimulatorConfig() BaseConfig {
+	yamlConfig := loadYamlConfig()
+	// Override logger component for simulator
+	yamlConfig.Logger.Component = "simulator"
+	if yamlConfig.Logger.Service == "unknown" {
+		yamlConfig.Logger.Service = "circulator-simulator"
+	}
+
+	baseConfig := BaseConfig{
+		DBConnection: nil, // Simulator only talks to Pulsar and agents
+		YamlConfig:   yamlConfig,
+	}
+
+	// Initialize logger with dependency injection
+	logger := NewLogger(yamlConfig.Logger, &baseConfig)
+	baseConfig.Logger = logger
+
+	return baseConfig
+}
+
 // NewClientConfigWithComponent creates a BaseConfig instance for client component
 func NewClientConfigWithComponent(service string) BaseConfig {
 	yamlConfig := loadYamlConfig()
@@ -277,8 +462,9 @@ type IncomingAgentData struct {
 	RawPayload []byte  `json:"raw_payload"`
 }
 
-// ProcessedAgentData represents processed agent data
+// ProcessedAgentData represents processed agent data. UUID echoes the incoming reading.
 type ProcessedAgentData struct {
+	UUID           string  `json:"uuid"`
 	AgentUUID      string  `json:"agent_uuid"`
 	OriginalValue  float64 `json:"original_value"`
 	ProcessedValue float64 `json:"processed_value"`
@@ -349,3 +535,22 @@ func (p *Pulsar) GetTopicName(topicType string) string {
 		return ""
 	}
 }
+
+// GetEncoding returns the payload encoding (json or protobuf) negotiated for a topic
+func (p *Pulsar) GetEncoding(topic string) string {
+	if encoding, ok := p.Encoding.Topics[topic]; ok && encoding != "" {
+		return encoding
+	}
+	if p.Encoding.Default != "" {
+		return p.Encoding.Default
+	}
+	return "json"
+}
+
+// GetDedupTTL returns how long processed message keys are remembered
+func (p *Pulsar) GetDedupTTL() time.Duration {
+	if p.Dedup.TTL <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(p.Dedup.TTL) * time.Second
+}
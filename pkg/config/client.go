@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ClientConfigFile is the kubeconfig-style file holding the named contexts of the CLI,
+// by default ~/.config/circulator/config.yaml
+type ClientConfigFile struct {
+	CurrentContext string          `yaml:"current-context"`
+	Contexts       []ClientContext `yaml:"contexts"`
+}
+
+// ClientContext is one server the CLI talks to. Relative paths are resolved against the
+// directory of the config file.
+type ClientContext struct {
+	Name                  string `yaml:"name"`
+	Server                string `yaml:"server"`
+	User                  string `yaml:"user,omitempty"`
+	CertificateAuthority  string `yaml:"certificate-authority,omitempty"`
+	InsecureSkipTLSVerify bool   `yaml:"insecure-skip-tls-verify,omitempty"`
+	TokenStore            string `yaml:"token-store,omitempty"` // directory, defaults to tokens/<name> next to the file
+	TokenBackend          string `yaml:"token-backend,omitempty"`
+}
+
+// Backends keeping the access and refresh tokens of the CLI
+const (
+	TokenBackendAuto          = "auto"           // keyring where available, encrypted file otherwise
+	TokenBackendKeyring       = "keyring"        // Secret Service over D-Bus, macOS Keychain or Windows Credential Manager
+	TokenBackendEncryptedFile = "encrypted-file" // files in the token store encrypted with a passphrase
+	TokenBackendFile          = "file"           // plain files in the token store, only when chosen explicitly
+)
+
+// TokenBackends lists the valid token backends
+var TokenBackends = []string{TokenBackendAuto, TokenBackendKeyring, TokenBackendEncryptedFile, TokenBackendFile}
+
+// ClientConfigPath returns $CIRCULATOR_CONFIG or config.yaml in the circulator directory
+// of the user config directory ($XDG_CONFIG_HOME or ~/.config)
+func ClientConfigPath() string {
+	if path := os.Getenv("CIRCULATOR_CONFIG"); path != "" {
+		return expandHome(path)
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "circulator", "config.yaml")
+}
+
+// LoadClientConfigFile reads the config file at path; a missing file has no contexts
+func LoadClientConfigFile(path string) (ClientConfigFile, error) {
+	var file ClientConfigFile
+	if path == "" {
+		return file, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, err
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// Save writes the config file readable only by the user, creating its directory
+func (f ClientConfigFile) Save(path string) error {
+	if path == "" {
+		return errors.New("no config file location, set CIRCULATOR_CONFIG")
+	}
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Context returns the context called name
+func (f ClientConfigFile) Context(name string) (ClientContext, bool) {
+	for _, ctx := range f.Contexts {
+		if ctx.Name == name {
+			return ctx, true
+		}
+	}
+	return ClientContext{}, false
+}
+
+// SetContext replaces the context of the same name or adds it
+func (f *ClientConfigFile) SetContext(ctx ClientContext) {
+	for i := range f.Contexts {
+		if f.Contexts[i].Name == ctx.Name {
+			f.Contexts[i] = ctx
+			return
+		}
+	}
+	f.Contexts = append(f.Contexts, ctx)
+}
+
+// DeleteContext removes the context called name, clearing current-context if it pointed there
+func (f *ClientConfigFile) DeleteContext(name string) bool {
+	for i := range f.Contexts {
+		if f.Contexts[i].Name == name {
+			f.Contexts = append(f.Contexts[:i], f.Contexts[i+1:]...)
+			if f.CurrentContext == name {
+				f.CurrentContext = ""
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveContextName picks the context to use: the --context flag, then $CIRCULATOR_CONTEXT,
+// then current-context. Empty means no context is selected.
+func (f ClientConfigFile) ResolveContextName(flag string) string {
+	if flag != "" {
+		return flag
+	}
+	if name := os.Getenv("CIRCULATOR_CONTEXT"); name != "" {
+		return name
+	}
+	return f.CurrentContext
+}
+
+// TokenDir returns the directory the tokens of the context are kept in
+func (c ClientContext) TokenDir(configPath string) string {
+	if c.TokenStore != "" {
+		return resolvePath(configPath, c.TokenStore)
+	}
+	return filepath.Join(filepath.Dir(configPath), "tokens", c.Name)
+}
+
+// UseClientContext points the client settings at the context, replacing those of etc/app.yaml
+func (conf *BaseConfig) UseClientContext(ctx ClientContext, configPath string) {
+	client := &conf.YamlConfig.Application.Client
+	client.Context = ctx.Name
+	client.ServerEndpoint = strings.TrimRight(ctx.Server, "/")
+	if ctx.User != "" {
+		client.UserEmail = ctx.User
+	}
+	client.CertificateAuthority = ""
+	if ctx.CertificateAuthority != "" {
+		client.CertificateAuthority = resolvePath(configPath, ctx.CertificateAuthority)
+	}
+	client.InsecureSkipTLSVerify = ctx.InsecureSkipTLSVerify
+	client.TokenDir = ctx.TokenDir(configPath)
+	client.TokenBackend = ctx.TokenBackend
+}
+
+// resolvePath expands ~ and makes path relative to the directory of the config file
+func resolvePath(configPath, path string) string {
+	path = expandHome(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(configPath), path)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
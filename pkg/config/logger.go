@@ -205,9 +205,15 @@ var (
 	AUASPC = MCode{"AUA-SPC", "Setting processing config"}
 	AUAGPC = MCode{"AUA-GPC", "Getting processing config"}
 	AUAPAD = MCode{"AUA-PAD", "Processed agent data"}
+	AUARPC = MCode{"AUA-RPC", "Reloading processing config"}
+	AUARPE = MCode{"AUA-RPE", "Keeping stale processing config"}
+	AUCMD  = MCode{"AUC-MD", "Handling agent command"}
+	AUCMDR = MCode{"AUC-MD-R", "Draining agent"}
+	AUCMDU = MCode{"AUC-MD-U", "Ignoring unknown agent command"}
 
 	// Agent Controller Agent codes
 	ACAPSD = MCode{"ACA-PSD", "Processing stream data via controller"}
+	ACAPSE = MCode{"ACA-PSE", "Failed to process streamed data"}
 
 	// Agent Repository API codes
 	AREGA    = MCode{"ARA-GA", "Getting all agents"}
@@ -267,6 +273,92 @@ var (
 	SUACPR  = MCode{"SUA-CPR", "Creating processing rule"}
 	SUAUPR  = MCode{"SUA-UPR", "Updating processing rule"}
 	SUADPR  = MCode{"SUA-DPR", "Deleting processing rule"}
+	SUAEVT  = MCode{"SUA-EVT", "Recording mutation event"}
+	SUAERR  = MCode{"SUA-ERR", "Agent mutation failed"}
+)
+
+// Server UseCase Report codes
+var (
+	SURHR   = MCode{"SUR-HR", "Handling agent report"}
+	SURUS   = MCode{"SUR-US", "Updating agent status from report"}
+	SURSM   = MCode{"SUR-SM", "Storing agent metrics from report"}
+	SURAE   = MCode{"SUR-AE", "Handling agent error report"}
+	SURPE   = MCode{"SUR-PE", "Publishing server event"}
+	SURUNK  = MCode{"SUR-UNK", "Unknown agent report type"}
+	SURSUCC = MCode{"SUR-SUCC", "Agent report handled successfully"}
+	SURERR  = MCode{"SUR-ERR", "Agent report handling error"}
+)
+
+// Server UseCase Metric codes
+var (
+	SUMPD  = MCode{"SUM-PD", "Storing processed stream data"}
+	SUMSM  = MCode{"SUM-SM", "Storing system metrics"}
+	SUMQ   = MCode{"SUM-Q", "Querying agent metrics"}
+	SUMSKP = MCode{"SUM-SKP", "Skipping stream data without agent"}
+)
+
+// Server UseCase Alert codes
+var (
+	SUALOPEN = MCode{"SUAL-OPEN", "Alert opened"}
+	SUALTR   = MCode{"SUAL-TR", "Changing alert status"}
+	SUALSKP  = MCode{"SUAL-SKP", "Skipping alert without agent"}
+)
+
+// Server UseCase Notification codes
+var (
+	SUNTRUN  = MCode{"SUNT-RUN", "Notification dispatcher starting"}
+	SUNTSTOP = MCode{"SUNT-STOP", "Notification dispatcher stopping"}
+	SUNTRT   = MCode{"SUNT-RT", "Routing notification"}
+	SUNTSEND = MCode{"SUNT-SEND", "Delivered notification"}
+	SUNTFAIL = MCode{"SUNT-FAIL", "Notification delivery failed"}
+	SUNTERR  = MCode{"SUNT-ERR", "Notification routing error"}
+)
+
+// Server UseCase Silence codes
+var (
+	SUSLCR  = MCode{"SUSL-CR", "Created silence"}
+	SUSLEXP = MCode{"SUSL-EXP", "Expired silence"}
+	SUSLSUP = MCode{"SUSL-SUP", "Suppressed notification"}
+)
+
+// Server UseCase Retention codes
+var (
+	SURTRUN  = MCode{"SURT-RUN", "Retention compactor starting"}
+	SURTSTOP = MCode{"SURT-STOP", "Retention compactor stopping"}
+	SURTCLS  = MCode{"SURT-CLS", "Compacted data class"}
+	SURTERR  = MCode{"SURT-ERR", "Retention compactor error"}
+)
+
+// Server UseCase Rule codes
+var (
+	SURLRUN  = MCode{"SURL-RUN", "Rule evaluator starting"}
+	SURLSTOP = MCode{"SURL-STOP", "Rule evaluator stopping"}
+	SURLCR   = MCode{"SURL-CR", "Created alert rule"}
+	SURLFIRE = MCode{"SURL-FIRE", "Alert rule fired"}
+	SURLERR  = MCode{"SURL-ERR", "Rule evaluator error"}
+)
+
+// Server UseCase Liveness codes
+var (
+	SULVRUN  = MCode{"SULV-RUN", "Liveness check starting"}
+	SULVSTOP = MCode{"SULV-STOP", "Liveness check stopping"}
+	SULVOFF  = MCode{"SULV-OFF", "Marked agent offline"}
+	SULVERR  = MCode{"SULV-ERR", "Liveness check error"}
+)
+
+// Server UseCase Outbox codes
+var (
+	SUORUN   = MCode{"SUO-RUN", "Outbox relay starting"}
+	SUOSTOP  = MCode{"SUO-STOP", "Outbox relay stopping"}
+	SUOPURGE = MCode{"SUO-PURGE", "Purging published outbox events"}
+	SUOERR   = MCode{"SUO-ERR", "Outbox relay error"}
+)
+
+// Server UseCase Watch codes
+var (
+	SUWOPEN  = MCode{"SUW-OPEN", "Watch stream opened"}
+	SUWCLOSE = MCode{"SUW-CLOSE", "Watch stream closed"}
+	SUWERR   = MCode{"SUW-ERR", "Watch stream error"}
 )
 
 // Server UseCase Common codes
@@ -278,15 +370,45 @@ var (
 	SUCRU = MCode{"SUC-RU", "Processing token refresh"}
 )
 
+// Server Base codes
+var (
+	SBM    = MCode{"SB-M", "Starting Server"}
+	SBHTTP = MCode{"SB-HTTP", "HTTP server listening"}
+	SBHSD  = MCode{"SB-HSD", "HTTP server shutting down"}
+	SBREP  = MCode{"SB-REP", "Agent report consumer starting"}
+	SBTS   = MCode{"SB-TS", "Time-series ingestion starting"}
+	SBRT   = MCode{"SB-RT", "Retention compactor starting"}
+	SBNT   = MCode{"SB-NT", "Notification dispatcher starting"}
+	SBRL   = MCode{"SB-RL", "Rule evaluator starting"}
+	SBLV   = MCode{"SB-LV", "Liveness check starting"}
+	SBSTOP = MCode{"SB-STOP", "Server stopped"}
+	SBERR  = MCode{"SB-ERR", "Server error"}
+)
+
+// Server Supervisor codes
+var (
+	SSVADD  = MCode{"SSV-ADD", "Worker registered with supervisor"}
+	SSVRUN  = MCode{"SSV-RUN", "Supervisor starting worker"}
+	SSVEXIT = MCode{"SSV-EXIT", "Supervised worker exited"}
+	SSVRST  = MCode{"SSV-RST", "Supervisor restarting worker"}
+	SSVSTOP = MCode{"SSV-STOP", "Supervisor stopping workers"}
+	SSVDONE = MCode{"SSV-DONE", "All supervised workers stopped"}
+	SSVERR  = MCode{"SSV-ERR", "Supervised worker error"}
+)
+
 // Agent Base codes
 var (
-	ABM    = MCode{"AB-M", "Starting Agent"}
-	ABME2  = MCode{"AB-M-E2", "Failed to register agent"}
-	ABME3  = MCode{"AB-M-E3", "Failed to start gRPC server"}
-	ABRA   = MCode{"AB-RA", "Registering agent with server"}
-	ABRAE3 = MCode{"AB-RA-E3", "Failed to get system info"}
-	ABRAE4 = MCode{"AB-RA-E4", "Failed to register agent"}
-	ABRAS  = MCode{"AB-RA-S", "Agent registration completed"}
+	ABM     = MCode{"AB-M", "Starting Agent"}
+	ABME2   = MCode{"AB-M-E2", "Failed to register agent"}
+	ABME3   = MCode{"AB-M-E3", "Failed to start gRPC server"}
+	ABME4   = MCode{"AB-M-E4", "Failed to create Pulsar producer"}
+	ABRA    = MCode{"AB-RA", "Registering agent with server"}
+	ABRAE3  = MCode{"AB-RA-E3", "Failed to get system info"}
+	ABRAE4  = MCode{"AB-RA-E4", "Failed to register agent"}
+	ABRAS   = MCode{"AB-RA-S", "Agent registration completed"}
+	ABP     = MCode{"AB-P", "Agent pipeline starting"}
+	ABPSTOP = MCode{"AB-P-STOP", "Agent pipeline stopped"}
+	ABPERR  = MCode{"AB-P-ERR", "Agent pipeline failed"}
 )
 
 // Client Repository Server codes
@@ -310,6 +432,42 @@ var (
 	CRPERR   = MCode{"CRP-ERR", "Client Pulsar operation error"}
 )
 
+// Client Repository Stream codes
+var (
+	CRSTINIT  = MCode{"CRST-INIT", "Client stream repository initialized"}
+	CRSTREAD  = MCode{"CRST-READ", "Client reading sensor data"}
+	CRSTCLOSE = MCode{"CRST-CLOSE", "Client stream repository closed"}
+	CRSTERR   = MCode{"CRST-ERR", "Client stream operation error"}
+)
+
+// Client Repository Watch codes
+var (
+	CRWOPEN = MCode{"CRW-OPEN", "Client watch stream connected"}
+	CRWERR  = MCode{"CRW-ERR", "Client watch stream error"}
+)
+
+// Client Repository Archive codes
+var (
+	CRAOPEN = MCode{"CRA-OPEN", "Client stream archive opened"}
+	CRAERR  = MCode{"CRA-ERR", "Client stream archive error"}
+)
+
+// Client UseCase Stream codes
+var (
+	CUSTREC  = MCode{"CUST-REC", "Client recording sensor data"}
+	CUSTRPL  = MCode{"CUST-RPL", "Client replaying sensor data"}
+	CUSTTAIL = MCode{"CUST-TAIL", "Client tailing a stream topic"}
+	CUSTPUB  = MCode{"CUST-PUB", "Client publishing sensor data"}
+	CUSTDONE = MCode{"CUST-DONE", "Client stream operation finished"}
+	CUSTERR  = MCode{"CUST-ERR", "Client stream operation error"}
+)
+
+// Client UseCase Command codes
+var (
+	CUCMDSEND = MCode{"CUCMD-SEND", "Client sending commands"}
+	CUCMDDONE = MCode{"CUCMD-DONE", "Client commands sent"}
+)
+
 // Server Repository MySQL codes
 var (
 	SRMCONN     = MCode{"SRM-CONN", "Server MySQL connection"}
@@ -338,6 +496,65 @@ var (
 	SRPCLOSE = MCode{"SRP-CLOSE", "Server Pulsar repository closed"}
 	SRPSUCC  = MCode{"SRP-SUCC", "Server Pulsar operation successful"}
 	SRPERR   = MCode{"SRP-ERR", "Server Pulsar operation error"}
+	SRPDUP   = MCode{"SRP-DUP", "Server skipped duplicate Pulsar message"}
+)
+
+// Server Repository Metric codes
+var (
+	SRMTINIT = MCode{"SRMT-INIT", "Server metric repository initialized"}
+	SRMTCM   = MCode{"SRMT-CM", "Server storing agent metrics"}
+	SRMTSUCC = MCode{"SRMT-SUCC", "Server metric operation successful"}
+	SRMTERR  = MCode{"SRMT-ERR", "Server metric operation error"}
+)
+
+// Server Repository Alert codes
+var (
+	SRALINIT = MCode{"SRAL-INIT", "Server alert repository initialized"}
+	SRALREC  = MCode{"SRAL-REC", "Server recorded alert"}
+	SRALERR  = MCode{"SRAL-ERR", "Server alert operation error"}
+)
+
+// Server Repository Notification codes
+var (
+	SRNTINIT = MCode{"SRNT-INIT", "Server notification repository initialized"}
+	SRNTERR  = MCode{"SRNT-ERR", "Server notification operation error"}
+)
+
+// Server Repository Silence codes
+var (
+	SRSLINIT = MCode{"SRSL-INIT", "Server silence repository initialized"}
+	SRSLERR  = MCode{"SRSL-ERR", "Server silence operation error"}
+)
+
+// Server Repository Retention codes
+var (
+	SRRTINIT = MCode{"SRRT-INIT", "Server retention repository initialized"}
+	SRRTDEL  = MCode{"SRRT-DEL", "Server deleting expired rows"}
+	SRRTERR  = MCode{"SRRT-ERR", "Server retention operation error"}
+)
+
+// Server Repository Rule codes
+var (
+	SRRLINIT = MCode{"SRRL-INIT", "Server rule repository initialized"}
+	SRRLERR  = MCode{"SRRL-ERR", "Server rule operation error"}
+)
+
+// Server Repository Outbox codes
+var (
+	SROINIT = MCode{"SRO-INIT", "Server outbox repository initialized"}
+	SROENQ  = MCode{"SRO-ENQ", "Server enqueuing event to outbox"}
+	SROPUB  = MCode{"SRO-PUB", "Server relaying outbox event"}
+	SROSUCC = MCode{"SRO-SUCC", "Server outbox operation successful"}
+	SROERR  = MCode{"SRO-ERR", "Server outbox operation error"}
+)
+
+// Server Repository Dedup codes
+var (
+	SRDINIT  = MCode{"SRD-INIT", "Server dedup repository initialized"}
+	SRDMARK  = MCode{"SRD-MARK", "Server marking idempotency key processed"}
+	SRDPURGE = MCode{"SRD-PURGE", "Server purging expired idempotency keys"}
+	SRDSUCC  = MCode{"SRD-SUCC", "Server dedup operation successful"}
+	SRDERR   = MCode{"SRD-ERR", "Server dedup operation error"}
 )
 
 // Agent Repository API Server codes
@@ -361,6 +578,10 @@ var (
 	ARPCLOSE = MCode{"ARPP-CLOSE", "Agent Pulsar producer closed"}
 	ARPSUCC  = MCode{"ARPP-SUCC", "Agent Pulsar producer operation successful"}
 	ARPERR   = MCode{"ARPP-ERR", "Agent Pulsar producer operation error"}
+	ARPSEQ   = MCode{"ARPP-SEQ", "Agent Pulsar producer sequence restored"}
+	ARPSPL   = MCode{"ARPP-SPL", "Agent spooling message for later delivery"}
+	ARPRPL   = MCode{"ARPP-RPL", "Agent replaying spooled messages"}
+	ARPHB    = MCode{"ARPP-HB", "Agent publishing heartbeat"}
 )
 
 // Agent Repository Pulsar Consumer codes
@@ -373,6 +594,7 @@ var (
 	ARCCLOSE = MCode{"ARCC-CLOSE", "Agent Pulsar consumer closed"}
 	ARCSUCC  = MCode{"ARCC-SUCC", "Agent Pulsar consumer operation successful"}
 	ARCERR   = MCode{"ARCC-ERR", "Agent Pulsar consumer operation error"}
+	ARCDUP   = MCode{"ARCC-DUP", "Agent skipped duplicate Pulsar message"}
 )
 
 // Agent Repository Local System codes
@@ -386,6 +608,37 @@ var (
 	ALSERR   = MCode{"ALS-ERR", "Agent local system operation error"}
 )
 
+// Agent Repository Local Dedup codes
+var (
+	ALDINIT  = MCode{"ALD-INIT", "Agent local dedup repository initialized"}
+	ALDLOAD  = MCode{"ALD-LOAD", "Agent loading idempotency keys"}
+	ALDMARK  = MCode{"ALD-MARK", "Agent marking idempotency key processed"}
+	ALDCOMP  = MCode{"ALD-COMP", "Agent compacting idempotency key log"}
+	ALDCLOSE = MCode{"ALD-CLOSE", "Agent local dedup repository closed"}
+	ALDSUCC  = MCode{"ALD-SUCC", "Agent local dedup operation successful"}
+	ALDERR   = MCode{"ALD-ERR", "Agent local dedup operation error"}
+)
+
+// Agent Repository Local Spool codes
+var (
+	ALSPINIT  = MCode{"ALSP-INIT", "Agent spool initialized"}
+	ALSPAPP   = MCode{"ALSP-APP", "Agent appending message to spool"}
+	ALSPDROP  = MCode{"ALSP-DROP", "Agent spool overflow, message dropped"}
+	ALSPCLOSE = MCode{"ALSP-CLOSE", "Agent spool closed"}
+	ALSPERR   = MCode{"ALSP-ERR", "Agent spool operation error"}
+)
+
+// Simulator codes
+var (
+	SIMRUN  = MCode{"SIM-RUN", "Simulator run starting"}
+	SIMPUB  = MCode{"SIM-PUB", "Simulator publisher initialized"}
+	SIMDONE = MCode{"SIM-DONE", "Simulator run finished"}
+	SIMSCN  = MCode{"SIM-SCN", "Simulator scenario loaded"}
+	SIMCOL  = MCode{"SIM-COL", "Simulator collecting agent output"}
+	SIMFLT  = MCode{"SIM-FLT", "Simulator fleet starting"}
+	SIMERR  = MCode{"SIM-ERR", "Simulator operation error"}
+)
+
 // LogLevel represents the log level
 type LogLevel int
 
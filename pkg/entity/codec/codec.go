@@ -0,0 +1,196 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+	pb "github.com/ryo-arima/circulator/pkg/agent/gengrpc"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Content types negotiated per topic
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+)
+
+// SchemaVersion is the envelope schema version written by this build
+const SchemaVersion = 1
+
+// Message property keys mirrored from the envelope so consumers can route without decoding
+const (
+	PropertyType          = "message-type"
+	PropertySchemaVersion = "schema-version"
+	PropertyProducer      = "producer"
+	PropertyTraceID       = "trace-id"
+	PropertyContentType   = "content-type"
+	PropertyTimestamp     = "timestamp"
+)
+
+// Codec encodes stream models into versioned envelopes and decodes them back
+type Codec interface {
+	ContentType(topic string) string
+	Schema(topic string) pulsar.Schema
+	Encode(topic, msgType, traceID string, v interface{}) (*pulsar.ProducerMessage, error)
+	Decode(payload []byte, properties map[string]string, out interface{}) (*model.Envelope, error)
+}
+
+type codec struct {
+	pulsarConfig config.Pulsar
+	producer     string
+}
+
+// NewCodec creates a Codec that stamps envelopes with the given producer name
+func NewCodec(conf config.BaseConfig, producer string) Codec {
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		producer = producer + "@" + hostname
+	}
+	return &codec{
+		pulsarConfig: conf.YamlConfig.Pulsar,
+		producer:     producer,
+	}
+}
+
+// ContentType returns the content type negotiated for a topic
+func (c *codec) ContentType(topic string) string {
+	if c.pulsarConfig.GetEncoding(topic) == "protobuf" {
+		return ContentTypeProtobuf
+	}
+	return ContentTypeJSON
+}
+
+// Schema returns the broker schema for a topic, or nil when the topic stays schemaless.
+// Only protobuf topics are registered; JSON envelopes are sent as raw bytes.
+func (c *codec) Schema(topic string) pulsar.Schema {
+	if !c.pulsarConfig.Encoding.RegisterSchema || c.ContentType(topic) != ContentTypeProtobuf {
+		return nil
+	}
+	return pulsar.NewProtoNativeSchemaWithMessage(&pb.Envelope{}, nil)
+}
+
+// Encode wraps v in an envelope using the topic's content type
+func (c *codec) Encode(topic, msgType, traceID string, v interface{}) (*pulsar.ProducerMessage, error) {
+	if traceID == "" {
+		traceID = uuid.New().String()
+	}
+	contentType := c.ContentType(topic)
+	now := time.Now().UTC()
+
+	var data []byte
+	switch contentType {
+	case ContentTypeProtobuf:
+		msg, err := toProto(v)
+		if err != nil {
+			return nil, err
+		}
+		payload, err := proto.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s payload: %w", msgType, err)
+		}
+		data, err = proto.Marshal(&pb.Envelope{
+			Type:          msgType,
+			SchemaVersion: SchemaVersion,
+			Producer:      c.producer,
+			TraceId:       traceID,
+			ContentType:   contentType,
+			Timestamp:     timestamppb.New(now),
+			Payload:       payload,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal envelope: %w", err)
+		}
+	default:
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s payload: %w", msgType, err)
+		}
+		data, err = json.Marshal(&model.Envelope{
+			Type:          msgType,
+			SchemaVersion: SchemaVersion,
+			Producer:      c.producer,
+			TraceID:       traceID,
+			ContentType:   contentType,
+			Timestamp:     now,
+			Payload:       payload,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal envelope: %w", err)
+		}
+	}
+
+	return &pulsar.ProducerMessage{
+		Payload: data,
+		Properties: map[string]string{
+			PropertyType:          msgType,
+			PropertySchemaVersion: strconv.Itoa(SchemaVersion),
+			PropertyProducer:      c.producer,
+			PropertyTraceID:       traceID,
+			PropertyContentType:   contentType,
+			PropertyTimestamp:     now.Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// Decode unwraps an envelope into out. Messages without a content-type property are
+// treated as bare JSON from producers that predate the envelope.
+func (c *codec) Decode(payload []byte, properties map[string]string, out interface{}) (*model.Envelope, error) {
+	return Decode(payload, properties, out)
+}
+
+// Decode is the stateless form of Codec.Decode, usable without configuration
+func Decode(payload []byte, properties map[string]string, out interface{}) (*model.Envelope, error) {
+	switch properties[PropertyContentType] {
+	case ContentTypeProtobuf:
+		var env pb.Envelope
+		if err := proto.Unmarshal(payload, &env); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal envelope: %w", err)
+		}
+		if err := fromProto(env.GetPayload(), out); err != nil {
+			return nil, err
+		}
+		return &model.Envelope{
+			Type:          env.GetType(),
+			SchemaVersion: int(env.GetSchemaVersion()),
+			Producer:      env.GetProducer(),
+			TraceID:       env.GetTraceId(),
+			ContentType:   env.GetContentType(),
+			Timestamp:     env.GetTimestamp().AsTime(),
+			Payload:       env.GetPayload(),
+		}, nil
+	case ContentTypeJSON:
+		var env model.Envelope
+		if err := json.Unmarshal(payload, &env); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal envelope: %w", err)
+		}
+		if err := json.Unmarshal(env.Payload, out); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s payload: %w", env.Type, err)
+		}
+		return &env, nil
+	default:
+		if err := json.Unmarshal(payload, out); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal legacy payload: %w", err)
+		}
+		return &model.Envelope{
+			Type:        properties[PropertyType],
+			ContentType: ContentTypeJSON,
+			Payload:     payload,
+		}, nil
+	}
+}
+
+// IdempotencyKey derives the dedup key for a message from its type and model ID.
+// Messages without an ID fall back to the Pulsar message ID, which is stable across redeliveries.
+func IdempotencyKey(msgType, id string, msgID pulsar.MessageID) string {
+	if id == "" && msgID != nil {
+		id = msgID.String()
+	}
+	return msgType + ":" + id
+}
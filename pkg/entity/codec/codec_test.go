@@ -0,0 +1,183 @@
+package codec
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	at := time.Date(2025, 3, 14, 9, 26, 53, 0, time.UTC)
+	tests := []struct {
+		name    string
+		msgType string
+		in      interface{}
+		out     func() interface{}
+	}{
+		{
+			name:    "incoming stream data",
+			msgType: model.MessageTypeIncomingStreamData,
+			in: &model.IncomingStreamData{
+				UUID:       "reading-1",
+				Source:     "plant-a",
+				SensorType: "temperature",
+				Value:      21.5,
+				Timestamp:  at,
+				RawPayload: []byte(`{"t":21.5}`),
+			},
+			out: func() interface{} { return &model.IncomingStreamData{} },
+		},
+		{
+			name:    "command",
+			msgType: model.MessageTypeCommand,
+			in: &model.Command{
+				ID:        "command-1",
+				Type:      "config",
+				Target:    "agent",
+				Action:    "reload",
+				Payload:   map[string]interface{}{"agent_uuid": "agent-1"},
+				Timestamp: at,
+			},
+			out: func() interface{} { return &model.Command{} },
+		},
+		{
+			name:    "agent report",
+			msgType: model.MessageTypeAgentReport,
+			in: &model.AgentReport{
+				ID:        "report-1",
+				AgentID:   "agent-1",
+				Type:      "heartbeat",
+				Status:    "online",
+				Data:      `{"messages":3}`,
+				Timestamp: at,
+			},
+			out: func() interface{} { return &model.AgentReport{} },
+		},
+		{
+			name:    "processed stream data",
+			msgType: model.MessageTypeProcessedStreamData,
+			in: &model.ProcessedStreamData{
+				UUID:           "reading-1",
+				AgentUUID:      "agent-1",
+				OriginalValue:  21.5,
+				ProcessedValue: 21.4,
+				Anomaly:        true,
+				Confidence:     0.9,
+				ProcessingTime: 120,
+				Timestamp:      at,
+				SensorType:     "temperature",
+			},
+			out: func() interface{} { return &model.ProcessedStreamData{} },
+		},
+		{
+			name:    "alert data",
+			msgType: model.MessageTypeAlertData,
+			in: &model.AlertData{
+				UUID:           "reading-1",
+				AgentUUID:      "agent-1",
+				SensorType:     "temperature",
+				OriginalValue:  61,
+				ProcessedValue: 61,
+				Threshold:      50,
+				Severity:       "high",
+				Message:        "Anomalous temperature reading 61.00",
+				Timestamp:      at,
+				Rule:           "outlier_detection",
+			},
+			out: func() interface{} { return &model.AlertData{} },
+		},
+	}
+
+	for _, encoding := range []string{"json", "protobuf"} {
+		conf := config.BaseConfig{YamlConfig: config.YamlConfig{Pulsar: config.Pulsar{
+			Encoding: config.PulsarEncoding{Default: encoding},
+		}}}
+		c := NewCodec(conf, "test")
+
+		for _, tt := range tests {
+			t.Run(encoding+"/"+tt.name, func(t *testing.T) {
+				msg, err := c.Encode("topic", tt.msgType, "trace-1", tt.in)
+				if err != nil {
+					t.Fatalf("Encode() error = %v", err)
+				}
+				if got, want := msg.Properties[PropertyContentType], c.ContentType("topic"); got != want {
+					t.Errorf("content-type property = %q, want %q", got, want)
+				}
+				if got := msg.Properties[PropertySchemaVersion]; got != strconv.Itoa(SchemaVersion) {
+					t.Errorf("schema-version property = %q, want %d", got, SchemaVersion)
+				}
+
+				out := tt.out()
+				env, err := c.Decode(msg.Payload, msg.Properties, out)
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				if !reflect.DeepEqual(out, tt.in) {
+					t.Errorf("Decode() = %+v, want %+v", out, tt.in)
+				}
+				if env.Type != tt.msgType || env.TraceID != "trace-1" || env.SchemaVersion != SchemaVersion {
+					t.Errorf("envelope = {type %q, trace %q, version %d}, want {%q, %q, %d}",
+						env.Type, env.TraceID, env.SchemaVersion, tt.msgType, "trace-1", SchemaVersion)
+				}
+			})
+		}
+	}
+}
+
+func TestDecodeLegacyJSON(t *testing.T) {
+	report := model.AgentReport{ID: "report-1", AgentID: "agent-1", Type: "status", Status: "online"}
+	payload, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		properties map[string]string
+		wantType   string
+	}{
+		{name: "no properties", properties: nil},
+		{name: "type property only", properties: map[string]string{PropertyType: model.MessageTypeAgentReport}, wantType: model.MessageTypeAgentReport},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got model.AgentReport
+			env, err := Decode(payload, tt.properties, &got)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got != report {
+				t.Errorf("Decode() = %+v, want %+v", got, report)
+			}
+			if env.ContentType != ContentTypeJSON || env.Type != tt.wantType {
+				t.Errorf("envelope = {content type %q, type %q}, want {%q, %q}", env.ContentType, env.Type, ContentTypeJSON, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		payload     string
+	}{
+		{name: "legacy payload not JSON", payload: "not json"},
+		{name: "JSON envelope not JSON", contentType: ContentTypeJSON, payload: "not json"},
+		{name: "JSON envelope payload of wrong shape", contentType: ContentTypeJSON, payload: `{"type":"agent_report","payload":[1,2]}`},
+		{name: "protobuf envelope corrupt", contentType: ContentTypeProtobuf, payload: "\xff\xff\xff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out model.AgentReport
+			if _, err := Decode([]byte(tt.payload), map[string]string{PropertyContentType: tt.contentType}, &out); err == nil {
+				t.Error("Decode() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package codec
+
+import (
+	"fmt"
+
+	pb "github.com/ryo-arima/circulator/pkg/agent/gengrpc"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// toProto converts a stream model into its protobuf message
+func toProto(v interface{}) (proto.Message, error) {
+	switch m := v.(type) {
+	case *model.IncomingStreamData:
+		return &pb.IncomingStreamData{
+			Uuid:       m.UUID,
+			Source:     m.Source,
+			SensorType: m.SensorType,
+			Value:      m.Value,
+			Timestamp:  timestamppb.New(m.Timestamp),
+			RawPayload: m.RawPayload,
+		}, nil
+	case *model.Command:
+		payload, err := structpb.NewStruct(m.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert command payload: %w", err)
+		}
+		return &pb.Command{
+			Id:        m.ID,
+			Type:      m.Type,
+			Target:    m.Target,
+			Action:    m.Action,
+			Payload:   payload,
+			Timestamp: timestamppb.New(m.Timestamp),
+		}, nil
+	case *model.Notification:
+		return &pb.Notification{
+			Id:        m.ID,
+			AgentId:   m.AgentID,
+			Type:      m.Type,
+			Message:   m.Message,
+			Timestamp: timestamppb.New(m.Timestamp),
+		}, nil
+	case *model.ServerEvent:
+		return &pb.ServerEvent{
+			Id:        m.ID,
+			Type:      m.Type,
+			AgentId:   m.AgentID,
+			Data:      m.Data,
+			Timestamp: timestamppb.New(m.Timestamp),
+		}, nil
+	case *model.AgentReport:
+		return &pb.AgentReport{
+			Id:        m.ID,
+			AgentId:   m.AgentID,
+			Type:      m.Type,
+			Status:    m.Status,
+			Data:      m.Data,
+			Timestamp: timestamppb.New(m.Timestamp),
+		}, nil
+	case *model.ProcessedStreamData:
+		return &pb.ProcessedStreamData{
+			Uuid:           m.UUID,
+			AgentUuid:      m.AgentUUID,
+			OriginalValue:  m.OriginalValue,
+			ProcessedValue: m.ProcessedValue,
+			Anomaly:        m.Anomaly,
+			Confidence:     m.Confidence,
+			ProcessingTime: m.ProcessingTime,
+			Timestamp:      timestamppb.New(m.Timestamp),
+			SensorType:     m.SensorType,
+		}, nil
+	case *model.SystemMetrics:
+		return &pb.SystemMetrics{
+			Uuid:        m.UUID,
+			AgentUuid:   m.AgentUUID,
+			CpuUsage:    m.CPUUsage,
+			MemoryUsage: m.MemoryUsage,
+			DiskUsage:   m.DiskUsage,
+			Timestamp:   timestamppb.New(m.Timestamp),
+		}, nil
+	case *model.AlertData:
+		return &pb.AlertData{
+			Uuid:           m.UUID,
+			AgentUuid:      m.AgentUUID,
+			SensorType:     m.SensorType,
+			OriginalValue:  m.OriginalValue,
+			ProcessedValue: m.ProcessedValue,
+			Threshold:      m.Threshold,
+			Severity:       m.Severity,
+			Message:        m.Message,
+			Timestamp:      timestamppb.New(m.Timestamp),
+			Rule:           m.Rule,
+		}, nil
+	case *model.StreamProcessingResult:
+		return &pb.StreamProcessingResult{
+			Uuid:           m.UUID,
+			AgentUuid:      m.AgentUUID,
+			ProcessingType: m.ProcessingType,
+			Success:        m.Success,
+			ErrorMessage:   m.ErrorMessage,
+			ProcessingTime: m.ProcessingTime,
+			Timestamp:      timestamppb.New(m.Timestamp),
+		}, nil
+	default:
+		return nil, fmt.Errorf("no protobuf mapping for %T", v)
+	}
+}
+
+// fromProto decodes a protobuf payload into the stream model pointed to by out
+func fromProto(payload []byte, out interface{}) error {
+	switch m := out.(type) {
+	case *model.IncomingStreamData:
+		var msg pb.IncomingStreamData
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal incoming stream data: %w", err)
+		}
+		*m = model.IncomingStreamData{
+			UUID:       msg.GetUuid(),
+			Source:     msg.GetSource(),
+			SensorType: msg.GetSensorType(),
+			Value:      msg.GetValue(),
+			Timestamp:  msg.GetTimestamp().AsTime(),
+			RawPayload: msg.GetRawPayload(),
+		}
+	case *model.Command:
+		var msg pb.Command
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal command: %w", err)
+		}
+		*m = model.Command{
+			ID:        msg.GetId(),
+			Type:      msg.GetType(),
+			Target:    msg.GetTarget(),
+			Action:    msg.GetAction(),
+			Payload:   msg.GetPayload().AsMap(),
+			Timestamp: msg.GetTimestamp().AsTime(),
+		}
+	case *model.Notification:
+		var msg pb.Notification
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal notification: %w", err)
+		}
+		*m = model.Notification{
+			ID:        msg.GetId(),
+			AgentID:   msg.GetAgentId(),
+			Type:      msg.GetType(),
+			Message:   msg.GetMessage(),
+			Timestamp: msg.GetTimestamp().AsTime(),
+		}
+	case *model.ServerEvent:
+		var msg pb.ServerEvent
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal server event: %w", err)
+		}
+		*m = model.ServerEvent{
+			ID:        msg.GetId(),
+			Type:      msg.GetType(),
+			AgentID:   msg.GetAgentId(),
+			Data:      msg.GetData(),
+			Timestamp: msg.GetTimestamp().AsTime(),
+		}
+	case *model.AgentReport:
+		var msg pb.AgentReport
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal agent report: %w", err)
+		}
+		*m = model.AgentReport{
+			ID:        msg.GetId(),
+			AgentID:   msg.GetAgentId(),
+			Type:      msg.GetType(),
+			Status:    msg.GetStatus(),
+			Data:      msg.GetData(),
+			Timestamp: msg.GetTimestamp().AsTime(),
+		}
+	case *model.ProcessedStreamData:
+		var msg pb.ProcessedStreamData
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal processed stream data: %w", err)
+		}
+		*m = model.ProcessedStreamData{
+			UUID:           msg.GetUuid(),
+			AgentUUID:      msg.GetAgentUuid(),
+			OriginalValue:  msg.GetOriginalValue(),
+			ProcessedValue: msg.GetProcessedValue(),
+			Anomaly:        msg.GetAnomaly(),
+			Confidence:     msg.GetConfidence(),
+			ProcessingTime: msg.GetProcessingTime(),
+			Timestamp:      msg.GetTimestamp().AsTime(),
+			SensorType:     msg.GetSensorType(),
+		}
+	case *model.SystemMetrics:
+		var msg pb.SystemMetrics
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal system metrics: %w", err)
+		}
+		*m = model.SystemMetrics{
+			UUID:        msg.GetUuid(),
+			AgentUUID:   msg.GetAgentUuid(),
+			CPUUsage:    msg.GetCpuUsage(),
+			MemoryUsage: msg.GetMemoryUsage(),
+			DiskUsage:   msg.GetDiskUsage(),
+			Timestamp:   msg.GetTimestamp().AsTime(),
+		}
+	case *model.AlertData:
+		var msg pb.AlertData
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal alert data: %w", err)
+		}
+		*m = model.AlertData{
+			UUID:           msg.GetUuid(),
+			AgentUUID:      msg.GetAgentUuid(),
+			SensorType:     msg.GetSensorType(),
+			OriginalValue:  msg.GetOriginalValue(),
+			ProcessedValue: msg.GetProcessedValue(),
+			Threshold:      msg.GetThreshold(),
+			Severity:       msg.GetSeverity(),
+			Message:        msg.GetMessage(),
+			Timestamp:      msg.GetTimestamp().AsTime(),
+			Rule:           msg.GetRule(),
+		}
+	case *model.StreamProcessingResult:
+		var msg pb.StreamProcessingResult
+		if err := proto.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal stream processing result: %w", err)
+		}
+		*m = model.StreamProcessingResult{
+			UUID:           msg.GetUuid(),
+			AgentUUID:      msg.GetAgentUuid(),
+			ProcessingType: msg.GetProcessingType(),
+			Success:        msg.GetSuccess(),
+			ErrorMessage:   msg.GetErrorMessage(),
+			ProcessingTime: msg.GetProcessingTime(),
+			Timestamp:      msg.GetTimestamp().AsTime(),
+		}
+	default:
+		return fmt.Errorf("no protobuf mapping for %T", out)
+	}
+	return nil
+}
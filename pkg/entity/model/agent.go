@@ -28,6 +28,9 @@ func (Agent) TableName() string {
 	return "agents"
 }
 
+// AgentStatusOffline is set by the server on agents that missed their heartbeats
+const AgentStatusOffline = "offline"
+
 // AgentInfo represents comprehensive agent information stored in MySQL
 type AgentInfo struct {
 	ID             uint              `gorm:"primarykey" json:"id"`
@@ -107,3 +110,46 @@ type ProcessingRule struct {
 func (ProcessingRule) TableName() string {
 	return "processing_rules"
 }
+
+// MetricSensorTypeSystem is the sensor type under which host metrics are stored
+const MetricSensorTypeSystem = "system"
+
+// Rollup resolutions kept in agent_metric_rollups
+const (
+	MetricResolutionMinute = "1m"
+	MetricResolutionHour   = "1h"
+)
+
+// AgentMetric represents a single numeric sample reported by an agent, stored in MySQL.
+// ReportID is the ID of the report or stream message the sample came from.
+type AgentMetric struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	AgentUUID  string    `gorm:"type:varchar(36);index:idx_agent_metrics_series,priority:1" json:"agent_uuid"`
+	SensorType string    `gorm:"type:varchar(100);index:idx_agent_metrics_series,priority:2" json:"sensor_type"`
+	ReportID   string    `gorm:"type:varchar(64)" json:"report_id"`
+	Name       string    `gorm:"type:varchar(100);index:idx_agent_metrics_series,priority:3" json:"name"`
+	Value      float64   `json:"value"`
+	RecordedAt time.Time `gorm:"type:datetime;index:idx_agent_metrics_series,priority:4" json:"recorded_at"`
+}
+
+func (AgentMetric) TableName() string {
+	return "agent_metrics"
+}
+
+// AgentMetricRollup aggregates the samples of one series over a 1m or 1h bucket
+type AgentMetricRollup struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	AgentUUID   string    `gorm:"type:varchar(36);uniqueIndex:idx_agent_metric_rollups_bucket,priority:1" json:"agent_uuid"`
+	SensorType  string    `gorm:"type:varchar(100);uniqueIndex:idx_agent_metric_rollups_bucket,priority:2" json:"sensor_type"`
+	Name        string    `gorm:"type:varchar(100);uniqueIndex:idx_agent_metric_rollups_bucket,priority:3" json:"name"`
+	Resolution  string    `gorm:"type:varchar(8);uniqueIndex:idx_agent_metric_rollups_bucket,priority:4" json:"resolution"`
+	BucketStart time.Time `gorm:"type:datetime;uniqueIndex:idx_agent_metric_rollups_bucket,priority:5" json:"bucket_start"`
+	Count       int64     `gorm:"column:sample_count" json:"count"`
+	Sum         float64   `gorm:"column:value_sum" json:"sum"`
+	Min         float64   `gorm:"column:value_min" json:"min"`
+	Max         float64   `gorm:"column:value_max" json:"max"`
+}
+
+func (AgentMetricRollup) TableName() string {
+	return "agent_metric_rollups"
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+)
+
+// Alert lifecycle states
+const (
+	AlertStatusOpen         = "open"
+	AlertStatusAcknowledged = "acknowledged"
+	AlertStatusResolved     = "resolved"
+)
+
+// AlertSeverityRank orders AlertData severities; unknown severities rank lowest
+var AlertSeverityRank = map[string]int{
+	"low":      1,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// Alert is an incident grouping repeated AlertData of one agent, sensor type and rule,
+// stored in MySQL. OpenKey holds the grouping key while the alert is not resolved and is
+// cleared on resolve, so its unique index allows one unresolved alert per key.
+type Alert struct {
+	ID             uint        `gorm:"primarykey" json:"-"`
+	UUID           string      `gorm:"type:varchar(36);uniqueIndex" json:"uuid"`
+	OpenKey        *string     `gorm:"type:varchar(255);uniqueIndex" json:"-"`
+	AgentUUID      string      `gorm:"type:varchar(36);index" json:"agent_uuid"`
+	SensorType     string      `gorm:"type:varchar(100)" json:"sensor_type"`
+	Rule           string      `gorm:"type:varchar(100)" json:"rule"`
+	Severity       string      `gorm:"type:varchar(20)" json:"severity"` // highest severity seen
+	Status         string      `gorm:"type:varchar(20);index" json:"status"`
+	Count          int64       `json:"count"`
+	Message        string      `gorm:"type:text" json:"message"` // latest message
+	LastValue      float64     `json:"last_value"`
+	Threshold      float64     `json:"threshold"`
+	Assignee       string      `gorm:"type:varchar(255)" json:"assignee,omitempty"`
+	FirstSeenAt    time.Time   `gorm:"type:datetime" json:"first_seen_at"`
+	LastSeenAt     time.Time   `gorm:"type:datetime" json:"last_seen_at"`
+	AcknowledgedAt *time.Time  `gorm:"type:datetime" json:"acknowledged_at,omitempty"`
+	ResolvedAt     *time.Time  `gorm:"type:datetime;index" json:"resolved_at,omitempty"`
+	Notes          []AlertNote `gorm:"foreignKey:AlertID;constraint:OnDelete:CASCADE" json:"notes,omitempty"`
+	CreatedAt      *time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time  `json:"updated_at"`
+}
+
+func (Alert) TableName() string {
+	return "alerts"
+}
+
+// AlertKey is the grouping key of an alert raised by rule for an agent's sensor type
+func AlertKey(agentUUID, sensorType, rule string) string {
+	return agentUUID + "/" + sensorType + "/" + rule
+}
+
+// AlertNote records a lifecycle change or comment on an alert
+type AlertNote struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	AlertID   uint      `gorm:"index" json:"-"`
+	Author    string    `gorm:"type:varchar(255)" json:"author"`
+	Status    string    `gorm:"type:varchar(20)" json:"status"` // alert status after the change
+	Text      string    `gorm:"type:text" json:"text,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (AlertNote) TableName() string {
+	return "alert_notes"
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+)
+
+// ProcessedMessage records an idempotency key that has already been handled,
+// so Pulsar redeliveries can be acknowledged without re-applying their effects
+type ProcessedMessage struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	Key         string    `gorm:"type:varchar(191);uniqueIndex" json:"key"`
+	Consumer    string    `gorm:"type:varchar(255)" json:"consumer"`
+	ProcessedAt time.Time `gorm:"type:datetime" json:"processed_at"`
+	ExpiresAt   time.Time `gorm:"type:datetime;index" json:"expires_at"`
+}
+
+func (ProcessedMessage) TableName() string {
+	return "processed_messages"
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+)
+
+// Notification channel types
+const (
+	ChannelTypeWebhook = "webhook"
+	ChannelTypeSlack   = "slack"
+	ChannelTypeEmail   = "email"
+)
+
+// Notification delivery states
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+	// DeliveryStatusSending marks a delivery claimed by a dispatcher until NextAttemptAt,
+	// after which another dispatcher may claim it again
+	DeliveryStatusSending = "sending"
+	// DeliveryStatusSuppressed records a delivery withheld by a silence or maintenance window
+	DeliveryStatusSuppressed = "suppressed"
+)
+
+// NotificationMessage is what the router sends to channels: an alert lifecycle event or a
+// client Notification. It is the JSON body of webhook deliveries and the data of channel templates.
+type NotificationMessage struct {
+	ID        string                 `json:"id"`   // server event or notification ID
+	Kind      string                 `json:"kind"` // alert or notification
+	Event     string                 `json:"event"`
+	Severity  string                 `json:"severity,omitempty"`
+	Labels    map[string]string      `json:"labels"`
+	Title     string                 `json:"title"`
+	Text      string                 `json:"text"`
+	Fields    map[string]interface{} `json:"fields,omitempty"` // the alert, for alert events
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// Kinds of NotificationMessage
+const (
+	NotificationKindAlert        = "alert"
+	NotificationKindNotification = "notification"
+	NotificationKindAgent        = "agent"
+)
+
+// NotificationDelivery is one message rendered for one channel, stored in MySQL as the
+// delivery log. Subject and Body are what is sent, so retries send the same content.
+type NotificationDelivery struct {
+	ID            uint       `gorm:"primarykey" json:"-"`
+	UUID          string     `gorm:"type:varchar(36);uniqueIndex" json:"uuid"`
+	MessageID     string     `gorm:"type:varchar(64);uniqueIndex:idx_notification_deliveries_message,priority:1" json:"message_id"`
+	Channel       string     `gorm:"type:varchar(100);uniqueIndex:idx_notification_deliveries_message,priority:2" json:"channel"`
+	ChannelType   string     `gorm:"type:varchar(20)" json:"channel_type"`
+	Event         string     `gorm:"type:varchar(100)" json:"event"`
+	Severity      string     `gorm:"type:varchar(20)" json:"severity,omitempty"`
+	Subject       string     `gorm:"type:varchar(255)" json:"subject,omitempty"`
+	Body          string     `gorm:"type:text" json:"body"`
+	Status        string     `gorm:"type:varchar(20);index:idx_notification_deliveries_due,priority:1" json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"type:datetime;index:idx_notification_deliveries_due,priority:2" json:"next_attempt_at"`
+	DeliveredAt   *time.Time `gorm:"type:datetime" json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
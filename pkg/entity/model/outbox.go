@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// Kinds of OutboxEvent, selecting the topic the relay publishes to
+const (
+	OutboxKindEvent        = "event"        // a ServerEvent for server-events
+	OutboxKindNotification = "notification" // a Notification for client-notifications
+)
+
+// OutboxEvent is a ServerEvent or Notification written in the same transaction as the
+// mutation it describes and relayed to Pulsar afterwards, stored in MySQL
+type OutboxEvent struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	Kind        string     `gorm:"type:varchar(20);default:event" json:"kind"`
+	EventID     string     `gorm:"type:varchar(36);uniqueIndex" json:"event_id"`
+	Type        string     `gorm:"type:varchar(100)" json:"type"`
+	AgentID     string     `gorm:"type:varchar(36);index" json:"agent_id"`
+	Data        string     `gorm:"type:text" json:"data"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt   time.Time  `gorm:"type:datetime" json:"created_at"`
+	PublishedAt *time.Time `gorm:"type:datetime;index" json:"published_at,omitempty"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// ToServerEvent converts the outbox row back into the event it carries
+func (e OutboxEvent) ToServerEvent() *ServerEvent {
+	return &ServerEvent{
+		ID:        e.EventID,
+		Type:      e.Type,
+		AgentID:   e.AgentID,
+		Data:      e.Data,
+		Timestamp: e.CreatedAt,
+	}
+}
+
+// ToNotification converts a notification outbox row back into the notification it carries
+func (e OutboxEvent) ToNotification() *Notification {
+	return &Notification{
+		ID:        e.EventID,
+		AgentID:   e.AgentID,
+		Type:      e.Type,
+		Message:   e.Data,
+		Timestamp: e.CreatedAt,
+	}
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+)
+
+// Retention data classes, each with its own configurable retention
+const (
+	RetentionClassMetricsRaw = "metrics_raw"
+	RetentionClassMetrics1m  = "metrics_1m"
+	RetentionClassMetrics1h  = "metrics_1h"
+	RetentionClassSystemInfo = "system_info"
+	RetentionClassEvents     = "events"
+	RetentionClassAlerts     = "alerts"
+	RetentionClassDeliveries = "notifications"
+	RetentionClassProcessed  = "processed_messages"
+)
+
+// RetentionRun records the last compactor pass over one data class, stored in MySQL
+type RetentionRun struct {
+	ID         uint      `gorm:"primarykey" json:"-"`
+	Class      string    `gorm:"type:varchar(50);uniqueIndex" json:"class"`
+	Cutoff     time.Time `gorm:"type:datetime" json:"cutoff"`
+	Matched    int64     `json:"matched"` // rows older than the cutoff when the pass started
+	Deleted    int64     `json:"deleted"`
+	DryRun     bool      `json:"dry_run"`
+	Error      string    `gorm:"type:text" json:"error,omitempty"`
+	StartedAt  time.Time `gorm:"type:datetime" json:"started_at"`
+	FinishedAt time.Time `gorm:"type:datetime" json:"finished_at"`
+}
+
+func (RetentionRun) TableName() string {
+	return "retention_runs"
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// Kinds of server-side alert rules
+const (
+	// RuleKindThreshold fires when every sample of a metric in the window breaches the threshold
+	RuleKindThreshold = "threshold"
+	// RuleKindAbsence fires when an agent that reported a sensor stops reporting it for the window
+	RuleKindAbsence = "absence"
+	// RuleKindAnomalyRate fires when the percentage of anomalous samples in scope breaches the threshold
+	RuleKindAnomalyRate = "anomaly_rate"
+)
+
+// RuleOperators compare a value against a rule threshold
+var RuleOperators = map[string]func(value, threshold float64) bool{
+	">":  func(value, threshold float64) bool { return value > threshold },
+	">=": func(value, threshold float64) bool { return value >= threshold },
+	"<":  func(value, threshold float64) bool { return value < threshold },
+	"<=": func(value, threshold float64) bool { return value <= threshold },
+}
+
+// AlertRule is evaluated periodically by the server over stored metrics. Matches raise
+// AlertData with the rule name, so they share the lifecycle of agent-generated alerts.
+// AgentUUID and Group narrow the rule to one agent or to the agents of a metadata group.
+type AlertRule struct {
+	ID          uint       `gorm:"primarykey" json:"-"`
+	UUID        string     `gorm:"type:varchar(36);uniqueIndex" json:"uuid"`
+	Name        string     `gorm:"type:varchar(100);uniqueIndex" json:"name"`
+	Kind        string     `gorm:"type:varchar(20)" json:"kind"`
+	SensorType  string     `gorm:"type:varchar(100)" json:"sensor_type"`
+	Metric      string     `gorm:"type:varchar(100)" json:"metric,omitempty"`
+	Operator    string     `gorm:"type:varchar(2)" json:"operator,omitempty"`
+	Threshold   float64    `json:"threshold"`
+	For         int        `gorm:"column:for_minutes" json:"for"` // minutes
+	AgentUUID   string     `gorm:"type:varchar(36)" json:"agent_uuid,omitempty"`
+	Group       string     `gorm:"column:agent_group;type:varchar(100)" json:"group,omitempty"`
+	Severity    string     `gorm:"type:varchar(20)" json:"severity"`
+	Message     string     `gorm:"type:text" json:"message,omitempty"`
+	Enabled     bool       `json:"enabled"`
+	CreatedBy   string     `gorm:"type:varchar(255)" json:"created_by"`
+	EvaluatedAt *time.Time `gorm:"type:datetime" json:"evaluated_at,omitempty"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
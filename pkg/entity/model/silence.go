@@ -0,0 +1,123 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys a silence can match on: the NotificationMessage labels, plus event for its type
+var SilenceMatcherKeys = []string{"agent_uuid", "group", "sensor_type", "rule", "event"}
+
+// Silence suppresses notifications of messages matching all of its matchers between
+// StartsAt and EndsAt. Alerts are still recorded while silenced.
+type Silence struct {
+	ID        uint              `gorm:"primarykey" json:"-"`
+	UUID      string            `gorm:"type:varchar(36);uniqueIndex" json:"uuid"`
+	Matchers  map[string]string `gorm:"type:json;serializer:json" json:"matchers"`
+	StartsAt  time.Time         `gorm:"type:datetime" json:"starts_at"`
+	EndsAt    time.Time         `gorm:"type:datetime;index" json:"ends_at"`
+	CreatedBy string            `gorm:"type:varchar(255)" json:"created_by"`
+	Comment   string            `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+func (Silence) TableName() string {
+	return "silences"
+}
+
+// ActiveAt reports whether the silence is in effect at t
+func (s Silence) ActiveAt(t time.Time) bool {
+	return !t.Before(s.StartsAt) && t.Before(s.EndsAt)
+}
+
+// Matches reports whether every matcher equals the message's label, or its event type for "event"
+func (s Silence) Matches(msg *NotificationMessage) bool {
+	for key, value := range s.Matchers {
+		actual := msg.Labels[key]
+		if key == "event" {
+			actual = msg.Event
+		}
+		if actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// MaintenanceWindow suppresses all notifications about the agents of a group during a
+// recurring window. Agents belong to the group named by their "group" metadata.
+type MaintenanceWindow struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	UUID      string    `gorm:"type:varchar(36);uniqueIndex" json:"uuid"`
+	Name      string    `gorm:"type:varchar(100)" json:"name"`
+	Group     string    `gorm:"column:agent_group;type:varchar(100);index" json:"group"`
+	Weekdays  []string  `gorm:"type:json;serializer:json" json:"weekdays,omitempty"` // sun..sat, empty for every day
+	StartTime string    `gorm:"type:varchar(5)" json:"start_time"`                   // HH:MM in Timezone
+	Duration  int       `json:"duration"`                                            // minutes
+	Timezone  string    `gorm:"type:varchar(64)" json:"timezone"`
+	CreatedBy string    `gorm:"type:varchar(255)" json:"created_by"`
+	Comment   string    `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (MaintenanceWindow) TableName() string {
+	return "maintenance_windows"
+}
+
+// ActiveAt reports whether a window occurrence covers t. Occurrences start on the listed
+// weekdays and may run past midnight into the next day.
+func (w MaintenanceWindow) ActiveAt(t time.Time) bool {
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	hour, minute, ok := ParseClock(w.StartTime)
+	if !ok || w.Duration <= 0 {
+		return false
+	}
+	duration := time.Duration(w.Duration) * time.Minute
+
+	local := t.In(loc)
+	for daysBack := 0; time.Duration(daysBack)*24*time.Hour < duration+24*time.Hour; daysBack++ {
+		day := local.AddDate(0, 0, -daysBack)
+		start := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+		if !w.onWeekday(start.Weekday()) {
+			continue
+		}
+		if !local.Before(start) && local.Before(start.Add(duration)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w MaintenanceWindow) onWeekday(weekday time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	name := strings.ToLower(weekday.String()[:3])
+	for _, day := range w.Weekdays {
+		if strings.ToLower(day) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClock parses an HH:MM time of day
+func ParseClock(value string) (int, int, bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, false
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
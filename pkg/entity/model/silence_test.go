@@ -0,0 +1,135 @@
+package model
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestSilenceActiveAt(t *testing.T) {
+	start := time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)
+	silence := Silence{StartsAt: start, EndsAt: start.Add(time.Hour)}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "before the start", at: start.Add(-time.Second), want: false},
+		{name: "at the start", at: start, want: true},
+		{name: "during", at: start.Add(30 * time.Minute), want: true},
+		{name: "at the end", at: start.Add(time.Hour), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := silence.ActiveAt(tt.at); got != tt.want {
+				t.Errorf("ActiveAt(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaintenanceWindowActiveAt(t *testing.T) {
+	// 2 June 2025 is a Monday
+	utc := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 6, day, hour, minute, 0, 0, time.UTC)
+	}
+	mondayNight := MaintenanceWindow{Weekdays: []string{"mon"}, StartTime: "22:00", Duration: 240, Timezone: "UTC"}
+
+	tests := []struct {
+		name   string
+		window MaintenanceWindow
+		at     time.Time
+		want   bool
+	}{
+		{name: "before the start", window: mondayNight, at: utc(2, 21, 59), want: false},
+		{name: "at the start", window: mondayNight, at: utc(2, 22, 0), want: true},
+		{name: "past midnight into the next day", window: mondayNight, at: utc(3, 1, 59), want: true},
+		{name: "at the end", window: mondayNight, at: utc(3, 2, 0), want: false},
+		{name: "same time on another weekday", window: mondayNight, at: utc(3, 22, 30), want: false},
+		{name: "same time a week later", window: mondayNight, at: utc(9, 23, 0), want: true},
+		{
+			name:   "weekday names are case insensitive",
+			window: MaintenanceWindow{Weekdays: []string{"Mon"}, StartTime: "22:00", Duration: 60, Timezone: "UTC"},
+			at:     utc(2, 22, 30),
+			want:   true,
+		},
+		{
+			name:   "no weekdays means every day",
+			window: MaintenanceWindow{StartTime: "22:00", Duration: 60, Timezone: "UTC"},
+			at:     utc(5, 22, 30),
+			want:   true,
+		},
+		{
+			name:   "window longer than a day",
+			window: MaintenanceWindow{Weekdays: []string{"sat"}, StartTime: "12:00", Duration: 36 * 60, Timezone: "UTC"},
+			at:     utc(8, 23, 0),
+			want:   true,
+		},
+		{
+			name:   "start time in the window's timezone",
+			window: MaintenanceWindow{Weekdays: []string{"mon"}, StartTime: "09:00", Duration: 60, Timezone: "Asia/Tokyo"},
+			at:     utc(2, 0, 30),
+			want:   true,
+		},
+		{
+			name:   "weekday in the window's timezone",
+			window: MaintenanceWindow{Weekdays: []string{"tue"}, StartTime: "01:00", Duration: 60, Timezone: "Asia/Tokyo"},
+			at:     utc(2, 16, 30),
+			want:   true,
+		},
+		{
+			name:   "unknown timezone falls back to UTC",
+			window: MaintenanceWindow{StartTime: "22:00", Duration: 60, Timezone: "Nowhere/City"},
+			at:     utc(2, 22, 30),
+			want:   true,
+		},
+		{
+			name:   "invalid start time never matches",
+			window: MaintenanceWindow{StartTime: "25:00", Duration: 60, Timezone: "UTC"},
+			at:     utc(2, 0, 30),
+			want:   false,
+		},
+		{
+			name:   "zero duration never matches",
+			window: MaintenanceWindow{StartTime: "22:00", Duration: 0, Timezone: "UTC"},
+			at:     utc(2, 22, 0),
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.ActiveAt(tt.at); got != tt.want {
+				t.Errorf("ActiveAt(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value      string
+		wantHour   int
+		wantMinute int
+		wantOK     bool
+	}{
+		{value: "00:00", wantOK: true},
+		{value: "09:30", wantHour: 9, wantMinute: 30, wantOK: true},
+		{value: "23:59", wantHour: 23, wantMinute: 59, wantOK: true},
+		{value: "24:00"},
+		{value: "12:60"},
+		{value: "-1:00"},
+		{value: "12"},
+		{value: "12:00:00"},
+		{value: "ab:cd"},
+		{value: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			hour, minute, ok := ParseClock(tt.value)
+			if hour != tt.wantHour || minute != tt.wantMinute || ok != tt.wantOK {
+				t.Errorf("ParseClock(%q) = %d, %d, %v, want %d, %d, %v", tt.value, hour, minute, ok, tt.wantHour, tt.wantMinute, tt.wantOK)
+			}
+		})
+	}
+}
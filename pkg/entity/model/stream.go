@@ -1,11 +1,100 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 )
 
 // Pulsar-only data structures for streaming (no GORM tags)
 
+// Message types carried in Envelope.Type
+const (
+	MessageTypeIncomingStreamData     = "incoming_stream_data"
+	MessageTypeCommand                = "command"
+	MessageTypeNotification           = "notification"
+	MessageTypeServerEvent            = "server_event"
+	MessageTypeAgentReport            = "agent_report"
+	MessageTypeProcessedStreamData    = "processed_stream_data"
+	MessageTypeSystemMetrics          = "system_metrics"
+	MessageTypeAlertData              = "alert_data"
+	MessageTypeStreamProcessingResult = "stream_processing_result"
+)
+
+// Command types and actions carried in Command.Type and Command.Action
+const (
+	CommandTypeConfig   = "config"
+	CommandTypeAgent    = "agent"
+	CommandActionReload = "reload"
+	CommandActionDrain  = "drain"
+)
+
+// Agent report types carried in AgentReport.Type
+const (
+	ReportTypeStatus    = "status"
+	ReportTypeMetrics   = "metrics"
+	ReportTypeError     = "error"
+	ReportTypeHeartbeat = "heartbeat"
+)
+
+// Server event types carried in ServerEvent.Type
+const (
+	ServerEventAgentRegistered    = "agent_registered"
+	ServerEventAgentUpdated       = "agent_updated"
+	ServerEventAgentDeleted       = "agent_deleted"
+	ServerEventAgentStatusChanged = "agent_status_changed"
+	ServerEventAgentError         = "agent_error"
+	ServerEventAgentInfoCreated   = "agent_info_created"
+	ServerEventAgentInfoUpdated   = "agent_info_updated"
+	ServerEventAgentInfoDeleted   = "agent_info_deleted"
+	ServerEventSystemCreated      = "agent_system_created"
+	ServerEventSystemUpdated      = "agent_system_updated"
+	ServerEventSystemDeleted      = "agent_system_deleted"
+	ServerEventConfigCreated      = "agent_config_created"
+	ServerEventConfigUpdated      = "agent_config_updated"
+	ServerEventConfigDeleted      = "agent_config_deleted"
+	ServerEventRuleCreated        = "agent_config_rule_created"
+	ServerEventRuleUpdated        = "agent_config_rule_updated"
+	ServerEventRuleDeleted        = "agent_config_rule_deleted"
+	ServerEventSystemStatus       = "system_status"
+	ServerEventAlertOpened        = "alert_opened"
+	ServerEventAlertAcknowledged  = "alert_acknowledged"
+	ServerEventAlertResolved      = "alert_resolved"
+)
+
+// Mutation actions carried in ResourceChange.Action
+const (
+	ChangeActionCreated = "created"
+	ChangeActionUpdated = "updated"
+	ChangeActionDeleted = "deleted"
+)
+
+// ResourceChange is the ServerEvent.Data payload of mutation events
+type ResourceChange struct {
+	Resource string                 `json:"resource"`
+	Action   string                 `json:"action"`
+	Before   map[string]interface{} `json:"before,omitempty"`
+	After    map[string]interface{} `json:"after,omitempty"`
+	Diff     map[string]FieldChange `json:"diff,omitempty"`
+}
+
+// FieldChange is a single field that differs between Before and After
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Envelope wraps every Pulsar payload with type, schema version and producer metadata.
+// Payload holds the encoded message in the envelope's ContentType.
+type Envelope struct {
+	Type          string          `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	Producer      string          `json:"producer"`
+	TraceID       string          `json:"trace_id,omitempty"`
+	ContentType   string          `json:"content_type"`
+	Timestamp     time.Time       `json:"timestamp"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
 // IncomingStreamData represents data from external sources for Pulsar streaming
 type IncomingStreamData struct {
 	UUID       string    `json:"uuid"`
@@ -16,6 +105,29 @@ type IncomingStreamData struct {
 	RawPayload []byte    `json:"raw_payload"`
 }
 
+// StreamArchiveHeader is the first line of a recorded stream archive
+type StreamArchiveHeader struct {
+	Version    int       `json:"version"`
+	Topic      string    `json:"topic"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// StreamArchiveRecord is one recorded message with the time the broker received it
+type StreamArchiveRecord struct {
+	At   time.Time          `json:"at"`
+	Data IncomingStreamData `json:"data"`
+}
+
+// TopicMessage is a message read from a topic, Data decoded into the model of its type
+type TopicMessage struct {
+	Topic       string      `json:"topic"`
+	MessageID   string      `json:"message_id"`
+	PublishTime time.Time   `json:"publish_time"`
+	Type        string      `json:"type"`
+	TraceID     string      `json:"trace_id,omitempty"`
+	Data        interface{} `json:"data"`
+}
+
 // Command represents a command message for Pulsar
 type Command struct {
 	ID        string                 `json:"id"`
@@ -38,7 +150,7 @@ type Notification struct {
 // ServerEvent represents events published by the server
 type ServerEvent struct {
 	ID        string    `json:"id"`
-	Type      string    `json:"type"` // "agent_registered", "agent_updated", "system_status"
+	Type      string    `json:"type"` // one of the ServerEvent* constants
 	AgentID   string    `json:"agent_id,omitempty"`
 	Data      string    `json:"data"`
 	Timestamp time.Time `json:"timestamp"`
@@ -64,6 +176,7 @@ type ProcessedStreamData struct {
 	Confidence     float64   `json:"confidence"`
 	ProcessingTime int64     `json:"processing_time"` // microseconds
 	Timestamp      time.Time `json:"timestamp"`
+	SensorType     string    `json:"sensor_type,omitempty"`
 }
 
 // AgentProcessingConfig represents processing configuration for agents
@@ -98,6 +211,7 @@ type AlertData struct {
 	Severity       string    `json:"severity"` // "low", "medium", "high", "critical"
 	Message        string    `json:"message"`
 	Timestamp      time.Time `json:"timestamp"`
+	Rule           string    `json:"rule,omitempty"` // rule that raised the alert, used to group repeats
 }
 
 // StreamProcessingResult represents the result of stream processing for Pulsar
@@ -97,6 +97,15 @@ type AgentConfigRulesRequest struct {
 	Params   map[string]interface{} `json:"params"`
 }
 
+// AgentMetricsRequest holds the query parameters of GET /v1/agent/:id/metrics
+type AgentMetricsRequest struct {
+	SensorType string `form:"sensor_type"`
+	Name       string `form:"name"`
+	From       string `form:"from"` // RFC3339 or unix seconds, defaults to one hour before to
+	To         string `form:"to"`   // RFC3339 or unix seconds, defaults to now
+	Step       string `form:"step"` // bucket width such as 1m, 5m or 1h; empty returns raw samples
+}
+
 type RegisterAgentRequest struct {
 	UUID           string            `json:"uuid"`
 	Hostname       string            `json:"hostname"`
@@ -0,0 +1,17 @@
+package request
+
+// AlertListRequest holds the query parameters of GET /v1/alerts
+type AlertListRequest struct {
+	Status     string `form:"status" json:"status,omitempty"` // open, acknowledged or resolved; empty lists unresolved alerts
+	AgentUUID  string `form:"agent_uuid" json:"agent_uuid,omitempty"`
+	SensorType string `form:"sensor_type" json:"sensor_type,omitempty"`
+	Severity   string `form:"severity" json:"severity,omitempty"`
+	Limit      int    `form:"limit" json:"limit,omitempty"`
+}
+
+// AlertActionRequest represents an acknowledge or resolve request
+type AlertActionRequest struct {
+	UUID     string `json:"uuid,omitempty"`
+	Assignee string `json:"assignee,omitempty"` // defaults to the caller on acknowledge
+	Note     string `json:"note,omitempty"`
+}
@@ -0,0 +1,8 @@
+package request
+
+// EventListRequest holds the query parameters of GET /v1/events
+type EventListRequest struct {
+	Types     []string `form:"type" json:"type,omitempty"` // server event types, all when empty
+	AgentUUID string   `form:"agent_uuid" json:"agent_uuid,omitempty"`
+	Since     string   `form:"since" json:"since,omitempty"` // RFC3339 or unix seconds; empty starts with new events
+}
@@ -0,0 +1,25 @@
+package request
+
+// ManifestKindAgentConfig is the kind of manifests that describe agent stream processing configs
+const ManifestKindAgentConfig = "AgentConfig"
+
+// AgentConfigManifest is the desired stream processing config of one or more agents, read by
+// `circulator apply` and `circulator diff`. Rules are matched to the server's rules by name.
+type AgentConfigManifest struct {
+	Kind            string                   `yaml:"kind" json:"kind"`
+	Agent           string                   `yaml:"agent,omitempty" json:"agent,omitempty"`
+	Agents          []string                 `yaml:"agents,omitempty" json:"agents,omitempty"`
+	SensorType      string                   `yaml:"sensor_type" json:"sensor_type"`
+	OutputStreams   []string                 `yaml:"output_streams" json:"output_streams"`
+	ProcessingRules []ProcessingRuleManifest `yaml:"processing_rules" json:"processing_rules"`
+
+	// Source is the file the manifest was read from
+	Source string `yaml:"-" json:"-"`
+}
+
+// ProcessingRuleManifest is one desired processing rule; Enabled defaults to true
+type ProcessingRuleManifest struct {
+	Name    string                 `yaml:"name" json:"name"`
+	Enabled *bool                  `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	Params  map[string]interface{} `yaml:"params,omitempty" json:"params,omitempty"`
+}
@@ -0,0 +1,9 @@
+package request
+
+// NotificationDeliveryListRequest holds the query parameters of GET /v1/admin/notifications
+type NotificationDeliveryListRequest struct {
+	Status    string `form:"status"` // pending, sending, delivered, failed or suppressed
+	Channel   string `form:"channel"`
+	MessageID string `form:"message_id"`
+	Limit     int    `form:"limit"` // defaults to 100
+}
@@ -0,0 +1,17 @@
+package request
+
+// AlertRuleRequest creates or replaces a server-side alert rule
+type AlertRuleRequest struct {
+	Name       string  `json:"name" binding:"required"`
+	Kind       string  `json:"kind" binding:"required"` // threshold, absence or anomaly_rate
+	SensorType string  `json:"sensor_type"`
+	Metric     string  `json:"metric,omitempty"`    // threshold rules, e.g. cpu_usage
+	Operator   string  `json:"operator,omitempty"`  // >, >=, < or <=
+	Threshold  float64 `json:"threshold,omitempty"` // percent for anomaly_rate rules
+	For        string  `json:"for,omitempty"`       // e.g. 5m, defaults to 5m
+	AgentUUID  string  `json:"agent_uuid,omitempty"`
+	Group      string  `json:"group,omitempty"`
+	Severity   string  `json:"severity,omitempty"` // defaults to medium
+	Message    string  `json:"message,omitempty"`
+	Enabled    *bool   `json:"enabled,omitempty"` // defaults to true
+}
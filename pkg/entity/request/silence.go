@@ -0,0 +1,28 @@
+package request
+
+import "time"
+
+// SilenceRequest creates a silence. StartsAt defaults to now; EndsAt or Duration sets the end.
+type SilenceRequest struct {
+	Matchers map[string]string `json:"matchers" binding:"required"`
+	StartsAt *time.Time        `json:"starts_at,omitempty"`
+	EndsAt   *time.Time        `json:"ends_at,omitempty"`
+	Duration string            `json:"duration,omitempty"` // e.g. 2h, used when ends_at is empty
+	Comment  string            `json:"comment,omitempty"`
+}
+
+// SilenceListRequest holds the query parameters of GET /v1/silences
+type SilenceListRequest struct {
+	All bool `form:"all"` // include expired silences
+}
+
+// MaintenanceWindowRequest creates a recurring maintenance window for an agent group
+type MaintenanceWindowRequest struct {
+	Name      string   `json:"name"`
+	Group     string   `json:"group" binding:"required"`
+	Weekdays  []string `json:"weekdays,omitempty"`            // sun..sat, empty for every day
+	StartTime string   `json:"start_time" binding:"required"` // HH:MM
+	Duration  string   `json:"duration" binding:"required"`   // e.g. 3h
+	Timezone  string   `json:"timezone,omitempty"`            // IANA name, defaults to UTC
+	Comment   string   `json:"comment,omitempty"`
+}
@@ -15,6 +15,13 @@ type AgentResponse struct {
 
 type AgentListResponse = AgentResponse
 
+// AgentDetailListResponse decodes GET /v1/agents, which returns the full agent records
+type AgentDetailListResponse struct {
+	Code    string        `json:"code,omitempty"`
+	Message string        `json:"message,omitempty"`
+	Agents  []model.Agent `json:"agents"`
+}
+
 type Agent struct {
 	ID        uint       `json:"id"`
 	UUID      string     `json:"uuid"`
@@ -111,6 +118,13 @@ type AgentConfigRulesResponse struct {
 	List    []AgentConfigRules `json:"list,omitempty"`
 }
 
+// AgentConfigRulesListResponse represents the processing rules of one agent config
+type AgentConfigRulesListResponse struct {
+	Code    string             `json:"code"`
+	Message string             `json:"message"`
+	Data    []AgentConfigRules `json:"data"`
+}
+
 type AgentConfigRules struct {
 	ID        uint                   `json:"id"`
 	UUID      string                 `json:"uuid"`
@@ -123,6 +137,38 @@ type AgentConfigRules struct {
 	DeletedAt *time.Time             `json:"deleted_at,omitempty"`
 }
 
+// AgentMetricsResponse represents a response for agent metrics queries
+type AgentMetricsResponse struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Data    *AgentMetrics `json:"data,omitempty"`
+}
+
+type AgentMetrics struct {
+	AgentUUID string         `json:"agent_uuid"`
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+	Step      string         `json:"step,omitempty"`
+	Source    string         `json:"source"` // raw, 1m or 1h
+	Series    []MetricSeries `json:"series"`
+}
+
+type MetricSeries struct {
+	SensorType string        `json:"sensor_type"`
+	Name       string        `json:"name"`
+	Points     []MetricPoint `json:"points"`
+}
+
+// MetricPoint aggregates the samples in [Timestamp, Timestamp+step); raw points have Count 1
+type MetricPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Count     int64     `json:"count"`
+	Avg       float64   `json:"avg"`
+	Min       float64   `json:"min"`
+	Max       float64   `json:"max"`
+	Sum       float64   `json:"sum"`
+}
+
 // Legacy type aliases for backward compatibility
 type (
 	SystemInfoResponse             = AgentSystemResponse
@@ -132,3 +178,25 @@ type (
 	ProcessingRuleResponse         = AgentConfigRulesResponse
 	ProcessingRule                 = AgentConfigRules
 )
+
+// RetentionResponse represents a response for the retention status endpoint
+type RetentionResponse struct {
+	Code    string           `json:"code"`
+	Message string           `json:"message"`
+	Data    *RetentionStatus `json:"data,omitempty"`
+}
+
+type RetentionStatus struct {
+	Enabled  bool              `json:"enabled"`
+	Interval string            `json:"interval"`
+	DryRun   bool              `json:"dry_run"`
+	Policies []RetentionPolicy `json:"policies"`
+}
+
+// RetentionPolicy is the retention of one data class. Pending is only set when a preview was requested.
+type RetentionPolicy struct {
+	Class     string              `json:"class"`
+	Retention string              `json:"retention"` // "forever" when the class is kept
+	Pending   *int64              `json:"pending,omitempty"`
+	LastRun   *model.RetentionRun `json:"last_run,omitempty"`
+}
@@ -0,0 +1,17 @@
+package response
+
+import (
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+type AlertResponse struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Data    *model.Alert `json:"data,omitempty"`
+}
+
+type AlertListResponse struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Data    []model.Alert `json:"data"`
+}
@@ -0,0 +1,25 @@
+package response
+
+// ClientContext describes a context of the client config file
+type ClientContext struct {
+	Current               bool   `json:"current"`
+	Name                  string `json:"name"`
+	Server                string `json:"server"`
+	User                  string `json:"user,omitempty"`
+	CertificateAuthority  string `json:"certificate_authority,omitempty"`
+	InsecureSkipTLSVerify bool   `json:"insecure_skip_tls_verify"`
+	TokenStore            string `json:"token_store"`
+	TokenBackend          string `json:"token_backend"`
+}
+
+type ClientContextResponse struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Data    *ClientContext `json:"data,omitempty"`
+}
+
+type ClientContextListResponse struct {
+	Code     string          `json:"code"`
+	Message  string          `json:"message"`
+	Contexts []ClientContext `json:"contexts"`
+}
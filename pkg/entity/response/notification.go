@@ -0,0 +1,11 @@
+package response
+
+import (
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+type NotificationDeliveryListResponse struct {
+	Code    string                       `json:"code"`
+	Message string                       `json:"message"`
+	Data    []model.NotificationDelivery `json:"data"`
+}
@@ -0,0 +1,17 @@
+package response
+
+import (
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+type AlertRuleResponse struct {
+	Code    string           `json:"code"`
+	Message string           `json:"message"`
+	Data    *model.AlertRule `json:"data,omitempty"`
+}
+
+type AlertRuleListResponse struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Data    []model.AlertRule `json:"data"`
+}
@@ -0,0 +1,29 @@
+package response
+
+import (
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+type SilenceResponse struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Data    *model.Silence `json:"data,omitempty"`
+}
+
+type SilenceListResponse struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Data    []model.Silence `json:"data"`
+}
+
+type MaintenanceWindowResponse struct {
+	Code    string                   `json:"code"`
+	Message string                   `json:"message"`
+	Data    *model.MaintenanceWindow `json:"data,omitempty"`
+}
+
+type MaintenanceWindowListResponse struct {
+	Code    string                    `json:"code"`
+	Message string                    `json:"message"`
+	Data    []model.MaintenanceWindow `json:"data"`
+}
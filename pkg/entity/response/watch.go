@@ -0,0 +1,19 @@
+package response
+
+import (
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// Changes reported by GET /v1/agents/watch
+const (
+	WatchEventAdded    = "ADDED"
+	WatchEventModified = "MODIFIED"
+	WatchEventDeleted  = "DELETED"
+)
+
+// AgentWatchEvent is one change of the agent list. A watch starts with an ADDED event
+// for every agent; DELETED carries the last state seen of the agent.
+type AgentWatchEvent struct {
+	Type  string      `json:"type"`
+	Agent model.Agent `json:"agent"`
+}
@@ -1,10 +1,264 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"github.com/ryo-arima/circulator/pkg/server/usecase"
 )
 
+// Main runs the HTTP API, the Pulsar workers, the notification dispatcher, the rule evaluator,
+// the liveness check and the retention compactor under a supervisor until
+// SIGINT or SIGTERM, then shuts everything down gracefully
 func Main(conf config.BaseConfig) {
-	router := InitRouter(conf)
-	router.Run(":" + conf.YamlConfig.Application.Common.Port)
+	conf.Logger.INFO(config.SBM, "Starting Server")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	supervisor := NewSupervisor(conf)
+	supervisor.Add("http", func(ctx context.Context) error {
+		return runHTTPServer(ctx, conf)
+	})
+	supervisor.Add("pulsar", func(ctx context.Context) error {
+		return runPulsarWorkers(ctx, conf)
+	})
+	if len(conf.YamlConfig.Application.Server.Notifications.Channels) > 0 {
+		supervisor.Add("notifications", func(ctx context.Context) error {
+			return runNotificationDispatcher(ctx, conf)
+		})
+	}
+	if conf.YamlConfig.Application.Server.Rules.Enabled {
+		supervisor.Add("rules", func(ctx context.Context) error {
+			return runRuleEvaluator(ctx, conf)
+		})
+	}
+	if conf.YamlConfig.Application.Server.Liveness.Enabled {
+		supervisor.Add("liveness", func(ctx context.Context) error {
+			return runLivenessCheck(ctx, conf)
+		})
+	}
+	if conf.YamlConfig.Application.Server.Retention.Enabled {
+		supervisor.Add("retention", func(ctx context.Context) error {
+			return runRetention(ctx, conf)
+		})
+	}
+
+	supervisor.Run(ctx)
+	conf.Logger.INFO(config.SBSTOP, "Server stopped", nil)
+}
+
+// runHTTPServer serves the Gin router until ctx is cancelled, then drains in-flight requests
+func runHTTPServer(ctx context.Context, conf config.BaseConfig) error {
+	router, err := InitRouter(conf)
+	if err != nil {
+		return err
+	}
+	// Shutdown waits for handlers to return, so request contexts are cancelled with it to
+	// end the watch streams instead of holding up the drain until the timeout
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	srv := &http.Server{
+		Addr:        ":" + conf.YamlConfig.Application.Common.Port,
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return requestCtx },
+	}
+	srv.RegisterOnShutdown(cancelRequests)
+
+	errCh := make(chan error, 1)
+	go func() {
+		conf.Logger.INFO(config.SBHTTP, "HTTP server listening", map[string]interface{}{
+			"addr": srv.Addr,
+		})
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	conf.Logger.INFO(config.SBHSD, "HTTP server shutting down", nil)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout(conf))
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		conf.Logger.ERROR(config.SBERR, "HTTP server shutdown failed", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// runPulsarWorkers consumes agent reports, stores processed data and system metrics as
+// time series, records alerts, routes notifications to channels and relays the event
+// outbox over one Pulsar connection. The connection is
+// owned by this worker, so a restart reconnects from scratch.
+func runPulsarWorkers(ctx context.Context, conf config.BaseConfig) error {
+	conf.Logger.INFO(config.SBREP, "Agent report consumer starting", map[string]interface{}{
+		"pulsar_url": conf.YamlConfig.Pulsar.URL,
+	})
+
+	metricRepository, err := repository.NewMetricRepository(conf)
+	if err != nil {
+		return err
+	}
+
+	outboxRepository, err := repository.NewOutboxRepository(conf)
+	if err != nil {
+		return err
+	}
+
+	alertRepository, err := repository.NewAlertRepository(conf)
+	if err != nil {
+		return err
+	}
+
+	notificationRepository, err := repository.NewNotificationRepository(conf)
+	if err != nil {
+		return err
+	}
+
+	silenceRepository, err := repository.NewSilenceRepository(conf)
+	if err != nil {
+		return err
+	}
+
+	pulsarRepository, err := repository.NewPulsarRepository(conf, conf.YamlConfig.Pulsar.URL)
+	if err != nil {
+		return err
+	}
+	defer pulsarRepository.Close()
+
+	agentRepository := repository.NewAgentRepository(conf)
+	reportUsecase := usecase.NewReportUsecase(conf, agentRepository, metricRepository)
+	outboxUsecase := usecase.NewOutboxUsecase(conf, outboxRepository, pulsarRepository)
+	metricUsecase := usecase.NewMetricUsecase(conf, metricRepository)
+	alertUsecase := usecase.NewAlertUsecase(conf, alertRepository)
+	notificationUsecase := usecase.NewNotificationUsecase(conf, notificationRepository, silenceRepository, agentRepository, repository.NewChannelSender(conf))
+
+	conf.Logger.INFO(config.SBTS, "Time-series ingestion starting", map[string]interface{}{
+		"processed_topic": conf.YamlConfig.Pulsar.Topics.ProcessedSensorData,
+		"metrics_topic":   conf.YamlConfig.Pulsar.Topics.SystemMetrics,
+	})
+
+	// Any task failing stops the others, so the supervisor restarts them together
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tasks := []func() error{
+		func() error {
+			return pulsarRepository.ConsumeAgentReports(workerCtx, reportUsecase.HandleReport)
+		},
+		func() error {
+			return pulsarRepository.ConsumeProcessedData(workerCtx, metricUsecase.HandleProcessedData)
+		},
+		func() error {
+			return pulsarRepository.ConsumeSystemMetrics(workerCtx, metricUsecase.HandleSystemMetrics)
+		},
+		func() error {
+			return pulsarRepository.ConsumeAlerts(workerCtx, alertUsecase.HandleAlert)
+		},
+		func() error {
+			return outboxUsecase.Relay(workerCtx)
+		},
+	}
+	if len(conf.YamlConfig.Application.Server.Notifications.Channels) > 0 {
+		tasks = append(tasks,
+			func() error {
+				return pulsarRepository.ConsumeServerEvents(workerCtx, notificationUsecase.HandleServerEvent)
+			},
+			func() error {
+				return pulsarRepository.ConsumeNotifications(workerCtx, notificationUsecase.HandleNotification)
+			},
+		)
+	}
+
+	errCh := make(chan error, len(tasks))
+	for _, task := range tasks {
+		go func(task func() error) {
+			errCh <- task()
+		}(task)
+	}
+
+	err = <-errCh
+	cancel()
+	for range tasks[1:] {
+		<-errCh
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// runNotificationDispatcher sends routed notifications to their channels until ctx is cancelled
+func runNotificationDispatcher(ctx context.Context, conf config.BaseConfig) error {
+	conf.Logger.INFO(config.SBNT, "Notification dispatcher starting", nil)
+
+	notificationRepository, err := repository.NewNotificationRepository(conf)
+	if err != nil {
+		return err
+	}
+	// Dispatch only sends deliveries that were already routed, so no silence or agent lookups are needed
+	return usecase.NewNotificationUsecase(conf, notificationRepository, nil, nil, repository.NewChannelSender(conf)).Dispatch(ctx)
+}
+
+// runRuleEvaluator raises alerts from the server-side rules until ctx is cancelled
+func runRuleEvaluator(ctx context.Context, conf config.BaseConfig) error {
+	conf.Logger.INFO(config.SBRL, "Rule evaluator starting", nil)
+
+	ruleRepository, err := repository.NewRuleRepository(conf)
+	if err != nil {
+		return err
+	}
+	metricRepository, err := repository.NewMetricRepository(conf)
+	if err != nil {
+		return err
+	}
+	alertRepository, err := repository.NewAlertRepository(conf)
+	if err != nil {
+		return err
+	}
+	alertUsecase := usecase.NewAlertUsecase(conf, alertRepository)
+	return usecase.NewRuleUsecase(conf, ruleRepository, metricRepository, repository.NewAgentRepository(conf), alertUsecase).Run(ctx)
+}
+
+// runLivenessCheck marks agents offline after missed heartbeats until ctx is cancelled
+func runLivenessCheck(ctx context.Context, conf config.BaseConfig) error {
+	conf.Logger.INFO(config.SBLV, "Liveness check starting", nil)
+	return usecase.NewLivenessUsecase(conf, repository.NewAgentRepository(conf)).Run(ctx)
+}
+
+// runRetention deletes history past its configured retention until ctx is cancelled
+func runRetention(ctx context.Context, conf config.BaseConfig) error {
+	conf.Logger.INFO(config.SBRT, "Retention compactor starting", nil)
+
+	retentionRepository, err := repository.NewRetentionRepository(conf)
+	if err != nil {
+		return err
+	}
+	return usecase.NewRetentionUsecase(conf, retentionRepository).Run(ctx)
+}
+
+// shutdownTimeout returns how long in-flight work is given to finish on shutdown
+func shutdownTimeout(conf config.BaseConfig) time.Duration {
+	if conf.YamlConfig.Application.Server.ShutdownTimeout <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(conf.YamlConfig.Application.Server.ShutdownTimeout) * time.Second
 }
@@ -114,6 +114,10 @@ func (ctrl *agentController) UpdateAgent(c *gin.Context) {
 	}
 
 	agent := ctrl.agentUsecase.UpdateAgent(id, req)
+	if agent.UUID == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"agent": agent})
 }
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"github.com/ryo-arima/circulator/pkg/server/usecase"
+)
+
+type AlertController interface {
+	GetAlerts(c *gin.Context)
+	GetAlert(c *gin.Context)
+	AcknowledgeAlert(c *gin.Context)
+	ResolveAlert(c *gin.Context)
+}
+
+type alertController struct {
+	config       config.BaseConfig
+	alertUsecase usecase.AlertUsecase
+}
+
+func NewAlertController(conf config.BaseConfig, alertRepo repository.AlertRepository) AlertController {
+	return &alertController{
+		config:       conf,
+		alertUsecase: usecase.NewAlertUsecase(conf, alertRepo),
+	}
+}
+
+// GetAlerts serves GET /v1/alerts?status=&agent_uuid=&sensor_type=&severity=&limit=
+func (ctrl *alertController) GetAlerts(c *gin.Context) {
+	var req request.AlertListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.AlertListResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+	switch req.Status {
+	case "", model.AlertStatusOpen, model.AlertStatusAcknowledged, model.AlertStatusResolved:
+	default:
+		c.JSON(http.StatusBadRequest, response.AlertListResponse{
+			Code:    "BAD_REQUEST",
+			Message: "status must be open, acknowledged or resolved",
+		})
+		return
+	}
+
+	alerts, err := ctrl.alertUsecase.GetAlerts(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.AlertListResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.AlertListResponse{
+		Code:    "SUCCESS",
+		Message: "Alerts retrieved successfully",
+		Data:    alerts,
+	})
+}
+
+func (ctrl *alertController) GetAlert(c *gin.Context) {
+	alert, err := ctrl.alertUsecase.GetAlert(c.Param("id"))
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.AlertResponse{
+		Code:    "SUCCESS",
+		Message: "Alert retrieved successfully",
+		Data:    alert,
+	})
+}
+
+// AcknowledgeAlert serves POST /v1/alert/:id/ack; the assignee defaults to the caller
+func (ctrl *alertController) AcknowledgeAlert(c *gin.Context) {
+	var req request.AlertActionRequest
+	if !ctrl.bindAction(c, &req) {
+		return
+	}
+
+	alert, err := ctrl.alertUsecase.Acknowledge(c.Param("id"), c.GetString("username"), req)
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.AlertResponse{
+		Code:    "SUCCESS",
+		Message: "Alert acknowledged successfully",
+		Data:    alert,
+	})
+}
+
+// ResolveAlert serves POST /v1/alert/:id/resolve
+func (ctrl *alertController) ResolveAlert(c *gin.Context) {
+	var req request.AlertActionRequest
+	if !ctrl.bindAction(c, &req) {
+		return
+	}
+
+	alert, err := ctrl.alertUsecase.Resolve(c.Param("id"), c.GetString("username"), req)
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.AlertResponse{
+		Code:    "SUCCESS",
+		Message: "Alert resolved successfully",
+		Data:    alert,
+	})
+}
+
+// bindAction binds an optional JSON body
+func (ctrl *alertController) bindAction(c *gin.Context, req *request.AlertActionRequest) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, response.AlertResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+func (ctrl *alertController) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrAlertNotFound):
+		c.JSON(http.StatusNotFound, response.AlertResponse{
+			Code:    "NOT_FOUND",
+			Message: err.Error(),
+		})
+	case errors.Is(err, usecase.ErrInvalidAlertTransition):
+		c.JSON(http.StatusConflict, response.AlertResponse{
+			Code:    "CONFLICT",
+			Message: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, response.AlertResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"github.com/ryo-arima/circulator/pkg/server/usecase"
+)
+
+type MetricController interface {
+	GetAgentMetrics(c *gin.Context)
+}
+
+type metricController struct {
+	config        config.BaseConfig
+	metricUsecase usecase.MetricUsecase
+}
+
+func NewMetricController(conf config.BaseConfig, metricRepo repository.MetricRepository) MetricController {
+	return &metricController{
+		config:        conf,
+		metricUsecase: usecase.NewMetricUsecase(conf, metricRepo),
+	}
+}
+
+// GetAgentMetrics serves GET /v1/agent/:id/metrics?sensor_type=&name=&from=&to=&step=
+func (ctrl *metricController) GetAgentMetrics(c *gin.Context) {
+	var req request.AgentMetricsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.AgentMetricsResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	metrics, err := ctrl.metricUsecase.GetAgentMetrics(c.Param("id"), req)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidMetricQuery) {
+			c.JSON(http.StatusBadRequest, response.AgentMetricsResponse{
+				Code:    "BAD_REQUEST",
+				Message: err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, response.AgentMetricsResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.AgentMetricsResponse{
+		Code:    "SUCCESS",
+		Message: "Agent metrics retrieved successfully",
+		Data:    metrics,
+	})
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"github.com/ryo-arima/circulator/pkg/server/usecase"
+)
+
+type NotificationController interface {
+	GetDeliveries(c *gin.Context)
+}
+
+type notificationController struct {
+	config              config.BaseConfig
+	notificationUsecase usecase.NotificationUsecase
+}
+
+func NewNotificationController(conf config.BaseConfig, notificationRepo repository.NotificationRepository) NotificationController {
+	return &notificationController{
+		config:              conf,
+		notificationUsecase: usecase.NewNotificationUsecase(conf, notificationRepo, nil, nil, repository.NewChannelSender(conf)),
+	}
+}
+
+// GetDeliveries serves GET /v1/admin/notifications?status=&channel=&message_id=&limit=
+func (ctrl *notificationController) GetDeliveries(c *gin.Context) {
+	var req request.NotificationDeliveryListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.NotificationDeliveryListResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	deliveries, err := ctrl.notificationUsecase.GetDeliveries(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.NotificationDeliveryListResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.NotificationDeliveryListResponse{
+		Code:    "SUCCESS",
+		Message: "Notification deliveries retrieved successfully",
+		Data:    deliveries,
+	})
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"github.com/ryo-arima/circulator/pkg/server/usecase"
+)
+
+type RetentionController interface {
+	GetRetention(c *gin.Context)
+}
+
+type retentionController struct {
+	config           config.BaseConfig
+	retentionUsecase usecase.RetentionUsecase
+}
+
+func NewRetentionController(conf config.BaseConfig, retentionRepo repository.RetentionRepository) RetentionController {
+	return &retentionController{
+		config:           conf,
+		retentionUsecase: usecase.NewRetentionUsecase(conf, retentionRepo),
+	}
+}
+
+// GetRetention serves GET /v1/admin/retention?dry_run=true. With dry_run the response also
+// counts the rows each class would lose if the compactor ran now, without deleting anything.
+func (ctrl *retentionController) GetRetention(c *gin.Context) {
+	preview := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if preview, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, response.RetentionResponse{
+				Code:    "BAD_REQUEST",
+				Message: "dry_run must be true or false",
+			})
+			return
+		}
+	}
+
+	status, err := ctrl.retentionUsecase.GetStatus(preview)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.RetentionResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.RetentionResponse{
+		Code:    "SUCCESS",
+		Message: "Retention status retrieved successfully",
+		Data:    status,
+	})
+}
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"github.com/ryo-arima/circulator/pkg/server/usecase"
+)
+
+type RuleController interface {
+	GetRules(c *gin.Context)
+	GetRule(c *gin.Context)
+	CreateRule(c *gin.Context)
+	UpdateRule(c *gin.Context)
+	DeleteRule(c *gin.Context)
+}
+
+type ruleController struct {
+	config      config.BaseConfig
+	ruleUsecase usecase.RuleUsecase
+}
+
+func NewRuleController(conf config.BaseConfig, ruleRepo repository.RuleRepository) RuleController {
+	return &ruleController{
+		config: conf,
+		// Rules are evaluated by the rule evaluator worker, so no metric or alert access is needed here
+		ruleUsecase: usecase.NewRuleUsecase(conf, ruleRepo, nil, nil, nil),
+	}
+}
+
+// GetRules serves GET /v1/alert-rules
+func (ctrl *ruleController) GetRules(c *gin.Context) {
+	rules, err := ctrl.ruleUsecase.GetRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.AlertRuleListResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.AlertRuleListResponse{
+		Code:    "SUCCESS",
+		Message: "Alert rules retrieved successfully",
+		Data:    rules,
+	})
+}
+
+func (ctrl *ruleController) GetRule(c *gin.Context) {
+	rule, err := ctrl.ruleUsecase.GetRule(c.Param("id"))
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.AlertRuleResponse{
+		Code:    "SUCCESS",
+		Message: "Alert rule retrieved successfully",
+		Data:    rule,
+	})
+}
+
+// CreateRule serves POST /v1/alert-rule
+func (ctrl *ruleController) CreateRule(c *gin.Context) {
+	var req request.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.AlertRuleResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	rule, err := ctrl.ruleUsecase.CreateRule(c.GetString("username"), req)
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response.AlertRuleResponse{
+		Code:    "SUCCESS",
+		Message: "Alert rule created successfully",
+		Data:    rule,
+	})
+}
+
+// UpdateRule serves PUT /v1/alert-rule/:id, replacing the whole definition
+func (ctrl *ruleController) UpdateRule(c *gin.Context) {
+	var req request.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.AlertRuleResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	rule, err := ctrl.ruleUsecase.UpdateRule(c.Param("id"), req)
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.AlertRuleResponse{
+		Code:    "SUCCESS",
+		Message: "Alert rule updated successfully",
+		Data:    rule,
+	})
+}
+
+func (ctrl *ruleController) DeleteRule(c *gin.Context) {
+	if err := ctrl.ruleUsecase.DeleteRule(c.Param("id")); err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.AlertRuleResponse{
+		Code:    "SUCCESS",
+		Message: "Alert rule deleted successfully",
+	})
+}
+
+func (ctrl *ruleController) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, response.AlertRuleResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+	case errors.Is(err, usecase.ErrRuleNotFound):
+		c.JSON(http.StatusNotFound, response.AlertRuleResponse{
+			Code:    "NOT_FOUND",
+			Message: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, response.AlertRuleResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+	}
+}
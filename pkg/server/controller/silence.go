@@ -0,0 +1,175 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"github.com/ryo-arima/circulator/pkg/server/usecase"
+)
+
+type SilenceController interface {
+	GetSilences(c *gin.Context)
+	CreateSilence(c *gin.Context)
+	ExpireSilence(c *gin.Context)
+
+	GetMaintenanceWindows(c *gin.Context)
+	CreateMaintenanceWindow(c *gin.Context)
+	DeleteMaintenanceWindow(c *gin.Context)
+}
+
+type silenceController struct {
+	config         config.BaseConfig
+	silenceUsecase usecase.SilenceUsecase
+}
+
+func NewSilenceController(conf config.BaseConfig, silenceRepo repository.SilenceRepository) SilenceController {
+	return &silenceController{
+		config:         conf,
+		silenceUsecase: usecase.NewSilenceUsecase(conf, silenceRepo),
+	}
+}
+
+// GetSilences serves GET /v1/silences?all=; ended silences are only listed with all=true
+func (ctrl *silenceController) GetSilences(c *gin.Context) {
+	var req request.SilenceListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.SilenceListResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	silences, err := ctrl.silenceUsecase.GetSilences(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.SilenceListResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SilenceListResponse{
+		Code:    "SUCCESS",
+		Message: "Silences retrieved successfully",
+		Data:    silences,
+	})
+}
+
+// CreateSilence serves POST /v1/silence
+func (ctrl *silenceController) CreateSilence(c *gin.Context) {
+	var req request.SilenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.SilenceResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	silence, err := ctrl.silenceUsecase.CreateSilence(c.GetString("username"), req)
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response.SilenceResponse{
+		Code:    "SUCCESS",
+		Message: "Silence created successfully",
+		Data:    silence,
+	})
+}
+
+// ExpireSilence serves DELETE /v1/silence/:id. The silence is ended rather than removed.
+func (ctrl *silenceController) ExpireSilence(c *gin.Context) {
+	silence, err := ctrl.silenceUsecase.ExpireSilence(c.Param("id"))
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.SilenceResponse{
+		Code:    "SUCCESS",
+		Message: "Silence expired successfully",
+		Data:    silence,
+	})
+}
+
+func (ctrl *silenceController) GetMaintenanceWindows(c *gin.Context) {
+	windows, err := ctrl.silenceUsecase.GetMaintenanceWindows()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.MaintenanceWindowListResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.MaintenanceWindowListResponse{
+		Code:    "SUCCESS",
+		Message: "Maintenance windows retrieved successfully",
+		Data:    windows,
+	})
+}
+
+// CreateMaintenanceWindow serves POST /v1/maintenance-window
+func (ctrl *silenceController) CreateMaintenanceWindow(c *gin.Context) {
+	var req request.MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.MaintenanceWindowResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	window, err := ctrl.silenceUsecase.CreateMaintenanceWindow(c.GetString("username"), req)
+	if err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response.MaintenanceWindowResponse{
+		Code:    "SUCCESS",
+		Message: "Maintenance window created successfully",
+		Data:    window,
+	})
+}
+
+// DeleteMaintenanceWindow serves DELETE /v1/maintenance-window/:id
+func (ctrl *silenceController) DeleteMaintenanceWindow(c *gin.Context) {
+	if err := ctrl.silenceUsecase.DeleteMaintenanceWindow(c.Param("id")); err != nil {
+		ctrl.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response.MaintenanceWindowResponse{
+		Code:    "SUCCESS",
+		Message: "Maintenance window deleted successfully",
+	})
+}
+
+func (ctrl *silenceController) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrInvalidSilence):
+		c.JSON(http.StatusBadRequest, response.SilenceResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+	case errors.Is(err, usecase.ErrSilenceNotFound), errors.Is(err, usecase.ErrMaintenanceWindowNotFound):
+		c.JSON(http.StatusNotFound, response.SilenceResponse{
+			Code:    "NOT_FOUND",
+			Message: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, response.SilenceResponse{
+			Code:    "INTERNAL_ERROR",
+			Message: err.Error(),
+		})
+	}
+}
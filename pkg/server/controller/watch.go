@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"github.com/ryo-arima/circulator/pkg/server/usecase"
+)
+
+type WatchController interface {
+	WatchAgents(c *gin.Context)
+	TailEvents(c *gin.Context)
+}
+
+type watchController struct {
+	config       config.BaseConfig
+	watchUsecase usecase.WatchUsecase
+}
+
+func NewWatchController(conf config.BaseConfig, agentRepo repository.AgentRepository, outboxRepo repository.OutboxRepository) WatchController {
+	return &watchController{
+		config:       conf,
+		watchUsecase: usecase.NewWatchUsecase(conf, agentRepo, outboxRepo),
+	}
+}
+
+// WatchAgents serves GET /v1/agents/watch as server-sent events named after the change,
+// ADDED, MODIFIED or DELETED, whose data is a response.AgentWatchEvent
+func (ctrl *watchController) WatchAgents(c *gin.Context) {
+	stream := ctrl.openStream(c)
+	defer stream.close()
+
+	err := ctrl.watchUsecase.WatchAgents(c.Request.Context(), func(event response.AgentWatchEvent) error {
+		return stream.send("", event.Type, event)
+	})
+	if err != nil {
+		stream.fail(err)
+	}
+}
+
+// TailEvents serves GET /v1/events?type=&agent_uuid=&since= as server-sent events named
+// after the event type, whose data is a model.ServerEvent. Each carries its outbox id, so
+// a client reconnecting with Last-Event-ID resumes where it stopped.
+func (ctrl *watchController) TailEvents(c *gin.Context) {
+	var req request.EventListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.CommonResponse{
+			Code:    "BAD_REQUEST",
+			Message: err.Error(),
+		})
+		return
+	}
+	cursor, err := ctrl.watchUsecase.EventCursor(req, c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+		if errors.Is(err, usecase.ErrInvalidWatchQuery) {
+			status, code = http.StatusBadRequest, "BAD_REQUEST"
+		}
+		c.JSON(status, response.CommonResponse{
+			Code:    code,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	stream := ctrl.openStream(c)
+	defer stream.close()
+
+	err = ctrl.watchUsecase.TailEvents(c.Request.Context(), req, cursor, func(id uint, event *model.ServerEvent) error {
+		return stream.send(strconv.FormatUint(uint64(id), 10), event.Type, event)
+	})
+	if err != nil {
+		stream.fail(err)
+	}
+}
+
+// eventStream writes server-sent events to one client. Writes are serialized because a
+// goroutine sends keep-alive comments while the stream is idle.
+type eventStream struct {
+	mu   sync.Mutex
+	c    *gin.Context
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (ctrl *watchController) openStream(c *gin.Context) *eventStream {
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no") // nginx would otherwise hold events back
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	s := &eventStream{c: c, done: make(chan struct{})}
+	keepAlive := time.Duration(ctrl.config.YamlConfig.Application.Server.Watch.KeepAlive) * time.Second
+	if keepAlive > 0 {
+		s.wg.Add(1)
+		go s.keepAlive(keepAlive)
+	}
+	return s
+}
+
+func (s *eventStream) keepAlive(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.c.Request.Context().Done():
+			return
+		case <-ticker.C:
+			s.write(": keep-alive\n\n")
+		}
+	}
+}
+
+func (s *eventStream) send(id, name string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	event := fmt.Sprintf("event: %s\ndata: %s\n\n", name, payload)
+	if id != "" {
+		event = "id: " + id + "\n" + event
+	}
+	return s.write(event)
+}
+
+// fail reports the error that ended the stream, after which the client reconnects
+func (s *eventStream) fail(err error) {
+	s.send("", "error", response.CommonResponse{Code: "INTERNAL_ERROR", Message: err.Error()})
+}
+
+func (s *eventStream) write(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.c.Writer.WriteString(text); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+// close stops the keep-alive goroutine, which must not write after the handler returns
+func (s *eventStream) close() {
+	close(s.done)
+	s.wg.Wait()
+}
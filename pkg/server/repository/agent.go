@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 
@@ -13,11 +14,24 @@ import (
 
 type AgentRepository interface {
 	GetAgents() []model.Agent
+	// ListAgents is GetAgents reporting database errors
+	ListAgents() ([]model.Agent, error)
 	GetAgentByUUID(uuid string) model.Agent
 	CountAgents() int64
-	CreateAgent(req request.AgentRequest) model.Agent
-	UpdateAgent(uuid string, req request.AgentUpdateRequest) model.Agent
+	CreateAgent(req request.AgentRequest) (model.Agent, error)
+	UpdateAgent(uuid string, req request.AgentUpdateRequest) (model.Agent, error)
 	DeleteAgent(uuid string) *gorm.DB
+	UpdateAgentStatus(uuid string, status string, heartbeatAt time.Time) (string, error)
+	// GetStaleAgents returns the agents not yet offline whose last heartbeat is before the time
+	GetStaleAgents(before time.Time) ([]model.Agent, error)
+	// MarkAgentOffline sets the agent offline unless it is already offline or sent a heartbeat
+	// since before, reporting whether it changed
+	MarkAgentOffline(uuid string, before time.Time) (bool, error)
+
+	// Transaction runs fn with repositories bound to a single database transaction
+	Transaction(fn func(repo AgentRepository, outbox OutboxRepository) error) error
+	// TransactionContext is Transaction joining the transaction carried by ctx, if any
+	TransactionContext(ctx context.Context, fn func(repo AgentRepository, outbox OutboxRepository) error) error
 
 	// Agent Info operations
 	GetAgentInfo(agentUUID string) (*model.AgentInfo, error)
@@ -60,6 +74,14 @@ func (r *agentRepository) GetAgents() []model.Agent {
 	return agents
 }
 
+func (r *agentRepository) ListAgents() ([]model.Agent, error) {
+	var agents []model.Agent
+	if err := r.BaseConfig.DBConnection.Order("id").Find(&agents).Error; err != nil {
+		return nil, err
+	}
+	return agents, nil
+}
+
 func (r *agentRepository) GetAgentByUUID(uuid string) model.Agent {
 	var agent model.Agent
 	r.BaseConfig.DBConnection.Where("uuid = ?", uuid).First(&agent)
@@ -72,7 +94,7 @@ func (r *agentRepository) CountAgents() int64 {
 	return count
 }
 
-func (r *agentRepository) CreateAgent(req request.AgentRequest) model.Agent {
+func (r *agentRepository) CreateAgent(req request.AgentRequest) (model.Agent, error) {
 	// Populate model from request via JSON to respect json tags, and assign UUID
 	agent := model.Agent{}
 	// Assign UUID (models use `UUID` field)
@@ -81,30 +103,94 @@ func (r *agentRepository) CreateAgent(req request.AgentRequest) model.Agent {
 	if b, err := json.Marshal(req); err == nil {
 		_ = json.Unmarshal(b, &agent)
 	}
-	r.BaseConfig.DBConnection.Create(&agent)
-	return agent
+	if err := r.BaseConfig.DBConnection.Create(&agent).Error; err != nil {
+		return model.Agent{}, err
+	}
+	return agent, nil
 }
 
-func (r *agentRepository) UpdateAgent(uuid string, req request.AgentUpdateRequest) model.Agent {
+func (r *agentRepository) UpdateAgent(uuid string, req request.AgentUpdateRequest) (model.Agent, error) {
 	var agent model.Agent
-	r.BaseConfig.DBConnection.Where("uuid = ?", uuid).First(&agent)
+	if err := r.BaseConfig.DBConnection.Where("uuid = ?", uuid).First(&agent).Error; err != nil {
+		return model.Agent{}, err
+	}
 	// Merge request fields using JSON (partial/overwrite semantics)
 	if b, err := json.Marshal(req); err == nil {
 		_ = json.Unmarshal(b, &agent)
 	}
-	r.BaseConfig.DBConnection.Save(&agent)
-	return agent
+	if err := r.BaseConfig.DBConnection.Save(&agent).Error; err != nil {
+		return model.Agent{}, err
+	}
+	return agent, nil
 }
 
 func (r *agentRepository) DeleteAgent(uuid string) *gorm.DB {
 	return r.BaseConfig.DBConnection.Where("uuid = ?", uuid).Delete(&model.Agent{})
 }
 
+// UpdateAgentStatus records a heartbeat and, when status is non-empty, the agent's status.
+// It returns the status held before the update.
+func (r *agentRepository) UpdateAgentStatus(uuid string, status string, heartbeatAt time.Time) (string, error) {
+	var agent model.Agent
+	result := r.BaseConfig.DBConnection.Where("uuid = ?", uuid).First(&agent)
+	if result.Error != nil {
+		return "", result.Error
+	}
+
+	updates := map[string]interface{}{
+		"heartbeat_at": heartbeatAt,
+	}
+	if status != "" {
+		updates["status"] = status
+	}
+
+	result = r.BaseConfig.DBConnection.Model(&model.Agent{}).Where("uuid = ?", uuid).Updates(updates)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return agent.Status, nil
+}
+
+func (r *agentRepository) GetStaleAgents(before time.Time) ([]model.Agent, error) {
+	var agents []model.Agent
+	result := r.BaseConfig.DBConnection.
+		Where("status <> ? AND heartbeat_at < ?", model.AgentStatusOffline, before).
+		Find(&agents)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return agents, nil
+}
+
+// MarkAgentOffline repeats the staleness condition in the update, so a heartbeat that
+// arrives after GetStaleAgents keeps the agent online
+func (r *agentRepository) MarkAgentOffline(uuid string, before time.Time) (bool, error) {
+	result := r.BaseConfig.DBConnection.Model(&model.Agent{}).
+		Where("uuid = ? AND status <> ? AND heartbeat_at < ?", uuid, model.AgentStatusOffline, before).
+		Update("status", model.AgentStatusOffline)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *agentRepository) Transaction(fn func(repo AgentRepository, outbox OutboxRepository) error) error {
+	return r.TransactionContext(context.Background(), fn)
+}
+
+func (r *agentRepository) TransactionContext(ctx context.Context, fn func(repo AgentRepository, outbox OutboxRepository) error) error {
+	return dbFor(ctx, r.BaseConfig.DBConnection).Transaction(func(tx *gorm.DB) error {
+		txConfig := r.BaseConfig
+		txConfig.DBConnection = tx
+		return fn(&agentRepository{BaseConfig: txConfig}, &outboxRepository{BaseConfig: txConfig})
+	})
+}
+
 // ============ AGENT INFO OPERATIONS ============
 
 func (r *agentRepository) GetAgentInfo(agentUUID string) (*model.AgentInfo, error) {
 	var agentInfo model.AgentInfo
-	result := r.BaseConfig.DBConnection.Where("uuid = ? OR agent_uuid = ?", agentUUID, agentUUID).First(&agentInfo)
+	result := r.BaseConfig.DBConnection.Where("uuid = ?", agentUUID).First(&agentInfo)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -249,11 +335,15 @@ func (r *agentRepository) CreateStreamProcessingConfig(req request.AgentConfigRe
 			Enabled:  ruleReq.Enabled,
 			Params:   ruleReq.Params,
 		}
-		r.BaseConfig.DBConnection.Create(rule)
+		if err := r.BaseConfig.DBConnection.Create(rule).Error; err != nil {
+			return nil, err
+		}
 	}
 
 	// Reload with rules
-	r.BaseConfig.DBConnection.Where("id = ?", config.ID).Preload("ProcessingRules").First(config)
+	if err := r.BaseConfig.DBConnection.Where("id = ?", config.ID).Preload("ProcessingRules").First(config).Error; err != nil {
+		return nil, err
+	}
 	return config, nil
 }
 
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AlertRepository defines the interface for the alert lifecycle store
+type AlertRepository interface {
+	// RecordAlert folds an AlertData into the unresolved alert of its key, opening a new
+	// alert when there is none. opened reports whether a new alert was created.
+	RecordAlert(data *model.AlertData) (alert *model.Alert, opened bool, err error)
+	GetAlerts(req request.AlertListRequest) ([]model.Alert, error)
+	GetAlert(alertUUID string) (*model.Alert, error)
+	// UpdateAlert saves the lifecycle fields of alert and appends note
+	UpdateAlert(alert *model.Alert, note *model.AlertNote) error
+
+	// Transaction runs fn with repositories bound to a single database transaction
+	Transaction(fn func(repo AlertRepository, outbox OutboxRepository) error) error
+	// TransactionContext is Transaction joining the transaction carried by ctx, if any
+	TransactionContext(ctx context.Context, fn func(repo AlertRepository, outbox OutboxRepository) error) error
+}
+
+type alertRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// NewAlertRepository creates a new alert repository and ensures its tables exist
+func NewAlertRepository(conf config.BaseConfig) (AlertRepository, error) {
+	if conf.DBConnection == nil {
+		return nil, fmt.Errorf("alert repository requires a database connection")
+	}
+
+	if err := conf.DBConnection.AutoMigrate(&model.Alert{}, &model.AlertNote{}); err != nil {
+		conf.Logger.ERROR(config.SRALERR, "Failed to migrate alert tables", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to migrate alerts: %w", err)
+	}
+
+	conf.Logger.INFO(config.SRALINIT, "Server alert repository initialized", nil)
+
+	return &alertRepository{
+		BaseConfig: conf,
+	}, nil
+}
+
+// RecordAlert locks the unresolved alert of the key, so concurrent consumers count each
+// repeat once. Two consumers opening the same key race on the OpenKey unique index; the
+// loser fails and its message is redelivered onto the alert the winner created.
+func (r *alertRepository) RecordAlert(data *model.AlertData) (*model.Alert, bool, error) {
+	key := model.AlertKey(data.AgentUUID, data.SensorType, data.Rule)
+	seenAt := data.Timestamp
+	if seenAt.IsZero() {
+		seenAt = time.Now()
+	}
+
+	var alert model.Alert
+	opened := false
+	err := r.BaseConfig.DBConnection.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("open_key = ?", key).First(&alert).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			opened = true
+			alert = model.Alert{
+				UUID:        uuid.New().String(),
+				OpenKey:     &key,
+				AgentUUID:   data.AgentUUID,
+				SensorType:  data.SensorType,
+				Rule:        data.Rule,
+				Severity:    data.Severity,
+				Status:      model.AlertStatusOpen,
+				Count:       1,
+				Message:     data.Message,
+				LastValue:   data.ProcessedValue,
+				Threshold:   data.Threshold,
+				FirstSeenAt: seenAt,
+				LastSeenAt:  seenAt,
+			}
+			return tx.Create(&alert).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		alert.Count++
+		alert.Message = data.Message
+		alert.LastValue = data.ProcessedValue
+		alert.Threshold = data.Threshold
+		if seenAt.After(alert.LastSeenAt) {
+			alert.LastSeenAt = seenAt
+		}
+		if model.AlertSeverityRank[data.Severity] > model.AlertSeverityRank[alert.Severity] {
+			alert.Severity = data.Severity
+		}
+		return tx.Model(&alert).Select("count", "message", "last_value", "threshold", "last_seen_at", "severity").Updates(&alert).Error
+	})
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRALERR, "Failed to record alert", map[string]interface{}{
+			"error":      err.Error(),
+			"agent_uuid": data.AgentUUID,
+			"key":        key,
+		})
+		return nil, false, fmt.Errorf("failed to record alert: %w", err)
+	}
+
+	r.BaseConfig.Logger.DEBUG(config.SRALREC, "Recorded alert", map[string]interface{}{
+		"alert_uuid": alert.UUID,
+		"count":      alert.Count,
+		"opened":     opened,
+	})
+	return &alert, opened, nil
+}
+
+// GetAlerts returns the most recently seen alerts first. Without a status it lists the
+// open and acknowledged alerts.
+func (r *alertRepository) GetAlerts(req request.AlertListRequest) ([]model.Alert, error) {
+	query := r.BaseConfig.DBConnection.Model(&model.Alert{})
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	} else {
+		query = query.Where("status <> ?", model.AlertStatusResolved)
+	}
+	if req.AgentUUID != "" {
+		query = query.Where("agent_uuid = ?", req.AgentUUID)
+	}
+	if req.SensorType != "" {
+		query = query.Where("sensor_type = ?", req.SensorType)
+	}
+	if req.Severity != "" {
+		query = query.Where("severity = ?", req.Severity)
+	}
+	if req.Limit > 0 {
+		query = query.Limit(req.Limit)
+	}
+
+	var alerts []model.Alert
+	if err := query.Order("last_seen_at DESC").Find(&alerts).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRALERR, "Failed to query alerts", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+// GetAlert returns the alert with its notes, oldest note first
+func (r *alertRepository) GetAlert(alertUUID string) (*model.Alert, error) {
+	var alert model.Alert
+	err := r.BaseConfig.DBConnection.
+		Preload("Notes", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Where("uuid = ?", alertUUID).
+		First(&alert).Error
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (r *alertRepository) UpdateAlert(alert *model.Alert, note *model.AlertNote) error {
+	err := r.BaseConfig.DBConnection.Model(alert).
+		Select("open_key", "status", "assignee", "acknowledged_at", "resolved_at").
+		Updates(alert).Error
+	if err == nil && note != nil {
+		note.AlertID = alert.ID
+		err = r.BaseConfig.DBConnection.Create(note).Error
+	}
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRALERR, "Failed to update alert", map[string]interface{}{
+			"error":      err.Error(),
+			"alert_uuid": alert.UUID,
+		})
+		return fmt.Errorf("failed to update alert: %w", err)
+	}
+	return nil
+}
+
+func (r *alertRepository) Transaction(fn func(repo AlertRepository, outbox OutboxRepository) error) error {
+	return r.TransactionContext(context.Background(), fn)
+}
+
+func (r *alertRepository) TransactionContext(ctx context.Context, fn func(repo AlertRepository, outbox OutboxRepository) error) error {
+	return dbFor(ctx, r.BaseConfig.DBConnection).Transaction(func(tx *gorm.DB) error {
+		txConfig := r.BaseConfig
+		txConfig.DBConnection = tx
+		return fn(&alertRepository{BaseConfig: txConfig}, &outboxRepository{BaseConfig: txConfig})
+	})
+}
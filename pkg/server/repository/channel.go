@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// Headers set on webhook deliveries. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the channel secret, prefixed with "sha256=".
+const (
+	WebhookHeaderDelivery  = "X-Circulator-Delivery"
+	WebhookHeaderEvent     = "X-Circulator-Event"
+	WebhookHeaderTimestamp = "X-Circulator-Timestamp"
+	WebhookHeaderSignature = "X-Circulator-Signature"
+)
+
+// ChannelSender delivers a rendered notification to an outbound channel
+type ChannelSender interface {
+	Send(ctx context.Context, channel config.NotificationChannel, delivery *model.NotificationDelivery) error
+}
+
+type channelSender struct {
+	config config.BaseConfig
+	client *http.Client
+	// tlsConfig is the base of the STARTTLS config; nil verifies against the system roots
+	tlsConfig *tls.Config
+}
+
+// NewChannelSender creates a sender for webhook, Slack-compatible webhook and SMTP channels
+func NewChannelSender(conf config.BaseConfig) ChannelSender {
+	return &channelSender{
+		config: conf,
+		client: &http.Client{},
+	}
+}
+
+func (s *channelSender) Send(ctx context.Context, channel config.NotificationChannel, delivery *model.NotificationDelivery) error {
+	switch channel.Type {
+	case model.ChannelTypeWebhook:
+		return s.post(ctx, channel, delivery, true)
+	case model.ChannelTypeSlack:
+		return s.post(ctx, channel, delivery, false)
+	case model.ChannelTypeEmail:
+		return s.sendMail(ctx, channel, delivery)
+	}
+	return fmt.Errorf("unknown channel type %q", channel.Type)
+}
+
+// post sends the JSON body, signing it when the channel has a secret
+func (s *channelSender) post(ctx context.Context, channel config.NotificationChannel, delivery *model.NotificationDelivery, signed bool) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.URL, strings.NewReader(delivery.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signed {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookHeaderDelivery, delivery.UUID)
+		req.Header.Set(WebhookHeaderEvent, delivery.Event)
+		req.Header.Set(WebhookHeaderTimestamp, timestamp)
+		if channel.Secret != "" {
+			req.Header.Set(WebhookHeaderSignature, SignWebhook(channel.Secret, timestamp, []byte(delivery.Body)))
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned status %d: %s", channel.Type, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// SignWebhook returns the X-Circulator-Signature value for a body sent at timestamp
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendMail delivers a plain text mail, using STARTTLS when the server offers it
+func (s *channelSender) sendMail(ctx context.Context, channel config.NotificationChannel, delivery *model.NotificationDelivery) error {
+	smtpConfig := s.config.YamlConfig.Application.Server.Notifications.SMTP
+	if smtpConfig.Host == "" || len(channel.To) == 0 {
+		return fmt.Errorf("email channel requires smtp.host and recipients")
+	}
+	port := smtpConfig.Port
+	if port == 0 {
+		port = 25
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(smtpConfig.Host, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, smtpConfig.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		tlsConfig := &tls.Config{}
+		if s.tlsConfig != nil {
+			tlsConfig = s.tlsConfig.Clone()
+		}
+		tlsConfig.ServerName = smtpConfig.Host
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if smtpConfig.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpConfig.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(smtpConfig.From); err != nil {
+		return err
+	}
+	for _, to := range channel.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(buildMail(smtpConfig.From, channel.To, delivery)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func buildMail(from string, to []string, delivery *model.NotificationDelivery) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", delivery.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@circulator>\r\n", delivery.UUID)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	body := strings.ReplaceAll(delivery.Body, "\r\n", "\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	msg.WriteString("\r\n")
+	return msg.Bytes()
+}
@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+func newTestSender() *channelSender {
+	conf := config.BaseConfig{}
+	conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+	return NewChannelSender(conf).(*channelSender)
+}
+
+// capturedRequest is what a test HTTP endpoint received
+type capturedRequest struct {
+	header http.Header
+	body   string
+}
+
+// newCaptureServer answers every request with status and records it
+func newCaptureServer(t *testing.T, status int) (*httptest.Server, <-chan capturedRequest) {
+	t.Helper()
+	requests := make(chan capturedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- capturedRequest{header: r.Header.Clone(), body: string(body)}
+		w.WriteHeader(status)
+		if status >= 300 {
+			io.WriteString(w, "upstream unavailable\n")
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestSendWebhook(t *testing.T) {
+	delivery := &model.NotificationDelivery{
+		UUID:  "delivery-1",
+		Event: model.ServerEventAlertOpened,
+		Body:  `{"id":"event-1","title":"Alert opened: temperature"}`,
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{name: "signed", secret: "change-me"},
+		{name: "unsigned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newCaptureServer(t, http.StatusNoContent)
+			channel := config.NotificationChannel{Name: "ops", Type: model.ChannelTypeWebhook, URL: server.URL, Secret: tt.secret}
+
+			if err := newTestSender().Send(context.Background(), channel, delivery); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			got := <-requests
+
+			if got.body != delivery.Body {
+				t.Errorf("body = %q, want %q", got.body, delivery.Body)
+			}
+			if ct := got.header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if id := got.header.Get(WebhookHeaderDelivery); id != delivery.UUID {
+				t.Errorf("%s = %q, want %q", WebhookHeaderDelivery, id, delivery.UUID)
+			}
+			if event := got.header.Get(WebhookHeaderEvent); event != delivery.Event {
+				t.Errorf("%s = %q, want %q", WebhookHeaderEvent, event, delivery.Event)
+			}
+			timestamp := got.header.Get(WebhookHeaderTimestamp)
+			if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+				t.Errorf("%s = %q, want the current unix time", WebhookHeaderTimestamp, timestamp)
+			}
+
+			signature := got.header.Get(WebhookHeaderSignature)
+			if tt.secret == "" {
+				if signature != "" {
+					t.Errorf("%s = %q, want none without a secret", WebhookHeaderSignature, signature)
+				}
+				return
+			}
+			mac := hmac.New(sha256.New, []byte(tt.secret))
+			mac.Write([]byte(timestamp + "." + got.body))
+			if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+				t.Errorf("%s = %q, want %q", WebhookHeaderSignature, signature, want)
+			}
+		})
+	}
+}
+
+func TestSendSlack(t *testing.T) {
+	server, requests := newCaptureServer(t, http.StatusOK)
+	channel := config.NotificationChannel{Name: "plant-a", Type: model.ChannelTypeSlack, URL: server.URL, Secret: "ignored"}
+	delivery := &model.NotificationDelivery{UUID: "delivery-1", Body: `{"text":"*[high] Alert opened*"}`}
+
+	if err := newTestSender().Send(context.Background(), channel, delivery); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	got := <-requests
+
+	if got.body != delivery.Body {
+		t.Errorf("body = %q, want %q", got.body, delivery.Body)
+	}
+	for _, header := range []string{WebhookHeaderDelivery, WebhookHeaderEvent, WebhookHeaderTimestamp, WebhookHeaderSignature} {
+		if value := got.header.Get(header); value != "" {
+			t.Errorf("%s = %q, want none on Slack deliveries", header, value)
+		}
+	}
+}
+
+func TestSendHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "server error", status: http.StatusServiceUnavailable, wantErr: "webhook returned status 503: upstream unavailable"},
+		{name: "client error", status: http.StatusNotFound, wantErr: "webhook returned status 404: upstream unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newCaptureServer(t, tt.status)
+			channel := config.NotificationChannel{Name: "ops", Type: model.ChannelTypeWebhook, URL: server.URL}
+
+			err := newTestSender().Send(context.Background(), channel, &model.NotificationDelivery{Body: "{}"})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Send() error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Send() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// smtpSession is what the stub SMTP server received in one session
+type smtpSession struct {
+	tls  bool
+	from string
+	to   []string
+	data string
+}
+
+// serveSMTP answers one SMTP session on listener, offering STARTTLS when tlsConfig is set
+func serveSMTP(t *testing.T, listener net.Listener, tlsConfig *tls.Config) <-chan smtpSession {
+	t.Helper()
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		defer close(sessions)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		var session smtpSession
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost ESMTP stub")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				if tlsConfig != nil && !session.tls {
+					text.PrintfLine("250-localhost")
+					text.PrintfLine("250 STARTTLS")
+				} else {
+					text.PrintfLine("250 localhost")
+				}
+			case "STARTTLS":
+				text.PrintfLine("220 ready to start TLS")
+				tlsConn := tls.Server(conn, tlsConfig)
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				session.tls = true
+				text = textproto.NewConn(tlsConn)
+			case "MAIL":
+				session.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+				text.PrintfLine("250 ok")
+			case "RCPT":
+				session.to = append(session.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+				text.PrintfLine("250 ok")
+			case "DATA":
+				text.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+				data, err := io.ReadAll(bufio.NewReader(text.DotReader()))
+				if err != nil {
+					return
+				}
+				session.data = string(data)
+				text.PrintfLine("250 queued")
+			case "QUIT":
+				text.PrintfLine("221 bye")
+				sessions <- session
+				return
+			default:
+				text.PrintfLine("502 unsupported")
+			}
+		}
+	}()
+	return sessions
+}
+
+func TestSendMail(t *testing.T) {
+	// httptest's certificate is valid for 127.0.0.1, which the sender uses as ServerName
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	tlsServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+
+	tests := []struct {
+		name     string
+		starttls bool
+	}{
+		{name: "plain"},
+		{name: "starttls", starttls: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer listener.Close()
+			var serverTLS *tls.Config
+			if tt.starttls {
+				serverTLS = tlsServer.TLS
+			}
+			sessions := serveSMTP(t, listener, serverTLS)
+
+			sender := newTestSender()
+			sender.tlsConfig = &tls.Config{RootCAs: roots}
+			port := listener.Addr().(*net.TCPAddr).Port
+			sender.config.YamlConfig.Application.Server.Notifications.SMTP = config.SMTP{
+				Host: "127.0.0.1",
+				Port: port,
+				From: "circulator@example.com",
+			}
+			channel := config.NotificationChannel{Name: "oncall", Type: model.ChannelTypeEmail, To: []string{"a@example.com", "b@example.com"}}
+			delivery := &model.NotificationDelivery{
+				UUID:    "delivery-1",
+				Subject: "[critical] Alert opened: temperature",
+				Body:    "Alert opened\n\nagent_uuid: agent-1\n",
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := sender.Send(ctx, channel, delivery); err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+
+			session, ok := <-sessions
+			if !ok {
+				t.Fatal("SMTP session did not complete")
+			}
+			if session.tls != tt.starttls {
+				t.Errorf("tls = %v, want %v", session.tls, tt.starttls)
+			}
+			if session.from != "circulator@example.com" {
+				t.Errorf("from = %q", session.from)
+			}
+			if strings.Join(session.to, ",") != "a@example.com,b@example.com" {
+				t.Errorf("to = %v", session.to)
+			}
+			for _, want := range []string{
+				"From: circulator@example.com\n",
+				"To: a@example.com, b@example.com\n",
+				"Subject: [critical] Alert opened: temperature\n",
+				"Message-ID: <delivery-1@circulator>\n",
+				"Content-Type: text/plain; charset=utf-8\n",
+				"\nAlert opened\n\nagent_uuid: agent-1\n",
+			} {
+				if !strings.Contains(session.data, want) {
+					t.Errorf("mail does not contain %q:\n%s", want, session.data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"gorm.io/gorm"
+)
+
+// dedupPurgeInterval bounds how often expired keys are deleted opportunistically
+const dedupPurgeInterval = time.Minute
+
+// DedupRepository defines the interface for the server's MySQL-backed idempotency store
+type DedupRepository interface {
+	// ProcessOnce records key as processed and runs fn in the same transaction, so the
+	// message's effects and its key commit or roll back together. It returns false without
+	// running fn when key was already processed and has not expired.
+	ProcessOnce(ctx context.Context, key, consumer string, fn func(ctx context.Context) error) (bool, error)
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+type txKey struct{}
+
+// withTx returns a copy of ctx carrying tx
+func withTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// dbFor returns the transaction carried by ctx, so repositories join the transaction of
+// ProcessOnce, or db outside of one
+func dbFor(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return db
+}
+
+type dedupRepository struct {
+	BaseConfig config.BaseConfig
+	ttl        time.Duration
+	mu         sync.Mutex
+	lastPurge  time.Time
+}
+
+// NewDedupRepository creates a new dedup repository and ensures its table exists
+func NewDedupRepository(conf config.BaseConfig) (DedupRepository, error) {
+	if conf.DBConnection == nil {
+		return nil, fmt.Errorf("dedup repository requires a database connection")
+	}
+
+	if err := conf.DBConnection.AutoMigrate(&model.ProcessedMessage{}); err != nil {
+		conf.Logger.ERROR(config.SRDERR, "Failed to migrate processed messages table", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to migrate processed messages: %w", err)
+	}
+
+	repo := &dedupRepository{
+		BaseConfig: conf,
+		ttl:        conf.YamlConfig.Pulsar.GetDedupTTL(),
+		lastPurge:  time.Now(),
+	}
+
+	conf.Logger.INFO(config.SRDINIT, "Server dedup repository initialized", map[string]interface{}{
+		"ttl": repo.ttl.String(),
+	})
+
+	return repo, nil
+}
+
+func (r *dedupRepository) ProcessOnce(ctx context.Context, key, consumer string, fn func(ctx context.Context) error) (bool, error) {
+	now := time.Now()
+	record := model.ProcessedMessage{
+		Key:         key,
+		Consumer:    consumer,
+		ProcessedAt: now,
+		ExpiresAt:   now.Add(r.ttl),
+	}
+
+	processed := true
+	var applyErr error
+	err := r.BaseConfig.DBConnection.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// An expired key no longer counts as processed
+		if err := tx.Where("`key` = ? AND expires_at <= ?", key, now).Delete(&model.ProcessedMessage{}).Error; err != nil {
+			return err
+		}
+		// The unique key makes a concurrent consumer of the same message wait here until
+		// this transaction ends, then fail with a duplicate key if it committed
+		if err := tx.Create(&record).Error; err != nil {
+			if isDuplicateKey(tx, err) {
+				processed = false
+				return nil
+			}
+			return err
+		}
+		applyErr = fn(withTx(ctx, tx))
+		return applyErr
+	})
+	if applyErr != nil {
+		// The caller reports the failure of its own handler
+		return false, applyErr
+	}
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRDERR, "Failed to mark idempotency key processed", map[string]interface{}{
+			"error": err.Error(),
+			"key":   key,
+		})
+		return false, fmt.Errorf("failed to mark idempotency key processed: %w", err)
+	}
+	if !processed {
+		return false, nil
+	}
+
+	r.BaseConfig.Logger.DEBUG(config.SRDMARK, "Marked idempotency key processed", map[string]interface{}{
+		"key":      key,
+		"consumer": consumer,
+	})
+
+	r.mu.Lock()
+	due := now.Sub(r.lastPurge) >= dedupPurgeInterval
+	if due {
+		r.lastPurge = now
+	}
+	r.mu.Unlock()
+
+	// Purge failures are logged but must not fail the message that triggered them
+	if due {
+		r.PurgeExpired(ctx)
+	}
+
+	return true, nil
+}
+
+// isDuplicateKey reports whether err is a unique key violation of the database behind db
+func isDuplicateKey(db *gorm.DB, err error) bool {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// PurgeExpired deletes keys whose TTL has elapsed
+func (r *dedupRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	result := r.BaseConfig.DBConnection.WithContext(ctx).
+		Where("expires_at <= ?", time.Now()).
+		Delete(&model.ProcessedMessage{})
+	if result.Error != nil {
+		r.BaseConfig.Logger.ERROR(config.SRDERR, "Failed to purge expired idempotency keys", map[string]interface{}{
+			"error": result.Error.Error(),
+		})
+		return 0, fmt.Errorf("failed to purge expired idempotency keys: %w", result.Error)
+	}
+
+	r.BaseConfig.Logger.DEBUG(config.SRDPURGE, "Purged expired idempotency keys", map[string]interface{}{
+		"deleted": result.RowsAffected,
+	})
+
+	return result.RowsAffected, nil
+}
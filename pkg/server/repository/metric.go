@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RollupResolutions maps each rollup resolution to its bucket width
+var RollupResolutions = map[string]time.Duration{
+	model.MetricResolutionMinute: time.Minute,
+	model.MetricResolutionHour:   time.Hour,
+}
+
+// MetricFilter selects samples in [From, To). Empty AgentUUID, SensorType and Name match all.
+type MetricFilter struct {
+	AgentUUID  string
+	SensorType string
+	Name       string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+// MetricRepository defines the interface for the agent metrics store
+type MetricRepository interface {
+	// CreateMetrics stores raw samples and folds them into the 1m and 1h rollups, joining the
+	// transaction carried by ctx, if any
+	CreateMetrics(ctx context.Context, metrics []model.AgentMetric) error
+	GetMetrics(filter MetricFilter) ([]model.AgentMetric, error)
+	GetMetricRollups(filter MetricFilter, resolution string) ([]model.AgentMetricRollup, error)
+	// GetLastSeen returns the start of the latest 1m bucket since From of each agent matching filter
+	GetLastSeen(filter MetricFilter) (map[string]time.Time, error)
+}
+
+type metricRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// NewMetricRepository creates a new metric repository and ensures its tables exist
+func NewMetricRepository(conf config.BaseConfig) (MetricRepository, error) {
+	if conf.DBConnection == nil {
+		return nil, fmt.Errorf("metric repository requires a database connection")
+	}
+
+	if err := conf.DBConnection.AutoMigrate(&model.AgentMetric{}, &model.AgentMetricRollup{}); err != nil {
+		conf.Logger.ERROR(config.SRMTERR, "Failed to migrate agent metrics tables", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to migrate agent metrics: %w", err)
+	}
+
+	conf.Logger.INFO(config.SRMTINIT, "Server metric repository initialized", nil)
+
+	return &metricRepository{
+		BaseConfig: conf,
+	}, nil
+}
+
+// CreateMetrics stores a batch of metric samples. Raw rows and rollups are written in one
+// transaction, so a failed batch can be redelivered without counting samples twice.
+func (r *metricRepository) CreateMetrics(ctx context.Context, metrics []model.AgentMetric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	r.BaseConfig.Logger.DEBUG(config.SRMTCM, "Storing agent metrics", map[string]interface{}{
+		"agent_uuid": metrics[0].AgentUUID,
+		"count":      len(metrics),
+	})
+
+	err := dbFor(ctx, r.BaseConfig.DBConnection).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&metrics).Error; err != nil {
+			return err
+		}
+
+		rollups := buildRollups(metrics)
+		return tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"sample_count": gorm.Expr("sample_count + VALUES(sample_count)"),
+				"value_sum":    gorm.Expr("value_sum + VALUES(value_sum)"),
+				"value_min":    gorm.Expr("LEAST(value_min, VALUES(value_min))"),
+				"value_max":    gorm.Expr("GREATEST(value_max, VALUES(value_max))"),
+			}),
+		}).Create(&rollups).Error
+	})
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRMTERR, "Failed to store agent metrics", map[string]interface{}{
+			"error":      err.Error(),
+			"agent_uuid": metrics[0].AgentUUID,
+		})
+		return fmt.Errorf("failed to store agent metrics: %w", err)
+	}
+
+	return nil
+}
+
+// GetMetrics returns raw samples in time order
+func (r *metricRepository) GetMetrics(filter MetricFilter) ([]model.AgentMetric, error) {
+	var metrics []model.AgentMetric
+	query := r.BaseConfig.DBConnection.
+		Where("recorded_at >= ? AND recorded_at < ?", filter.From, filter.To)
+	query = applyMetricFilter(query, filter)
+	if err := query.Order("recorded_at").Find(&metrics).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRMTERR, "Failed to query agent metrics", map[string]interface{}{
+			"error":      err.Error(),
+			"agent_uuid": filter.AgentUUID,
+		})
+		return nil, fmt.Errorf("failed to query agent metrics: %w", err)
+	}
+	return metrics, nil
+}
+
+// GetMetricRollups returns the buckets of one resolution that start in [From, To), in time order
+func (r *metricRepository) GetMetricRollups(filter MetricFilter, resolution string) ([]model.AgentMetricRollup, error) {
+	var rollups []model.AgentMetricRollup
+	query := r.BaseConfig.DBConnection.
+		Where("resolution = ? AND bucket_start >= ? AND bucket_start < ?", resolution, filter.From, filter.To)
+	query = applyMetricFilter(query, filter)
+	if err := query.Order("bucket_start").Find(&rollups).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRMTERR, "Failed to query agent metric rollups", map[string]interface{}{
+			"error":      err.Error(),
+			"agent_uuid": filter.AgentUUID,
+			"resolution": resolution,
+		})
+		return nil, fmt.Errorf("failed to query agent metric rollups: %w", err)
+	}
+	return rollups, nil
+}
+
+// GetLastSeen groups the 1m rollups by agent, so it stays cheap over long ranges
+func (r *metricRepository) GetLastSeen(filter MetricFilter) (map[string]time.Time, error) {
+	var rows []struct {
+		AgentUUID string
+		LastSeen  time.Time
+	}
+	query := r.BaseConfig.DBConnection.Model(&model.AgentMetricRollup{}).
+		Select("agent_uuid, MAX(bucket_start) AS last_seen").
+		Where("resolution = ? AND bucket_start >= ?", model.MetricResolutionMinute, filter.From)
+	if !filter.To.IsZero() {
+		query = query.Where("bucket_start < ?", filter.To)
+	}
+	query = applyMetricFilter(query, filter)
+	if err := query.Group("agent_uuid").Scan(&rows).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRMTERR, "Failed to query agent last seen", map[string]interface{}{
+			"error":       err.Error(),
+			"sensor_type": filter.SensorType,
+		})
+		return nil, fmt.Errorf("failed to query agent last seen: %w", err)
+	}
+
+	lastSeen := make(map[string]time.Time, len(rows))
+	for _, row := range rows {
+		lastSeen[row.AgentUUID] = row.LastSeen
+	}
+	return lastSeen, nil
+}
+
+func applyMetricFilter(query *gorm.DB, filter MetricFilter) *gorm.DB {
+	if filter.AgentUUID != "" {
+		query = query.Where("agent_uuid = ?", filter.AgentUUID)
+	}
+	if filter.SensorType != "" {
+		query = query.Where("sensor_type = ?", filter.SensorType)
+	}
+	if filter.Name != "" {
+		query = query.Where("name = ?", filter.Name)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	return query
+}
+
+// buildRollups aggregates a batch into one row per series, resolution and bucket
+func buildRollups(metrics []model.AgentMetric) []model.AgentMetricRollup {
+	type bucketKey struct {
+		agentUUID, sensorType, name, resolution string
+		start                                   time.Time
+	}
+
+	index := map[bucketKey]int{}
+	var rollups []model.AgentMetricRollup
+	for _, metric := range metrics {
+		for resolution, width := range RollupResolutions {
+			key := bucketKey{metric.AgentUUID, metric.SensorType, metric.Name, resolution, metric.RecordedAt.Truncate(width)}
+			if i, ok := index[key]; ok {
+				rollup := &rollups[i]
+				rollup.Count++
+				rollup.Sum += metric.Value
+				if metric.Value < rollup.Min {
+					rollup.Min = metric.Value
+				}
+				if metric.Value > rollup.Max {
+					rollup.Max = metric.Value
+				}
+				continue
+			}
+			index[key] = len(rollups)
+			rollups = append(rollups, model.AgentMetricRollup{
+				AgentUUID:   key.agentUUID,
+				SensorType:  key.sensorType,
+				Name:        key.name,
+				Resolution:  resolution,
+				BucketStart: key.start,
+				Count:       1,
+				Sum:         metric.Value,
+				Min:         metric.Value,
+				Max:         metric.Value,
+			})
+		}
+	}
+	return rollups
+}
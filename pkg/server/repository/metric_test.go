@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+func TestBuildRollups(t *testing.T) {
+	base := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	sample := func(agentUUID, name string, offset time.Duration, value float64) model.AgentMetric {
+		return model.AgentMetric{
+			AgentUUID:  agentUUID,
+			SensorType: model.MetricSensorTypeSystem,
+			Name:       name,
+			Value:      value,
+			RecordedAt: base.Add(offset),
+		}
+	}
+	rollup := func(agentUUID, name, resolution string, start time.Time, count int64, sum, min, max float64) model.AgentMetricRollup {
+		return model.AgentMetricRollup{
+			AgentUUID:   agentUUID,
+			SensorType:  model.MetricSensorTypeSystem,
+			Name:        name,
+			Resolution:  resolution,
+			BucketStart: start,
+			Count:       count,
+			Sum:         sum,
+			Min:         min,
+			Max:         max,
+		}
+	}
+	minute, hour := model.MetricResolutionMinute, model.MetricResolutionHour
+
+	tests := []struct {
+		name    string
+		metrics []model.AgentMetric
+		want    []model.AgentMetricRollup
+	}{
+		{
+			name: "no samples",
+		},
+		{
+			name:    "one sample fills a bucket of each resolution",
+			metrics: []model.AgentMetric{sample("a", "cpu_usage", 30*time.Second, 42)},
+			want: []model.AgentMetricRollup{
+				rollup("a", "cpu_usage", minute, base, 1, 42, 42, 42),
+				rollup("a", "cpu_usage", hour, base, 1, 42, 42, 42),
+			},
+		},
+		{
+			name: "samples in one minute are folded together",
+			metrics: []model.AgentMetric{
+				sample("a", "cpu_usage", 5*time.Second, 40),
+				sample("a", "cpu_usage", 20*time.Second, 10),
+				sample("a", "cpu_usage", 59*time.Second, 70),
+			},
+			want: []model.AgentMetricRollup{
+				rollup("a", "cpu_usage", minute, base, 3, 120, 10, 70),
+				rollup("a", "cpu_usage", hour, base, 3, 120, 10, 70),
+			},
+		},
+		{
+			name: "minute buckets split within the hour",
+			metrics: []model.AgentMetric{
+				sample("a", "cpu_usage", 59*time.Second, 10),
+				sample("a", "cpu_usage", time.Minute, 20),
+				sample("a", "cpu_usage", 61*time.Minute, 30),
+			},
+			want: []model.AgentMetricRollup{
+				rollup("a", "cpu_usage", minute, base, 1, 10, 10, 10),
+				rollup("a", "cpu_usage", minute, base.Add(time.Minute), 1, 20, 20, 20),
+				rollup("a", "cpu_usage", minute, base.Add(61*time.Minute), 1, 30, 30, 30),
+				rollup("a", "cpu_usage", hour, base, 2, 30, 10, 20),
+				rollup("a", "cpu_usage", hour, base.Add(time.Hour), 1, 30, 30, 30),
+			},
+		},
+		{
+			name: "agents and names get their own buckets",
+			metrics: []model.AgentMetric{
+				sample("a", "cpu_usage", 0, 10),
+				sample("a", "disk_usage", 0, 20),
+				sample("b", "cpu_usage", 0, 30),
+			},
+			want: []model.AgentMetricRollup{
+				rollup("a", "cpu_usage", minute, base, 1, 10, 10, 10),
+				rollup("a", "cpu_usage", hour, base, 1, 10, 10, 10),
+				rollup("a", "disk_usage", minute, base, 1, 20, 20, 20),
+				rollup("a", "disk_usage", hour, base, 1, 20, 20, 20),
+				rollup("b", "cpu_usage", minute, base, 1, 30, 30, 30),
+				rollup("b", "cpu_usage", hour, base, 1, 30, 30, 30),
+			},
+		},
+		{
+			name: "negative values keep the true minimum and maximum",
+			metrics: []model.AgentMetric{
+				sample("a", "temperature", 0, -5),
+				sample("a", "temperature", time.Second, -15),
+			},
+			want: []model.AgentMetricRollup{
+				rollup("a", "temperature", minute, base, 2, -20, -15, -5),
+				rollup("a", "temperature", hour, base, 2, -20, -15, -5),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rollupsByBucket(buildRollups(tt.metrics))
+			want := rollupsByBucket(tt.want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("buildRollups() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// rollupsByBucket indexes rollups by their unique key, as buildRollups returns them in no particular order
+func rollupsByBucket(rollups []model.AgentMetricRollup) map[string]model.AgentMetricRollup {
+	byBucket := make(map[string]model.AgentMetricRollup, len(rollups))
+	for _, rollup := range rollups {
+		key := fmt.Sprintf("%s/%s/%s/%s/%s", rollup.AgentUUID, rollup.SensorType, rollup.Name, rollup.Resolution, rollup.BucketStart.Format(time.RFC3339))
+		byBucket[key] = rollup
+	}
+	return byBucket
+}
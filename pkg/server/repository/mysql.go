@@ -52,6 +52,9 @@ func (r *MySQLRepository) autoMigrate() error {
 		&model.Agent{},
 		&model.AgentInfo{},
 		&model.AgentProcessingConfig{},
+		&model.ProcessedMessage{},
+		&model.AgentMetric{},
+		&model.OutboxEvent{},
 	}
 
 	for _, model := range models {
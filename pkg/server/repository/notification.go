@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRepository defines the interface for the notification delivery log
+type NotificationRepository interface {
+	// EnqueueDeliveries stores pending deliveries, skipping message/channel pairs that
+	// already exist so a redelivered message is not sent twice. It joins the transaction
+	// carried by ctx, if any.
+	EnqueueDeliveries(ctx context.Context, deliveries []model.NotificationDelivery) error
+	// ClaimDue marks up to limit deliveries due at now as sending until now+lease and returns
+	// them. Deliveries whose lease expired, e.g. after a dispatcher crash, are claimed again.
+	ClaimDue(limit int, now time.Time, lease time.Duration) ([]model.NotificationDelivery, error)
+	// SaveResult stores the status, attempts and next attempt time of a claimed delivery
+	SaveResult(delivery *model.NotificationDelivery) error
+	GetDeliveries(req request.NotificationDeliveryListRequest) ([]model.NotificationDelivery, error)
+}
+
+type notificationRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// NewNotificationRepository creates a new notification repository and ensures its table exists
+func NewNotificationRepository(conf config.BaseConfig) (NotificationRepository, error) {
+	if conf.DBConnection == nil {
+		return nil, fmt.Errorf("notification repository requires a database connection")
+	}
+
+	if err := conf.DBConnection.AutoMigrate(&model.NotificationDelivery{}); err != nil {
+		conf.Logger.ERROR(config.SRNTERR, "Failed to migrate notification deliveries table", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to migrate notification deliveries: %w", err)
+	}
+
+	conf.Logger.INFO(config.SRNTINIT, "Server notification repository initialized", nil)
+
+	return &notificationRepository{
+		BaseConfig: conf,
+	}, nil
+}
+
+func (r *notificationRepository) EnqueueDeliveries(ctx context.Context, deliveries []model.NotificationDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := dbFor(ctx, r.BaseConfig.DBConnection).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRNTERR, "Failed to enqueue notification deliveries", map[string]interface{}{
+			"error":      err.Error(),
+			"message_id": deliveries[0].MessageID,
+		})
+		return fmt.Errorf("failed to enqueue notification deliveries: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue uses SKIP LOCKED so several dispatchers never claim the same delivery at once.
+// The claim is committed before anything is sent, so no row lock is held during a send.
+func (r *notificationRepository) ClaimDue(limit int, now time.Time, lease time.Duration) ([]model.NotificationDelivery, error) {
+	var deliveries []model.NotificationDelivery
+	err := r.BaseConfig.DBConnection.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{model.DeliveryStatusPending, model.DeliveryStatusSending}, now).
+			Order("next_attempt_at, id").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i := range deliveries {
+			ids[i] = deliveries[i].ID
+			deliveries[i].Status = model.DeliveryStatusSending
+			deliveries[i].NextAttemptAt = now.Add(lease)
+		}
+		return tx.Model(&model.NotificationDelivery{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":          model.DeliveryStatusSending,
+				"next_attempt_at": now.Add(lease),
+			}).Error
+	})
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRNTERR, "Failed to claim notification deliveries", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to claim notification deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// SaveResult only updates a delivery that is still sending, so a result arriving after the
+// lease expired and the delivery was claimed and finished elsewhere does not overwrite it
+func (r *notificationRepository) SaveResult(delivery *model.NotificationDelivery) error {
+	err := r.BaseConfig.DBConnection.Model(delivery).
+		Where("status = ?", model.DeliveryStatusSending).
+		Select("status", "attempts", "last_error", "next_attempt_at", "delivered_at").
+		Updates(delivery).Error
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRNTERR, "Failed to save notification delivery", map[string]interface{}{
+			"error":         err.Error(),
+			"delivery_uuid": delivery.UUID,
+		})
+		return fmt.Errorf("failed to save notification delivery: %w", err)
+	}
+	return nil
+}
+
+// GetDeliveries returns the delivery log, newest first
+func (r *notificationRepository) GetDeliveries(req request.NotificationDeliveryListRequest) ([]model.NotificationDelivery, error) {
+	query := r.BaseConfig.DBConnection.Model(&model.NotificationDelivery{})
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.Channel != "" {
+		query = query.Where("channel = ?", req.Channel)
+	}
+	if req.MessageID != "" {
+		query = query.Where("message_id = ?", req.MessageID)
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var deliveries []model.NotificationDelivery
+	if err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRNTERR, "Failed to query notification deliveries", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to query notification deliveries: %w", err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository defines the interface for the transactional event outbox
+type OutboxRepository interface {
+	Enqueue(event *model.ServerEvent) error
+	// EnqueueNotification writes a client notification to the outbox, like Enqueue
+	EnqueueNotification(notification *model.Notification) error
+	PublishPending(limit int, publish func(event *model.OutboxEvent) error) (int, error)
+	PurgePublished(before time.Time) (int64, error)
+	// GetEvents returns up to limit events after the row id afterID matching req, oldest first
+	GetEvents(req request.EventListRequest, afterID uint, limit int) ([]model.OutboxEvent, error)
+	// LastEventID returns the row id of the newest event created before the time, or of
+	// the newest event when before is zero
+	LastEventID(before time.Time) (uint, error)
+}
+
+type outboxRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// NewOutboxRepository creates a new outbox repository and ensures its table exists
+func NewOutboxRepository(conf config.BaseConfig) (OutboxRepository, error) {
+	if conf.DBConnection == nil {
+		return nil, fmt.Errorf("outbox repository requires a database connection")
+	}
+
+	if err := conf.DBConnection.AutoMigrate(&model.OutboxEvent{}); err != nil {
+		conf.Logger.ERROR(config.SROERR, "Failed to migrate outbox events table", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to migrate outbox events: %w", err)
+	}
+
+	conf.Logger.INFO(config.SROINIT, "Server outbox repository initialized", nil)
+
+	return &outboxRepository{
+		BaseConfig: conf,
+	}, nil
+}
+
+// Enqueue writes an event to the outbox. Called inside a transaction, the event is
+// committed or rolled back together with the mutation it describes.
+func (r *outboxRepository) Enqueue(event *model.ServerEvent) error {
+	r.BaseConfig.Logger.DEBUG(config.SROENQ, "Enqueuing server event", map[string]interface{}{
+		"event_id":   event.ID,
+		"event_type": event.Type,
+		"agent_id":   event.AgentID,
+	})
+
+	record := &model.OutboxEvent{
+		Kind:      model.OutboxKindEvent,
+		EventID:   event.ID,
+		Type:      event.Type,
+		AgentID:   event.AgentID,
+		Data:      event.Data,
+		CreatedAt: event.Timestamp,
+	}
+	if err := r.BaseConfig.DBConnection.Create(record).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SROERR, "Failed to enqueue server event", map[string]interface{}{
+			"error":      err.Error(),
+			"event_type": event.Type,
+		})
+		return fmt.Errorf("failed to enqueue server event: %w", err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) EnqueueNotification(notification *model.Notification) error {
+	r.BaseConfig.Logger.DEBUG(config.SROENQ, "Enqueuing client notification", map[string]interface{}{
+		"notification_id":   notification.ID,
+		"notification_type": notification.Type,
+		"agent_id":          notification.AgentID,
+	})
+
+	record := &model.OutboxEvent{
+		Kind:      model.OutboxKindNotification,
+		EventID:   notification.ID,
+		Type:      notification.Type,
+		AgentID:   notification.AgentID,
+		Data:      notification.Message,
+		CreatedAt: notification.Timestamp,
+	}
+	if err := r.BaseConfig.DBConnection.Create(record).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SROERR, "Failed to enqueue client notification", map[string]interface{}{
+			"error":             err.Error(),
+			"notification_type": notification.Type,
+		})
+		return fmt.Errorf("failed to enqueue client notification: %w", err)
+	}
+	return nil
+}
+
+// PublishPending locks up to limit unpublished events in insertion order and hands them
+// to publish, marking each one that succeeds. It stops at the first failure to keep
+// events in order. SKIP LOCKED lets several relays run without sending an event twice.
+func (r *outboxRepository) PublishPending(limit int, publish func(event *model.OutboxEvent) error) (int, error) {
+	published := 0
+	err := r.BaseConfig.DBConnection.Transaction(func(tx *gorm.DB) error {
+		var events []model.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL").
+			Order("id").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range events {
+			event := &events[i]
+			if err := publish(event); err != nil {
+				r.BaseConfig.Logger.WARN(config.SROPUB, "Failed to relay server event", map[string]interface{}{
+					"error":    err.Error(),
+					"event_id": event.EventID,
+					"attempts": event.Attempts + 1,
+				})
+				return tx.Model(event).Updates(map[string]interface{}{
+					"attempts":   gorm.Expr("attempts + 1"),
+					"last_error": err.Error(),
+				}).Error
+			}
+
+			now := time.Now()
+			if err := tx.Model(event).Update("published_at", &now).Error; err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SROERR, "Failed to relay outbox events", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return published, fmt.Errorf("failed to relay outbox events: %w", err)
+	}
+
+	if published > 0 {
+		r.BaseConfig.Logger.DEBUG(config.SROSUCC, "Relayed outbox events", map[string]interface{}{
+			"count": published,
+		})
+	}
+	return published, nil
+}
+
+// PurgePublished deletes events that were published before the given time
+func (r *outboxRepository) PurgePublished(before time.Time) (int64, error) {
+	result := r.BaseConfig.DBConnection.
+		Where("published_at IS NOT NULL AND published_at < ?", before).
+		Delete(&model.OutboxEvent{})
+	if result.Error != nil {
+		r.BaseConfig.Logger.ERROR(config.SROERR, "Failed to purge published outbox events", map[string]interface{}{
+			"error": result.Error.Error(),
+		})
+		return 0, fmt.Errorf("failed to purge published outbox events: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *outboxRepository) GetEvents(req request.EventListRequest, afterID uint, limit int) ([]model.OutboxEvent, error) {
+	query := r.BaseConfig.DBConnection.Where("id > ? AND kind = ?", afterID, model.OutboxKindEvent)
+	if len(req.Types) > 0 {
+		query = query.Where("type IN ?", req.Types)
+	}
+	if req.AgentUUID != "" {
+		query = query.Where("agent_id = ?", req.AgentUUID)
+	}
+
+	var events []model.OutboxEvent
+	if err := query.Order("id").Limit(limit).Find(&events).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SROERR, "Failed to read server events", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to read server events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) LastEventID(before time.Time) (uint, error) {
+	query := r.BaseConfig.DBConnection.Model(&model.OutboxEvent{})
+	if !before.IsZero() {
+		query = query.Where("created_at < ?", before)
+	}
+
+	var id uint
+	if err := query.Select("COALESCE(MAX(id), 0)").Scan(&id).Error; err != nil {
+		return 0, fmt.Errorf("failed to read last server event: %w", err)
+	}
+	return id, nil
+}
@@ -2,21 +2,28 @@ package repository
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/apache/pulsar-client-go/pulsar"
 	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/codec"
 	"github.com/ryo-arima/circulator/pkg/entity/model"
 )
 
+// EventPublisher publishes server events and client notifications
+type EventPublisher interface {
+	PublishEvent(ctx context.Context, event *model.ServerEvent) error
+	PublishNotification(ctx context.Context, notification *model.Notification) error
+}
+
 // PulsarRepository handles Pulsar messaging for Server
 type PulsarRepository struct {
 	config   config.BaseConfig
 	client   pulsar.Client
 	producer pulsar.Producer
 	consumer pulsar.Consumer
+	codec    codec.Codec
+	dedup    DedupRepository
 }
 
 // NewPulsarRepository creates a new PulsarRepository instance
@@ -28,10 +35,23 @@ func NewPulsarRepository(cfg config.BaseConfig, pulsarURL string) (*PulsarReposi
 		return nil, fmt.Errorf("failed to create pulsar client: %w", err)
 	}
 
+	messageCodec := codec.NewCodec(cfg, "server")
+
+	// Idempotency store so redelivered reports are acknowledged without re-applying them
+	var dedup DedupRepository
+	if cfg.YamlConfig.Pulsar.Dedup.Enabled {
+		dedup, err = NewDedupRepository(cfg)
+		if err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to create dedup repository: %w", err)
+		}
+	}
+
 	// Create producer for server events
 	producer, err := client.CreateProducer(pulsar.ProducerOptions{
-		Topic: "server-events",
-		Name:  "server-producer",
+		Topic:  "server-events",
+		Name:   "server-producer",
+		Schema: messageCodec.Schema("server-events"),
 	})
 	if err != nil {
 		client.Close()
@@ -43,6 +63,7 @@ func NewPulsarRepository(cfg config.BaseConfig, pulsarURL string) (*PulsarReposi
 		Topic:            "agent-reports",
 		SubscriptionName: "server-consumer",
 		Type:             pulsar.Shared,
+		Schema:           messageCodec.Schema("agent-reports"),
 	})
 	if err != nil {
 		producer.Close()
@@ -55,10 +76,13 @@ func NewPulsarRepository(cfg config.BaseConfig, pulsarURL string) (*PulsarReposi
 		client:   client,
 		producer: producer,
 		consumer: consumer,
+		codec:    messageCodec,
+		dedup:    dedup,
 	}
 
 	cfg.Logger.INFO(config.SRPINIT, "Server Pulsar repository initialized", map[string]interface{}{
 		"pulsar_url": pulsarURL,
+		"dedup":      dedup != nil,
 	})
 
 	return repo, nil
@@ -71,20 +95,15 @@ func (r *PulsarRepository) PublishEvent(ctx context.Context, event *model.Server
 		"event_id":   event.ID,
 	})
 
-	payload, err := json.Marshal(event)
+	msg, err := r.codec.Encode("server-events", model.MessageTypeServerEvent, "", event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
+	msg.Key = event.ID
+	msg.Properties["type"] = event.Type
+	msg.Properties["source"] = "server"
 
-	msgID, err := r.producer.Send(ctx, &pulsar.ProducerMessage{
-		Payload: payload,
-		Key:     event.ID,
-		Properties: map[string]string{
-			"type":      event.Type,
-			"source":    "server",
-			"timestamp": time.Now().UTC().Format(time.RFC3339),
-		},
-	})
+	msgID, err := r.producer.Send(ctx, msg)
 	if err != nil {
 		r.config.Logger.ERROR(config.SRPERR, "Failed to publish event", map[string]interface{}{
 			"error":      err.Error(),
@@ -112,28 +131,24 @@ func (r *PulsarRepository) PublishNotification(ctx context.Context, notification
 
 	// Create producer for client notifications if not exists
 	notificationProducer, err := r.client.CreateProducer(pulsar.ProducerOptions{
-		Topic: "client-notifications",
-		Name:  "server-notification-producer",
+		Topic:  "client-notifications",
+		Name:   "server-notification-producer",
+		Schema: r.codec.Schema("client-notifications"),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create notification producer: %w", err)
 	}
 	defer notificationProducer.Close()
 
-	payload, err := json.Marshal(notification)
+	msg, err := r.codec.Encode("client-notifications", model.MessageTypeNotification, "", notification)
 	if err != nil {
 		return fmt.Errorf("failed to marshal notification: %w", err)
 	}
+	msg.Key = notification.ID
+	msg.Properties["type"] = notification.Type
+	msg.Properties["source"] = "server"
 
-	msgID, err := notificationProducer.Send(ctx, &pulsar.ProducerMessage{
-		Payload: payload,
-		Key:     notification.ID,
-		Properties: map[string]string{
-			"type":      notification.Type,
-			"source":    "server",
-			"timestamp": time.Now().UTC().Format(time.RFC3339),
-		},
-	})
+	msgID, err := notificationProducer.Send(ctx, msg)
 	if err != nil {
 		r.config.Logger.ERROR(config.SRPERR, "Failed to publish notification", map[string]interface{}{
 			"error":             err.Error(),
@@ -153,7 +168,7 @@ func (r *PulsarRepository) PublishNotification(ctx context.Context, notification
 }
 
 // ConsumeAgentReports consumes agent reports from Pulsar
-func (r *PulsarRepository) ConsumeAgentReports(ctx context.Context, handler func(*model.AgentReport) error) error {
+func (r *PulsarRepository) ConsumeAgentReports(ctx context.Context, handler func(context.Context, *model.AgentReport) error) error {
 	r.config.Logger.INFO(config.SRPCONS, "Starting agent report consumption from Pulsar", nil)
 
 	for {
@@ -171,7 +186,8 @@ func (r *PulsarRepository) ConsumeAgentReports(ctx context.Context, handler func
 			}
 
 			var report model.AgentReport
-			if err := json.Unmarshal(msg.Payload(), &report); err != nil {
+			envelope, err := r.codec.Decode(msg.Payload(), msg.Properties(), &report)
+			if err != nil {
 				r.config.Logger.ERROR(config.SRPERR, "Failed to unmarshal agent report", map[string]interface{}{
 					"error":      err.Error(),
 					"message_id": msg.ID().String(),
@@ -185,9 +201,15 @@ func (r *PulsarRepository) ConsumeAgentReports(ctx context.Context, handler func
 				"report_id":   report.ID,
 				"agent_id":    report.AgentID,
 				"message_id":  msg.ID().String(),
+				"trace_id":    envelope.TraceID,
+				"producer":    envelope.Producer,
 			})
 
-			if err := handler(&report); err != nil {
+			key := codec.IdempotencyKey(model.MessageTypeAgentReport, report.ID, msg.ID())
+			applied, err := r.processOnce(ctx, key, "server-consumer", func(ctx context.Context) error {
+				return handler(ctx, &report)
+			})
+			if err != nil {
 				r.config.Logger.ERROR(config.SRPERR, "Failed to handle agent report", map[string]interface{}{
 					"error":       err.Error(),
 					"report_type": report.Type,
@@ -197,6 +219,16 @@ func (r *PulsarRepository) ConsumeAgentReports(ctx context.Context, handler func
 				r.consumer.Nack(msg)
 				continue
 			}
+			if !applied {
+				r.config.Logger.INFO(config.SRPDUP, "Skipping already processed agent report", map[string]interface{}{
+					"report_id":  report.ID,
+					"agent_id":   report.AgentID,
+					"message_id": msg.ID().String(),
+					"key":        key,
+				})
+				r.consumer.Ack(msg)
+				continue
+			}
 
 			r.consumer.Ack(msg)
 			r.config.Logger.DEBUG(config.SRPSUCC, "Agent report processed successfully", map[string]interface{}{
@@ -208,6 +240,140 @@ func (r *PulsarRepository) ConsumeAgentReports(ctx context.Context, handler func
 	}
 }
 
+// Server subscriptions on the agent stream topics
+const (
+	timeseriesSubscription = "server-timeseries" // processed data and system metrics
+	alertSubscription      = "server-alerts"
+	notifySubscription     = "server-notify" // routes server events and notifications to channels
+)
+
+// ConsumeProcessedData consumes agent processing results for time-series storage
+func (r *PulsarRepository) ConsumeProcessedData(ctx context.Context, handler func(context.Context, *model.ProcessedStreamData) error) error {
+	topic := r.config.YamlConfig.Pulsar.Topics.ProcessedSensorData
+	return r.consumeStream(ctx, topic, timeseriesSubscription, model.MessageTypeProcessedStreamData, func(msg pulsar.Message) (string, func(context.Context) error, error) {
+		var data model.ProcessedStreamData
+		if _, err := r.codec.Decode(msg.Payload(), msg.Properties(), &data); err != nil {
+			return "", nil, err
+		}
+		return data.UUID, func(ctx context.Context) error { return handler(ctx, &data) }, nil
+	})
+}
+
+// ConsumeSystemMetrics consumes agent host metrics for time-series storage
+func (r *PulsarRepository) ConsumeSystemMetrics(ctx context.Context, handler func(context.Context, *model.SystemMetrics) error) error {
+	topic := r.config.YamlConfig.Pulsar.Topics.SystemMetrics
+	return r.consumeStream(ctx, topic, timeseriesSubscription, model.MessageTypeSystemMetrics, func(msg pulsar.Message) (string, func(context.Context) error, error) {
+		var metrics model.SystemMetrics
+		if _, err := r.codec.Decode(msg.Payload(), msg.Properties(), &metrics); err != nil {
+			return "", nil, err
+		}
+		return metrics.UUID, func(ctx context.Context) error { return handler(ctx, &metrics) }, nil
+	})
+}
+
+// ConsumeAlerts consumes alerts raised by agents for the alert lifecycle
+func (r *PulsarRepository) ConsumeAlerts(ctx context.Context, handler func(context.Context, *model.AlertData) error) error {
+	topic := r.config.YamlConfig.Pulsar.Topics.AlertData
+	return r.consumeStream(ctx, topic, alertSubscription, model.MessageTypeAlertData, func(msg pulsar.Message) (string, func(context.Context) error, error) {
+		var alert model.AlertData
+		if _, err := r.codec.Decode(msg.Payload(), msg.Properties(), &alert); err != nil {
+			return "", nil, err
+		}
+		return alert.UUID, func(ctx context.Context) error { return handler(ctx, &alert) }, nil
+	})
+}
+
+// ConsumeServerEvents consumes the server's own events for notification routing
+func (r *PulsarRepository) ConsumeServerEvents(ctx context.Context, handler func(context.Context, *model.ServerEvent) error) error {
+	return r.consumeStream(ctx, "server-events", notifySubscription, model.MessageTypeServerEvent, func(msg pulsar.Message) (string, func(context.Context) error, error) {
+		var event model.ServerEvent
+		if _, err := r.codec.Decode(msg.Payload(), msg.Properties(), &event); err != nil {
+			return "", nil, err
+		}
+		return event.ID, func(ctx context.Context) error { return handler(ctx, &event) }, nil
+	})
+}
+
+// ConsumeNotifications consumes client notifications for notification routing
+func (r *PulsarRepository) ConsumeNotifications(ctx context.Context, handler func(context.Context, *model.Notification) error) error {
+	return r.consumeStream(ctx, "client-notifications", notifySubscription, model.MessageTypeNotification, func(msg pulsar.Message) (string, func(context.Context) error, error) {
+		var notification model.Notification
+		if _, err := r.codec.Decode(msg.Payload(), msg.Properties(), &notification); err != nil {
+			return "", nil, err
+		}
+		return notification.ID, func(ctx context.Context) error { return handler(ctx, &notification) }, nil
+	})
+}
+
+// consumeStream receives from topic on a shared subscription until ctx is cancelled.
+// decode returns the message's model ID for deduplication and a function that applies it.
+func (r *PulsarRepository) consumeStream(ctx context.Context, topic, subscription, msgType string, decode func(pulsar.Message) (string, func(context.Context) error, error)) error {
+	consumer, err := r.client.Subscribe(pulsar.ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: subscription,
+		Type:             pulsar.Shared,
+		Schema:           r.codec.Schema(topic),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+	defer consumer.Close()
+
+	r.config.Logger.INFO(config.SRPCONS, "Starting stream data consumption from Pulsar", map[string]interface{}{
+		"topic": topic,
+	})
+
+	for {
+		msg, err := consumer.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				r.config.Logger.INFO(config.SRPSTOP, "Stopping stream data consumption", map[string]interface{}{
+					"topic": topic,
+				})
+				return ctx.Err()
+			}
+			r.config.Logger.ERROR(config.SRPERR, "Failed to receive message", map[string]interface{}{
+				"error": err.Error(),
+				"topic": topic,
+			})
+			continue
+		}
+
+		id, apply, err := decode(msg)
+		if err != nil {
+			r.config.Logger.ERROR(config.SRPERR, "Failed to unmarshal stream data", map[string]interface{}{
+				"error":      err.Error(),
+				"topic":      topic,
+				"message_id": msg.ID().String(),
+			})
+			consumer.Ack(msg)
+			continue
+		}
+
+		key := codec.IdempotencyKey(msgType, id, msg.ID())
+		if _, err := r.processOnce(ctx, key, subscription, apply); err != nil {
+			r.config.Logger.ERROR(config.SRPERR, "Failed to handle stream data", map[string]interface{}{
+				"error":      err.Error(),
+				"topic":      topic,
+				"message_id": msg.ID().String(),
+			})
+			consumer.Nack(msg)
+			continue
+		}
+		consumer.Ack(msg)
+	}
+}
+
+// processOnce applies a message unless its key was already processed, reporting whether it
+// was applied. The key is recorded in the transaction passed to apply through its context,
+// so a failed handler or a crash before the commit leaves the message to be applied again.
+func (r *PulsarRepository) processOnce(ctx context.Context, key, consumer string, apply func(ctx context.Context) error) (bool, error) {
+	if r.dedup == nil {
+		return true, apply(ctx)
+	}
+	return r.dedup.ProcessOnce(ctx, key, consumer, apply)
+}
+
 // Close closes the Pulsar repository
 func (r *PulsarRepository) Close() {
 	if r.consumer != nil {
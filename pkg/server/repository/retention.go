@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"gorm.io/gorm/clause"
+)
+
+// retentionClass locates the rows of a data class older than a cutoff
+type retentionClass struct {
+	table string
+	where string // condition with a single ? for the cutoff
+}
+
+// retentionClasses are the data classes the compactor can expire
+var retentionClasses = map[string]retentionClass{
+	model.RetentionClassMetricsRaw: {
+		table: "agent_metrics",
+		where: "recorded_at < ?",
+	},
+	model.RetentionClassMetrics1m: {
+		table: "agent_metric_rollups",
+		where: "resolution = '" + model.MetricResolutionMinute + "' AND bucket_start < ?",
+	},
+	model.RetentionClassMetrics1h: {
+		table: "agent_metric_rollups",
+		where: "resolution = '" + model.MetricResolutionHour + "' AND bucket_start < ?",
+	},
+	// The newest row per agent is its current system info and is never expired
+	model.RetentionClassSystemInfo: {
+		table: "system_info",
+		where: "timestamp < ? AND id NOT IN (SELECT id FROM (SELECT MAX(id) AS id FROM system_info GROUP BY agent_uuid) latest)",
+	},
+	// Only events the outbox relay has already published
+	model.RetentionClassEvents: {
+		table: "outbox_events",
+		where: "published_at IS NOT NULL AND published_at < ?",
+	},
+	// Only resolved alerts; their notes are removed by the foreign key cascade
+	model.RetentionClassAlerts: {
+		table: "alerts",
+		where: "status = '" + model.AlertStatusResolved + "' AND resolved_at < ?",
+	},
+	// Finished deliveries of the notification delivery log
+	model.RetentionClassDeliveries: {
+		table: "notification_deliveries",
+		where: "status NOT IN ('" + model.DeliveryStatusPending + "', '" + model.DeliveryStatusSending + "') AND updated_at < ?",
+	},
+	// Dedup keys of consumed Pulsar messages, by the time they were processed
+	model.RetentionClassProcessed: {
+		table: "processed_messages",
+		where: "processed_at < ?",
+	},
+}
+
+// RetentionClasses returns the names of all data classes in a stable order
+func RetentionClasses() []string {
+	classes := make([]string, 0, len(retentionClasses))
+	for class := range retentionClasses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// RetentionRepository defines the interface for expiring history and recording compactor runs
+type RetentionRepository interface {
+	// CountExpired returns the number of rows of class older than before
+	CountExpired(class string, before time.Time) (int64, error)
+	// DeleteExpired deletes up to limit rows of class older than before
+	DeleteExpired(class string, before time.Time, limit int) (int64, error)
+	SaveRun(run *model.RetentionRun) error
+	GetRuns() ([]model.RetentionRun, error)
+}
+
+type retentionRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// NewRetentionRepository creates a new retention repository and ensures its table exists
+func NewRetentionRepository(conf config.BaseConfig) (RetentionRepository, error) {
+	if conf.DBConnection == nil {
+		return nil, fmt.Errorf("retention repository requires a database connection")
+	}
+
+	if err := conf.DBConnection.AutoMigrate(&model.RetentionRun{}); err != nil {
+		conf.Logger.ERROR(config.SRRTERR, "Failed to migrate retention runs table", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to migrate retention runs: %w", err)
+	}
+
+	conf.Logger.INFO(config.SRRTINIT, "Server retention repository initialized", nil)
+
+	return &retentionRepository{
+		BaseConfig: conf,
+	}, nil
+}
+
+// CountExpired returns 0 for classes whose table has not been created yet
+func (r *retentionRepository) CountExpired(class string, before time.Time) (int64, error) {
+	target, ok, err := r.lookup(class)
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	var count int64
+	err = r.BaseConfig.DBConnection.Table(target.table).Where(target.where, before).Count(&count).Error
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRRTERR, "Failed to count expired rows", map[string]interface{}{
+			"error": err.Error(),
+			"class": class,
+		})
+		return 0, fmt.Errorf("failed to count expired %s: %w", class, err)
+	}
+	return count, nil
+}
+
+// DeleteExpired deletes in bounded batches so a large backlog does not hold long locks
+func (r *retentionRepository) DeleteExpired(class string, before time.Time, limit int) (int64, error) {
+	target, ok, err := r.lookup(class)
+	if err != nil || !ok {
+		return 0, err
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s LIMIT ?", target.table, target.where)
+	result := r.BaseConfig.DBConnection.Exec(query, before, limit)
+	if result.Error != nil {
+		r.BaseConfig.Logger.ERROR(config.SRRTERR, "Failed to delete expired rows", map[string]interface{}{
+			"error": result.Error.Error(),
+			"class": class,
+		})
+		return 0, fmt.Errorf("failed to delete expired %s: %w", class, result.Error)
+	}
+
+	r.BaseConfig.Logger.DEBUG(config.SRRTDEL, "Deleted expired rows", map[string]interface{}{
+		"class":   class,
+		"deleted": result.RowsAffected,
+	})
+	return result.RowsAffected, nil
+}
+
+// SaveRun replaces the recorded run of the run's class
+func (r *retentionRepository) SaveRun(run *model.RetentionRun) error {
+	err := r.BaseConfig.DBConnection.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "class"}},
+		DoUpdates: clause.AssignmentColumns([]string{"cutoff", "matched", "deleted", "dry_run", "error", "started_at", "finished_at"}),
+	}).Create(run).Error
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRRTERR, "Failed to record retention run", map[string]interface{}{
+			"error": err.Error(),
+			"class": run.Class,
+		})
+		return fmt.Errorf("failed to record retention run: %w", err)
+	}
+	return nil
+}
+
+// GetRuns returns the last recorded run of every class
+func (r *retentionRepository) GetRuns() ([]model.RetentionRun, error) {
+	var runs []model.RetentionRun
+	if err := r.BaseConfig.DBConnection.Order("class").Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get retention runs: %w", err)
+	}
+	return runs, nil
+}
+
+func (r *retentionRepository) lookup(class string) (retentionClass, bool, error) {
+	target, ok := retentionClasses[class]
+	if !ok {
+		return retentionClass{}, false, fmt.Errorf("unknown retention class: %s", class)
+	}
+	return target, r.BaseConfig.DBConnection.Migrator().HasTable(target.table), nil
+}
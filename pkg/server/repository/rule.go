@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"gorm.io/gorm"
+)
+
+// RuleRepository defines the interface for server-side alert rules
+type RuleRepository interface {
+	CreateRule(rule *model.AlertRule) error
+	// GetRules returns the rules ordered by name, only the enabled ones with enabledOnly
+	GetRules(enabledOnly bool) ([]model.AlertRule, error)
+	GetRule(ruleUUID string) (*model.AlertRule, error)
+	// UpdateRule saves the definition of rule, leaving its evaluation state alone
+	UpdateRule(rule *model.AlertRule) error
+	DeleteRule(ruleUUID string) error
+	// SaveEvaluation records when a rule was last evaluated and the error, if any
+	SaveEvaluation(ruleUUID string, evaluatedAt time.Time, evalErr string) error
+}
+
+type ruleRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// NewRuleRepository creates a new rule repository and ensures its table exists
+func NewRuleRepository(conf config.BaseConfig) (RuleRepository, error) {
+	if conf.DBConnection == nil {
+		return nil, fmt.Errorf("rule repository requires a database connection")
+	}
+
+	if err := conf.DBConnection.AutoMigrate(&model.AlertRule{}); err != nil {
+		conf.Logger.ERROR(config.SRRLERR, "Failed to migrate alert rules table", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to migrate alert rules: %w", err)
+	}
+
+	conf.Logger.INFO(config.SRRLINIT, "Server rule repository initialized", nil)
+
+	return &ruleRepository{
+		BaseConfig: conf,
+	}, nil
+}
+
+func (r *ruleRepository) CreateRule(rule *model.AlertRule) error {
+	if err := r.BaseConfig.DBConnection.Create(rule).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRRLERR, "Failed to create alert rule", map[string]interface{}{
+			"error": err.Error(),
+			"name":  rule.Name,
+		})
+		return fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	return nil
+}
+
+func (r *ruleRepository) GetRules(enabledOnly bool) ([]model.AlertRule, error) {
+	query := r.BaseConfig.DBConnection.Model(&model.AlertRule{})
+	if enabledOnly {
+		query = query.Where("enabled = ?", true)
+	}
+
+	var rules []model.AlertRule
+	if err := query.Order("name").Find(&rules).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRRLERR, "Failed to query alert rules", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to query alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (r *ruleRepository) GetRule(ruleUUID string) (*model.AlertRule, error) {
+	var rule model.AlertRule
+	if err := r.BaseConfig.DBConnection.Where("uuid = ?", ruleUUID).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *ruleRepository) UpdateRule(rule *model.AlertRule) error {
+	err := r.BaseConfig.DBConnection.Model(rule).
+		Select("name", "kind", "sensor_type", "metric", "operator", "threshold", "for_minutes", "agent_uuid", "agent_group", "severity", "message", "enabled", "updated_at").
+		Updates(rule).Error
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRRLERR, "Failed to update alert rule", map[string]interface{}{
+			"error":     err.Error(),
+			"rule_uuid": rule.UUID,
+		})
+		return fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteRule returns gorm.ErrRecordNotFound when no rule has the UUID
+func (r *ruleRepository) DeleteRule(ruleUUID string) error {
+	result := r.BaseConfig.DBConnection.Where("uuid = ?", ruleUUID).Delete(&model.AlertRule{})
+	if result.Error != nil {
+		r.BaseConfig.Logger.ERROR(config.SRRLERR, "Failed to delete alert rule", map[string]interface{}{
+			"error":     result.Error.Error(),
+			"rule_uuid": ruleUUID,
+		})
+		return fmt.Errorf("failed to delete alert rule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *ruleRepository) SaveEvaluation(ruleUUID string, evaluatedAt time.Time, evalErr string) error {
+	err := r.BaseConfig.DBConnection.Model(&model.AlertRule{}).
+		Where("uuid = ?", ruleUUID).
+		UpdateColumns(map[string]interface{}{"evaluated_at": evaluatedAt, "last_error": evalErr}).Error
+	if err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRRLERR, "Failed to save alert rule evaluation", map[string]interface{}{
+			"error":     err.Error(),
+			"rule_uuid": ruleUUID,
+		})
+		return fmt.Errorf("failed to save alert rule evaluation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"gorm.io/gorm"
+)
+
+// SilenceRepository defines the interface for silences and maintenance windows
+type SilenceRepository interface {
+	CreateSilence(silence *model.Silence) error
+	// GetSilences returns silences that have not ended at now, or all of them with includeExpired
+	GetSilences(now time.Time, includeExpired bool) ([]model.Silence, error)
+	GetSilence(silenceUUID string) (*model.Silence, error)
+	// ExpireSilence ends a silence at now, keeping it for the record
+	ExpireSilence(silenceUUID string, now time.Time) error
+
+	CreateMaintenanceWindow(window *model.MaintenanceWindow) error
+	GetMaintenanceWindows() ([]model.MaintenanceWindow, error)
+	DeleteMaintenanceWindow(windowUUID string) error
+}
+
+type silenceRepository struct {
+	BaseConfig config.BaseConfig
+}
+
+// NewSilenceRepository creates a new silence repository and ensures its tables exist
+func NewSilenceRepository(conf config.BaseConfig) (SilenceRepository, error) {
+	if conf.DBConnection == nil {
+		return nil, fmt.Errorf("silence repository requires a database connection")
+	}
+
+	if err := conf.DBConnection.AutoMigrate(&model.Silence{}, &model.MaintenanceWindow{}); err != nil {
+		conf.Logger.ERROR(config.SRSLERR, "Failed to migrate silence tables", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to migrate silences: %w", err)
+	}
+
+	conf.Logger.INFO(config.SRSLINIT, "Server silence repository initialized", nil)
+
+	return &silenceRepository{
+		BaseConfig: conf,
+	}, nil
+}
+
+func (r *silenceRepository) CreateSilence(silence *model.Silence) error {
+	if err := r.BaseConfig.DBConnection.Create(silence).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRSLERR, "Failed to create silence", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return fmt.Errorf("failed to create silence: %w", err)
+	}
+	return nil
+}
+
+func (r *silenceRepository) GetSilences(now time.Time, includeExpired bool) ([]model.Silence, error) {
+	query := r.BaseConfig.DBConnection.Model(&model.Silence{})
+	if !includeExpired {
+		query = query.Where("ends_at > ?", now)
+	}
+
+	var silences []model.Silence
+	if err := query.Order("starts_at").Find(&silences).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRSLERR, "Failed to query silences", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to query silences: %w", err)
+	}
+	return silences, nil
+}
+
+func (r *silenceRepository) GetSilence(silenceUUID string) (*model.Silence, error) {
+	var silence model.Silence
+	if err := r.BaseConfig.DBConnection.Where("uuid = ?", silenceUUID).First(&silence).Error; err != nil {
+		return nil, err
+	}
+	return &silence, nil
+}
+
+func (r *silenceRepository) ExpireSilence(silenceUUID string, now time.Time) error {
+	result := r.BaseConfig.DBConnection.Model(&model.Silence{}).
+		Where("uuid = ? AND ends_at > ?", silenceUUID, now).
+		Update("ends_at", now)
+	if result.Error != nil {
+		r.BaseConfig.Logger.ERROR(config.SRSLERR, "Failed to expire silence", map[string]interface{}{
+			"error":        result.Error.Error(),
+			"silence_uuid": silenceUUID,
+		})
+		return fmt.Errorf("failed to expire silence: %w", result.Error)
+	}
+	return nil
+}
+
+func (r *silenceRepository) CreateMaintenanceWindow(window *model.MaintenanceWindow) error {
+	if err := r.BaseConfig.DBConnection.Create(window).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRSLERR, "Failed to create maintenance window", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return fmt.Errorf("failed to create maintenance window: %w", err)
+	}
+	return nil
+}
+
+func (r *silenceRepository) GetMaintenanceWindows() ([]model.MaintenanceWindow, error) {
+	var windows []model.MaintenanceWindow
+	if err := r.BaseConfig.DBConnection.Order("agent_group, start_time").Find(&windows).Error; err != nil {
+		r.BaseConfig.Logger.ERROR(config.SRSLERR, "Failed to query maintenance windows", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, fmt.Errorf("failed to query maintenance windows: %w", err)
+	}
+	return windows, nil
+}
+
+// DeleteMaintenanceWindow returns gorm.ErrRecordNotFound when no window has the UUID
+func (r *silenceRepository) DeleteMaintenanceWindow(windowUUID string) error {
+	result := r.BaseConfig.DBConnection.Where("uuid = ?", windowUUID).Delete(&model.MaintenanceWindow{})
+	if result.Error != nil {
+		r.BaseConfig.Logger.ERROR(config.SRSLERR, "Failed to delete maintenance window", map[string]interface{}{
+			"error":       result.Error.Error(),
+			"window_uuid": windowUUID,
+		})
+		return fmt.Errorf("failed to delete maintenance window: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -8,20 +8,62 @@ import (
 	"github.com/ryo-arima/circulator/pkg/server/repository"
 )
 
-func InitRouter(conf config.BaseConfig) *gin.Engine {
+func InitRouter(conf config.BaseConfig) (*gin.Engine, error) {
 	conf.Logger.INFO(config.SRIR, "")
 
 	// Initialize required repositories with config injection
 	commonRepository := repository.NewCommonRepository(conf)
 	agentRepository := repository.NewAgentRepository(conf)
+	metricRepository, err := repository.NewMetricRepository(conf)
+	if err != nil {
+		return nil, err
+	}
+	retentionRepository, err := repository.NewRetentionRepository(conf)
+	if err != nil {
+		return nil, err
+	}
+	alertRepository, err := repository.NewAlertRepository(conf)
+	if err != nil {
+		return nil, err
+	}
+	notificationRepository, err := repository.NewNotificationRepository(conf)
+	if err != nil {
+		return nil, err
+	}
+	silenceRepository, err := repository.NewSilenceRepository(conf)
+	if err != nil {
+		return nil, err
+	}
+	ruleRepository, err := repository.NewRuleRepository(conf)
+	if err != nil {
+		return nil, err
+	}
+	outboxRepository, err := repository.NewOutboxRepository(conf)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize required controllers with config injection
 	commonController := controller.NewCommonController(conf, commonRepository)
 	agentController := controller.NewAgentController(conf, agentRepository, commonRepository)
+	metricController := controller.NewMetricController(conf, metricRepository)
+	retentionController := controller.NewRetentionController(conf, retentionRepository)
+	alertController := controller.NewAlertController(conf, alertRepository)
+	notificationController := controller.NewNotificationController(conf, notificationRepository)
+	silenceController := controller.NewSilenceController(conf, silenceRepository)
+	ruleController := controller.NewRuleController(conf, ruleRepository)
+	watchController := controller.NewWatchController(conf, agentRepository, outboxRepository)
 
 	conf.Logger.DEBUG(config.SRCARI, "", map[string]interface{}{
-		"common_controller": "initialized",
-		"agent_controller":  "initialized",
+		"common_controller":       "initialized",
+		"agent_controller":        "initialized",
+		"metric_controller":       "initialized",
+		"retention_controller":    "initialized",
+		"alert_controller":        "initialized",
+		"notification_controller": "initialized",
+		"silence_controller":      "initialized",
+		"rule_controller":         "initialized",
+		"watch_controller":        "initialized",
 	})
 
 	router := gin.Default()
@@ -49,6 +91,7 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 		// ============ AGENT ENDPOINTS ============
 		v1.GET("/agents", agentController.GetAgents)
 		v1.GET("/agents/count", agentController.CountAgents)
+		v1.GET("/agents/watch", watchController.WatchAgents)
 		v1.POST("/agent", agentController.CreateAgent)
 		v1.PUT("/agent/:id", agentController.UpdateAgent)
 		v1.DELETE("/agent/:id", agentController.DeleteAgent)
@@ -77,6 +120,37 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 		v1.POST("/agent/:id/config/rules", agentController.CreateAgentConfigRules)
 		v1.PUT("/agent/:id/config/rules/:rule_id", agentController.UpdateAgentConfigRules)
 		v1.DELETE("/agent/:id/config/rules/:rule_id", agentController.DeleteAgentConfigRules)
+
+		// Time-series metrics
+		v1.GET("/agent/:id/metrics", metricController.GetAgentMetrics)
+
+		// ============ ALERT ENDPOINTS ============
+		v1.GET("/alerts", alertController.GetAlerts)
+		v1.GET("/alert/:id", alertController.GetAlert)
+		v1.POST("/alert/:id/ack", alertController.AcknowledgeAlert)
+		v1.POST("/alert/:id/resolve", alertController.ResolveAlert)
+
+		// ============ ALERT RULE ENDPOINTS ============
+		v1.GET("/alert-rules", ruleController.GetRules)
+		v1.GET("/alert-rule/:id", ruleController.GetRule)
+		v1.POST("/alert-rule", ruleController.CreateRule)
+		v1.PUT("/alert-rule/:id", ruleController.UpdateRule)
+		v1.DELETE("/alert-rule/:id", ruleController.DeleteRule)
+
+		// ============ EVENT ENDPOINTS ============
+		v1.GET("/events", watchController.TailEvents)
+
+		// ============ SILENCE ENDPOINTS ============
+		v1.GET("/silences", silenceController.GetSilences)
+		v1.POST("/silence", silenceController.CreateSilence)
+		v1.DELETE("/silence/:id", silenceController.ExpireSilence)
+		v1.GET("/maintenance-windows", silenceController.GetMaintenanceWindows)
+		v1.POST("/maintenance-window", silenceController.CreateMaintenanceWindow)
+		v1.DELETE("/maintenance-window/:id", silenceController.DeleteMaintenanceWindow)
+
+		// ============ ADMIN ENDPOINTS ============
+		v1.GET("/admin/retention", retentionController.GetRetention)
+		v1.GET("/admin/notifications", notificationController.GetDeliveries)
 	}
 
 	conf.Logger.INFO(config.SRHRIS, "", map[string]interface{}{
@@ -84,5 +158,5 @@ func InitRouter(conf config.BaseConfig) *gin.Engine {
 		"middleware_applied":   "authentication and logging",
 	})
 
-	return router
+	return router, nil
 }
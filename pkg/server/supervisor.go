@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+)
+
+const (
+	// supervisorMinBackoff and supervisorMaxBackoff bound the delay between worker restarts
+	supervisorMinBackoff = time.Second
+	supervisorMaxBackoff = 30 * time.Second
+)
+
+// Worker is a long-running task owned by the Supervisor. Run must return once ctx is done.
+type Worker struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Supervisor runs workers concurrently, restarting any that fail until its context is cancelled
+type Supervisor struct {
+	conf    config.BaseConfig
+	workers []Worker
+}
+
+// NewSupervisor creates a new Supervisor
+func NewSupervisor(conf config.BaseConfig) *Supervisor {
+	return &Supervisor{
+		conf: conf,
+	}
+}
+
+// Add registers a worker. Workers must be added before Run is called.
+func (s *Supervisor) Add(name string, run func(ctx context.Context) error) {
+	s.conf.Logger.DEBUG(config.SSVADD, "Worker registered with supervisor", map[string]interface{}{
+		"worker": name,
+	})
+	s.workers = append(s.workers, Worker{Name: name, Run: run})
+}
+
+// Run starts all workers and blocks until ctx is cancelled and every worker has returned
+func (s *Supervisor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, worker := range s.workers {
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			s.supervise(ctx, worker)
+		}(worker)
+	}
+
+	<-ctx.Done()
+	s.conf.Logger.INFO(config.SSVSTOP, "Supervisor stopping workers", map[string]interface{}{
+		"workers": len(s.workers),
+	})
+
+	wg.Wait()
+	s.conf.Logger.INFO(config.SSVDONE, "All supervised workers stopped", nil)
+}
+
+// supervise runs a single worker, restarting it with exponential backoff when it exits early
+func (s *Supervisor) supervise(ctx context.Context, worker Worker) {
+	backoff := supervisorMinBackoff
+	for {
+		s.conf.Logger.INFO(config.SSVRUN, "Supervisor starting worker", map[string]interface{}{
+			"worker": worker.Name,
+		})
+
+		started := time.Now()
+		err := s.runWorker(ctx, worker)
+
+		if ctx.Err() != nil {
+			s.conf.Logger.INFO(config.SSVEXIT, "Supervised worker exited", map[string]interface{}{
+				"worker": worker.Name,
+			})
+			return
+		}
+
+		if err != nil {
+			s.conf.Logger.ERROR(config.SSVERR, "Supervised worker failed", map[string]interface{}{
+				"worker": worker.Name,
+				"error":  err.Error(),
+			})
+		}
+
+		// A worker that ran for a while before failing starts over from the minimum delay
+		if time.Since(started) > supervisorMaxBackoff {
+			backoff = supervisorMinBackoff
+		}
+
+		s.conf.Logger.WARN(config.SSVRST, "Supervisor restarting worker", map[string]interface{}{
+			"worker":  worker.Name,
+			"backoff": backoff.String(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > supervisorMaxBackoff {
+			backoff = supervisorMaxBackoff
+		}
+	}
+}
+
+// runWorker runs the worker once, converting a panic into an error so it can be restarted
+func (s *Supervisor) runWorker(ctx context.Context, worker Worker) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("worker panicked")
+			s.conf.Logger.ERROR(config.SSVERR, "Supervised worker panicked", map[string]interface{}{
+				"worker": worker.Name,
+				"panic":  r,
+			})
+		}
+	}()
+	return worker.Run(ctx)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/ryo-arima/circulator/pkg/entity/model"
 	"github.com/ryo-arima/circulator/pkg/entity/request"
 	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"gorm.io/gorm"
 )
 
 type AgentUsecase interface {
@@ -75,7 +76,21 @@ func (u *agentUsecase) CreateAgent(req request.AgentRequest) model.Agent {
 	u.config.Logger.INFO(config.SUACA1, "Creating new agent", map[string]interface{}{
 		"agent_name": req.Name,
 	})
-	return u.repo.CreateAgent(req)
+
+	var agent model.Agent
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		var err error
+		agent, err = repo.CreateAgent(req)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventAgentRegistered, agent.UUID, "agent", model.ChangeActionCreated, nil, agent)
+	})
+	if err != nil {
+		u.logMutationError("CreateAgent", "", err)
+		return model.Agent{}
+	}
+	return agent
 }
 
 func (u *agentUsecase) UpdateAgent(uuid string, req request.AgentUpdateRequest) model.Agent {
@@ -83,15 +98,43 @@ func (u *agentUsecase) UpdateAgent(uuid string, req request.AgentUpdateRequest)
 		"agent_uuid": uuid,
 		"agent_name": req.Name,
 	})
-	return u.repo.UpdateAgent(uuid, req)
+
+	var agent model.Agent
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before := repo.GetAgentByUUID(uuid)
+		if before.UUID == "" {
+			return gorm.ErrRecordNotFound
+		}
+		var err error
+		agent, err = repo.UpdateAgent(uuid, req)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventAgentUpdated, uuid, "agent", model.ChangeActionUpdated, before, agent)
+	})
+	if err != nil {
+		u.logMutationError("UpdateAgent", uuid, err)
+		return model.Agent{}
+	}
+	return agent
 }
 
 func (u *agentUsecase) DeleteAgent(uuid string) error {
 	u.config.Logger.INFO(config.SUADA, "Deleting agent", map[string]interface{}{
 		"agent_uuid": uuid,
 	})
-	result := u.repo.DeleteAgent(uuid)
-	return result.Error
+
+	return u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before := repo.GetAgentByUUID(uuid)
+		result := repo.DeleteAgent(uuid)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return u.recordChange(outbox, model.ServerEventAgentDeleted, uuid, "agent", model.ChangeActionDeleted, before, nil)
+	})
 }
 
 // Agent Info operations
@@ -106,7 +149,20 @@ func (u *agentUsecase) CreateAgentInfo(req request.AgentInfoRequest) (*model.Age
 	u.config.Logger.INFO(config.SUACAI, "Creating agent info", map[string]interface{}{
 		"hostname": req.Hostname,
 	})
-	return u.repo.CreateAgentInfo(req)
+
+	var created *model.AgentInfo
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		var err error
+		created, err = repo.CreateAgentInfo(req)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventAgentInfoCreated, created.UUID, "agent_info", model.ChangeActionCreated, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func (u *agentUsecase) UpdateAgentInfo(agentUUID string, req request.AgentInfoRequest) (*model.AgentInfo, error) {
@@ -114,14 +170,43 @@ func (u *agentUsecase) UpdateAgentInfo(agentUUID string, req request.AgentInfoRe
 		"agent_uuid": agentUUID,
 		"hostname":   req.Hostname,
 	})
-	return u.repo.UpdateAgentInfo(agentUUID, req)
+
+	var updated *model.AgentInfo
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before, err := repo.GetAgentInfo(agentUUID)
+		if err != nil {
+			return err
+		}
+		if _, err := repo.UpdateAgentInfo(agentUUID, req); err != nil {
+			return err
+		}
+		updated, err = repo.GetAgentInfo(agentUUID)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventAgentInfoUpdated, updated.UUID, "agent_info", model.ChangeActionUpdated, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (u *agentUsecase) DeleteAgentInfo(agentUUID string) error {
 	u.config.Logger.INFO(config.SUADAI, "Deleting agent info", map[string]interface{}{
 		"agent_uuid": agentUUID,
 	})
-	return u.repo.DeleteAgentInfo(agentUUID)
+
+	return u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before, _ := repo.GetAgentInfo(agentUUID)
+		if err := repo.DeleteAgentInfo(agentUUID); err != nil {
+			return err
+		}
+		if before == nil {
+			return nil
+		}
+		return u.recordChange(outbox, model.ServerEventAgentInfoDeleted, before.UUID, "agent_info", model.ChangeActionDeleted, before, nil)
+	})
 }
 
 // System Info operations
@@ -136,7 +221,20 @@ func (u *agentUsecase) CreateAgentSystem(req request.AgentSystemRequest) (*model
 	u.config.Logger.INFO(config.SUACAS, "Creating agent system info", map[string]interface{}{
 		"os": req.OS,
 	})
-	return u.repo.CreateSystemInfo(req)
+
+	var created *model.SystemInfo
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		var err error
+		created, err = repo.CreateSystemInfo(req)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventSystemCreated, created.AgentUUID, "agent_system", model.ChangeActionCreated, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func (u *agentUsecase) UpdateAgentSystem(agentUUID string, req request.AgentSystemRequest) (*model.SystemInfo, error) {
@@ -144,14 +242,43 @@ func (u *agentUsecase) UpdateAgentSystem(agentUUID string, req request.AgentSyst
 		"agent_uuid": agentUUID,
 		"os":         req.OS,
 	})
-	return u.repo.UpdateSystemInfo(agentUUID, req)
+
+	var updated *model.SystemInfo
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before, err := repo.GetSystemInfo(agentUUID)
+		if err != nil {
+			return err
+		}
+		if _, err := repo.UpdateSystemInfo(agentUUID, req); err != nil {
+			return err
+		}
+		updated, err = repo.GetSystemInfo(agentUUID)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventSystemUpdated, updated.AgentUUID, "agent_system", model.ChangeActionUpdated, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (u *agentUsecase) DeleteAgentSystem(agentUUID string) error {
 	u.config.Logger.INFO(config.SUADAS, "Deleting agent system info", map[string]interface{}{
 		"agent_uuid": agentUUID,
 	})
-	return u.repo.DeleteSystemInfo(agentUUID)
+
+	return u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before, _ := repo.GetSystemInfo(agentUUID)
+		if err := repo.DeleteSystemInfo(agentUUID); err != nil {
+			return err
+		}
+		if before == nil {
+			return nil
+		}
+		return u.recordChange(outbox, model.ServerEventSystemDeleted, before.AgentUUID, "agent_system", model.ChangeActionDeleted, before, nil)
+	})
 }
 
 // Stream Processing Config operations
@@ -167,7 +294,20 @@ func (u *agentUsecase) CreateStreamProcessingConfig(req request.AgentConfigReque
 		"agent_uuid":  req.AgentUUID,
 		"sensor_type": req.SensorType,
 	})
-	return u.repo.CreateStreamProcessingConfig(req)
+
+	var created *model.StreamProcessingConfig
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		var err error
+		created, err = repo.CreateStreamProcessingConfig(req)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventConfigCreated, created.AgentUUID, "agent_config", model.ChangeActionCreated, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func (u *agentUsecase) UpdateStreamProcessingConfig(agentUUID string, req request.AgentConfigRequest) (*model.StreamProcessingConfig, error) {
@@ -175,14 +315,43 @@ func (u *agentUsecase) UpdateStreamProcessingConfig(agentUUID string, req reques
 		"agent_uuid":  agentUUID,
 		"sensor_type": req.SensorType,
 	})
-	return u.repo.UpdateStreamProcessingConfig(agentUUID, req)
+
+	var updated *model.StreamProcessingConfig
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before, err := repo.GetStreamProcessingConfig(agentUUID)
+		if err != nil {
+			return err
+		}
+		if _, err := repo.UpdateStreamProcessingConfig(agentUUID, req); err != nil {
+			return err
+		}
+		updated, err = repo.GetStreamProcessingConfig(agentUUID)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventConfigUpdated, updated.AgentUUID, "agent_config", model.ChangeActionUpdated, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (u *agentUsecase) DeleteStreamProcessingConfig(agentUUID string) error {
 	u.config.Logger.INFO(config.SUADSPC, "Deleting stream processing config", map[string]interface{}{
 		"agent_uuid": agentUUID,
 	})
-	return u.repo.DeleteStreamProcessingConfig(agentUUID)
+
+	return u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before, _ := repo.GetStreamProcessingConfig(agentUUID)
+		if err := repo.DeleteStreamProcessingConfig(agentUUID); err != nil {
+			return err
+		}
+		if before == nil {
+			return nil
+		}
+		return u.recordChange(outbox, model.ServerEventConfigDeleted, before.AgentUUID, "agent_config", model.ChangeActionDeleted, before, nil)
+	})
 }
 
 // Processing Rules operations
@@ -198,7 +367,20 @@ func (u *agentUsecase) CreateProcessingRule(agentUUID string, req request.AgentC
 		"agent_uuid": agentUUID,
 		"rule_name":  req.Name,
 	})
-	return u.repo.CreateProcessingRule(agentUUID, req)
+
+	var created *model.ProcessingRule
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		var err error
+		created, err = repo.CreateProcessingRule(agentUUID, req)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventRuleCreated, agentUUID, "agent_config_rule", model.ChangeActionCreated, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
 }
 
 func (u *agentUsecase) UpdateProcessingRule(agentUUID, ruleUUID string, req request.AgentConfigRulesRequest) (*model.ProcessingRule, error) {
@@ -207,7 +389,23 @@ func (u *agentUsecase) UpdateProcessingRule(agentUUID, ruleUUID string, req requ
 		"rule_uuid":  ruleUUID,
 		"rule_name":  req.Name,
 	})
-	return u.repo.UpdateProcessingRule(agentUUID, ruleUUID, req)
+
+	var updated *model.ProcessingRule
+	err := u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before, err := findProcessingRule(repo, agentUUID, ruleUUID)
+		if err != nil {
+			return err
+		}
+		updated, err = repo.UpdateProcessingRule(agentUUID, ruleUUID, req)
+		if err != nil {
+			return err
+		}
+		return u.recordChange(outbox, model.ServerEventRuleUpdated, agentUUID, "agent_config_rule", model.ChangeActionUpdated, before, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 func (u *agentUsecase) DeleteProcessingRule(agentUUID, ruleUUID string) error {
@@ -215,5 +413,53 @@ func (u *agentUsecase) DeleteProcessingRule(agentUUID, ruleUUID string) error {
 		"agent_uuid": agentUUID,
 		"rule_uuid":  ruleUUID,
 	})
-	return u.repo.DeleteProcessingRule(agentUUID, ruleUUID)
+
+	return u.repo.Transaction(func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		before, _ := findProcessingRule(repo, agentUUID, ruleUUID)
+		if err := repo.DeleteProcessingRule(agentUUID, ruleUUID); err != nil {
+			return err
+		}
+		if before == nil {
+			return nil
+		}
+		return u.recordChange(outbox, model.ServerEventRuleDeleted, agentUUID, "agent_config_rule", model.ChangeActionDeleted, before, nil)
+	})
+}
+
+// recordChange enqueues a mutation event in the caller's transaction
+func (u *agentUsecase) recordChange(outbox repository.OutboxRepository, eventType, agentUUID, resource, action string, before, after interface{}) error {
+	event, err := newChangeEvent(eventType, agentUUID, resource, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	u.config.Logger.DEBUG(config.SUAEVT, "Recording mutation event", map[string]interface{}{
+		"event_id":   event.ID,
+		"event_type": eventType,
+		"agent_uuid": agentUUID,
+	})
+	return outbox.Enqueue(event)
+}
+
+// logMutationError logs failures of mutations whose signature has no error return
+func (u *agentUsecase) logMutationError(operation, agentUUID string, err error) {
+	u.config.Logger.ERROR(config.SUAERR, "Agent mutation failed", map[string]interface{}{
+		"operation":  operation,
+		"agent_uuid": agentUUID,
+		"error":      err.Error(),
+	})
+}
+
+// findProcessingRule looks up a single rule of an agent by UUID
+func findProcessingRule(repo repository.AgentRepository, agentUUID, ruleUUID string) (*model.ProcessingRule, error) {
+	rules, err := repo.GetProcessingRules(agentUUID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if rules[i].UUID == ruleUUID {
+			return &rules[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
 }
@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"gorm.io/gorm"
+)
+
+// Alert lifecycle errors, mapped to 404 and 409 by the controller
+var (
+	ErrAlertNotFound          = errors.New("alert not found")
+	ErrInvalidAlertTransition = errors.New("invalid alert transition")
+)
+
+type AlertUsecase interface {
+	HandleAlert(ctx context.Context, data *model.AlertData) error
+	GetAlerts(req request.AlertListRequest) ([]model.Alert, error)
+	GetAlert(alertUUID string) (*model.Alert, error)
+	Acknowledge(alertUUID, user string, req request.AlertActionRequest) (*model.Alert, error)
+	Resolve(alertUUID, user string, req request.AlertActionRequest) (*model.Alert, error)
+}
+
+type alertUsecase struct {
+	config    config.BaseConfig
+	alertRepo repository.AlertRepository
+}
+
+func NewAlertUsecase(conf config.BaseConfig, alertRepo repository.AlertRepository) AlertUsecase {
+	return &alertUsecase{
+		config:    conf,
+		alertRepo: alertRepo,
+	}
+}
+
+// HandleAlert records an AlertData, emitting an event only when it opens a new alert so
+// a flapping sensor does not flood subscribers
+func (u *alertUsecase) HandleAlert(ctx context.Context, data *model.AlertData) error {
+	if data.AgentUUID == "" {
+		u.config.Logger.WARN(config.SUALSKP, "Skipping alert without agent", map[string]interface{}{
+			"uuid": data.UUID,
+		})
+		return nil
+	}
+
+	return u.alertRepo.TransactionContext(ctx, func(repo repository.AlertRepository, outbox repository.OutboxRepository) error {
+		alert, opened, err := repo.RecordAlert(data)
+		if err != nil || !opened {
+			return err
+		}
+
+		u.config.Logger.INFO(config.SUALOPEN, "Alert opened", map[string]interface{}{
+			"alert_uuid":  alert.UUID,
+			"agent_uuid":  alert.AgentUUID,
+			"sensor_type": alert.SensorType,
+			"rule":        alert.Rule,
+			"severity":    alert.Severity,
+		})
+		return u.recordChange(outbox, model.ServerEventAlertOpened, alert, model.ChangeActionCreated, nil, alert)
+	})
+}
+
+func (u *alertUsecase) GetAlerts(req request.AlertListRequest) ([]model.Alert, error) {
+	return u.alertRepo.GetAlerts(req)
+}
+
+func (u *alertUsecase) GetAlert(alertUUID string) (*model.Alert, error) {
+	alert, err := u.alertRepo.GetAlert(alertUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAlertNotFound
+	}
+	return alert, err
+}
+
+// Acknowledge marks an unresolved alert as being worked on. Acknowledging again reassigns it.
+func (u *alertUsecase) Acknowledge(alertUUID, user string, req request.AlertActionRequest) (*model.Alert, error) {
+	return u.transition(alertUUID, user, req, model.AlertStatusAcknowledged, model.ServerEventAlertAcknowledged, func(alert *model.Alert, now time.Time) {
+		alert.Assignee = req.Assignee
+		if alert.Assignee == "" {
+			alert.Assignee = user
+		}
+		if alert.AcknowledgedAt == nil {
+			alert.AcknowledgedAt = &now
+		}
+	})
+}
+
+// Resolve closes an alert. The next AlertData for its key opens a new alert.
+func (u *alertUsecase) Resolve(alertUUID, user string, req request.AlertActionRequest) (*model.Alert, error) {
+	return u.transition(alertUUID, user, req, model.AlertStatusResolved, model.ServerEventAlertResolved, func(alert *model.Alert, now time.Time) {
+		alert.OpenKey = nil
+		alert.ResolvedAt = &now
+		if req.Assignee != "" {
+			alert.Assignee = req.Assignee
+		}
+	})
+}
+
+// transition moves an unresolved alert to status, recording a note and an event in one transaction
+func (u *alertUsecase) transition(alertUUID, user string, req request.AlertActionRequest, status, eventType string, apply func(alert *model.Alert, now time.Time)) (*model.Alert, error) {
+	u.config.Logger.INFO(config.SUALTR, "Changing alert status", map[string]interface{}{
+		"alert_uuid": alertUUID,
+		"status":     status,
+		"user":       user,
+	})
+
+	var updated *model.Alert
+	err := u.alertRepo.Transaction(func(repo repository.AlertRepository, outbox repository.OutboxRepository) error {
+		alert, err := repo.GetAlert(alertUUID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAlertNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if alert.Status == model.AlertStatusResolved {
+			return fmt.Errorf("%w: alert %s is already resolved", ErrInvalidAlertTransition, alertUUID)
+		}
+
+		before := *alert
+		before.Notes = nil
+		now := time.Now()
+		alert.Status = status
+		apply(alert, now)
+
+		note := &model.AlertNote{
+			Author:    user,
+			Status:    status,
+			Text:      req.Note,
+			CreatedAt: now,
+		}
+		if err := repo.UpdateAlert(alert, note); err != nil {
+			return err
+		}
+		alert.Notes = append(alert.Notes, *note)
+		updated = alert
+
+		after := *alert
+		after.Notes = nil
+		return u.recordChange(outbox, eventType, alert, model.ChangeActionUpdated, &before, &after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// recordChange enqueues an alert lifecycle event in the caller's transaction
+func (u *alertUsecase) recordChange(outbox repository.OutboxRepository, eventType string, alert *model.Alert, action string, before, after interface{}) error {
+	event, err := newChangeEvent(eventType, alert.AgentUUID, "alert", action, before, after)
+	if err != nil {
+		return err
+	}
+	return outbox.Enqueue(event)
+}
@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// newChangeEvent builds a mutation ServerEvent carrying the before/after state of a
+// resource and the fields that differ between them. Either side may be nil.
+func newChangeEvent(eventType, agentID, resource, action string, before, after interface{}) (*model.ServerEvent, error) {
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	change := model.ResourceChange{
+		Resource: resource,
+		Action:   action,
+		Before:   beforeFields,
+		After:    afterFields,
+		Diff:     diffFields(beforeFields, afterFields),
+	}
+	data, err := json.Marshal(change)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s change: %w", resource, err)
+	}
+
+	return &model.ServerEvent{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		AgentID:   agentID,
+		Data:      string(data),
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// toFields converts a model into its JSON field map, so the diff uses the API field names
+func toFields(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %T: %w", v, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to convert %T to fields: %w", v, err)
+	}
+	return fields, nil
+}
+
+// diffFields returns every field whose value differs between before and after
+func diffFields(before, after map[string]interface{}) map[string]model.FieldChange {
+	diff := make(map[string]model.FieldChange)
+	for key, value := range before {
+		if !reflect.DeepEqual(value, after[key]) {
+			diff[key] = model.FieldChange{Before: value, After: after[key]}
+		}
+	}
+	for key, value := range after {
+		if _, ok := before[key]; !ok {
+			diff[key] = model.FieldChange{Before: nil, After: value}
+		}
+	}
+	return diff
+}
@@ -0,0 +1,105 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+)
+
+type LivenessUsecase interface {
+	// Run checks for missed heartbeats every interval until ctx is cancelled
+	Run(ctx context.Context) error
+	// Check marks the agents whose last heartbeat is older than the timeout offline
+	Check(ctx context.Context, now time.Time)
+}
+
+type livenessUsecase struct {
+	config    config.BaseConfig
+	agentRepo repository.AgentRepository
+}
+
+func NewLivenessUsecase(conf config.BaseConfig, agentRepo repository.AgentRepository) LivenessUsecase {
+	return &livenessUsecase{
+		config:    conf,
+		agentRepo: agentRepo,
+	}
+}
+
+func (u *livenessUsecase) Run(ctx context.Context) error {
+	livenessConfig := u.config.YamlConfig.Application.Server.Liveness
+	interval := time.Duration(livenessConfig.Interval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	u.config.Logger.INFO(config.SULVRUN, "Liveness check starting", map[string]interface{}{
+		"interval": interval.String(),
+		"timeout":  u.timeout().String(),
+	})
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			u.config.Logger.INFO(config.SULVSTOP, "Liveness check stopping", nil)
+			return nil
+		case <-ticker.C:
+			u.Check(ctx, time.Now())
+		}
+	}
+}
+
+// Check records each transition to offline as an agent_status_changed event in the same
+// transaction, so notification routing sees exactly the agents that went offline
+func (u *livenessUsecase) Check(ctx context.Context, now time.Time) {
+	before := now.Add(-u.timeout())
+	agents, err := u.agentRepo.GetStaleAgents(before)
+	if err != nil {
+		u.config.Logger.ERROR(config.SULVERR, "Failed to find agents with missed heartbeats", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	for _, agent := range agents {
+		if ctx.Err() != nil {
+			return
+		}
+		err := u.agentRepo.TransactionContext(ctx, func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+			changed, err := repo.MarkAgentOffline(agent.UUID, before)
+			if err != nil || !changed {
+				return err
+			}
+			event, err := newChangeEvent(model.ServerEventAgentStatusChanged, agent.UUID, "agent", model.ChangeActionUpdated,
+				map[string]interface{}{"status": agent.Status},
+				map[string]interface{}{"status": model.AgentStatusOffline})
+			if err != nil {
+				return err
+			}
+			u.config.Logger.INFO(config.SULVOFF, "Marked agent offline", map[string]interface{}{
+				"agent_uuid":      agent.UUID,
+				"previous_status": agent.Status,
+				"heartbeat_at":    agent.HeartbeatAt,
+			})
+			return outbox.Enqueue(event)
+		})
+		if err != nil {
+			u.config.Logger.ERROR(config.SULVERR, "Failed to mark agent offline", map[string]interface{}{
+				"error":      err.Error(),
+				"agent_uuid": agent.UUID,
+			})
+		}
+	}
+}
+
+func (u *livenessUsecase) timeout() time.Duration {
+	seconds := u.config.YamlConfig.Application.Server.Liveness.Timeout
+	if seconds <= 0 {
+		seconds = 90
+	}
+	return time.Duration(seconds) * time.Second
+}
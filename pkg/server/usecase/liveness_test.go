@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+)
+
+// fakeLivenessRepository keeps agents in memory and applies the staleness condition of
+// the MySQL queries. Heartbeats listed in late arrive between the lookup and the update.
+type fakeLivenessRepository struct {
+	repository.AgentRepository
+	agents map[string]*model.Agent
+	late   map[string]time.Time
+	failOn string
+	outbox *fakeOutboxRepository
+}
+
+func (r *fakeLivenessRepository) stale(agent *model.Agent, before time.Time) bool {
+	return agent.Status != model.AgentStatusOffline && agent.HeartbeatAt != nil && agent.HeartbeatAt.Before(before)
+}
+
+func (r *fakeLivenessRepository) GetStaleAgents(before time.Time) ([]model.Agent, error) {
+	var agents []model.Agent
+	for _, uuid := range sortedKeys(r.agents) {
+		if agent := r.agents[uuid]; r.stale(agent, before) {
+			agents = append(agents, *agent)
+		}
+	}
+	return agents, nil
+}
+
+func (r *fakeLivenessRepository) MarkAgentOffline(uuid string, before time.Time) (bool, error) {
+	if uuid == r.failOn {
+		return false, errors.New("lock wait timeout")
+	}
+	agent := r.agents[uuid]
+	if at, ok := r.late[uuid]; ok {
+		agent.HeartbeatAt = &at
+	}
+	if !r.stale(agent, before) {
+		return false, nil
+	}
+	agent.Status = model.AgentStatusOffline
+	return true, nil
+}
+
+func (r *fakeLivenessRepository) TransactionContext(ctx context.Context, fn func(repo repository.AgentRepository, outbox repository.OutboxRepository) error) error {
+	return fn(r, r.outbox)
+}
+
+type fakeOutboxRepository struct {
+	repository.OutboxRepository
+	events []*model.ServerEvent
+}
+
+func (r *fakeOutboxRepository) Enqueue(event *model.ServerEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestLivenessCheck(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+
+	tests := []struct {
+		name        string
+		agents      []model.Agent
+		late        map[string]time.Time
+		failOn      string
+		wantOffline []string
+		wantBefore  map[string]string // previous status in each recorded event
+	}{
+		{
+			name: "missed heartbeats",
+			agents: []model.Agent{
+				{UUID: "a", Status: "online", HeartbeatAt: ago(2 * time.Minute)},
+				{UUID: "b", Status: "draining", HeartbeatAt: ago(5 * time.Minute)},
+				{UUID: "c", Status: "online", HeartbeatAt: ago(30 * time.Second)},
+			},
+			wantOffline: []string{"a", "b"},
+			wantBefore:  map[string]string{"a": "online", "b": "draining"},
+		},
+		{
+			name: "already offline or never seen",
+			agents: []model.Agent{
+				{UUID: "a", Status: model.AgentStatusOffline, HeartbeatAt: ago(time.Hour)},
+				{UUID: "b", Status: "online"},
+			},
+			wantOffline: []string{"a"},
+			wantBefore:  map[string]string{},
+		},
+		{
+			name: "heartbeat after the lookup",
+			agents: []model.Agent{
+				{UUID: "a", Status: "online", HeartbeatAt: ago(2 * time.Minute)},
+			},
+			late:       map[string]time.Time{"a": now},
+			wantBefore: map[string]string{},
+		},
+		{
+			name: "one failing agent",
+			agents: []model.Agent{
+				{UUID: "a", Status: "online", HeartbeatAt: ago(2 * time.Minute)},
+				{UUID: "b", Status: "online", HeartbeatAt: ago(2 * time.Minute)},
+			},
+			failOn:      "a",
+			wantOffline: []string{"b"},
+			wantBefore:  map[string]string{"b": "online"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := config.BaseConfig{}
+			conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+			conf.YamlConfig.Application.Server.Liveness.Timeout = 90
+
+			outbox := &fakeOutboxRepository{}
+			repo := &fakeLivenessRepository{agents: map[string]*model.Agent{}, late: tt.late, failOn: tt.failOn, outbox: outbox}
+			for i := range tt.agents {
+				agent := tt.agents[i]
+				repo.agents[agent.UUID] = &agent
+			}
+
+			NewLivenessUsecase(conf, repo).Check(context.Background(), now)
+
+			var offline []string
+			for _, uuid := range sortedKeys(repo.agents) {
+				if repo.agents[uuid].Status == model.AgentStatusOffline {
+					offline = append(offline, uuid)
+				}
+			}
+			if !reflect.DeepEqual(offline, tt.wantOffline) {
+				t.Errorf("offline agents = %v, want %v", offline, tt.wantOffline)
+			}
+
+			before := map[string]string{}
+			for _, event := range outbox.events {
+				if event.Type != model.ServerEventAgentStatusChanged {
+					t.Errorf("event type = %q", event.Type)
+				}
+				var change model.ResourceChange
+				if err := json.Unmarshal([]byte(event.Data), &change); err != nil {
+					t.Fatalf("event data: %v", err)
+				}
+				if status := change.After["status"]; status != model.AgentStatusOffline {
+					t.Errorf("event for %s changes status to %v", event.AgentID, status)
+				}
+				before[event.AgentID], _ = change.Before["status"].(string)
+			}
+			if !reflect.DeepEqual(before, tt.wantBefore) {
+				t.Errorf("recorded transitions = %v, want %v", before, tt.wantBefore)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+)
+
+// ErrInvalidMetricQuery is returned for metric queries with bad parameters or too large a result
+var ErrInvalidMetricQuery = errors.New("invalid metric query")
+
+// Query bounds, so a single request cannot pull an unbounded range into memory
+const (
+	maxMetricRows    = 50000
+	maxMetricBuckets = 10000
+)
+
+// metricSourceRaw marks query results computed from raw samples
+const metricSourceRaw = "raw"
+
+type MetricUsecase interface {
+	HandleProcessedData(ctx context.Context, data *model.ProcessedStreamData) error
+	HandleSystemMetrics(ctx context.Context, metrics *model.SystemMetrics) error
+	GetAgentMetrics(agentUUID string, req request.AgentMetricsRequest) (*response.AgentMetrics, error)
+}
+
+type metricUsecase struct {
+	config     config.BaseConfig
+	metricRepo repository.MetricRepository
+}
+
+func NewMetricUsecase(conf config.BaseConfig, metricRepo repository.MetricRepository) MetricUsecase {
+	return &metricUsecase{
+		config:     conf,
+		metricRepo: metricRepo,
+	}
+}
+
+// HandleProcessedData stores the numeric fields of a processing result under its sensor type
+func (u *metricUsecase) HandleProcessedData(ctx context.Context, data *model.ProcessedStreamData) error {
+	if data.AgentUUID == "" {
+		u.config.Logger.WARN(config.SUMSKP, "Skipping processed stream data without agent", map[string]interface{}{
+			"uuid": data.UUID,
+		})
+		return nil
+	}
+
+	anomaly := 0.0
+	if data.Anomaly {
+		anomaly = 1
+	}
+	values := map[string]float64{
+		"processed_value": data.ProcessedValue,
+		"original_value":  data.OriginalValue,
+		"confidence":      data.Confidence,
+		"anomaly":         anomaly,
+		"processing_time": float64(data.ProcessingTime),
+	}
+
+	u.config.Logger.DEBUG(config.SUMPD, "Storing processed stream data", map[string]interface{}{
+		"agent_uuid":  data.AgentUUID,
+		"sensor_type": data.SensorType,
+		"uuid":        data.UUID,
+	})
+	return u.metricRepo.CreateMetrics(ctx, toMetrics(data.AgentUUID, data.SensorType, data.UUID, data.Timestamp, values))
+}
+
+// HandleSystemMetrics stores host metrics under the system sensor type
+func (u *metricUsecase) HandleSystemMetrics(ctx context.Context, metrics *model.SystemMetrics) error {
+	if metrics.AgentUUID == "" {
+		u.config.Logger.WARN(config.SUMSKP, "Skipping system metrics without agent", map[string]interface{}{
+			"uuid": metrics.UUID,
+		})
+		return nil
+	}
+
+	values := map[string]float64{
+		"cpu_usage":    metrics.CPUUsage,
+		"memory_usage": metrics.MemoryUsage,
+		"disk_usage":   metrics.DiskUsage,
+	}
+
+	u.config.Logger.DEBUG(config.SUMSM, "Storing system metrics", map[string]interface{}{
+		"agent_uuid": metrics.AgentUUID,
+		"uuid":       metrics.UUID,
+	})
+	return u.metricRepo.CreateMetrics(ctx, toMetrics(metrics.AgentUUID, model.MetricSensorTypeSystem, metrics.UUID, metrics.Timestamp, values))
+}
+
+// GetAgentMetrics returns one series per sensor type and name. With a step the samples are
+// aggregated into step buckets, read from the coarsest rollup the step is a multiple of.
+func (u *metricUsecase) GetAgentMetrics(agentUUID string, req request.AgentMetricsRequest) (*response.AgentMetrics, error) {
+	now := time.Now()
+	to, err := parseMetricTime(req.To, now)
+	if err != nil {
+		return nil, fmt.Errorf("%w: to: %v", ErrInvalidMetricQuery, err)
+	}
+	from, err := parseMetricTime(req.From, to.Add(-time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("%w: from: %v", ErrInvalidMetricQuery, err)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidMetricQuery)
+	}
+
+	var step time.Duration
+	if req.Step != "" {
+		step, err = time.ParseDuration(req.Step)
+		if err != nil || step <= 0 {
+			return nil, fmt.Errorf("%w: step must be a positive duration such as 1m or 1h", ErrInvalidMetricQuery)
+		}
+		if to.Sub(from)/step > maxMetricBuckets {
+			return nil, fmt.Errorf("%w: more than %d buckets, use a larger step or a shorter range", ErrInvalidMetricQuery, maxMetricBuckets)
+		}
+	}
+
+	u.config.Logger.DEBUG(config.SUMQ, "Querying agent metrics", map[string]interface{}{
+		"agent_uuid":  agentUUID,
+		"sensor_type": req.SensorType,
+		"from":        from,
+		"to":          to,
+		"step":        req.Step,
+	})
+
+	filter := repository.MetricFilter{
+		AgentUUID:  agentUUID,
+		SensorType: req.SensorType,
+		Name:       req.Name,
+		From:       from,
+		To:         to,
+		Limit:      maxMetricRows + 1,
+	}
+	result := &response.AgentMetrics{
+		AgentUUID: agentUUID,
+		From:      from,
+		To:        to,
+		Step:      req.Step,
+		Source:    rollupResolution(step),
+	}
+	series := newSeriesBuilder()
+
+	if result.Source == metricSourceRaw {
+		metrics, err := u.metricRepo.GetMetrics(filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(metrics) > maxMetricRows {
+			return nil, fmt.Errorf("%w: more than %d samples, use a step or a shorter range", ErrInvalidMetricQuery, maxMetricRows)
+		}
+		for _, metric := range metrics {
+			at := metric.RecordedAt
+			if step > 0 {
+				at = at.Truncate(step)
+			}
+			series.add(metric.SensorType, metric.Name, at, 1, metric.Value, metric.Value, metric.Value)
+		}
+	} else {
+		// Include the bucket that contains from, so the first step is complete
+		filter.From = from.Truncate(step)
+		rollups, err := u.metricRepo.GetMetricRollups(filter, result.Source)
+		if err != nil {
+			return nil, err
+		}
+		if len(rollups) > maxMetricRows {
+			return nil, fmt.Errorf("%w: more than %d buckets, use a larger step or a shorter range", ErrInvalidMetricQuery, maxMetricRows)
+		}
+		for _, rollup := range rollups {
+			series.add(rollup.SensorType, rollup.Name, rollup.BucketStart.Truncate(step), rollup.Count, rollup.Sum, rollup.Min, rollup.Max)
+		}
+	}
+
+	result.Series = series.build()
+	return result, nil
+}
+
+// rollupResolution picks the coarsest rollup whose buckets divide step, or raw samples
+func rollupResolution(step time.Duration) string {
+	switch {
+	case step <= 0:
+		return metricSourceRaw
+	case step%time.Hour == 0:
+		return model.MetricResolutionHour
+	case step%time.Minute == 0:
+		return model.MetricResolutionMinute
+	}
+	return metricSourceRaw
+}
+
+// parseMetricTime accepts RFC3339 or unix seconds, returning def for an empty value
+func parseMetricTime(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func toMetrics(agentUUID, sensorType, sourceID string, recordedAt time.Time, values map[string]float64) []model.AgentMetric {
+	if recordedAt.IsZero() {
+		recordedAt = time.Now()
+	}
+	metrics := make([]model.AgentMetric, 0, len(values))
+	for name, value := range values {
+		metrics = append(metrics, model.AgentMetric{
+			AgentUUID:  agentUUID,
+			SensorType: sensorType,
+			ReportID:   sourceID,
+			Name:       name,
+			Value:      value,
+			RecordedAt: recordedAt,
+		})
+	}
+	return metrics
+}
+
+// seriesBuilder merges time-ordered samples or buckets into per-series points
+type seriesBuilder struct {
+	series map[[2]string]*response.MetricSeries
+}
+
+func newSeriesBuilder() *seriesBuilder {
+	return &seriesBuilder{series: map[[2]string]*response.MetricSeries{}}
+}
+
+func (b *seriesBuilder) add(sensorType, name string, at time.Time, count int64, sum, min, max float64) {
+	key := [2]string{sensorType, name}
+	series, ok := b.series[key]
+	if !ok {
+		series = &response.MetricSeries{SensorType: sensorType, Name: name}
+		b.series[key] = series
+	}
+
+	if n := len(series.Points); n > 0 && series.Points[n-1].Timestamp.Equal(at) {
+		point := &series.Points[n-1]
+		point.Count += count
+		point.Sum += sum
+		if min < point.Min {
+			point.Min = min
+		}
+		if max > point.Max {
+			point.Max = max
+		}
+		return
+	}
+	series.Points = append(series.Points, response.MetricPoint{
+		Timestamp: at,
+		Count:     count,
+		Sum:       sum,
+		Min:       min,
+		Max:       max,
+	})
+}
+
+// build computes averages and returns the series sorted by sensor type and name
+func (b *seriesBuilder) build() []response.MetricSeries {
+	result := make([]response.MetricSeries, 0, len(b.series))
+	for _, series := range b.series {
+		for i := range series.Points {
+			point := &series.Points[i]
+			if point.Count > 0 {
+				point.Avg = point.Sum / float64(point.Count)
+			}
+		}
+		result = append(result, *series)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].SensorType != result[j].SensorType {
+			return result[i].SensorType < result[j].SensorType
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
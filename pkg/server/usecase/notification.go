@@ -0,0 +1,464 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+)
+
+// Templates used when a channel does not set its own
+const (
+	defaultSubjectTemplate = "{{if .Severity}}[{{.Severity}}] {{end}}{{.Title}}"
+	defaultEmailTemplate   = "{{.Title}}\n\n{{.Text}}\n{{range $key, $value := .Labels}}\n{{$key}}: {{$value}}{{end}}\n"
+	defaultSlackTemplate   = "*{{if .Severity}}[{{.Severity}}] {{end}}{{.Title}}*\n{{.Text}}"
+	defaultWebhookTemplate = "{{.Text}}"
+)
+
+type NotificationUsecase interface {
+	// HandleServerEvent routes alert lifecycle events and agents going offline; other events are ignored
+	HandleServerEvent(ctx context.Context, event *model.ServerEvent) error
+	HandleNotification(ctx context.Context, notification *model.Notification) error
+	// Dispatch sends due deliveries until ctx is cancelled
+	Dispatch(ctx context.Context) error
+	GetDeliveries(req request.NotificationDeliveryListRequest) ([]model.NotificationDelivery, error)
+}
+
+type notificationUsecase struct {
+	config           config.BaseConfig
+	notificationRepo repository.NotificationRepository
+	silenceRepo      repository.SilenceRepository
+	agentRepo        repository.AgentRepository
+	sender           repository.ChannelSender
+}
+
+func NewNotificationUsecase(conf config.BaseConfig, notificationRepo repository.NotificationRepository, silenceRepo repository.SilenceRepository, agentRepo repository.AgentRepository, sender repository.ChannelSender) NotificationUsecase {
+	return &notificationUsecase{
+		config:           conf,
+		notificationRepo: notificationRepo,
+		silenceRepo:      silenceRepo,
+		agentRepo:        agentRepo,
+		sender:           sender,
+	}
+}
+
+func (u *notificationUsecase) HandleServerEvent(ctx context.Context, event *model.ServerEvent) error {
+	var verb string
+	switch event.Type {
+	case model.ServerEventAlertOpened:
+		verb = "opened"
+	case model.ServerEventAlertAcknowledged:
+		verb = "acknowledged"
+	case model.ServerEventAlertResolved:
+		verb = "resolved"
+	case model.ServerEventAgentStatusChanged:
+		return u.handleStatusChange(ctx, event)
+	default:
+		return nil
+	}
+
+	var change model.ResourceChange
+	if err := json.Unmarshal([]byte(event.Data), &change); err != nil {
+		u.config.Logger.WARN(config.SUNTRT, "Ignoring alert event with invalid data", map[string]interface{}{
+			"error":    err.Error(),
+			"event_id": event.ID,
+		})
+		return nil
+	}
+	alert := change.After
+	if alert == nil {
+		alert = change.Before
+	}
+	field := func(name string) string {
+		value, _ := alert[name].(string)
+		return value
+	}
+
+	subject := field("sensor_type")
+	if rule := field("rule"); rule != "" {
+		subject += "/" + rule
+	}
+	title := fmt.Sprintf("Alert %s: %s", verb, subject)
+	if verb == "acknowledged" && field("assignee") != "" {
+		title += " by " + field("assignee")
+	}
+	text := field("message")
+	if value, ok := alert["last_value"].(float64); ok {
+		text += fmt.Sprintf(" (value %g, threshold %g)", value, alert["threshold"])
+	}
+
+	return u.route(ctx, &model.NotificationMessage{
+		ID:       event.ID,
+		Kind:     model.NotificationKindAlert,
+		Event:    event.Type,
+		Severity: field("severity"),
+		Labels: map[string]string{
+			"agent_uuid":  event.AgentID,
+			"sensor_type": field("sensor_type"),
+			"rule":        field("rule"),
+		},
+		Title:     title,
+		Text:      strings.TrimSpace(text),
+		Fields:    alert,
+		Timestamp: event.Timestamp,
+	})
+}
+
+// handleStatusChange pages on agents going offline; other transitions are not routed
+func (u *notificationUsecase) handleStatusChange(ctx context.Context, event *model.ServerEvent) error {
+	var change model.ResourceChange
+	if err := json.Unmarshal([]byte(event.Data), &change); err != nil {
+		return nil
+	}
+	status, _ := change.After["status"].(string)
+	if status != model.AgentStatusOffline {
+		return nil
+	}
+	previous, _ := change.Before["status"].(string)
+
+	return u.route(ctx, &model.NotificationMessage{
+		ID:       event.ID,
+		Kind:     model.NotificationKindAgent,
+		Event:    event.Type,
+		Severity: "high",
+		Labels: map[string]string{
+			"agent_uuid": event.AgentID,
+		},
+		Title:     "Agent offline",
+		Text:      fmt.Sprintf("Agent %s went offline (was %s)", event.AgentID, previous),
+		Fields:    map[string]interface{}{"status": status, "previous_status": previous},
+		Timestamp: event.Timestamp,
+	})
+}
+
+// HandleNotification routes a client notification. Notifications carry no severity, so
+// they only reach channels without a severity filter.
+func (u *notificationUsecase) HandleNotification(ctx context.Context, notification *model.Notification) error {
+	title := strings.ReplaceAll(notification.Type, "_", " ")
+	if title != "" {
+		title = strings.ToUpper(title[:1]) + title[1:]
+	}
+	return u.route(ctx, &model.NotificationMessage{
+		ID:    notification.ID,
+		Kind:  model.NotificationKindNotification,
+		Event: notification.Type,
+		Labels: map[string]string{
+			"agent_uuid": notification.AgentID,
+		},
+		Title:     title,
+		Text:      notification.Message,
+		Timestamp: notification.Timestamp,
+	})
+}
+
+// route renders the message for every matching channel and stores the deliveries in the
+// transaction carried by ctx. While a silence or maintenance window applies, deliveries
+// are stored as suppressed.
+func (u *notificationUsecase) route(ctx context.Context, msg *model.NotificationMessage) error {
+	now := time.Now()
+	if agentUUID := msg.Labels["agent_uuid"]; agentUUID != "" && u.agentRepo != nil {
+		if group, _ := u.agentRepo.GetAgentByUUID(agentUUID).Metadata["group"].(string); group != "" {
+			msg.Labels["group"] = group
+		}
+	}
+	suppressedBy, err := u.suppression(msg, now)
+	if err != nil {
+		return err
+	}
+
+	var deliveries []model.NotificationDelivery
+	for _, channel := range u.config.YamlConfig.Application.Server.Notifications.Channels {
+		if !channelMatches(channel, msg) {
+			continue
+		}
+		subject, body, err := u.render(channel, msg)
+		if err != nil {
+			u.config.Logger.ERROR(config.SUNTERR, "Failed to render notification", map[string]interface{}{
+				"error":   err.Error(),
+				"channel": channel.Name,
+			})
+			continue
+		}
+		deliveries = append(deliveries, model.NotificationDelivery{
+			UUID:          uuid.New().String(),
+			MessageID:     msg.ID,
+			Channel:       channel.Name,
+			ChannelType:   channel.Type,
+			Event:         msg.Event,
+			Severity:      msg.Severity,
+			Subject:       subject,
+			Body:          body,
+			Status:        model.DeliveryStatusPending,
+			NextAttemptAt: now,
+		})
+		if suppressedBy != "" {
+			delivery := &deliveries[len(deliveries)-1]
+			delivery.Status = model.DeliveryStatusSuppressed
+			delivery.LastError = suppressedBy
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if suppressedBy != "" {
+		u.config.Logger.INFO(config.SUSLSUP, "Suppressed notification", map[string]interface{}{
+			"message_id":    msg.ID,
+			"event":         msg.Event,
+			"suppressed_by": suppressedBy,
+		})
+	} else {
+		u.config.Logger.DEBUG(config.SUNTRT, "Routing notification", map[string]interface{}{
+			"message_id": msg.ID,
+			"event":      msg.Event,
+			"channels":   len(deliveries),
+		})
+	}
+	return u.notificationRepo.EnqueueDeliveries(ctx, deliveries)
+}
+
+// suppression names the silence or maintenance window that applies to msg at now, if any
+func (u *notificationUsecase) suppression(msg *model.NotificationMessage, now time.Time) (string, error) {
+	if u.silenceRepo == nil {
+		return "", nil
+	}
+	silences, err := u.silenceRepo.GetSilences(now, false)
+	if err != nil {
+		return "", err
+	}
+	for _, silence := range silences {
+		if silence.ActiveAt(now) && silence.Matches(msg) {
+			return "silenced by " + silence.UUID, nil
+		}
+	}
+
+	group := msg.Labels["group"]
+	if group == "" {
+		return "", nil
+	}
+	windows, err := u.silenceRepo.GetMaintenanceWindows()
+	if err != nil {
+		return "", err
+	}
+	for _, window := range windows {
+		if window.Group == group && window.ActiveAt(now) {
+			return "maintenance window " + window.UUID, nil
+		}
+	}
+	return "", nil
+}
+
+// channelMatches reports whether every filter of the channel accepts msg
+func channelMatches(channel config.NotificationChannel, msg *model.NotificationMessage) bool {
+	if len(channel.Severities) > 0 && !containsString(channel.Severities, msg.Severity) {
+		return false
+	}
+	if len(channel.Events) > 0 && !containsString(channel.Events, msg.Event) {
+		return false
+	}
+	for key, value := range channel.Labels {
+		if msg.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// render produces the subject and the body sent to the channel: the message JSON for
+// webhooks, a Slack payload for slack and the mail text for email
+func (u *notificationUsecase) render(channel config.NotificationChannel, msg *model.NotificationMessage) (string, string, error) {
+	subject, err := renderTemplate(channel.Subject, defaultSubjectTemplate, msg)
+	if err != nil {
+		return "", "", fmt.Errorf("subject: %w", err)
+	}
+
+	switch channel.Type {
+	case model.ChannelTypeWebhook:
+		text, err := renderTemplate(channel.Template, defaultWebhookTemplate, msg)
+		if err != nil {
+			return "", "", err
+		}
+		payload := *msg
+		payload.Text = text
+		body, err := json.Marshal(payload)
+		return subject, string(body), err
+	case model.ChannelTypeSlack:
+		text, err := renderTemplate(channel.Template, defaultSlackTemplate, msg)
+		if err != nil {
+			return "", "", err
+		}
+		body, err := json.Marshal(map[string]string{"text": text})
+		return subject, string(body), err
+	case model.ChannelTypeEmail:
+		text, err := renderTemplate(channel.Template, defaultEmailTemplate, msg)
+		return subject, text, err
+	}
+	return "", "", fmt.Errorf("unknown channel type %q", channel.Type)
+}
+
+func renderTemplate(text, fallback string, msg *model.NotificationMessage) (string, error) {
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New("notification").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, msg); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func (u *notificationUsecase) Dispatch(ctx context.Context) error {
+	notificationConfig := u.config.YamlConfig.Application.Server.Notifications
+	pollInterval := time.Duration(notificationConfig.PollInterval) * time.Millisecond
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+	batchSize := notificationConfig.BatchSize
+	if batchSize <= 0 {
+		batchSize = 10
+	}
+
+	timeout := time.Duration(notificationConfig.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	// A claimed batch is sent one delivery at a time, so its lease covers a timeout per
+	// delivery plus one to save the results
+	lease := time.Duration(batchSize+1) * timeout
+
+	u.config.Logger.INFO(config.SUNTRUN, "Notification dispatcher starting", map[string]interface{}{
+		"channels":      len(notificationConfig.Channels),
+		"poll_interval": pollInterval.String(),
+	})
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			u.config.Logger.INFO(config.SUNTSTOP, "Notification dispatcher stopping", nil)
+			return nil
+		case <-ticker.C:
+			for ctx.Err() == nil {
+				deliveries, err := u.notificationRepo.ClaimDue(batchSize, time.Now(), lease)
+				if err != nil {
+					break
+				}
+				for i := range deliveries {
+					u.deliver(ctx, &deliveries[i])
+					if err := u.notificationRepo.SaveResult(&deliveries[i]); err != nil {
+						break
+					}
+				}
+				if len(deliveries) < batchSize {
+					break
+				}
+			}
+		}
+	}
+}
+
+// deliver attempts one delivery and schedules a retry with exponential backoff on failure
+func (u *notificationUsecase) deliver(ctx context.Context, delivery *model.NotificationDelivery) {
+	notificationConfig := u.config.YamlConfig.Application.Server.Notifications
+
+	var channel *config.NotificationChannel
+	for i := range notificationConfig.Channels {
+		if notificationConfig.Channels[i].Name == delivery.Channel {
+			channel = &notificationConfig.Channels[i]
+			break
+		}
+	}
+	if channel == nil {
+		delivery.Status = model.DeliveryStatusFailed
+		delivery.LastError = "channel is no longer configured"
+		return
+	}
+
+	timeout := time.Duration(notificationConfig.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
+	err := u.sender.Send(sendCtx, *channel, delivery)
+	cancel()
+
+	delivery.Attempts++
+	now := time.Now()
+	if err == nil {
+		delivery.Status = model.DeliveryStatusDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		u.config.Logger.INFO(config.SUNTSEND, "Delivered notification", map[string]interface{}{
+			"delivery_uuid": delivery.UUID,
+			"channel":       delivery.Channel,
+			"event":         delivery.Event,
+			"attempts":      delivery.Attempts,
+		})
+		return
+	}
+
+	delivery.LastError = err.Error()
+	maxAttempts := notificationConfig.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = model.DeliveryStatusFailed
+	} else {
+		delivery.Status = model.DeliveryStatusPending
+		delivery.NextAttemptAt = now.Add(deliveryBackoff(notificationConfig, delivery.Attempts))
+	}
+	u.config.Logger.WARN(config.SUNTFAIL, "Notification delivery failed", map[string]interface{}{
+		"error":         err.Error(),
+		"delivery_uuid": delivery.UUID,
+		"channel":       delivery.Channel,
+		"attempts":      delivery.Attempts,
+		"status":        delivery.Status,
+	})
+}
+
+// deliveryBackoff doubles the initial backoff per failed attempt, up to the maximum
+func deliveryBackoff(notificationConfig config.Notifications, attempts int) time.Duration {
+	backoff := time.Duration(notificationConfig.InitialBackoff) * time.Second
+	if backoff <= 0 {
+		backoff = 5 * time.Second
+	}
+	maxBackoff := time.Duration(notificationConfig.MaxBackoff) * time.Second
+	if maxBackoff <= 0 {
+		maxBackoff = 10 * time.Minute
+	}
+	for i := 1; i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+func (u *notificationUsecase) GetDeliveries(req request.NotificationDeliveryListRequest) ([]model.NotificationDelivery, error) {
+	return u.notificationRepo.GetDeliveries(req)
+}
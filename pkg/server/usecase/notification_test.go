@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+)
+
+func newTestNotificationUsecase(notifications config.Notifications) *notificationUsecase {
+	conf := config.BaseConfig{}
+	conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+	conf.YamlConfig.Application.Server.Notifications = notifications
+	return &notificationUsecase{
+		config: conf,
+		sender: repository.NewChannelSender(conf),
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name: "default template",
+			want: "*[high] Alert opened: temperature/outlier_detection*\nAnomalous temperature reading 61.00",
+		},
+		{
+			name:     "channel template",
+			template: "{{.Title}} on {{.Labels.agent_uuid}}: {{.Text}}",
+			want:     "Alert opened: temperature/outlier_detection on agent-1: Anomalous temperature reading 61.00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := config.NotificationChannel{Name: "plant-a", Type: model.ChannelTypeSlack, URL: server.URL, Template: tt.template}
+			u := newTestNotificationUsecase(config.Notifications{Channels: []config.NotificationChannel{channel}})
+			msg := &model.NotificationMessage{
+				ID:       "event-1",
+				Kind:     model.NotificationKindAlert,
+				Event:    model.ServerEventAlertOpened,
+				Severity: "high",
+				Labels:   map[string]string{"agent_uuid": "agent-1"},
+				Title:    "Alert opened: temperature/outlier_detection",
+				Text:     "Anomalous temperature reading 61.00",
+			}
+
+			_, body, err := u.render(channel, msg)
+			if err != nil {
+				t.Fatalf("render() error = %v", err)
+			}
+			delivery := &model.NotificationDelivery{UUID: "delivery-1", Channel: channel.Name, Body: body, Status: model.DeliveryStatusSending}
+			u.deliver(context.Background(), delivery)
+			if delivery.Status != model.DeliveryStatusDelivered {
+				t.Fatalf("status = %q, last error %q", delivery.Status, delivery.LastError)
+			}
+
+			var payload map[string]string
+			if err := json.Unmarshal(<-bodies, &payload); err != nil {
+				t.Fatalf("payload is not JSON: %v", err)
+			}
+			if len(payload) != 1 || payload["text"] != tt.want {
+				t.Errorf("payload = %v, want text %q", payload, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeliverRetriesServerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try later", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	channel := config.NotificationChannel{Name: "ops", Type: model.ChannelTypeWebhook, URL: server.URL}
+	u := newTestNotificationUsecase(config.Notifications{
+		MaxAttempts:    4,
+		InitialBackoff: 5,
+		MaxBackoff:     15,
+		Channels:       []config.NotificationChannel{channel},
+	})
+	delivery := &model.NotificationDelivery{UUID: "delivery-1", Channel: channel.Name, Body: "{}", Status: model.DeliveryStatusSending}
+
+	// 5s, doubled per attempt up to the 15s maximum, then failed after the fourth attempt
+	backoffs := []time.Duration{5 * time.Second, 10 * time.Second, 15 * time.Second}
+	for attempt, backoff := range backoffs {
+		before := time.Now()
+		u.deliver(context.Background(), delivery)
+
+		if delivery.Attempts != attempt+1 {
+			t.Fatalf("attempts = %d, want %d", delivery.Attempts, attempt+1)
+		}
+		if delivery.Status != model.DeliveryStatusPending {
+			t.Fatalf("attempt %d: status = %q, want a retry", delivery.Attempts, delivery.Status)
+		}
+		if delay := delivery.NextAttemptAt.Sub(before); delay < backoff || delay > backoff+time.Second {
+			t.Errorf("attempt %d: retried after %v, want %v", delivery.Attempts, delay, backoff)
+		}
+		if delivery.LastError != "webhook returned status 502: try later" {
+			t.Errorf("attempt %d: last error = %q", delivery.Attempts, delivery.LastError)
+		}
+	}
+
+	u.deliver(context.Background(), delivery)
+	if delivery.Status != model.DeliveryStatusFailed || delivery.Attempts != 4 {
+		t.Errorf("status = %q after %d attempts, want failed after 4", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestDeliveryBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   config.Notifications
+		attempts int
+		want     time.Duration
+	}{
+		{name: "first retry", config: config.Notifications{InitialBackoff: 5, MaxBackoff: 600}, attempts: 1, want: 5 * time.Second},
+		{name: "doubled", config: config.Notifications{InitialBackoff: 5, MaxBackoff: 600}, attempts: 4, want: 40 * time.Second},
+		{name: "capped", config: config.Notifications{InitialBackoff: 5, MaxBackoff: 600}, attempts: 12, want: 10 * time.Minute},
+		{name: "defaults", attempts: 2, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deliveryBackoff(tt.config, tt.attempts); got != tt.want {
+				t.Errorf("deliveryBackoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
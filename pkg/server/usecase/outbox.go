@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+)
+
+// outboxPurgeInterval is how often the relay purges published events when the
+// retention compactor is disabled
+const outboxPurgeInterval = time.Hour
+
+type OutboxUsecase interface {
+	Relay(ctx context.Context) error
+}
+
+type outboxUsecase struct {
+	config     config.BaseConfig
+	outboxRepo repository.OutboxRepository
+	publisher  repository.EventPublisher
+}
+
+func NewOutboxUsecase(conf config.BaseConfig, outboxRepo repository.OutboxRepository, publisher repository.EventPublisher) OutboxUsecase {
+	return &outboxUsecase{
+		config:     conf,
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+	}
+}
+
+// Relay publishes committed outbox events and notifications to Pulsar until ctx is cancelled
+func (u *outboxUsecase) Relay(ctx context.Context) error {
+	outboxConfig := u.config.YamlConfig.Application.Server.Outbox
+	pollInterval := time.Duration(outboxConfig.PollInterval) * time.Millisecond
+	if pollInterval <= 0 {
+		pollInterval = 500 * time.Millisecond
+	}
+	batchSize := outboxConfig.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	u.config.Logger.INFO(config.SUORUN, "Outbox relay starting", map[string]interface{}{
+		"poll_interval": pollInterval.String(),
+		"batch_size":    batchSize,
+	})
+
+	pollTicker := time.NewTicker(pollInterval)
+	defer pollTicker.Stop()
+
+	// Published events are expired by the retention compactor when it runs. Without it the
+	// relay purges them itself, so the outbox does not grow forever.
+	var purgeTick <-chan time.Time
+	if !u.config.YamlConfig.Application.Server.Retention.Enabled {
+		purgeTicker := time.NewTicker(outboxPurgeInterval)
+		defer purgeTicker.Stop()
+		purgeTick = purgeTicker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			u.config.Logger.INFO(config.SUOSTOP, "Outbox relay stopping", nil)
+			return nil
+		case <-pollTicker.C:
+			u.drain(ctx, batchSize)
+		case <-purgeTick:
+			u.purge()
+		}
+	}
+}
+
+// purge deletes events published longer ago than outbox.retention
+func (u *outboxUsecase) purge() {
+	retention := time.Duration(u.config.YamlConfig.Application.Server.Outbox.Retention) * time.Hour
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+
+	deleted, err := u.outboxRepo.PurgePublished(time.Now().Add(-retention))
+	if err != nil {
+		u.config.Logger.ERROR(config.SUOERR, "Outbox relay error", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if deleted > 0 {
+		u.config.Logger.INFO(config.SUOPURGE, "Purged published outbox events", map[string]interface{}{
+			"deleted": deleted,
+		})
+	}
+}
+
+// drain relays full batches until the outbox is empty or a publish fails
+func (u *outboxUsecase) drain(ctx context.Context, batchSize int) {
+	for ctx.Err() == nil {
+		published, err := u.outboxRepo.PublishPending(batchSize, func(event *model.OutboxEvent) error {
+			if event.Kind == model.OutboxKindNotification {
+				return u.publisher.PublishNotification(ctx, event.ToNotification())
+			}
+			return u.publisher.PublishEvent(ctx, event.ToServerEvent())
+		})
+		if err != nil {
+			u.config.Logger.ERROR(config.SUOERR, "Outbox relay error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		if published < batchSize {
+			return
+		}
+	}
+}
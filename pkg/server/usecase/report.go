@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"gorm.io/gorm"
+)
+
+type ReportUsecase interface {
+	HandleReport(ctx context.Context, report *model.AgentReport) error
+}
+
+type reportUsecase struct {
+	config     config.BaseConfig
+	agentRepo  repository.AgentRepository
+	metricRepo repository.MetricRepository
+}
+
+func NewReportUsecase(conf config.BaseConfig, agentRepo repository.AgentRepository, metricRepo repository.MetricRepository) ReportUsecase {
+	return &reportUsecase{
+		config:     conf,
+		agentRepo:  agentRepo,
+		metricRepo: metricRepo,
+	}
+}
+
+// HandleReport applies an agent report to server state. Returning an error causes
+// the report to be redelivered, so reports that can never succeed return nil.
+func (u *reportUsecase) HandleReport(ctx context.Context, report *model.AgentReport) error {
+	u.config.Logger.DEBUG(config.SURHR, "Handling agent report", map[string]interface{}{
+		"report_id":   report.ID,
+		"report_type": report.Type,
+		"agent_id":    report.AgentID,
+	})
+
+	var err error
+	switch report.Type {
+	case model.ReportTypeStatus:
+		err = u.handleStatus(ctx, report)
+	case model.ReportTypeHeartbeat:
+		err = u.handleStatus(ctx, report)
+		if err == nil && report.Data != "" {
+			// Heartbeats carry the agent's spool backlog, kept as metrics
+			err = u.handleMetrics(ctx, report)
+		}
+	case model.ReportTypeMetrics:
+		err = u.handleMetrics(ctx, report)
+	case model.ReportTypeError:
+		err = u.handleError(ctx, report)
+	default:
+		u.config.Logger.WARN(config.SURUNK, "Ignoring agent report with unknown type", map[string]interface{}{
+			"report_id":   report.ID,
+			"report_type": report.Type,
+		})
+		return nil
+	}
+	if err != nil {
+		u.config.Logger.ERROR(config.SURERR, "Failed to handle agent report", map[string]interface{}{
+			"error":       err.Error(),
+			"report_id":   report.ID,
+			"report_type": report.Type,
+			"agent_id":    report.AgentID,
+		})
+		return err
+	}
+
+	u.config.Logger.DEBUG(config.SURSUCC, "Agent report handled successfully", map[string]interface{}{
+		"report_id": report.ID,
+	})
+	return nil
+}
+
+// handleStatus updates agents.status/heartbeat_at and records status transitions in the outbox
+func (u *reportUsecase) handleStatus(ctx context.Context, report *model.AgentReport) error {
+	u.config.Logger.DEBUG(config.SURUS, "Updating agent status from report", map[string]interface{}{
+		"agent_id": report.AgentID,
+		"status":   report.Status,
+	})
+
+	err := u.agentRepo.TransactionContext(ctx, func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		previous, err := repo.UpdateAgentStatus(report.AgentID, report.Status, reportTime(report))
+		if err != nil {
+			return err
+		}
+		if report.Status == "" || report.Status == previous {
+			return nil
+		}
+
+		event, err := newChangeEvent(model.ServerEventAgentStatusChanged, report.AgentID, "agent", model.ChangeActionUpdated,
+			map[string]interface{}{"status": previous},
+			map[string]interface{}{"status": report.Status})
+		if err != nil {
+			return err
+		}
+		u.config.Logger.DEBUG(config.SURPE, "Recording agent status change", map[string]interface{}{
+			"event_id":        event.ID,
+			"agent_id":        report.AgentID,
+			"previous_status": previous,
+			"status":          report.Status,
+		})
+		return outbox.Enqueue(event)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		u.config.Logger.WARN(config.SURUS, "Ignoring report from unregistered agent", map[string]interface{}{
+			"agent_id":  report.AgentID,
+			"report_id": report.ID,
+		})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update agent status: %w", err)
+	}
+	return nil
+}
+
+// handleMetrics stores every numeric field of the report data as a metric sample
+func (u *reportUsecase) handleMetrics(ctx context.Context, report *model.AgentReport) error {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(report.Data), &data); err != nil {
+		u.config.Logger.WARN(config.SURSM, "Ignoring metrics report with invalid data", map[string]interface{}{
+			"error":     err.Error(),
+			"report_id": report.ID,
+		})
+		return nil
+	}
+
+	recordedAt := reportTime(report)
+	metrics := make([]model.AgentMetric, 0, len(data))
+	for name, value := range data {
+		number, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		metrics = append(metrics, model.AgentMetric{
+			AgentUUID:  report.AgentID,
+			SensorType: model.MetricSensorTypeSystem,
+			ReportID:   report.ID,
+			Name:       name,
+			Value:      number,
+			RecordedAt: recordedAt,
+		})
+	}
+
+	u.config.Logger.DEBUG(config.SURSM, "Storing agent metrics from report", map[string]interface{}{
+		"agent_id": report.AgentID,
+		"count":    len(metrics),
+	})
+
+	return u.metricRepo.CreateMetrics(ctx, metrics)
+}
+
+// handleError records an error report as an outbox event and a client notification, both
+// published by the outbox relay once the transaction commits
+func (u *reportUsecase) handleError(ctx context.Context, report *model.AgentReport) error {
+	u.config.Logger.INFO(config.SURAE, "Handling agent error report", map[string]interface{}{
+		"agent_id":  report.AgentID,
+		"report_id": report.ID,
+	})
+
+	message := report.Data
+	if message == "" {
+		message = report.Status
+	}
+
+	notification := &model.Notification{
+		ID:        uuid.New().String(),
+		AgentID:   report.AgentID,
+		Type:      model.ServerEventAgentError,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+	event, err := newChangeEvent(model.ServerEventAgentError, report.AgentID, "agent", model.ChangeActionUpdated, nil,
+		map[string]interface{}{"message": message, "report_id": report.ID})
+	if err != nil {
+		return err
+	}
+	return u.agentRepo.TransactionContext(ctx, func(repo repository.AgentRepository, outbox repository.OutboxRepository) error {
+		if err := outbox.Enqueue(event); err != nil {
+			return err
+		}
+		return outbox.EnqueueNotification(notification)
+	})
+}
+
+// reportTime returns the agent-side report timestamp, falling back to now
+func reportTime(report *model.AgentReport) time.Time {
+	if report.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return report.Timestamp
+}
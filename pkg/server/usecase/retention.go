@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+)
+
+type RetentionUsecase interface {
+	// Run compacts every interval until ctx is cancelled
+	Run(ctx context.Context) error
+	// Compact makes one pass over all data classes
+	Compact(ctx context.Context)
+	// GetStatus returns the policies and last runs, counting pending deletions when preview is set
+	GetStatus(preview bool) (*response.RetentionStatus, error)
+}
+
+type retentionUsecase struct {
+	config        config.BaseConfig
+	retentionRepo repository.RetentionRepository
+}
+
+func NewRetentionUsecase(conf config.BaseConfig, retentionRepo repository.RetentionRepository) RetentionUsecase {
+	return &retentionUsecase{
+		config:        conf,
+		retentionRepo: retentionRepo,
+	}
+}
+
+func (u *retentionUsecase) Run(ctx context.Context) error {
+	interval := u.interval()
+	u.config.Logger.INFO(config.SURTRUN, "Retention compactor starting", map[string]interface{}{
+		"interval": interval.String(),
+		"dry_run":  u.config.YamlConfig.Application.Server.Retention.DryRun,
+	})
+
+	// Compact once at startup, so a server restarted more often than the interval still compacts
+	u.Compact(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			u.config.Logger.INFO(config.SURTSTOP, "Retention compactor stopping", nil)
+			return nil
+		case <-ticker.C:
+			u.Compact(ctx)
+		}
+	}
+}
+
+// Compact deletes the expired rows of each class in batches and records the pass.
+// A failing class is recorded and logged without stopping the others.
+func (u *retentionUsecase) Compact(ctx context.Context) {
+	retentionConfig := u.config.YamlConfig.Application.Server.Retention
+	batchSize := retentionConfig.BatchSize
+	if batchSize <= 0 {
+		batchSize = 10000
+	}
+
+	for _, class := range repository.RetentionClasses() {
+		if ctx.Err() != nil {
+			return
+		}
+		hours := u.retentionHours(class)
+		if hours <= 0 {
+			continue
+		}
+
+		run := &model.RetentionRun{
+			Class:     class,
+			Cutoff:    time.Now().Add(-time.Duration(hours) * time.Hour),
+			DryRun:    retentionConfig.DryRun,
+			StartedAt: time.Now(),
+		}
+		err := u.compactClass(ctx, run, batchSize)
+		run.FinishedAt = time.Now()
+		if err != nil {
+			run.Error = err.Error()
+			u.config.Logger.ERROR(config.SURTERR, "Retention compactor error", map[string]interface{}{
+				"error": err.Error(),
+				"class": class,
+			})
+		} else if run.Matched > 0 {
+			u.config.Logger.INFO(config.SURTCLS, "Compacted data class", map[string]interface{}{
+				"class":   class,
+				"cutoff":  run.Cutoff,
+				"matched": run.Matched,
+				"deleted": run.Deleted,
+				"dry_run": run.DryRun,
+			})
+		}
+
+		// SaveRun logs its own failures, and the next pass records the class again
+		_ = u.retentionRepo.SaveRun(run)
+	}
+}
+
+func (u *retentionUsecase) compactClass(ctx context.Context, run *model.RetentionRun, batchSize int) error {
+	matched, err := u.retentionRepo.CountExpired(run.Class, run.Cutoff)
+	if err != nil {
+		return err
+	}
+	run.Matched = matched
+	if run.DryRun || matched == 0 {
+		return nil
+	}
+
+	for ctx.Err() == nil {
+		deleted, err := u.retentionRepo.DeleteExpired(run.Class, run.Cutoff, batchSize)
+		run.Deleted += deleted
+		if err != nil {
+			return err
+		}
+		if deleted < int64(batchSize) {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (u *retentionUsecase) GetStatus(preview bool) (*response.RetentionStatus, error) {
+	runs, err := u.retentionRepo.GetRuns()
+	if err != nil {
+		return nil, err
+	}
+	lastRuns := make(map[string]*model.RetentionRun, len(runs))
+	for i := range runs {
+		lastRuns[runs[i].Class] = &runs[i]
+	}
+
+	retentionConfig := u.config.YamlConfig.Application.Server.Retention
+	status := &response.RetentionStatus{
+		Enabled:  retentionConfig.Enabled,
+		Interval: u.interval().String(),
+		DryRun:   retentionConfig.DryRun,
+	}
+	for _, class := range repository.RetentionClasses() {
+		policy := response.RetentionPolicy{
+			Class:     class,
+			Retention: "forever",
+			LastRun:   lastRuns[class],
+		}
+		hours := u.retentionHours(class)
+		if hours > 0 {
+			policy.Retention = fmt.Sprintf("%dh", hours)
+			if preview {
+				pending, err := u.retentionRepo.CountExpired(class, time.Now().Add(-time.Duration(hours)*time.Hour))
+				if err != nil {
+					return nil, err
+				}
+				policy.Pending = &pending
+			}
+		}
+		status.Policies = append(status.Policies, policy)
+	}
+	return status, nil
+}
+
+func (u *retentionUsecase) interval() time.Duration {
+	interval := time.Duration(u.config.YamlConfig.Application.Server.Retention.Interval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return interval
+}
+
+// retentionHours returns how long class is kept, 0 meaning forever. Published outbox events
+// default to outbox.retention, which governed them before the compactor existed, and
+// processed message keys to the dedup TTL rounded up to whole hours.
+func (u *retentionUsecase) retentionHours(class string) int {
+	serverConfig := u.config.YamlConfig.Application.Server
+	if hours, ok := serverConfig.Retention.Policies[class]; ok {
+		return hours
+	}
+	switch class {
+	case model.RetentionClassEvents:
+		if serverConfig.Outbox.Retention > 0 {
+			return serverConfig.Outbox.Retention
+		}
+		return 168
+	case model.RetentionClassProcessed:
+		ttl := u.config.YamlConfig.Pulsar.GetDedupTTL()
+		return int((ttl + time.Hour - 1) / time.Hour)
+	}
+	return 0
+}
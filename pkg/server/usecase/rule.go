@@ -0,0 +1,505 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"gorm.io/gorm"
+)
+
+// Alert rule errors, mapped to 400 and 404 by the controller
+var (
+	ErrInvalidRule  = errors.New("invalid alert rule")
+	ErrRuleNotFound = errors.New("alert rule not found")
+)
+
+// Bounds of a rule window. Absence rules consider the agents that reported the sensor
+// within absenceLookback before their window.
+const (
+	defaultRuleWindow = 5 * time.Minute
+	maxRuleWindow     = 24 * time.Hour
+	absenceLookback   = 24 * time.Hour
+)
+
+type RuleUsecase interface {
+	CreateRule(user string, req request.AlertRuleRequest) (*model.AlertRule, error)
+	GetRules() ([]model.AlertRule, error)
+	GetRule(ruleUUID string) (*model.AlertRule, error)
+	UpdateRule(ruleUUID string, req request.AlertRuleRequest) (*model.AlertRule, error)
+	DeleteRule(ruleUUID string) error
+
+	// Run evaluates the enabled rules every interval until ctx is cancelled
+	Run(ctx context.Context) error
+	// Evaluate makes one pass over the enabled rules
+	Evaluate(ctx context.Context)
+}
+
+type ruleUsecase struct {
+	config       config.BaseConfig
+	ruleRepo     repository.RuleRepository
+	metricRepo   repository.MetricRepository
+	agentRepo    repository.AgentRepository
+	alertUsecase AlertUsecase
+}
+
+func NewRuleUsecase(conf config.BaseConfig, ruleRepo repository.RuleRepository, metricRepo repository.MetricRepository, agentRepo repository.AgentRepository, alertUsecase AlertUsecase) RuleUsecase {
+	return &ruleUsecase{
+		config:       conf,
+		ruleRepo:     ruleRepo,
+		metricRepo:   metricRepo,
+		agentRepo:    agentRepo,
+		alertUsecase: alertUsecase,
+	}
+}
+
+func (u *ruleUsecase) CreateRule(user string, req request.AlertRuleRequest) (*model.AlertRule, error) {
+	rule := &model.AlertRule{
+		UUID:      uuid.New().String(),
+		CreatedBy: user,
+	}
+	if err := applyRuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+	if err := u.ruleRepo.CreateRule(rule); err != nil {
+		return nil, err
+	}
+
+	u.config.Logger.INFO(config.SURLCR, "Created alert rule", map[string]interface{}{
+		"rule_uuid":  rule.UUID,
+		"name":       rule.Name,
+		"kind":       rule.Kind,
+		"created_by": user,
+	})
+	return rule, nil
+}
+
+func (u *ruleUsecase) GetRules() ([]model.AlertRule, error) {
+	return u.ruleRepo.GetRules(false)
+}
+
+func (u *ruleUsecase) GetRule(ruleUUID string) (*model.AlertRule, error) {
+	rule, err := u.ruleRepo.GetRule(ruleUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRuleNotFound
+	}
+	return rule, err
+}
+
+// UpdateRule replaces the definition of a rule. Renaming a rule starts new alerts, since
+// alerts are grouped by rule name.
+func (u *ruleUsecase) UpdateRule(ruleUUID string, req request.AlertRuleRequest) (*model.AlertRule, error) {
+	rule, err := u.GetRule(ruleUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyRuleRequest(rule, req); err != nil {
+		return nil, err
+	}
+	rule.UpdatedAt = time.Now()
+	if err := u.ruleRepo.UpdateRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (u *ruleUsecase) DeleteRule(ruleUUID string) error {
+	err := u.ruleRepo.DeleteRule(ruleUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRuleNotFound
+	}
+	return err
+}
+
+// applyRuleRequest validates req and copies it onto rule, filling in defaults
+func applyRuleRequest(rule *model.AlertRule, req request.AlertRuleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return fmt.Errorf("%w: name must be 1 to 100 characters", ErrInvalidRule)
+	}
+
+	window := defaultRuleWindow
+	if req.For != "" {
+		parsed, err := time.ParseDuration(req.For)
+		if err != nil || parsed < time.Minute || parsed > maxRuleWindow {
+			return fmt.Errorf("%w: for must be a duration between 1m and 24h", ErrInvalidRule)
+		}
+		window = parsed.Round(time.Minute)
+	}
+
+	severity := req.Severity
+	if severity == "" {
+		severity = "medium"
+	}
+	if _, ok := model.AlertSeverityRank[severity]; !ok {
+		return fmt.Errorf("%w: severity must be low, medium, high or critical", ErrInvalidRule)
+	}
+
+	metric, operator, threshold := req.Metric, req.Operator, req.Threshold
+	switch req.Kind {
+	case model.RuleKindThreshold:
+		if req.SensorType == "" || metric == "" {
+			return fmt.Errorf("%w: threshold rules need sensor_type and metric", ErrInvalidRule)
+		}
+		if _, ok := model.RuleOperators[operator]; !ok {
+			return fmt.Errorf("%w: operator must be >, >=, < or <=", ErrInvalidRule)
+		}
+	case model.RuleKindAbsence:
+		if req.SensorType == "" {
+			return fmt.Errorf("%w: absence rules need sensor_type", ErrInvalidRule)
+		}
+		operator, threshold = "", 0
+	case model.RuleKindAnomalyRate:
+		metric = "anomaly"
+		if operator == "" {
+			operator = ">"
+		}
+		if _, ok := model.RuleOperators[operator]; !ok {
+			return fmt.Errorf("%w: operator must be >, >=, < or <=", ErrInvalidRule)
+		}
+		if threshold < 0 || threshold > 100 {
+			return fmt.Errorf("%w: anomaly_rate threshold is a percentage between 0 and 100", ErrInvalidRule)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be threshold, absence or anomaly_rate", ErrInvalidRule)
+	}
+
+	rule.Name = name
+	rule.Kind = req.Kind
+	rule.SensorType = req.SensorType
+	rule.Metric = metric
+	rule.Operator = operator
+	rule.Threshold = threshold
+	rule.For = int(window / time.Minute)
+	rule.AgentUUID = req.AgentUUID
+	rule.Group = req.Group
+	rule.Severity = severity
+	rule.Message = req.Message
+	rule.Enabled = req.Enabled == nil || *req.Enabled
+	return nil
+}
+
+func (u *ruleUsecase) Run(ctx context.Context) error {
+	interval := u.interval()
+	u.config.Logger.INFO(config.SURLRUN, "Rule evaluator starting", map[string]interface{}{
+		"interval": interval.String(),
+	})
+
+	u.Evaluate(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			u.config.Logger.INFO(config.SURLSTOP, "Rule evaluator stopping", nil)
+			return nil
+		case <-ticker.C:
+			u.Evaluate(ctx)
+		}
+	}
+}
+
+// Evaluate checks each enabled rule and records the outcome. A failing rule is recorded
+// and logged without stopping the others.
+func (u *ruleUsecase) Evaluate(ctx context.Context) {
+	rules, err := u.ruleRepo.GetRules(true)
+	if err != nil {
+		u.config.Logger.ERROR(config.SURLERR, "Failed to load alert rules", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	groups := map[string]string{}
+	for i := range rules {
+		if ctx.Err() != nil {
+			return
+		}
+		rule := &rules[i]
+		now := time.Now()
+
+		var evalErr string
+		if err := u.evaluateRule(ctx, rule, now, groups); err != nil {
+			evalErr = err.Error()
+			u.config.Logger.ERROR(config.SURLERR, "Failed to evaluate alert rule", map[string]interface{}{
+				"error": err.Error(),
+				"rule":  rule.Name,
+			})
+		}
+		// SaveEvaluation logs its own failures, and the next pass records the rule again
+		_ = u.ruleRepo.SaveEvaluation(rule.UUID, now, evalErr)
+	}
+}
+
+// ruleMatch is an agent for which a rule fires, with the value that breached it
+type ruleMatch struct {
+	agentUUID string
+	value     float64
+	detail    string
+}
+
+func (u *ruleUsecase) evaluateRule(ctx context.Context, rule *model.AlertRule, now time.Time, groups map[string]string) error {
+	window := time.Duration(rule.For) * time.Minute
+	filter := repository.MetricFilter{
+		AgentUUID:  rule.AgentUUID,
+		SensorType: rule.SensorType,
+		Name:       rule.Metric,
+		From:       now.Add(-window),
+		To:         now,
+	}
+
+	var matches []ruleMatch
+	var err error
+	switch rule.Kind {
+	case model.RuleKindThreshold:
+		matches, err = u.evaluateThreshold(rule, filter, groups)
+	case model.RuleKindAbsence:
+		matches, err = u.evaluateAbsence(rule, filter, now, groups)
+	case model.RuleKindAnomalyRate:
+		matches, err = u.evaluateAnomalyRate(rule, filter, groups)
+	default:
+		err = fmt.Errorf("unknown rule kind %q", rule.Kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, match := range matches {
+		message := match.detail
+		if rule.Message != "" {
+			message = rule.Message + ": " + match.detail
+		}
+		threshold := rule.Threshold
+		if rule.Kind == model.RuleKindAbsence {
+			threshold = float64(rule.For)
+		}
+
+		u.config.Logger.INFO(config.SURLFIRE, "Alert rule fired", map[string]interface{}{
+			"rule":       rule.Name,
+			"agent_uuid": match.agentUUID,
+			"value":      match.value,
+		})
+		err := u.alertUsecase.HandleAlert(ctx, &model.AlertData{
+			UUID:           uuid.New().String(),
+			AgentUUID:      match.agentUUID,
+			SensorType:     rule.SensorType,
+			OriginalValue:  match.value,
+			ProcessedValue: match.value,
+			Threshold:      threshold,
+			Severity:       rule.Severity,
+			Message:        message,
+			Timestamp:      now,
+			Rule:           rule.Name,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateThreshold fires for each agent whose every 1m bucket in the window breaches the
+// threshold, comparing the bucket minimum for > and >= and the maximum for < and <=
+func (u *ruleUsecase) evaluateThreshold(rule *model.AlertRule, filter repository.MetricFilter, groups map[string]string) ([]ruleMatch, error) {
+	windowStart := filter.From
+	// Include the bucket the window starts in, so full coverage can be told apart from data
+	// that only begins partway through the window
+	filter.From = windowStart.Truncate(time.Minute)
+	rollups, err := u.windowRollups(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []ruleMatch
+	for _, match := range thresholdMatches(rule, rollups, windowStart) {
+		if u.inScope(rule, match.agentUUID, groups) {
+			matches = append(matches, match)
+		}
+	}
+	return matches, nil
+}
+
+// thresholdMatches returns the agents whose 1m buckets, ordered by start, cover the whole
+// window from windowStart and all breach the rule's threshold. An agent needs at least
+// rule.For buckets, the first starting at or before windowStart; an agent that only began
+// reporting during the window, or that reported too few buckets, does not fire.
+func thresholdMatches(rule *model.AlertRule, rollups []model.AgentMetricRollup, windowStart time.Time) []ruleMatch {
+	compare := model.RuleOperators[rule.Operator]
+	below := rule.Operator == "<" || rule.Operator == "<="
+
+	type state struct {
+		breached bool
+		worst    float64
+		first    time.Time
+		last     time.Time
+		buckets  int
+	}
+	agents := map[string]*state{}
+	for _, rollup := range rollups {
+		value := rollup.Min
+		if below {
+			value = rollup.Max
+		}
+		s, ok := agents[rollup.AgentUUID]
+		if !ok {
+			s = &state{breached: true, worst: value, first: rollup.BucketStart}
+			agents[rollup.AgentUUID] = s
+		}
+		// Several metrics of one sensor type share a bucket
+		if s.buckets == 0 || !rollup.BucketStart.Equal(s.last) {
+			s.buckets++
+			s.last = rollup.BucketStart
+		}
+		if !compare(value, rule.Threshold) {
+			s.breached = false
+		}
+		if (below && value > s.worst) || (!below && value < s.worst) {
+			s.worst = value
+		}
+	}
+
+	var matches []ruleMatch
+	for _, agentUUID := range sortedKeys(agents) {
+		s := agents[agentUUID]
+		if !s.breached || s.buckets < rule.For || s.first.After(windowStart) {
+			continue
+		}
+		matches = append(matches, ruleMatch{
+			agentUUID: agentUUID,
+			value:     s.worst,
+			detail:    fmt.Sprintf("%s %s %g for %dm (worst %g)", rule.Metric, rule.Operator, rule.Threshold, rule.For, s.worst),
+		})
+	}
+	return matches
+}
+
+// evaluateAbsence fires for each agent that reported the sensor within absenceLookback
+// but not during the window
+func (u *ruleUsecase) evaluateAbsence(rule *model.AlertRule, filter repository.MetricFilter, now time.Time, groups map[string]string) ([]ruleMatch, error) {
+	window := time.Duration(rule.For) * time.Minute
+	filter.From = now.Add(-window - absenceLookback)
+	lastSeen, err := u.metricRepo.GetLastSeen(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []ruleMatch
+	for _, agentUUID := range sortedKeys(lastSeen) {
+		// The latest sample falls somewhere in its bucket; assume its end
+		silent := now.Sub(lastSeen[agentUUID].Add(time.Minute))
+		if silent < window || !u.inScope(rule, agentUUID, groups) {
+			continue
+		}
+		subject := rule.SensorType
+		if rule.Metric != "" {
+			subject += "/" + rule.Metric
+		}
+		matches = append(matches, ruleMatch{
+			agentUUID: agentUUID,
+			value:     silent.Minutes(),
+			detail:    fmt.Sprintf("no %s data for %s", subject, silent.Truncate(time.Minute)),
+		})
+	}
+	return matches, nil
+}
+
+// evaluateAnomalyRate compares the share of anomalous samples across every agent in scope
+// with the threshold, and fires for each agent that reported anomalies in the window
+func (u *ruleUsecase) evaluateAnomalyRate(rule *model.AlertRule, filter repository.MetricFilter, groups map[string]string) ([]ruleMatch, error) {
+	rollups, err := u.windowRollups(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples int64
+	var anomalies float64
+	perAgent := map[string]float64{}
+	for _, rollup := range rollups {
+		if !u.inScope(rule, rollup.AgentUUID, groups) {
+			continue
+		}
+		samples += rollup.Count
+		anomalies += rollup.Sum
+		perAgent[rollup.AgentUUID] += rollup.Sum
+	}
+	if samples == 0 {
+		return nil, nil
+	}
+	rate := 100 * anomalies / float64(samples)
+	if !model.RuleOperators[rule.Operator](rate, rule.Threshold) {
+		return nil, nil
+	}
+
+	scope := "all agents"
+	switch {
+	case rule.AgentUUID != "":
+		scope = "agent " + rule.AgentUUID
+	case rule.Group != "":
+		scope = "group " + rule.Group
+	}
+	var matches []ruleMatch
+	for _, agentUUID := range sortedKeys(perAgent) {
+		if perAgent[agentUUID] == 0 {
+			continue
+		}
+		matches = append(matches, ruleMatch{
+			agentUUID: agentUUID,
+			value:     rate,
+			detail: fmt.Sprintf("anomaly rate %.2f%% %s %g%% across %s for %dm (%d of %d samples from this agent)",
+				rate, rule.Operator, rule.Threshold, scope, rule.For, int64(perAgent[agentUUID]), samples),
+		})
+	}
+	return matches, nil
+}
+
+// windowRollups returns the 1m buckets that start in the window
+func (u *ruleUsecase) windowRollups(filter repository.MetricFilter) ([]model.AgentMetricRollup, error) {
+	rollups, err := u.metricRepo.GetMetricRollups(filter, model.MetricResolutionMinute)
+	if err != nil {
+		return nil, err
+	}
+	if len(rollups) > maxMetricRows {
+		return nil, fmt.Errorf("more than %d buckets in the window, narrow the rule to an agent, group or sensor type", maxMetricRows)
+	}
+	return rollups, nil
+}
+
+// inScope reports whether an agent belongs to the rule's group, looking groups up once per pass
+func (u *ruleUsecase) inScope(rule *model.AlertRule, agentUUID string, groups map[string]string) bool {
+	if rule.Group == "" {
+		return true
+	}
+	group, ok := groups[agentUUID]
+	if !ok {
+		group, _ = u.agentRepo.GetAgentByUUID(agentUUID).Metadata["group"].(string)
+		groups[agentUUID] = group
+	}
+	return group == rule.Group
+}
+
+func (u *ruleUsecase) interval() time.Duration {
+	seconds := u.config.YamlConfig.Application.Server.Rules.Interval
+	if seconds <= 0 {
+		seconds = 60
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,219 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+)
+
+// fakeMetricRepository serves 1m rollups and last-seen times from memory, applying the
+// window of the filter the way the MySQL queries do
+type fakeMetricRepository struct {
+	repository.MetricRepository
+	rollups  []model.AgentMetricRollup
+	lastSeen map[string]time.Time
+}
+
+func (r *fakeMetricRepository) GetMetricRollups(filter repository.MetricFilter, resolution string) ([]model.AgentMetricRollup, error) {
+	var rollups []model.AgentMetricRollup
+	for _, rollup := range r.rollups {
+		if rollup.Resolution == resolution && !rollup.BucketStart.Before(filter.From) && rollup.BucketStart.Before(filter.To) {
+			rollups = append(rollups, rollup)
+		}
+	}
+	return rollups, nil
+}
+
+func (r *fakeMetricRepository) GetLastSeen(filter repository.MetricFilter) (map[string]time.Time, error) {
+	lastSeen := map[string]time.Time{}
+	for agentUUID, at := range r.lastSeen {
+		if !at.Before(filter.From) {
+			lastSeen[agentUUID] = at
+		}
+	}
+	return lastSeen, nil
+}
+
+// fakeAgentRepository looks up agent groups from memory
+type fakeAgentRepository struct {
+	repository.AgentRepository
+	groups map[string]string
+}
+
+func (r *fakeAgentRepository) GetAgentByUUID(uuid string) model.Agent {
+	return model.Agent{UUID: uuid, Metadata: map[string]any{"group": r.groups[uuid]}}
+}
+
+// fakeAlertUsecase records the alerts raised by rules
+type fakeAlertUsecase struct {
+	AlertUsecase
+	alerts []*model.AlertData
+}
+
+func (u *fakeAlertUsecase) HandleAlert(ctx context.Context, data *model.AlertData) error {
+	u.alerts = append(u.alerts, data)
+	return nil
+}
+
+func TestEvaluateRule(t *testing.T) {
+	// Rules are evaluated 30s into a minute, so windows start partway through a bucket
+	now := time.Date(2025, 6, 2, 12, 0, 30, 0, time.UTC)
+	bucket := func(agentUUID string, minutesAgo int, min, max float64) model.AgentMetricRollup {
+		return model.AgentMetricRollup{
+			AgentUUID:   agentUUID,
+			SensorType:  model.MetricSensorTypeSystem,
+			Name:        "cpu_usage",
+			Resolution:  model.MetricResolutionMinute,
+			BucketStart: now.Truncate(time.Minute).Add(-time.Duration(minutesAgo) * time.Minute),
+			Count:       1,
+			Sum:         max,
+			Min:         min,
+			Max:         max,
+		}
+	}
+	// buckets returns one bucket per minute from minutesAgo down to the current minute
+	buckets := func(agentUUID string, minutesAgo int, min, max float64) []model.AgentMetricRollup {
+		var rollups []model.AgentMetricRollup
+		for ago := minutesAgo; ago >= 0; ago-- {
+			rollups = append(rollups, bucket(agentUUID, ago, min, max))
+		}
+		return rollups
+	}
+	anomalies := func(agentUUID string, minutesAgo int, samples int64, anomalous float64) model.AgentMetricRollup {
+		rollup := bucket(agentUUID, minutesAgo, 0, 1)
+		rollup.Name = "anomaly"
+		rollup.Count = samples
+		rollup.Sum = anomalous
+		return rollup
+	}
+	join := func(groups ...[]model.AgentMetricRollup) []model.AgentMetricRollup {
+		var rollups []model.AgentMetricRollup
+		for _, group := range groups {
+			rollups = append(rollups, group...)
+		}
+		return rollups
+	}
+	highCPU := model.AlertRule{Name: "high_cpu", Kind: model.RuleKindThreshold, SensorType: model.MetricSensorTypeSystem, Metric: "cpu_usage", Operator: ">", Threshold: 90, For: 5, Severity: "high"}
+	lowCPU := highCPU
+	lowCPU.Name, lowCPU.Operator, lowCPU.Threshold = "low_cpu", "<", 10
+	silent := model.AlertRule{Name: "silent", Kind: model.RuleKindAbsence, SensorType: model.MetricSensorTypeSystem, For: 10, Severity: "critical"}
+	anomalyRate := model.AlertRule{Name: "anomalies", Kind: model.RuleKindAnomalyRate, SensorType: model.MetricSensorTypeSystem, Metric: "anomaly", Operator: ">", Threshold: 10, For: 15, Severity: "medium"}
+	plantA := highCPU
+	plantA.Group = "plant-a"
+
+	tests := []struct {
+		name       string
+		rule       model.AlertRule
+		rollups    []model.AgentMetricRollup
+		lastSeen   map[string]time.Time
+		wantAgents []string
+		wantValues []float64
+	}{
+		{
+			name:       "threshold breached over the whole window",
+			rule:       highCPU,
+			rollups:    buckets("a", 5, 95, 99),
+			wantAgents: []string{"a"},
+			wantValues: []float64{95},
+		},
+		{
+			name:    "threshold compares the bucket minimum for >",
+			rule:    highCPU,
+			rollups: append(buckets("a", 5, 95, 99)[:3], buckets("a", 2, 85, 99)...),
+		},
+		{
+			name:       "threshold compares the bucket maximum for <",
+			rule:       lowCPU,
+			rollups:    buckets("a", 5, 1, 8),
+			wantAgents: []string{"a"},
+			wantValues: []float64{8},
+		},
+		{
+			name:    "threshold does not fire for an agent that started reporting during the window",
+			rule:    highCPU,
+			rollups: buckets("a", 4, 95, 99),
+		},
+		{
+			name:    "threshold does not fire with fewer buckets than minutes in the window",
+			rule:    highCPU,
+			rollups: []model.AgentMetricRollup{bucket("a", 5, 95, 99), bucket("a", 0, 95, 99)},
+		},
+		{
+			name:       "threshold tolerates a missing current minute",
+			rule:       highCPU,
+			rollups:    buckets("a", 5, 95, 99)[:5],
+			wantAgents: []string{"a"},
+			wantValues: []float64{95},
+		},
+		{
+			name:       "threshold counts buckets per agent",
+			rule:       highCPU,
+			rollups:    join(buckets("a", 5, 95, 99), buckets("b", 2, 95, 99)),
+			wantAgents: []string{"a"},
+			wantValues: []float64{95},
+		},
+		{
+			name:       "threshold only fires for agents in the rule's group",
+			rule:       plantA,
+			rollups:    join(buckets("a", 5, 95, 99), buckets("b", 5, 95, 99)),
+			wantAgents: []string{"a"},
+			wantValues: []float64{95},
+		},
+		{
+			name:       "absence fires once the agent is silent for the window",
+			rule:       silent,
+			lastSeen:   map[string]time.Time{"a": now.Truncate(time.Minute).Add(-12 * time.Minute), "b": now.Truncate(time.Minute).Add(-2 * time.Minute)},
+			wantAgents: []string{"a"},
+			wantValues: []float64{11.5},
+		},
+		{
+			name:       "anomaly rate fires for the agents that reported anomalies",
+			rule:       anomalyRate,
+			rollups:    []model.AgentMetricRollup{anomalies("a", 3, 10, 3), anomalies("b", 2, 10, 0)},
+			wantAgents: []string{"a"},
+			wantValues: []float64{15},
+		},
+		{
+			name:    "anomaly rate below the threshold",
+			rule:    anomalyRate,
+			rollups: []model.AgentMetricRollup{anomalies("a", 3, 10, 1), anomalies("b", 2, 10, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := config.BaseConfig{}
+			conf.Logger = config.NewLogger(config.LoggerConfig{Level: "FATAL"}, &conf)
+			alerts := &fakeAlertUsecase{}
+			u := &ruleUsecase{
+				config:       conf,
+				metricRepo:   &fakeMetricRepository{rollups: tt.rollups, lastSeen: tt.lastSeen},
+				agentRepo:    &fakeAgentRepository{groups: map[string]string{"a": "plant-a", "b": "plant-b"}},
+				alertUsecase: alerts,
+			}
+
+			rule := tt.rule
+			if err := u.evaluateRule(context.Background(), &rule, now, map[string]string{}); err != nil {
+				t.Fatalf("evaluateRule() error = %v", err)
+			}
+
+			var agents []string
+			var values []float64
+			for _, alert := range alerts.alerts {
+				agents = append(agents, alert.AgentUUID)
+				values = append(values, alert.OriginalValue)
+				if alert.Rule != rule.Name || alert.Severity != rule.Severity {
+					t.Errorf("alert rule, severity = %q, %q, want %q, %q", alert.Rule, alert.Severity, rule.Name, rule.Severity)
+				}
+			}
+			if !reflect.DeepEqual(agents, tt.wantAgents) || !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("alerts for %v with values %v, want %v with %v", agents, values, tt.wantAgents, tt.wantValues)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+	"gorm.io/gorm"
+)
+
+// Silence errors, mapped to 400 and 404 by the controller
+var (
+	ErrInvalidSilence            = errors.New("invalid silence")
+	ErrSilenceNotFound           = errors.New("silence not found")
+	ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+)
+
+type SilenceUsecase interface {
+	CreateSilence(user string, req request.SilenceRequest) (*model.Silence, error)
+	GetSilences(req request.SilenceListRequest) ([]model.Silence, error)
+	ExpireSilence(silenceUUID string) (*model.Silence, error)
+
+	CreateMaintenanceWindow(user string, req request.MaintenanceWindowRequest) (*model.MaintenanceWindow, error)
+	GetMaintenanceWindows() ([]model.MaintenanceWindow, error)
+	DeleteMaintenanceWindow(windowUUID string) error
+}
+
+type silenceUsecase struct {
+	config      config.BaseConfig
+	silenceRepo repository.SilenceRepository
+}
+
+func NewSilenceUsecase(conf config.BaseConfig, silenceRepo repository.SilenceRepository) SilenceUsecase {
+	return &silenceUsecase{
+		config:      conf,
+		silenceRepo: silenceRepo,
+	}
+}
+
+func (u *silenceUsecase) CreateSilence(user string, req request.SilenceRequest) (*model.Silence, error) {
+	if len(req.Matchers) == 0 {
+		return nil, fmt.Errorf("%w: at least one matcher is required", ErrInvalidSilence)
+	}
+	for key := range req.Matchers {
+		if !containsString(model.SilenceMatcherKeys, key) {
+			return nil, fmt.Errorf("%w: unknown matcher %q, use one of %s", ErrInvalidSilence, key, strings.Join(model.SilenceMatcherKeys, ", "))
+		}
+	}
+
+	now := time.Now()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+	var endsAt time.Time
+	switch {
+	case req.EndsAt != nil:
+		endsAt = *req.EndsAt
+	case req.Duration != "":
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("%w: duration must be a positive duration such as 30m or 2h", ErrInvalidSilence)
+		}
+		endsAt = startsAt.Add(duration)
+	default:
+		return nil, fmt.Errorf("%w: ends_at or duration is required", ErrInvalidSilence)
+	}
+	if !endsAt.After(startsAt) || !endsAt.After(now) {
+		return nil, fmt.Errorf("%w: the silence must end after it starts and in the future", ErrInvalidSilence)
+	}
+
+	silence := &model.Silence{
+		UUID:      uuid.New().String(),
+		Matchers:  req.Matchers,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		CreatedBy: user,
+		Comment:   req.Comment,
+	}
+	if err := u.silenceRepo.CreateSilence(silence); err != nil {
+		return nil, err
+	}
+
+	u.config.Logger.INFO(config.SUSLCR, "Created silence", map[string]interface{}{
+		"silence_uuid": silence.UUID,
+		"matchers":     silence.Matchers,
+		"starts_at":    silence.StartsAt,
+		"ends_at":      silence.EndsAt,
+		"created_by":   user,
+	})
+	return silence, nil
+}
+
+func (u *silenceUsecase) GetSilences(req request.SilenceListRequest) ([]model.Silence, error) {
+	return u.silenceRepo.GetSilences(time.Now(), req.All)
+}
+
+// ExpireSilence ends a silence now. Expiring an ended silence leaves it unchanged.
+func (u *silenceUsecase) ExpireSilence(silenceUUID string) (*model.Silence, error) {
+	if err := u.silenceRepo.ExpireSilence(silenceUUID, time.Now()); err != nil {
+		return nil, err
+	}
+	silence, err := u.silenceRepo.GetSilence(silenceUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSilenceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u.config.Logger.INFO(config.SUSLEXP, "Expired silence", map[string]interface{}{
+		"silence_uuid": silenceUUID,
+	})
+	return silence, nil
+}
+
+func (u *silenceUsecase) CreateMaintenanceWindow(user string, req request.MaintenanceWindowRequest) (*model.MaintenanceWindow, error) {
+	if _, _, ok := model.ParseClock(req.StartTime); !ok {
+		return nil, fmt.Errorf("%w: start_time must be HH:MM", ErrInvalidSilence)
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration < time.Minute || duration > 7*24*time.Hour {
+		return nil, fmt.Errorf("%w: duration must be between 1m and 168h", ErrInvalidSilence)
+	}
+	weekdays := make([]string, 0, len(req.Weekdays))
+	for _, day := range req.Weekdays {
+		day = strings.ToLower(day)
+		if len(day) > 3 {
+			day = day[:3]
+		}
+		if !containsString([]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}, day) {
+			return nil, fmt.Errorf("%w: unknown weekday %q", ErrInvalidSilence, day)
+		}
+		weekdays = append(weekdays, day)
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidSilence, timezone)
+	}
+
+	window := &model.MaintenanceWindow{
+		UUID:      uuid.New().String(),
+		Name:      req.Name,
+		Group:     req.Group,
+		Weekdays:  weekdays,
+		StartTime: req.StartTime,
+		Duration:  int(duration / time.Minute),
+		Timezone:  timezone,
+		CreatedBy: user,
+		Comment:   req.Comment,
+	}
+	if err := u.silenceRepo.CreateMaintenanceWindow(window); err != nil {
+		return nil, err
+	}
+
+	u.config.Logger.INFO(config.SUSLCR, "Created maintenance window", map[string]interface{}{
+		"window_uuid": window.UUID,
+		"group":       window.Group,
+		"created_by":  user,
+	})
+	return window, nil
+}
+
+func (u *silenceUsecase) GetMaintenanceWindows() ([]model.MaintenanceWindow, error) {
+	return u.silenceRepo.GetMaintenanceWindows()
+}
+
+func (u *silenceUsecase) DeleteMaintenanceWindow(windowUUID string) error {
+	err := u.silenceRepo.DeleteMaintenanceWindow(windowUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrMaintenanceWindowNotFound
+	}
+	return err
+}
@@ -0,0 +1,196 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/ryo-arima/circulator/pkg/entity/response"
+	"github.com/ryo-arima/circulator/pkg/server/repository"
+)
+
+// ErrInvalidWatchQuery is returned for event streams with a bad since or Last-Event-ID
+var ErrInvalidWatchQuery = errors.New("invalid watch query")
+
+// watchBatchSize bounds the events read per query while a stream catches up
+const watchBatchSize = 100
+
+type WatchUsecase interface {
+	// WatchAgents sends every agent as ADDED, then the changes of the agent list until
+	// ctx is cancelled or send fails
+	WatchAgents(ctx context.Context, send func(event response.AgentWatchEvent) error) error
+	// EventCursor returns the outbox row id a stream resumes after: the Last-Event-ID of a
+	// reconnecting client, else the last event before req.Since, else the newest event
+	EventCursor(req request.EventListRequest, lastEventID string) (uint, error)
+	// TailEvents sends the events after cursor matching req, with their row id, until ctx
+	// is cancelled or send fails
+	TailEvents(ctx context.Context, req request.EventListRequest, cursor uint, send func(id uint, event *model.ServerEvent) error) error
+}
+
+type watchUsecase struct {
+	config     config.BaseConfig
+	agentRepo  repository.AgentRepository
+	outboxRepo repository.OutboxRepository
+}
+
+func NewWatchUsecase(conf config.BaseConfig, agentRepo repository.AgentRepository, outboxRepo repository.OutboxRepository) WatchUsecase {
+	return &watchUsecase{
+		config:     conf,
+		agentRepo:  agentRepo,
+		outboxRepo: outboxRepo,
+	}
+}
+
+// WatchAgents polls the agent table and diffs it against the previous read. Heartbeats do
+// not produce server events, so the table rather than the outbox is the source of changes.
+func (u *watchUsecase) WatchAgents(ctx context.Context, send func(event response.AgentWatchEvent) error) (err error) {
+	defer u.logStream(ctx, "agents", nil)(&err)
+	ticker := time.NewTicker(u.pollInterval())
+	defer ticker.Stop()
+
+	known := make(map[string]model.Agent)
+	for {
+		agents, err := u.agentRepo.ListAgents()
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[string]bool, len(agents))
+		for _, agent := range agents {
+			seen[agent.UUID] = true
+			previous, ok := known[agent.UUID]
+			known[agent.UUID] = agent
+			switch {
+			case !ok:
+				err = send(response.AgentWatchEvent{Type: response.WatchEventAdded, Agent: agent})
+			case !reflect.DeepEqual(previous, agent):
+				err = send(response.AgentWatchEvent{Type: response.WatchEventModified, Agent: agent})
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for uuid, agent := range known {
+			if seen[uuid] {
+				continue
+			}
+			delete(known, uuid)
+			if err := send(response.AgentWatchEvent{Type: response.WatchEventDeleted, Agent: agent}); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (u *watchUsecase) EventCursor(req request.EventListRequest, lastEventID string) (uint, error) {
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 0)
+		if err != nil {
+			return 0, fmt.Errorf("%w: Last-Event-ID must be an event id", ErrInvalidWatchQuery)
+		}
+		return uint(id), nil
+	}
+
+	since, err := parseMetricTime(req.Since, time.Time{})
+	if err != nil {
+		return 0, fmt.Errorf("%w: since must be RFC3339 or unix seconds", ErrInvalidWatchQuery)
+	}
+	return u.outboxRepo.LastEventID(since)
+}
+
+// TailEvents reads the outbox rather than Pulsar, so a stream sees events already relayed
+// and resumes after a reconnect until the outbox retention removes them. Row ids are taken
+// at insert but become visible at commit, so a lower id can appear after a higher one;
+// events younger than the settle delay are held back, and the cursor only passes ids whose
+// transactions have had that long to commit.
+func (u *watchUsecase) TailEvents(ctx context.Context, req request.EventListRequest, cursor uint, send func(id uint, event *model.ServerEvent) error) (err error) {
+	defer u.logStream(ctx, "events", map[string]interface{}{
+		"types":      req.Types,
+		"agent_uuid": req.AgentUUID,
+		"cursor":     cursor,
+	})(&err)
+	ticker := time.NewTicker(u.pollInterval())
+	defer ticker.Stop()
+	settle := u.settleDelay()
+
+	for {
+		for {
+			events, err := u.outboxRepo.GetEvents(req, cursor, watchBatchSize)
+			if err != nil {
+				return err
+			}
+			settled := len(events) == watchBatchSize
+			cutoff := time.Now().Add(-settle)
+			for _, event := range events {
+				if !event.CreatedAt.Before(cutoff) {
+					settled = false
+					break
+				}
+				if err := send(event.ID, event.ToServerEvent()); err != nil {
+					return err
+				}
+				cursor = event.ID
+			}
+			if !settled {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// logStream logs the opening of a stream and returns the func logging how it ended. A
+// failed write after the client went away is a normal close.
+func (u *watchUsecase) logStream(ctx context.Context, stream string, fields map[string]interface{}) func(err *error) {
+	if fields == nil {
+		fields = map[string]interface{}{}
+	}
+	fields["stream"] = stream
+	u.config.Logger.INFO(config.SUWOPEN, "Watch stream opened", fields)
+	return func(err *error) {
+		if *err != nil && ctx.Err() != nil {
+			*err = nil
+		}
+		if *err != nil {
+			u.config.Logger.WARN(config.SUWERR, "Watch stream ended with error", map[string]interface{}{
+				"stream": stream,
+				"error":  (*err).Error(),
+			})
+			return
+		}
+		u.config.Logger.INFO(config.SUWCLOSE, "Watch stream closed", map[string]interface{}{
+			"stream": stream,
+		})
+	}
+}
+
+func (u *watchUsecase) settleDelay() time.Duration {
+	if ms := u.config.YamlConfig.Application.Server.Watch.Settle; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return 2 * time.Second
+}
+
+func (u *watchUsecase) pollInterval() time.Duration {
+	if ms := u.config.YamlConfig.Application.Server.Watch.PollInterval; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return time.Second
+}
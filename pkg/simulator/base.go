@@ -0,0 +1,325 @@
+package simulator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// Options controls a simulator run
+type Options struct {
+	Generator  GeneratorOptions
+	Target     string
+	AgentAddr  string
+	Rate       float64 // readings per second across all sensors, 0 for as fast as possible
+	Duration   time.Duration
+	Count      int64
+	LabelsPath string
+	Alerts     bool          // collect AlertData from Pulsar for evaluation
+	Settle     time.Duration // time to wait for late agent output after publishing stops
+}
+
+// Stats summarises a simulator run
+type Stats struct {
+	Sent        int64
+	Errors      int64
+	Injected    int64
+	Duplicates  int64
+	Results     int64
+	Flagged     int64
+	Faults      map[string]int64
+	Evaluations []Evaluation
+	Elapsed     time.Duration
+}
+
+// Main runs the simulator command line until the run completes or SIGINT/SIGTERM
+func Main(conf config.BaseConfig) {
+	if err := InitRootCmd(conf).Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// InitRootCmd creates the simulator root command
+func InitRootCmd(conf config.BaseConfig) *cobra.Command {
+	var options Options
+	var group SensorGroup
+	var sensorTypes []string
+	var scenarioPath string
+
+	rootCmd := &cobra.Command{
+		Use:   "simulator",
+		Short: "'simulator' generates synthetic sensor data for circulator",
+		Long:  `Generate IncomingStreamData for virtual sensors and publish it to Pulsar or to an agent's gRPC stream endpoint.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, sensorType := range sensorTypes {
+				g := group
+				g.Type = sensorType
+				options.Generator.Groups = append(options.Generator.Groups, g)
+			}
+
+			if scenarioPath != "" {
+				scenario, err := LoadScenario(scenarioPath)
+				if err != nil {
+					return err
+				}
+				scenario.Apply(&options)
+				conf.Logger.INFO(config.SIMSCN, "Simulator scenario loaded", map[string]interface{}{
+					"scenario": scenario.Name,
+					"sensors":  len(scenario.Sensors),
+					"events":   len(scenario.Events),
+				})
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			stats, err := Run(ctx, conf, options)
+			if err != nil {
+				return err
+			}
+			PrintStats(stats)
+			return nil
+		},
+	}
+
+	flags := rootCmd.Flags()
+	flags.StringVar(&scenarioPath, "scenario", "", "Scenario YAML file; its sensors, events, rate and duration replace the signal flags")
+	flags.StringSliceVar(&sensorTypes, "sensor-types", []string{"temperature"}, "Sensor types to simulate")
+	flags.IntVar(&group.Count, "sensors", 10, "Virtual sensors per sensor type")
+	flags.StringVar(&group.Waveform, "waveform", WaveformSine, "Waveform: sine|random_walk|step|sawtooth")
+	flags.Float64Var(&group.Baseline, "baseline", 20, "Signal baseline")
+	flags.Float64Var(&group.Amplitude, "amplitude", 5, "Signal amplitude")
+	flags.DurationVar(&group.Period, "period", time.Minute, "Waveform period")
+	flags.Float64Var(&group.Noise, "noise", 0.1, "Standard deviation of gaussian noise")
+	flags.Float64Var(&options.Generator.AnomalyRate, "anomaly-rate", 0.01, "Probability that a reading is an injected spike anomaly")
+	flags.Float64Var(&options.Generator.AnomalyMagnitude, "anomaly-magnitude", 5, "Spike offset as a multiple of the amplitude")
+	flags.Int64Var(&options.Generator.Seed, "seed", 0, "Random seed, 0 for a time-based seed")
+	flags.StringVar(&options.Target, "target", TargetPulsar, "Publish target: pulsar|grpc")
+	flags.StringVar(&options.AgentAddr, "agent-addr", "localhost:50051", "Agent gRPC address for --target grpc")
+	flags.Float64Var(&options.Rate, "rate", 100, "Readings per second across all sensors, 0 for as fast as possible")
+	flags.DurationVar(&options.Duration, "duration", time.Minute, "Run duration, 0 to run until interrupted or --count is reached")
+	flags.Int64Var(&options.Count, "count", 0, "Stop after this many readings, 0 for no limit")
+	flags.StringVar(&options.LabelsPath, "labels", "", "Write ground-truth labels as JSON lines to this file")
+	flags.BoolVar(&options.Alerts, "alerts", false, "Collect AlertData from Pulsar and score it against injected anomalies")
+	flags.DurationVar(&options.Settle, "settle", 5*time.Second, "Time to wait for late agent output before scoring")
+
+	rootCmd.AddCommand(initFleetCmd(conf))
+
+	return rootCmd
+}
+
+// Run generates and publishes readings until the duration or count is reached or ctx
+// is cancelled, then scores the agent's detections against the injected anomalies
+func Run(ctx context.Context, conf config.BaseConfig, options Options) (*Stats, error) {
+	generator, err := NewGenerator(options.Generator)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{Faults: map[string]int64{}}
+	eval := newEvaluator()
+
+	// Processed results come back on the gRPC stream; with Pulsar they are read from the processed topic
+	collectProcessed := options.Target == TargetPulsar
+	var agentOutput *collector
+	if collectProcessed || options.Alerts {
+		agentOutput, err = newCollector(conf, collectProcessed, options.Alerts, eval)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	publisher, err := NewPublisher(conf, options.Target, options.AgentAddr, stats)
+	if err != nil {
+		if agentOutput != nil {
+			agentOutput.Close()
+		}
+		return nil, err
+	}
+
+	var labels *json.Encoder
+	if options.LabelsPath != "" {
+		file, err := os.Create(options.LabelsPath)
+		if err != nil {
+			publisher.Close()
+			if agentOutput != nil {
+				agentOutput.Close()
+			}
+			return nil, fmt.Errorf("failed to create labels file: %w", err)
+		}
+		defer file.Close()
+		writer := bufio.NewWriter(file)
+		defer writer.Flush()
+		labels = json.NewEncoder(writer)
+	}
+
+	runCtx := ctx
+	if options.Duration > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, options.Duration)
+		defer cancel()
+	}
+
+	conf.Logger.INFO(config.SIMRUN, "Simulator run starting", map[string]interface{}{
+		"target":   options.Target,
+		"sensors":  generator.Sensors(),
+		"faults":   len(options.Generator.Faults),
+		"rate":     options.Rate,
+		"duration": options.Duration.String(),
+		"count":    options.Count,
+	})
+
+	var wg sync.WaitGroup
+	streamResults := publisher.Results()
+	if streamResults != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range streamResults {
+				atomic.AddInt64(&stats.Results, 1)
+				if result.Anomaly {
+					atomic.AddInt64(&stats.Flagged, 1)
+				}
+				eval.processedResult(result)
+			}
+		}()
+	}
+
+	started := time.Now()
+	err = publishLoop(runCtx, generator, publisher, labels, eval, options, stats)
+
+	if closeErr := publisher.Close(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	wg.Wait()
+
+	if agentOutput != nil {
+		select {
+		case <-ctx.Done():
+		case <-time.After(options.Settle):
+		}
+		agentOutput.Close()
+	}
+	stats.Elapsed = time.Since(started)
+	stats.Evaluations = eval.evaluate(collectProcessed || streamResults != nil, options.Alerts)
+
+	conf.Logger.INFO(config.SIMDONE, "Simulator run finished", map[string]interface{}{
+		"sent":     stats.Sent,
+		"errors":   stats.Errors,
+		"injected": stats.Injected,
+		"flagged":  stats.Flagged,
+		"elapsed":  stats.Elapsed.String(),
+	})
+	return stats, err
+}
+
+// publishLoop paces publishing to the target rate. Each tick sends however many
+// readings are due, so rates above the tick frequency are still reached.
+func publishLoop(ctx context.Context, generator *Generator, publisher Publisher, labels *json.Encoder, eval *evaluator, options Options, stats *Stats) error {
+	tick := 10 * time.Millisecond
+	if options.Rate > 0 && time.Duration(float64(time.Second)/options.Rate) > tick {
+		tick = time.Duration(float64(time.Second) / options.Rate)
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	readings := int64(0)
+	deliver := func(samples []*Sample) error {
+		for _, sample := range samples {
+			if sample.Duplicate {
+				stats.Duplicates++
+			} else {
+				if labels != nil {
+					if err := labels.Encode(sample.Label()); err != nil {
+						return fmt.Errorf("failed to write label: %w", err)
+					}
+				}
+				if sample.Anomaly {
+					stats.Injected++
+				}
+				for _, fault := range sample.Faults {
+					stats.Faults[fault]++
+				}
+				eval.record(sample)
+			}
+			if err := publisher.Publish(ctx, sample.Data); err != nil {
+				atomic.AddInt64(&stats.Errors, 1)
+			}
+			stats.Sent++
+		}
+		return nil
+	}
+
+	unlimited := options.Rate <= 0
+	started := time.Now()
+	for {
+		var due int64
+		if !unlimited {
+			due = int64(time.Since(started).Seconds()*options.Rate) - readings
+		}
+
+		for unlimited || due > 0 {
+			if (options.Count > 0 && readings >= options.Count) || ctx.Err() != nil {
+				return deliver(generator.Drain())
+			}
+			if err := deliver(generator.Next(time.Now())); err != nil {
+				return err
+			}
+			readings++
+			due--
+		}
+
+		select {
+		case <-ctx.Done():
+			return deliver(generator.Drain())
+		case <-ticker.C:
+		}
+	}
+}
+
+// PrintStats writes a run summary to stdout
+func PrintStats(stats *Stats) {
+	rate := 0.0
+	if stats.Elapsed > 0 {
+		rate = float64(stats.Sent) / stats.Elapsed.Seconds()
+	}
+	fmt.Printf("Sent:       %d (%.1f msg/s)\n", stats.Sent, rate)
+	fmt.Printf("Errors:     %d\n", atomic.LoadInt64(&stats.Errors))
+	fmt.Printf("Injected:   %d anomalies\n", stats.Injected)
+	if stats.Duplicates > 0 {
+		fmt.Printf("Duplicates: %d\n", stats.Duplicates)
+	}
+	if len(stats.Faults) > 0 {
+		kinds := make([]string, 0, len(stats.Faults))
+		for kind := range stats.Faults {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		fmt.Println("Faults:")
+		for _, kind := range kinds {
+			fmt.Printf("  %-13s %d\n", kind, stats.Faults[kind])
+		}
+	}
+	if stats.Results > 0 {
+		fmt.Printf("Processed:  %d\n", stats.Results)
+		fmt.Printf("Flagged:    %d anomalies\n", stats.Flagged)
+	}
+	for _, evaluation := range stats.Evaluations {
+		fmt.Printf("Detection (%s): %d readings, TP %d, FP %d, FN %d, precision %.3f, recall %.3f\n",
+			evaluation.Source, evaluation.Readings,
+			evaluation.TruePositives, evaluation.FalsePositives, evaluation.FalseNegatives,
+			evaluation.Precision(), evaluation.Recall())
+	}
+	fmt.Printf("Elapsed:    %s\n", stats.Elapsed.Round(time.Millisecond))
+}
@@ -0,0 +1,126 @@
+package simulator
+
+import (
+	"context"
+	"sync"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/codec"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// collector reads agent output from Pulsar so it can be matched to generated readings.
+// Agents publish the result of every reading, from Pulsar or gRPC, carrying the incoming
+// reading's UUID in ProcessedStreamData.UUID and, for anomalies, AlertData.UUID.
+type collector struct {
+	config    config.BaseConfig
+	client    pulsar.Client
+	consumers []pulsar.Consumer
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// newCollector subscribes to the processed and/or alert topics from the latest message.
+// A private subscription keeps the simulator from stealing messages from other consumers.
+func newCollector(conf config.BaseConfig, processed, alerts bool, eval *evaluator) (*collector, error) {
+	client, err := config.NewPulsarClient(conf.YamlConfig)
+	if err != nil {
+		conf.Logger.ERROR(config.SIMERR, "Failed to create Pulsar client", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &collector{
+		config: conf,
+		client: client,
+		cancel: cancel,
+	}
+
+	subscription := "simulator-" + uuid.New().String()
+	topics := conf.YamlConfig.Pulsar.Topics
+	if processed {
+		if err := c.subscribe(ctx, topics.ProcessedSensorData, subscription, func(msg pulsar.Message) error {
+			var result model.ProcessedStreamData
+			if _, err := codec.Decode(msg.Payload(), msg.Properties(), &result); err != nil {
+				return err
+			}
+			eval.processedResult(&result)
+			return nil
+		}); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if alerts {
+		if err := c.subscribe(ctx, topics.AlertData, subscription, func(msg pulsar.Message) error {
+			var alert model.AlertData
+			if _, err := codec.Decode(msg.Payload(), msg.Properties(), &alert); err != nil {
+				return err
+			}
+			eval.alert(&alert)
+			return nil
+		}); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// subscribe starts a receive loop on topic that hands every message to handle
+func (c *collector) subscribe(ctx context.Context, topic, subscription string, handle func(pulsar.Message) error) error {
+	consumer, err := c.client.Subscribe(pulsar.ConsumerOptions{
+		Topic:                       topic,
+		SubscriptionName:            subscription,
+		Type:                        pulsar.Exclusive,
+		SubscriptionInitialPosition: pulsar.SubscriptionPositionLatest,
+	})
+	if err != nil {
+		c.config.Logger.ERROR(config.SIMERR, "Failed to subscribe to agent output", map[string]interface{}{
+			"error": err.Error(),
+			"topic": topic,
+		})
+		return err
+	}
+	c.consumers = append(c.consumers, consumer)
+
+	c.config.Logger.INFO(config.SIMCOL, "Simulator collecting agent output", map[string]interface{}{
+		"topic":        topic,
+		"subscription": subscription,
+	})
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			msg, err := consumer.Receive(ctx)
+			if err != nil {
+				return
+			}
+			if err := handle(msg); err != nil {
+				c.config.Logger.WARN(config.SIMERR, "Failed to decode agent output", map[string]interface{}{
+					"error": err.Error(),
+					"topic": topic,
+				})
+			}
+			consumer.Ack(msg)
+		}
+	}()
+	return nil
+}
+
+// Close stops the receive loops and drops the private subscriptions
+func (c *collector) Close() {
+	c.cancel()
+	c.wg.Wait()
+	for _, consumer := range c.consumers {
+		consumer.Unsubscribe()
+		consumer.Close()
+	}
+	c.client.Close()
+}
@@ -0,0 +1,118 @@
+package simulator
+
+import (
+	"sync"
+
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// Detection sources compared against ground truth
+const (
+	DetectionProcessed = "processed"
+	DetectionAlerts    = "alerts"
+)
+
+// Evaluation compares the agent's detections from one source against injected anomalies
+type Evaluation struct {
+	Source         string
+	Readings       int64
+	TruePositives  int64
+	FalsePositives int64
+	FalseNegatives int64
+	TrueNegatives  int64
+}
+
+// Precision returns the share of flagged readings that were injected anomalies
+func (e Evaluation) Precision() float64 {
+	if e.TruePositives+e.FalsePositives == 0 {
+		return 0
+	}
+	return float64(e.TruePositives) / float64(e.TruePositives+e.FalsePositives)
+}
+
+// Recall returns the share of injected anomalies that were flagged
+func (e Evaluation) Recall() float64 {
+	if e.TruePositives+e.FalseNegatives == 0 {
+		return 0
+	}
+	return float64(e.TruePositives) / float64(e.TruePositives+e.FalseNegatives)
+}
+
+// evaluator matches detections to ground truth by reading UUID
+type evaluator struct {
+	mu        sync.Mutex
+	truth     map[string]bool
+	processed map[string]bool
+	alerted   map[string]bool
+}
+
+func newEvaluator() *evaluator {
+	return &evaluator{
+		truth:     map[string]bool{},
+		processed: map[string]bool{},
+		alerted:   map[string]bool{},
+	}
+}
+
+// record stores the ground truth of a delivered reading
+func (e *evaluator) record(sample *Sample) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.truth[sample.Data.UUID] = sample.Anomaly
+}
+
+// processedResult records ProcessedStreamData.Anomaly; duplicates count as flagged if any delivery was
+func (e *evaluator) processedResult(result *model.ProcessedStreamData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.processed[result.UUID] = e.processed[result.UUID] || result.Anomaly
+}
+
+// alert records an AlertData raised for a reading
+func (e *evaluator) alert(alert *model.AlertData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.alerted[alert.UUID] = true
+}
+
+// evaluate scores processed results over the readings that have one, and alerts over
+// every reading, since a reading without an alert is a negative
+func (e *evaluator) evaluate(processed, alerts bool) []Evaluation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var evaluations []Evaluation
+	if processed {
+		evaluation := Evaluation{Source: DetectionProcessed}
+		for id, anomaly := range e.truth {
+			flagged, ok := e.processed[id]
+			if !ok {
+				continue
+			}
+			evaluation.add(anomaly, flagged)
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+	if alerts {
+		evaluation := Evaluation{Source: DetectionAlerts}
+		for id, anomaly := range e.truth {
+			evaluation.add(anomaly, e.alerted[id])
+		}
+		evaluations = append(evaluations, evaluation)
+	}
+	return evaluations
+}
+
+func (e *Evaluation) add(anomaly, flagged bool) {
+	e.Readings++
+	switch {
+	case anomaly && flagged:
+		e.TruePositives++
+	case anomaly:
+		e.FalseNegatives++
+	case flagged:
+		e.FalsePositives++
+	default:
+		e.TrueNegatives++
+	}
+}
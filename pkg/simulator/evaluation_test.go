@@ -0,0 +1,142 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+func TestPrecisionRecall(t *testing.T) {
+	tests := []struct {
+		name          string
+		evaluation    Evaluation
+		wantPrecision float64
+		wantRecall    float64
+	}{
+		{name: "perfect", evaluation: Evaluation{TruePositives: 5, TrueNegatives: 95}, wantPrecision: 1, wantRecall: 1},
+		{name: "mixed", evaluation: Evaluation{TruePositives: 6, FalsePositives: 2, FalseNegatives: 4, TrueNegatives: 88}, wantPrecision: 0.75, wantRecall: 0.6},
+		{name: "nothing flagged", evaluation: Evaluation{FalseNegatives: 3, TrueNegatives: 97}, wantPrecision: 0, wantRecall: 0},
+		{name: "no anomalies", evaluation: Evaluation{FalsePositives: 1, TrueNegatives: 99}, wantPrecision: 0, wantRecall: 0},
+		{name: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.evaluation.Precision(); math.Abs(got-tt.wantPrecision) > 1e-9 {
+				t.Errorf("Precision() = %v, want %v", got, tt.wantPrecision)
+			}
+			if got := tt.evaluation.Recall(); math.Abs(got-tt.wantRecall) > 1e-9 {
+				t.Errorf("Recall() = %v, want %v", got, tt.wantRecall)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	// readings r1..r6 with their ground truth
+	truth := []struct {
+		uuid    string
+		anomaly bool
+	}{
+		{"r1", true}, {"r2", true}, {"r3", true}, {"r4", false}, {"r5", false}, {"r6", false},
+	}
+
+	tests := []struct {
+		name      string
+		processed []model.ProcessedStreamData
+		alerts    []string
+		want      []Evaluation
+	}{
+		{
+			name: "processed results",
+			processed: []model.ProcessedStreamData{
+				{UUID: "r1", Anomaly: true},
+				{UUID: "r2", Anomaly: false},
+				{UUID: "r4", Anomaly: true},
+				{UUID: "r5", Anomaly: false},
+				// r3 and r6 were never processed and are left out
+			},
+			want: []Evaluation{
+				{Source: DetectionProcessed, Readings: 4, TruePositives: 1, FalseNegatives: 1, FalsePositives: 1, TrueNegatives: 1},
+				{Source: DetectionAlerts, Readings: 6, FalseNegatives: 3, TrueNegatives: 3},
+			},
+		},
+		{
+			name: "duplicate deliveries",
+			processed: []model.ProcessedStreamData{
+				{UUID: "r1", Anomaly: false},
+				{UUID: "r1", Anomaly: true},
+				{UUID: "r4", Anomaly: true},
+				{UUID: "r4", Anomaly: false},
+			},
+			want: []Evaluation{
+				{Source: DetectionProcessed, Readings: 2, TruePositives: 1, FalsePositives: 1},
+				{Source: DetectionAlerts, Readings: 6, FalseNegatives: 3, TrueNegatives: 3},
+			},
+		},
+		{
+			name:   "alerts",
+			alerts: []string{"r1", "r2", "r5", "r2"},
+			want: []Evaluation{
+				{Source: DetectionProcessed},
+				{Source: DetectionAlerts, Readings: 6, TruePositives: 2, FalseNegatives: 1, FalsePositives: 1, TrueNegatives: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newEvaluator()
+			for _, reading := range truth {
+				e.record(&Sample{Data: &model.IncomingStreamData{UUID: reading.uuid}, Anomaly: reading.anomaly})
+			}
+			for i := range tt.processed {
+				e.processedResult(&tt.processed[i])
+			}
+			for _, uuid := range tt.alerts {
+				e.alert(&model.AlertData{UUID: uuid})
+			}
+
+			got := e.evaluate(true, true)
+			if len(got) != len(tt.want) {
+				t.Fatalf("evaluate() = %d sources, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("evaluate()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateSources(t *testing.T) {
+	e := newEvaluator()
+	e.record(&Sample{Data: &model.IncomingStreamData{UUID: "r1"}})
+
+	tests := []struct {
+		name      string
+		processed bool
+		alerts    bool
+		want      []string
+	}{
+		{name: "processed only", processed: true, want: []string{DetectionProcessed}},
+		{name: "alerts only", alerts: true, want: []string{DetectionAlerts}},
+		{name: "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := e.evaluate(tt.processed, tt.alerts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("evaluate() = %d sources, want %v", len(got), tt.want)
+			}
+			for i, source := range tt.want {
+				if got[i].Source != source {
+					t.Errorf("source %d = %q, want %q", i, got[i].Source, source)
+				}
+			}
+		})
+	}
+}
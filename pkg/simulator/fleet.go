@@ -0,0 +1,434 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/agent/repository/api"
+	agentpulsar "github.com/ryo-arima/circulator/pkg/agent/repository/pulsar"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"github.com/ryo-arima/circulator/pkg/entity/request"
+	"github.com/spf13/cobra"
+)
+
+// Fleet operations, in the order they are printed
+const (
+	OperationRegister  = "register"
+	OperationHeartbeat = "heartbeat"
+	OperationConfig    = "config"
+	OperationReport    = "report"
+)
+
+var fleetOperations = []string{OperationRegister, OperationHeartbeat, OperationConfig, OperationReport}
+
+// FleetOptions controls a simulated agent fleet
+type FleetOptions struct {
+	Agents            int
+	RampUp            time.Duration // agents start evenly spread over this period
+	Duration          time.Duration
+	HeartbeatInterval time.Duration
+	ConfigInterval    time.Duration // 0 fetches config once after registering
+	ReportInterval    time.Duration // 0 disables Pulsar reports
+}
+
+// OperationStats summarises the server latency of one fleet operation
+type OperationStats struct {
+	Operation string
+	Calls     int64
+	Errors    int64
+	P50       time.Duration
+	P90       time.Duration
+	P99       time.Duration
+	Max       time.Duration
+}
+
+// ErrorRate returns the fraction of calls that failed
+func (s OperationStats) ErrorRate() float64 {
+	if s.Calls == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Calls)
+}
+
+// FleetStats summarises a fleet run
+type FleetStats struct {
+	Agents     int64
+	Failed     int64 // agents that could not create their Pulsar producer
+	Operations []OperationStats
+	Elapsed    time.Duration
+}
+
+// latencyRecorder keeps every successful call's latency per operation
+type latencyRecorder struct {
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	errors    map[string]int64
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{
+		latencies: map[string][]time.Duration{},
+		errors:    map[string]int64{},
+	}
+}
+
+// observe times call and records its latency, or an error when it fails. Calls cut
+// short by the end of the run are not recorded.
+func (r *latencyRecorder) observe(ctx context.Context, operation string, call func() error) error {
+	started := time.Now()
+	err := call()
+	elapsed := time.Since(started)
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.errors[operation]++
+		return err
+	}
+	r.latencies[operation] = append(r.latencies[operation], elapsed)
+	return nil
+}
+
+// summarise returns percentiles over successful calls; failed calls only count as errors
+func (r *latencyRecorder) summarise() []OperationStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]OperationStats, 0, len(fleetOperations))
+	for _, operation := range fleetOperations {
+		latencies := r.latencies[operation]
+		stats := OperationStats{
+			Operation: operation,
+			Calls:     int64(len(latencies)) + r.errors[operation],
+			Errors:    r.errors[operation],
+		}
+		if len(latencies) > 0 {
+			sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+			stats.P50 = percentile(latencies, 0.50)
+			stats.P90 = percentile(latencies, 0.90)
+			stats.P99 = percentile(latencies, 0.99)
+			stats.Max = latencies[len(latencies)-1]
+		}
+		result = append(result, stats)
+	}
+	return result
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(float64(len(sorted))*p)) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// initFleetCmd creates the fleet subcommand
+func initFleetCmd(conf config.BaseConfig) *cobra.Command {
+	var options FleetOptions
+
+	fleetCmd := &cobra.Command{
+		Use:   "fleet",
+		Short: "Run many virtual agents against the server",
+		Long: `Start lightweight virtual agents in one process. Each registers, sends heartbeats and
+fetches its config through the agent API repositories and publishes metrics reports
+through the agent Pulsar producer, and the run reports server latency percentiles and
+error rates per operation.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			stats, err := RunFleet(ctx, conf, options)
+			if err != nil {
+				return err
+			}
+			PrintFleetStats(stats)
+			return nil
+		},
+	}
+
+	flags := fleetCmd.Flags()
+	flags.IntVar(&options.Agents, "agents", 100, "Number of virtual agents")
+	flags.DurationVar(&options.RampUp, "ramp-up", 10*time.Second, "Spread agent start-up over this period")
+	flags.DurationVar(&options.Duration, "duration", time.Minute, "Run duration, 0 to run until interrupted")
+	flags.DurationVar(&options.HeartbeatInterval, "heartbeat-interval", 10*time.Second, "Heartbeat interval per agent")
+	flags.DurationVar(&options.ConfigInterval, "config-interval", time.Minute, "Config fetch interval per agent, 0 to fetch once")
+	flags.DurationVar(&options.ReportInterval, "report-interval", 10*time.Second, "Metrics report interval per agent, 0 to skip Pulsar")
+
+	return fleetCmd
+}
+
+// RunFleet runs the virtual agents until the duration is over or ctx is cancelled
+func RunFleet(ctx context.Context, conf config.BaseConfig, options FleetOptions) (*FleetStats, error) {
+	if options.Agents <= 0 {
+		return nil, fmt.Errorf("agents must be positive")
+	}
+	if options.HeartbeatInterval <= 0 {
+		return nil, fmt.Errorf("heartbeat interval must be positive")
+	}
+
+	// Virtual agents share one process, so the on-disk spool is turned off and every
+	// agent's producer runs on a single Pulsar client
+	agentConf := conf
+	agentConf.YamlConfig.Application.Agent.Spool.Enabled = false
+
+	var client pulsar.Client
+	if options.ReportInterval > 0 {
+		var err error
+		client, err = config.NewPulsarClient(conf.YamlConfig)
+		if err != nil {
+			conf.Logger.ERROR(config.SIMERR, "Failed to create Pulsar client", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return nil, err
+		}
+		defer client.Close()
+	}
+
+	if options.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Duration)
+		defer cancel()
+	}
+
+	conf.Logger.INFO(config.SIMFLT, "Simulator fleet starting", map[string]interface{}{
+		"agents":             options.Agents,
+		"ramp_up":            options.RampUp.String(),
+		"duration":           options.Duration.String(),
+		"heartbeat_interval": options.HeartbeatInterval.String(),
+		"report_interval":    options.ReportInterval.String(),
+		"server_endpoint":    conf.YamlConfig.Application.Agent.ServerEndpoint,
+	})
+
+	recorder := newLatencyRecorder()
+	stats := &FleetStats{}
+	var wg sync.WaitGroup
+
+	started := time.Now()
+	for i := 0; i < options.Agents; i++ {
+		delay := time.Duration(0)
+		if options.Agents > 1 {
+			delay = options.RampUp * time.Duration(i) / time.Duration(options.Agents)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Until(started.Add(delay))):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		agent := &virtualAgent{
+			options:  options,
+			uuid:     uuid.New().String(),
+			hostname: fmt.Sprintf("sim-agent-%05d", i+1),
+			common:   api.NewAPICommonRepository(agentConf),
+			api:      api.NewAPIAgentRepository(agentConf),
+			recorder: recorder,
+			random:   rand.New(rand.NewSource(time.Now().UnixNano() + int64(i))),
+		}
+		if client != nil {
+			producer, err := agentpulsar.NewProducerRepositoryWithClient(&agentConf, client, agent.hostname)
+			if err != nil {
+				conf.Logger.WARN(config.SIMERR, "Virtual agent could not create its producer", map[string]interface{}{
+					"error":    err.Error(),
+					"hostname": agent.hostname,
+				})
+				stats.Failed++
+				continue
+			}
+			agent.producer = producer
+		}
+
+		stats.Agents++
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			agent.run(ctx)
+		}()
+	}
+
+	wg.Wait()
+	stats.Elapsed = time.Since(started)
+	stats.Operations = recorder.summarise()
+
+	conf.Logger.INFO(config.SIMDONE, "Simulator fleet finished", map[string]interface{}{
+		"agents":  stats.Agents,
+		"failed":  stats.Failed,
+		"elapsed": stats.Elapsed.String(),
+	})
+	return stats, nil
+}
+
+// virtualAgent follows the real agent's life cycle against the server
+type virtualAgent struct {
+	options  FleetOptions
+	uuid     string
+	hostname string
+	common   api.APICommonRepository
+	api      api.APIAgentRepository
+	producer agentpulsar.ProducerRepository
+	recorder *latencyRecorder
+	random   *rand.Rand
+}
+
+// run registers and then sends heartbeats, config fetches and reports until ctx is
+// done. Failed calls are recorded and the agent carries on, so a failing endpoint does
+// not hide the latency of the others.
+func (a *virtualAgent) run(ctx context.Context) {
+	if a.producer != nil {
+		defer a.producer.Close()
+	}
+
+	a.recorder.observe(ctx, OperationRegister, func() error {
+		_, err := a.common.RegisterAgent(ctx, request.RegisterAgentRequest{
+			UUID:           a.uuid,
+			Hostname:       a.hostname,
+			IPAddress:      "127.0.0.1",
+			Port:           50051,
+			ThreadCount:    4,
+			MaxThreadCount: 8,
+			Version:        "1.0.0",
+			Capabilities:   []string{"stream_processing", "anomaly_detection", "system_monitoring"},
+			Metadata:       map[string]string{"simulated": "true"},
+		})
+		return err
+	})
+	a.fetchConfig(ctx)
+
+	heartbeats := a.every(ctx, a.options.HeartbeatInterval)
+	configFetches := a.every(ctx, a.options.ConfigInterval)
+	reports := a.every(ctx, a.options.ReportInterval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeats:
+			a.recorder.observe(ctx, OperationHeartbeat, func() error {
+				return a.common.SendHeartbeat(ctx, request.HeartbeatRequest{
+					AgentUUID: a.uuid,
+					Status:    "active",
+					Timestamp: time.Now(),
+				})
+			})
+		case <-configFetches:
+			a.fetchConfig(ctx)
+		case <-reports:
+			a.publishReport(ctx)
+		}
+	}
+}
+
+func (a *virtualAgent) fetchConfig(ctx context.Context) {
+	a.recorder.observe(ctx, OperationConfig, func() error {
+		resp, err := a.api.GetProcessingConfig(ctx, a.uuid)
+		if err != nil {
+			return err
+		}
+		if resp.Code != "" && resp.Code != "SUCCESS" {
+			return fmt.Errorf("config fetch failed: %s", resp.Message)
+		}
+		return nil
+	})
+}
+
+func (a *virtualAgent) publishReport(ctx context.Context) {
+	if a.producer == nil {
+		return
+	}
+	data, _ := json.Marshal(map[string]float64{
+		"cpu_usage":    20 + a.random.Float64()*60,
+		"memory_usage": 30 + a.random.Float64()*50,
+		"disk_usage":   40 + a.random.Float64()*10,
+	})
+	a.recorder.observe(ctx, OperationReport, func() error {
+		return a.producer.PublishReport(&model.AgentReport{
+			ID:        uuid.New().String(),
+			AgentID:   a.uuid,
+			Type:      model.ReportTypeMetrics,
+			Status:    "active",
+			Data:      string(data),
+			Timestamp: time.Now(),
+		})
+	})
+}
+
+// every ticks each interval after a random first offset, so agents started together do
+// not call the server in lockstep. Ticks are dropped while the agent is busy, as with
+// time.Ticker. A zero interval returns nil, which never fires.
+func (a *virtualAgent) every(ctx context.Context, interval time.Duration) <-chan time.Time {
+	if interval <= 0 {
+		return nil
+	}
+	ticks := make(chan time.Time, 1)
+	offset := time.Duration(a.random.Int63n(int64(interval)))
+
+	go func() {
+		timer := time.NewTimer(offset)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case ticks <- time.Now():
+			default:
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return ticks
+}
+
+// PrintFleetStats writes a fleet summary to stdout
+func PrintFleetStats(stats *FleetStats) {
+	fmt.Printf("Agents:     %d (%d failed to start)\n", stats.Agents, stats.Failed)
+	fmt.Printf("Elapsed:    %s\n", stats.Elapsed.Round(time.Millisecond))
+	fmt.Printf("%-10s %8s %8s %7s %10s %10s %10s %10s %10s\n",
+		"OPERATION", "CALLS", "ERRORS", "ERR%", "RATE/S", "P50", "P90", "P99", "MAX")
+	for _, op := range stats.Operations {
+		rate := 0.0
+		if stats.Elapsed > 0 {
+			rate = float64(op.Calls) / stats.Elapsed.Seconds()
+		}
+		fmt.Printf("%-10s %8d %8d %6.2f%% %10.1f %10s %10s %10s %10s\n",
+			op.Operation, op.Calls, op.Errors, op.ErrorRate()*100, rate,
+			formatLatency(op.P50), formatLatency(op.P90), formatLatency(op.P99), formatLatency(op.Max))
+	}
+}
+
+func formatLatency(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(10 * time.Microsecond).String()
+}
@@ -0,0 +1,102 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	// ms returns 1ms..n ms in order
+	ms := func(n int) []time.Duration {
+		sorted := make([]time.Duration, n)
+		for i := range sorted {
+			sorted[i] = time.Duration(i+1) * time.Millisecond
+		}
+		return sorted
+	}
+
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{name: "single", sorted: ms(1), p: 0.99, want: 1 * time.Millisecond},
+		{name: "median of even", sorted: ms(4), p: 0.50, want: 2 * time.Millisecond},
+		{name: "median of odd", sorted: ms(7), p: 0.50, want: 4 * time.Millisecond},
+		{name: "p90 of 7 rounds up", sorted: ms(7), p: 0.90, want: 7 * time.Millisecond},
+		{name: "p90 of 10", sorted: ms(10), p: 0.90, want: 9 * time.Millisecond},
+		{name: "p99 of 10", sorted: ms(10), p: 0.99, want: 10 * time.Millisecond},
+		{name: "p50 of 100", sorted: ms(100), p: 0.50, want: 50 * time.Millisecond},
+		{name: "p99 of 100", sorted: ms(100), p: 0.99, want: 99 * time.Millisecond},
+		{name: "p99 of 1000", sorted: ms(1000), p: 0.99, want: 990 * time.Millisecond},
+		{name: "zero", sorted: ms(5), p: 0, want: 1 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("percentile(%d samples, %v) = %v, want %v", len(tt.sorted), tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyRecorder(t *testing.T) {
+	r := newLatencyRecorder()
+
+	// 1..100ms heartbeats recorded in a seeded random order
+	latencies := rand.New(rand.NewSource(3)).Perm(100)
+	for _, i := range latencies {
+		r.latencies[OperationHeartbeat] = append(r.latencies[OperationHeartbeat], time.Duration(i+1)*time.Millisecond)
+	}
+	r.errors[OperationHeartbeat] = 25
+	r.errors[OperationRegister] = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := r.observe(ctx, OperationConfig, func() error { return nil }); err != nil {
+		t.Fatalf("observe() error = %v", err)
+	}
+	if err := r.observe(ctx, OperationConfig, func() error { return errors.New("503") }); err == nil {
+		t.Fatal("observe() dropped the call's error")
+	}
+	cancel()
+	// Calls cut short by the end of the run are neither latencies nor errors
+	r.observe(ctx, OperationConfig, func() error { return context.Canceled })
+
+	stats := r.summarise()
+	if len(stats) != len(fleetOperations) {
+		t.Fatalf("summarise() = %d operations, want %d", len(stats), len(fleetOperations))
+	}
+	byOperation := map[string]OperationStats{}
+	for i, s := range stats {
+		if s.Operation != fleetOperations[i] {
+			t.Errorf("operation %d = %s, want %s", i, s.Operation, fleetOperations[i])
+		}
+		byOperation[s.Operation] = s
+	}
+
+	heartbeat := byOperation[OperationHeartbeat]
+	want := OperationStats{Operation: OperationHeartbeat, Calls: 125, Errors: 25, P50: 50 * time.Millisecond, P90: 90 * time.Millisecond, P99: 99 * time.Millisecond, Max: 100 * time.Millisecond}
+	if heartbeat != want {
+		t.Errorf("heartbeat = %+v, want %+v", heartbeat, want)
+	}
+	if rate := heartbeat.ErrorRate(); rate != 0.2 {
+		t.Errorf("heartbeat error rate = %v, want 0.2", rate)
+	}
+
+	register := byOperation[OperationRegister]
+	if register.Calls != 2 || register.Errors != 2 || register.P50 != 0 || register.ErrorRate() != 1 {
+		t.Errorf("register = %+v, want 2 failed calls without latencies", register)
+	}
+	config := byOperation[OperationConfig]
+	if config.Calls != 2 || config.Errors != 1 {
+		t.Errorf("config = %d calls, %d errors, want 2 and 1", config.Calls, config.Errors)
+	}
+	if report := byOperation[OperationReport]; report.Calls != 0 || report.ErrorRate() != 0 {
+		t.Errorf("report = %+v, want no calls", report)
+	}
+}
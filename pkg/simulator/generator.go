@@ -0,0 +1,301 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+)
+
+// Supported waveforms
+const (
+	WaveformSine       = "sine"
+	WaveformRandomWalk = "random_walk"
+	WaveformStep       = "step"
+	WaveformSawtooth   = "sawtooth"
+)
+
+// Anomaly kinds injected by the generator outside of scenario faults
+const (
+	AnomalySpike = "spike"
+)
+
+// SensorGroup describes Count virtual sensors of one type sharing a waveform
+type SensorGroup struct {
+	Type      string        `yaml:"type"`
+	Count     int           `yaml:"count"`
+	Waveform  string        `yaml:"waveform"`
+	Baseline  float64       `yaml:"baseline"`
+	Amplitude float64       `yaml:"amplitude"`
+	Period    time.Duration `yaml:"period"`
+	Noise     float64       `yaml:"noise"` // standard deviation of gaussian noise
+}
+
+// GeneratorOptions describes the synthetic signal and the faults injected into it
+type GeneratorOptions struct {
+	Groups           []SensorGroup
+	AnomalyRate      float64 // probability that a sample is a random spike anomaly
+	AnomalyMagnitude float64 // random spike offset as a multiple of the amplitude
+	Faults           []Fault
+	Seed             int64
+}
+
+// Sample is one delivery of a generated reading with its ground-truth label
+type Sample struct {
+	Data        *model.IncomingStreamData
+	Anomaly     bool
+	AnomalyKind string
+	Faults      []string
+	Duplicate   bool // repeated delivery of a reading already sent
+}
+
+// Label is the ground truth written for every reading, keyed by the data UUID
+type Label struct {
+	UUID        string    `json:"uuid"`
+	Source      string    `json:"source"`
+	SensorType  string    `json:"sensor_type"`
+	Value       float64   `json:"value"`
+	Anomaly     bool      `json:"anomaly"`
+	AnomalyKind string    `json:"anomaly_kind,omitempty"`
+	Faults      []string  `json:"faults,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// sensor holds the per-sensor waveform and fault state
+type sensor struct {
+	source string
+	group  *SensorGroup
+	phase  float64
+	walk   float64
+	stuck  map[int]float64 // value held by each active stuck fault
+	held   *Sample         // sample delayed by an out-of-order fault
+}
+
+// Generator produces samples round-robin across its virtual sensors
+type Generator struct {
+	options GeneratorOptions
+	sensors []*sensor
+	rand    *rand.Rand
+	start   time.Time
+	next    int
+}
+
+// NewGenerator creates a Generator with the sensors of every group
+func NewGenerator(options GeneratorOptions) (*Generator, error) {
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	random := rand.New(rand.NewSource(seed))
+
+	var sensors []*sensor
+	for i := range options.Groups {
+		group := &options.Groups[i]
+		switch group.Waveform {
+		case WaveformSine, WaveformRandomWalk, WaveformStep, WaveformSawtooth:
+		case "":
+			group.Waveform = WaveformSine
+		default:
+			return nil, fmt.Errorf("unknown waveform: %s", group.Waveform)
+		}
+		if group.Period <= 0 {
+			group.Period = time.Minute
+		}
+
+		for j := 0; j < group.Count; j++ {
+			sensors = append(sensors, &sensor{
+				source: fmt.Sprintf("sim-%s-%d", group.Type, j),
+				group:  group,
+				phase:  random.Float64() * 2 * math.Pi,
+				walk:   group.Baseline,
+				stuck:  map[int]float64{},
+			})
+		}
+	}
+	if len(sensors) == 0 {
+		return nil, fmt.Errorf("at least one sensor is required")
+	}
+
+	for _, fault := range options.Faults {
+		if err := fault.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Generator{
+		options: options,
+		sensors: sensors,
+		rand:    random,
+		start:   time.Now(),
+	}, nil
+}
+
+// Sensors returns the number of virtual sensors
+func (g *Generator) Sensors() int {
+	return len(g.sensors)
+}
+
+// Next takes a reading from the next sensor and returns the deliveries it produces.
+// Faults can turn one reading into none (dropout), two (duplicate) or delay it
+// behind the sensor's next reading (out of order).
+func (g *Generator) Next(now time.Time) []*Sample {
+	s := g.sensors[g.next]
+	g.next = (g.next + 1) % len(g.sensors)
+
+	elapsed := now.Sub(g.start)
+	value := g.waveform(s, elapsed.Seconds())
+	if s.group.Noise > 0 {
+		value += g.rand.NormFloat64() * s.group.Noise
+	}
+
+	sample := &Sample{}
+	if g.options.AnomalyRate > 0 && g.rand.Float64() < g.options.AnomalyRate {
+		value += g.spikeOffset(g.options.AnomalyMagnitude * math.Max(s.group.Amplitude, 1))
+		sample.Anomaly = true
+		sample.AnomalyKind = AnomalySpike
+	}
+
+	timestamp := now
+	var rawPayload []byte
+	duplicate, delay := false, false
+
+	for i, fault := range g.options.Faults {
+		if !fault.active(elapsed) || !fault.matches(s) {
+			delete(s.stuck, i)
+			continue
+		}
+		if fault.Probability > 0 && g.rand.Float64() >= fault.Probability {
+			continue
+		}
+
+		switch fault.Kind {
+		case FaultDropout:
+			return nil
+		case FaultDrift:
+			value += fault.Magnitude * fault.progress(elapsed)
+		case FaultSpike:
+			value += g.spikeOffset(fault.Magnitude)
+		case FaultStuck:
+			if _, ok := s.stuck[i]; !ok {
+				s.stuck[i] = value
+			}
+			value = s.stuck[i]
+		case FaultClockSkew:
+			timestamp = timestamp.Add(time.Duration(fault.Magnitude * float64(time.Second)))
+		case FaultDuplicate:
+			duplicate = true
+		case FaultOutOfOrder:
+			delay = true
+		case FaultMalformed:
+			rawPayload = g.malformedPayload()
+		}
+
+		sample.Faults = append(sample.Faults, fault.Kind)
+		if fault.isAnomaly() {
+			sample.Anomaly = true
+			sample.AnomalyKind = fault.Kind
+		}
+	}
+
+	sample.Data = &model.IncomingStreamData{
+		UUID:       uuid.New().String(),
+		Source:     s.source,
+		SensorType: s.group.Type,
+		Value:      value,
+		Timestamp:  timestamp,
+		RawPayload: rawPayload,
+	}
+
+	var deliveries []*Sample
+	if delay {
+		if s.held != nil {
+			deliveries = append(deliveries, s.held)
+		}
+		s.held = sample
+		return deliveries
+	}
+
+	deliveries = append(deliveries, sample)
+	if duplicate {
+		copied := *sample
+		copied.Duplicate = true
+		deliveries = append(deliveries, &copied)
+	}
+	if s.held != nil {
+		deliveries = append(deliveries, s.held)
+		s.held = nil
+	}
+	return deliveries
+}
+
+// Drain returns samples still delayed by out-of-order faults
+func (g *Generator) Drain() []*Sample {
+	var deliveries []*Sample
+	for _, s := range g.sensors {
+		if s.held != nil {
+			deliveries = append(deliveries, s.held)
+			s.held = nil
+		}
+	}
+	return deliveries
+}
+
+// waveform returns the noiseless value of a sensor at t seconds since the start
+func (g *Generator) waveform(s *sensor, t float64) float64 {
+	group := s.group
+	position := t/group.Period.Seconds() + s.phase/(2*math.Pi)
+
+	switch group.Waveform {
+	case WaveformRandomWalk:
+		s.walk += g.rand.NormFloat64() * group.Amplitude * 0.05
+		return s.walk
+	case WaveformStep:
+		if int64(math.Floor(position))%2 == 0 {
+			return group.Baseline
+		}
+		return group.Baseline + group.Amplitude
+	case WaveformSawtooth:
+		return group.Baseline + group.Amplitude*(2*(position-math.Floor(position))-1)
+	default:
+		return group.Baseline + group.Amplitude*math.Sin(2*math.Pi*position)
+	}
+}
+
+// spikeOffset returns magnitude with a random sign
+func (g *Generator) spikeOffset(magnitude float64) float64 {
+	if g.rand.Intn(2) == 0 {
+		return -magnitude
+	}
+	return magnitude
+}
+
+// malformedPayload returns one of several kinds of broken raw payload
+func (g *Generator) malformedPayload() []byte {
+	switch g.rand.Intn(3) {
+	case 0:
+		return []byte(`{"value": 12.`)
+	case 1:
+		return []byte(`{"value": "NaN", "unit": }`)
+	default:
+		payload := make([]byte, 16)
+		g.rand.Read(payload)
+		return payload
+	}
+}
+
+// Label returns the ground truth for a sample
+func (s *Sample) Label() Label {
+	return Label{
+		UUID:        s.Data.UUID,
+		Source:      s.Data.Source,
+		SensorType:  s.Data.SensorType,
+		Value:       s.Data.Value,
+		Anomaly:     s.Anomaly,
+		AnomalyKind: s.AnomalyKind,
+		Faults:      s.Faults,
+		Timestamp:   s.Data.Timestamp,
+	}
+}
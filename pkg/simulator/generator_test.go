@@ -0,0 +1,164 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newTestGenerator returns a seeded generator whose sensors start at phase 0 at testStart
+func newTestGenerator(t *testing.T, options GeneratorOptions) *Generator {
+	t.Helper()
+	if options.Seed == 0 {
+		options.Seed = 42
+	}
+	g, err := NewGenerator(options)
+	if err != nil {
+		t.Fatalf("NewGenerator() error = %v", err)
+	}
+	g.start = testStart
+	for _, s := range g.sensors {
+		s.phase = 0
+	}
+	return g
+}
+
+// values takes one reading from a single-sensor generator at each offset
+func values(t *testing.T, g *Generator, offsets []time.Duration) []float64 {
+	t.Helper()
+	var got []float64
+	for _, offset := range offsets {
+		samples := g.Next(testStart.Add(offset))
+		if len(samples) != 1 {
+			t.Fatalf("at %v: %d deliveries, want 1", offset, len(samples))
+		}
+		got = append(got, samples[0].Data.Value)
+	}
+	return got
+}
+
+func TestWaveforms(t *testing.T) {
+	offsets := []time.Duration{0, 15 * time.Second, 30 * time.Second, 45 * time.Second, 60 * time.Second, 90 * time.Second}
+
+	tests := []struct {
+		name     string
+		waveform string
+		want     []float64
+	}{
+		{name: "sine", waveform: WaveformSine, want: []float64{20, 25, 20, 15, 20, 20}},
+		{name: "default is sine", want: []float64{20, 25, 20, 15, 20, 20}},
+		{name: "step", waveform: WaveformStep, want: []float64{20, 20, 20, 20, 25, 25}},
+		{name: "sawtooth", waveform: WaveformSawtooth, want: []float64{15, 17.5, 20, 22.5, 15, 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGenerator(t, GeneratorOptions{Groups: []SensorGroup{
+				{Type: "temperature", Count: 1, Waveform: tt.waveform, Baseline: 20, Amplitude: 5, Period: time.Minute},
+			}})
+
+			got := values(t, g, offsets)
+			for i := range tt.want {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Errorf("value at %v = %v, want %v", offsets[i], got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRandomWalk(t *testing.T) {
+	group := SensorGroup{Type: "pressure", Count: 1, Waveform: WaveformRandomWalk, Baseline: 100, Amplitude: 10}
+	offsets := make([]time.Duration, 200)
+	for i := range offsets {
+		offsets[i] = time.Duration(i) * time.Second
+	}
+
+	walk := func(seed int64) []float64 {
+		return values(t, newTestGenerator(t, GeneratorOptions{Groups: []SensorGroup{group}, Seed: seed}), offsets)
+	}
+	first, again, other := walk(7), walk(7), walk(8)
+
+	var moved bool
+	for i := range first {
+		if first[i] != again[i] {
+			t.Fatalf("step %d: %v and %v from the same seed", i, first[i], again[i])
+		}
+		step := first[i] - group.Baseline
+		if i > 0 {
+			step = first[i] - first[i-1]
+		}
+		// Each step is gaussian with a standard deviation of 5% of the amplitude
+		if math.Abs(step) > 6*group.Amplitude*0.05 {
+			t.Errorf("step %d moved %v", i, step)
+		}
+		moved = moved || first[i] != other[i]
+	}
+	if !moved {
+		t.Error("different seeds produced the same walk")
+	}
+}
+
+func TestNoise(t *testing.T) {
+	g := newTestGenerator(t, GeneratorOptions{Groups: []SensorGroup{
+		{Type: "humidity", Count: 1, Baseline: 50, Noise: 2},
+	}})
+
+	var sum, squares float64
+	const n = 5000
+	for i := 0; i < n; i++ {
+		value := g.Next(testStart.Add(time.Duration(i) * time.Second))[0].Data.Value
+		sum += value
+		squares += value * value
+	}
+	mean := sum / n
+	stddev := math.Sqrt(squares/n - mean*mean)
+	if math.Abs(mean-50) > 0.2 || math.Abs(stddev-2) > 0.2 {
+		t.Errorf("noise mean %v and stddev %v, want 50 and 2", mean, stddev)
+	}
+}
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     GeneratorOptions
+		wantErr     bool
+		wantSensors int
+		wantSources []string
+	}{
+		{
+			name: "groups",
+			options: GeneratorOptions{Groups: []SensorGroup{
+				{Type: "temperature", Count: 2},
+				{Type: "pressure", Count: 1, Waveform: WaveformStep},
+			}},
+			wantSensors: 3,
+			wantSources: []string{"sim-temperature-0", "sim-temperature-1", "sim-pressure-0"},
+		},
+		{name: "no sensors", options: GeneratorOptions{Groups: []SensorGroup{{Type: "temperature"}}}, wantErr: true},
+		{name: "unknown waveform", options: GeneratorOptions{Groups: []SensorGroup{{Type: "temperature", Count: 1, Waveform: "square"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGenerator(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewGenerator() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if g.Sensors() != tt.wantSensors {
+				t.Errorf("Sensors() = %d, want %d", g.Sensors(), tt.wantSensors)
+			}
+			// Readings are taken round-robin across the sensors
+			for i, want := range tt.wantSources {
+				if source := g.Next(testStart)[0].Data.Source; source != want {
+					t.Errorf("reading %d from %s, want %s", i, source, want)
+				}
+			}
+		})
+	}
+}
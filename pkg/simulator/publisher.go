@@ -0,0 +1,227 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	pb "github.com/ryo-arima/circulator/pkg/agent/gengrpc"
+	"github.com/ryo-arima/circulator/pkg/config"
+	"github.com/ryo-arima/circulator/pkg/entity/codec"
+	"github.com/ryo-arima/circulator/pkg/entity/model"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Publish targets
+const (
+	TargetPulsar = "pulsar"
+	TargetGRPC   = "grpc"
+)
+
+// Publisher delivers generated samples to the pipeline
+type Publisher interface {
+	Publish(ctx context.Context, data *model.IncomingStreamData) error
+	// Results returns processing results when the target reports them, otherwise nil
+	Results() <-chan *model.ProcessedStreamData
+	Close() error
+}
+
+// NewPublisher creates the publisher for the given target
+func NewPublisher(conf config.BaseConfig, target, agentAddr string, stats *Stats) (Publisher, error) {
+	switch target {
+	case TargetPulsar:
+		return newPulsarPublisher(conf, stats)
+	case TargetGRPC:
+		return newGRPCPublisher(conf, agentAddr)
+	default:
+		return nil, fmt.Errorf("unknown target: %s", target)
+	}
+}
+
+// pulsarPublisher sends samples to the external sensor data topic
+type pulsarPublisher struct {
+	config   config.BaseConfig
+	client   pulsar.Client
+	producer pulsar.Producer
+	codec    codec.Codec
+	topic    string
+	stats    *Stats
+}
+
+func newPulsarPublisher(conf config.BaseConfig, stats *Stats) (Publisher, error) {
+	topic := conf.YamlConfig.Pulsar.Topics.ExternalSensorData
+
+	client, err := config.NewPulsarClient(conf.YamlConfig)
+	if err != nil {
+		conf.Logger.ERROR(config.SIMERR, "Failed to create Pulsar client", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	messageCodec := codec.NewCodec(conf, "simulator")
+	producer, err := client.CreateProducer(pulsar.ProducerOptions{
+		Topic:  topic,
+		Schema: messageCodec.Schema(topic),
+	})
+	if err != nil {
+		conf.Logger.ERROR(config.SIMERR, "Failed to create Pulsar producer", map[string]interface{}{
+			"error": err.Error(),
+			"topic": topic,
+		})
+		client.Close()
+		return nil, err
+	}
+
+	conf.Logger.INFO(config.SIMPUB, "Simulator publishing to Pulsar", map[string]interface{}{
+		"topic": topic,
+	})
+
+	return &pulsarPublisher{
+		config:   conf,
+		client:   client,
+		producer: producer,
+		codec:    messageCodec,
+		topic:    topic,
+		stats:    stats,
+	}, nil
+}
+
+// Publish sends asynchronously so the target rate is not bounded by broker round trips.
+// Failed sends are counted as errors when the broker reports them.
+func (p *pulsarPublisher) Publish(ctx context.Context, data *model.IncomingStreamData) error {
+	msg, err := p.codec.Encode(p.topic, model.MessageTypeIncomingStreamData, "", data)
+	if err != nil {
+		return err
+	}
+	msg.Key = data.Source
+	msg.EventTime = data.Timestamp
+
+	p.producer.SendAsync(ctx, msg, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+		if err != nil {
+			atomic.AddInt64(&p.stats.Errors, 1)
+			p.config.Logger.DEBUG(config.SIMERR, "Simulated message send failed", map[string]interface{}{
+				"error": err.Error(),
+			})
+		}
+	})
+	return nil
+}
+
+func (p *pulsarPublisher) Results() <-chan *model.ProcessedStreamData {
+	return nil
+}
+
+func (p *pulsarPublisher) Close() error {
+	err := p.producer.Flush()
+	p.producer.Close()
+	p.client.Close()
+	return err
+}
+
+// grpcPublisher streams samples to an agent's StreamService and collects its results
+type grpcPublisher struct {
+	config  config.BaseConfig
+	conn    *grpc.ClientConn
+	stream  pb.StreamService_ProcessStreamClient
+	results chan *model.ProcessedStreamData
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func newGRPCPublisher(conf config.BaseConfig, agentAddr string) (Publisher, error) {
+	conn, err := grpc.NewClient(agentAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		conf.Logger.ERROR(config.SIMERR, "Failed to connect to agent", map[string]interface{}{
+			"error":      err.Error(),
+			"agent_addr": agentAddr,
+		})
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := pb.NewStreamServiceClient(conn).ProcessStream(ctx)
+	if err != nil {
+		conf.Logger.ERROR(config.SIMERR, "Failed to open agent stream", map[string]interface{}{
+			"error":      err.Error(),
+			"agent_addr": agentAddr,
+		})
+		cancel()
+		conn.Close()
+		return nil, err
+	}
+
+	conf.Logger.INFO(config.SIMPUB, "Simulator streaming to agent", map[string]interface{}{
+		"agent_addr": agentAddr,
+	})
+
+	p := &grpcPublisher{
+		config:  conf,
+		conn:    conn,
+		stream:  stream,
+		results: make(chan *model.ProcessedStreamData, 1024),
+		cancel:  cancel,
+	}
+	p.wg.Add(1)
+	go p.receive()
+	return p, nil
+}
+
+func (p *grpcPublisher) Publish(ctx context.Context, data *model.IncomingStreamData) error {
+	return p.stream.Send(&pb.IncomingStreamData{
+		Uuid:       data.UUID,
+		Source:     data.Source,
+		SensorType: data.SensorType,
+		Value:      data.Value,
+		Timestamp:  timestamppb.New(data.Timestamp),
+		RawPayload: data.RawPayload,
+	})
+}
+
+func (p *grpcPublisher) Results() <-chan *model.ProcessedStreamData {
+	return p.results
+}
+
+// receive forwards agent results until the stream ends
+func (p *grpcPublisher) receive() {
+	defer p.wg.Done()
+	defer close(p.results)
+
+	for {
+		out, err := p.stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			p.config.Logger.WARN(config.SIMERR, "Agent stream closed with error", map[string]interface{}{
+				"error": err.Error(),
+			})
+			return
+		}
+		p.results <- &model.ProcessedStreamData{
+			UUID:           out.GetUuid(),
+			AgentUUID:      out.GetAgentUuid(),
+			OriginalValue:  out.GetOriginalValue(),
+			ProcessedValue: out.GetProcessedValue(),
+			Anomaly:        out.GetAnomaly(),
+			Confidence:     out.GetConfidence(),
+			ProcessingTime: out.GetProcessingTime(),
+			Timestamp:      out.GetTimestamp().AsTime(),
+			SensorType:     out.GetSensorType(),
+		}
+	}
+}
+
+// Close half-closes the stream and waits for the agent to answer what was already sent
+func (p *grpcPublisher) Close() error {
+	err := p.stream.CloseSend()
+	p.wg.Wait()
+	p.cancel()
+	p.conn.Close()
+	return err
+}
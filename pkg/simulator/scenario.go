@@ -0,0 +1,139 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fault kinds available in scenario events
+const (
+	FaultDrift      = "drift"
+	FaultSpike      = "spike"
+	FaultDropout    = "dropout"
+	FaultStuck      = "stuck"
+	FaultClockSkew  = "clock_skew"
+	FaultDuplicate  = "duplicate"
+	FaultOutOfOrder = "out_of_order"
+	FaultMalformed  = "malformed"
+)
+
+// Scenario is a timeline of sensor behavior loaded from a YAML file
+type Scenario struct {
+	Name             string        `yaml:"name"`
+	Duration         time.Duration `yaml:"duration"`
+	Rate             float64       `yaml:"rate"`
+	Seed             int64         `yaml:"seed"`
+	AnomalyRate      float64       `yaml:"anomaly_rate"`
+	AnomalyMagnitude float64       `yaml:"anomaly_magnitude"`
+	Sensors          []SensorGroup `yaml:"sensors"`
+	Events           []Fault       `yaml:"events"`
+}
+
+// Fault is a scenario event applied to matching sensors between At and At+Duration
+type Fault struct {
+	Kind        string        `yaml:"fault"`
+	At          time.Duration `yaml:"at"`
+	Duration    time.Duration `yaml:"duration"` // 0 lasts until the end of the run
+	SensorType  string        `yaml:"sensor_type"`
+	Sources     []string      `yaml:"sources"`
+	Magnitude   float64       `yaml:"magnitude"`   // drift total offset, spike offset or clock skew seconds
+	Probability float64       `yaml:"probability"` // chance per reading, 0 applies to every reading
+	Anomaly     *bool         `yaml:"anomaly"`     // overrides whether affected readings count as anomalies
+}
+
+// LoadScenario reads and validates a scenario file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	var scenario Scenario
+	if err := yaml.Unmarshal(data, &scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if len(scenario.Sensors) == 0 {
+		return nil, fmt.Errorf("scenario %s defines no sensors", path)
+	}
+	for _, event := range scenario.Events {
+		if err := event.validate(); err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", path, err)
+		}
+	}
+	return &scenario, nil
+}
+
+// Apply overrides run options with the scenario's settings
+func (s *Scenario) Apply(options *Options) {
+	options.Generator.Groups = s.Sensors
+	options.Generator.Faults = s.Events
+	options.Generator.AnomalyRate = s.AnomalyRate
+	options.Generator.AnomalyMagnitude = s.AnomalyMagnitude
+	if s.Seed != 0 {
+		options.Generator.Seed = s.Seed
+	}
+	if s.Rate > 0 {
+		options.Rate = s.Rate
+	}
+	if s.Duration > 0 {
+		options.Duration = s.Duration
+	}
+}
+
+func (f Fault) validate() error {
+	switch f.Kind {
+	case FaultDrift, FaultSpike, FaultDropout, FaultStuck, FaultClockSkew, FaultDuplicate, FaultOutOfOrder, FaultMalformed:
+		return nil
+	default:
+		return fmt.Errorf("unknown fault: %q", f.Kind)
+	}
+}
+
+// active reports whether the fault applies at the given time since the start of the run
+func (f Fault) active(elapsed time.Duration) bool {
+	if elapsed < f.At {
+		return false
+	}
+	return f.Duration <= 0 || elapsed < f.At+f.Duration
+}
+
+// matches reports whether the fault targets the sensor
+func (f Fault) matches(s *sensor) bool {
+	if f.SensorType != "" && f.SensorType != s.group.Type {
+		return false
+	}
+	if len(f.Sources) == 0 {
+		return true
+	}
+	for _, source := range f.Sources {
+		if source == s.source {
+			return true
+		}
+	}
+	return false
+}
+
+// progress returns how far through its window the fault is, from 0 to 1
+func (f Fault) progress(elapsed time.Duration) float64 {
+	if f.Duration <= 0 {
+		return 1
+	}
+	return float64(elapsed-f.At) / float64(f.Duration)
+}
+
+// isAnomaly reports whether readings affected by the fault count as anomalies.
+// Value faults do by default; delivery faults do not.
+func (f Fault) isAnomaly() bool {
+	if f.Anomaly != nil {
+		return *f.Anomaly
+	}
+	switch f.Kind {
+	case FaultDrift, FaultSpike, FaultStuck:
+		return true
+	default:
+		return false
+	}
+}
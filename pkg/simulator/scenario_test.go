@@ -0,0 +1,322 @@
+package simulator
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// step is one reading taken at an offset from the start with the deliveries it produced
+type step struct {
+	offset  time.Duration
+	samples []*Sample
+}
+
+func TestFaults(t *testing.T) {
+	boolPtr := func(b bool) *bool { return &b }
+	// flat reads a constant 10; ramp rises from 5 to 15 over each minute
+	flat := SensorGroup{Type: "temperature", Count: 1, Baseline: 10}
+	ramp := SensorGroup{Type: "temperature", Count: 1, Waveform: WaveformSawtooth, Baseline: 10, Amplitude: 5, Period: time.Minute}
+
+	tests := []struct {
+		name    string
+		group   SensorGroup
+		fault   Fault
+		offsets []time.Duration
+		check   func(t *testing.T, steps []step, g *Generator)
+	}{
+		{
+			name:    "dropout",
+			group:   flat,
+			fault:   Fault{Kind: FaultDropout, At: 10 * time.Second, Duration: 10 * time.Second},
+			offsets: []time.Duration{0, 10 * time.Second, 19 * time.Second, 20 * time.Second},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				for i, want := range []int{1, 0, 0, 1} {
+					if len(steps[i].samples) != want {
+						t.Errorf("at %v: %d deliveries, want %d", steps[i].offset, len(steps[i].samples), want)
+					}
+				}
+			},
+		},
+		{
+			name:    "drift",
+			group:   flat,
+			fault:   Fault{Kind: FaultDrift, Duration: 10 * time.Second, Magnitude: 4},
+			offsets: []time.Duration{0, 5 * time.Second, 10 * time.Second},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				wantLabels(t, steps[0].samples[0], 10, true, FaultDrift, []string{FaultDrift})
+				wantLabels(t, steps[1].samples[0], 12, true, FaultDrift, []string{FaultDrift})
+				wantLabels(t, steps[2].samples[0], 10, false, "", nil)
+			},
+		},
+		{
+			name:    "spike",
+			group:   flat,
+			fault:   Fault{Kind: FaultSpike, Magnitude: 3},
+			offsets: []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				signs := map[float64]bool{}
+				for _, s := range steps {
+					sample := s.samples[0]
+					if offset := sample.Data.Value - 10; math.Abs(offset) != 3 {
+						t.Errorf("at %v: spike offset %v, want ±3", s.offset, offset)
+					}
+					signs[math.Copysign(1, sample.Data.Value-10)] = true
+					if !sample.Anomaly || sample.AnomalyKind != FaultSpike {
+						t.Errorf("at %v: anomaly %v kind %q, want a spike anomaly", s.offset, sample.Anomaly, sample.AnomalyKind)
+					}
+				}
+				if len(signs) != 2 {
+					t.Errorf("spikes only went one way: %v", signs)
+				}
+			},
+		},
+		{
+			name:    "stuck",
+			group:   ramp,
+			fault:   Fault{Kind: FaultStuck, Duration: 30 * time.Second},
+			offsets: []time.Duration{0, 15 * time.Second, 29 * time.Second, 30 * time.Second},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				wantLabels(t, steps[0].samples[0], 5, true, FaultStuck, []string{FaultStuck})
+				wantLabels(t, steps[1].samples[0], 5, true, FaultStuck, []string{FaultStuck})
+				wantLabels(t, steps[2].samples[0], 5, true, FaultStuck, []string{FaultStuck})
+				wantLabels(t, steps[3].samples[0], 10, false, "", nil)
+			},
+		},
+		{
+			name:    "clock skew",
+			group:   flat,
+			fault:   Fault{Kind: FaultClockSkew, Magnitude: -2.5},
+			offsets: []time.Duration{time.Minute},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				sample := steps[0].samples[0]
+				wantLabels(t, sample, 10, false, "", []string{FaultClockSkew})
+				if want := testStart.Add(time.Minute - 2500*time.Millisecond); !sample.Data.Timestamp.Equal(want) {
+					t.Errorf("timestamp = %v, want %v", sample.Data.Timestamp, want)
+				}
+			},
+		},
+		{
+			name:    "duplicate",
+			group:   flat,
+			fault:   Fault{Kind: FaultDuplicate},
+			offsets: []time.Duration{0},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				samples := steps[0].samples
+				if len(samples) != 2 {
+					t.Fatalf("%d deliveries, want 2", len(samples))
+				}
+				if samples[0].Duplicate || !samples[1].Duplicate {
+					t.Errorf("duplicate flags = %v, %v", samples[0].Duplicate, samples[1].Duplicate)
+				}
+				if samples[0].Data != samples[1].Data {
+					t.Error("the duplicate is a different reading")
+				}
+				wantLabels(t, samples[0], 10, false, "", []string{FaultDuplicate})
+			},
+		},
+		{
+			name:    "out of order",
+			group:   flat,
+			fault:   Fault{Kind: FaultOutOfOrder, Duration: 2 * time.Second},
+			offsets: []time.Duration{0, time.Second, 2 * time.Second},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				if len(steps[0].samples) != 0 {
+					t.Fatalf("first reading delivered right away")
+				}
+				// Each delayed reading follows the sensor's next one
+				if len(steps[1].samples) != 1 || !steps[1].samples[0].Data.Timestamp.Equal(testStart) {
+					t.Fatalf("second step did not deliver the first reading: %v", steps[1].samples)
+				}
+				late := steps[2].samples
+				if len(late) != 2 || !late[0].Data.Timestamp.Equal(testStart.Add(2*time.Second)) || !late[1].Data.Timestamp.Equal(testStart.Add(time.Second)) {
+					t.Fatalf("third step delivered %d readings out of the expected order", len(late))
+				}
+				wantLabels(t, late[1], 10, false, "", []string{FaultOutOfOrder})
+				if drained := g.Drain(); len(drained) != 0 {
+					t.Errorf("Drain() = %d readings, want none", len(drained))
+				}
+			},
+		},
+		{
+			name:    "out of order until the end",
+			group:   flat,
+			fault:   Fault{Kind: FaultOutOfOrder},
+			offsets: []time.Duration{0},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				if drained := g.Drain(); len(drained) != 1 || !drained[0].Data.Timestamp.Equal(testStart) {
+					t.Errorf("Drain() did not return the held reading")
+				}
+			},
+		},
+		{
+			name:    "malformed",
+			group:   flat,
+			fault:   Fault{Kind: FaultMalformed},
+			offsets: []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second, 5 * time.Second},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				for _, s := range steps {
+					sample := s.samples[0]
+					if len(sample.Data.RawPayload) == 0 || json.Valid(sample.Data.RawPayload) {
+						t.Errorf("at %v: payload %q is not malformed", s.offset, sample.Data.RawPayload)
+					}
+					wantLabels(t, sample, 10, false, "", []string{FaultMalformed})
+				}
+			},
+		},
+		{
+			name:    "anomaly override",
+			group:   flat,
+			fault:   Fault{Kind: FaultClockSkew, Magnitude: 1, Anomaly: boolPtr(true)},
+			offsets: []time.Duration{0},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				wantLabels(t, steps[0].samples[0], 10, true, FaultClockSkew, []string{FaultClockSkew})
+			},
+		},
+		{
+			name:    "other sensor type",
+			group:   flat,
+			fault:   Fault{Kind: FaultDropout, SensorType: "pressure"},
+			offsets: []time.Duration{0},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				wantLabels(t, steps[0].samples[0], 10, false, "", nil)
+			},
+		},
+		{
+			name:    "other source",
+			group:   flat,
+			fault:   Fault{Kind: FaultDropout, Sources: []string{"sim-temperature-1"}},
+			offsets: []time.Duration{0},
+			check: func(t *testing.T, steps []step, g *Generator) {
+				wantLabels(t, steps[0].samples[0], 10, false, "", nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGenerator(t, GeneratorOptions{Groups: []SensorGroup{tt.group}, Faults: []Fault{tt.fault}})
+			var steps []step
+			for _, offset := range tt.offsets {
+				steps = append(steps, step{offset: offset, samples: g.Next(testStart.Add(offset))})
+			}
+			tt.check(t, steps, g)
+		})
+	}
+}
+
+func wantLabels(t *testing.T, sample *Sample, value float64, anomaly bool, kind string, faults []string) {
+	t.Helper()
+	label := sample.Label()
+	if math.Abs(label.Value-value) > 1e-9 || label.Anomaly != anomaly || label.AnomalyKind != kind || !reflect.DeepEqual(label.Faults, faults) {
+		t.Errorf("label = value %v anomaly %v kind %q faults %v, want %v %v %q %v",
+			label.Value, label.Anomaly, label.AnomalyKind, label.Faults, value, anomaly, kind, faults)
+	}
+}
+
+func TestFaultProbability(t *testing.T) {
+	g := newTestGenerator(t, GeneratorOptions{
+		Groups: []SensorGroup{{Type: "temperature", Count: 1, Baseline: 10}},
+		Faults: []Fault{{Kind: FaultDropout, Probability: 0.25}},
+	})
+
+	const n = 4000
+	dropped := 0
+	for i := 0; i < n; i++ {
+		if len(g.Next(testStart.Add(time.Duration(i)*time.Second))) == 0 {
+			dropped++
+		}
+	}
+	if rate := float64(dropped) / n; math.Abs(rate-0.25) > 0.03 {
+		t.Errorf("dropped %.3f of readings, want 0.25", rate)
+	}
+}
+
+func TestRandomAnomalies(t *testing.T) {
+	g := newTestGenerator(t, GeneratorOptions{
+		Groups:           []SensorGroup{{Type: "temperature", Count: 1, Baseline: 10, Amplitude: 0.5}},
+		AnomalyRate:      0.1,
+		AnomalyMagnitude: 4,
+	})
+
+	const n = 4000
+	anomalies := 0
+	for i := 0; i < n; i++ {
+		offset := time.Duration(i) * time.Second
+		sample := g.Next(testStart.Add(offset))[0]
+		// The sine stays within ±0.5; a spike moves it by 4 times the amplitude
+		deviation := math.Abs(sample.Data.Value - 10)
+		if sample.Anomaly != (deviation > 1) {
+			t.Fatalf("at %v: value %v labelled anomaly %v", offset, sample.Data.Value, sample.Anomaly)
+		}
+		if sample.Anomaly {
+			anomalies++
+			if sample.AnomalyKind != AnomalySpike {
+				t.Errorf("anomaly kind = %q", sample.AnomalyKind)
+			}
+		}
+	}
+	if rate := float64(anomalies) / n; math.Abs(rate-0.1) > 0.02 {
+		t.Errorf("anomaly rate %.3f, want 0.1", rate)
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			yaml: `
+name: drift
+duration: 2m
+rate: 20
+seed: 7
+sensors:
+  - type: temperature
+    count: 2
+    baseline: 20
+events:
+  - fault: drift
+    at: 30s
+    duration: 1m
+    magnitude: 5
+`,
+		},
+		{name: "no sensors", yaml: "name: empty\n", wantErr: true},
+		{name: "unknown fault", yaml: "sensors:\n  - type: temperature\n    count: 1\nevents:\n  - fault: flood\n", wantErr: true},
+		{name: "invalid yaml", yaml: "sensors: [", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scenario.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			scenario, err := LoadScenario(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadScenario() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var options Options
+			scenario.Apply(&options)
+			if options.Duration != 2*time.Minute || options.Rate != 20 || options.Generator.Seed != 7 {
+				t.Errorf("options = duration %v rate %v seed %d", options.Duration, options.Rate, options.Generator.Seed)
+			}
+			want := Fault{Kind: FaultDrift, At: 30 * time.Second, Duration: time.Minute, Magnitude: 5}
+			if len(options.Generator.Faults) != 1 || !reflect.DeepEqual(options.Generator.Faults[0], want) {
+				t.Errorf("faults = %+v, want %+v", options.Generator.Faults, want)
+			}
+		})
+	}
+}